@@ -1,18 +1,23 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/confirmation"
 	"github.com/y9mo/covidvax/domain"
 	"github.com/y9mo/covidvax/repository"
+	"github.com/y9mo/covidvax/waitlist"
 	"go.uber.org/zap"
 )
 
 type AppointmentsResponse struct {
 	Appointments []*domain.Appointment `json:"appointments,omitempty"`
-	Error        *ErrorResponse        `json:"error,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
 }
 
 type AppointmentResponse struct {
@@ -27,7 +32,11 @@ type AppointmentBookingResponse struct {
 
 type AppointmentsController struct {
 	appointmentsRepository        repository.Appointments
+	treatmentCentersRepository    repository.TreatmentCenters
 	appointmentBookingsRepository repository.AppointmentBookings
+	unitOfWork                    repository.UnitOfWork
+	confirmations                 *confirmation.Service
+	offers                        *waitlist.Service
 	logger                        *zap.Logger
 }
 
@@ -36,15 +45,22 @@ type inputAppointment struct {
 }
 
 func (t *inputAppointment) buildDomain() domain.Appointment {
-	return domain.Appointment{
+	appointment := domain.Appointment{
 		ID:                uuid.New(),
 		TreatmentCenterID: t.TreatmentCenterID,
-		StartTime:         t.StartTime,
 	}
+	t.updateModel(&appointment)
+	return appointment
 }
 
+// updateModel sets the start time and capacity of an appointment, an
+// appointment can't move to another treatment center
 func (t *inputAppointment) updateModel(appointment *domain.Appointment) {
-	// appointment.Name = t.Name
+	appointment.StartTime = t.StartTime
+	appointment.Capacity = t.Capacity
+	if appointment.Capacity == 0 {
+		appointment.Capacity = 1
+	}
 }
 
 type inputAppointmentBooking struct {
@@ -59,36 +75,145 @@ func (t *inputAppointmentBooking) buildDomain(appointmentID uuid.UUID) domain.Ap
 	}
 }
 
+// AppointmentSearchRequest are the query parameters of the appointment search,
+// from and to are days in UTC, to included, after and before are times of day
+// of the treatment centers formatted as 15:04, before excluded
+type AppointmentSearchRequest struct {
+	TreatmentCenterID string     `form:"treatment_center_id" binding:"omitempty,uuid"`
+	From              *time.Time `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	To                *time.Time `form:"to" time_format:"2006-01-02" time_utc:"1"`
+	After             string     `form:"after"`
+	Before            string     `form:"before"`
+}
+
+func (r *AppointmentSearchRequest) buildSearch() (domain.AppointmentSearch, error) {
+	search := domain.AppointmentSearch{From: r.From}
+	if r.TreatmentCenterID != "" {
+		id := uuid.MustParse(r.TreatmentCenterID)
+		search.TreatmentCenterID = &id
+	}
+	if r.To != nil {
+		to := r.To.AddDate(0, 0, 1)
+		search.To = &to
+	}
+	if r.After != "" {
+		after := domain.Clock(r.After)
+		search.After = &after
+	}
+	if r.Before != "" {
+		before := domain.Clock(r.Before)
+		search.Before = &before
+	}
+	return search, search.Validate()
+}
+
+// DeleteRequest are the query parameters of the deletion of an appointment or
+// a treatment center, the active bookings are only cancelled when asked to
+type DeleteRequest struct {
+	CancelBookings bool `form:"cancel_bookings"`
+}
+
+type RescheduleBookingRequest struct {
+	AppointmentID uuid.UUID `json:"appointment_id" binding:"required"`
+}
+
 func SetupAppointment(router gin.IRouter,
 	appointmentsRepository repository.Appointments,
+	treatmentCentersRepository repository.TreatmentCenters,
 	appointmentBookingsRepository repository.AppointmentBookings,
+	unitOfWork repository.UnitOfWork,
+	confirmations *confirmation.Service,
+	offers *waitlist.Service,
+	authenticator *Authenticator,
 	logger *zap.Logger) {
 	c := AppointmentsController{
 		appointmentsRepository:        appointmentsRepository,
+		treatmentCentersRepository:    treatmentCentersRepository,
 		appointmentBookingsRepository: appointmentBookingsRepository,
+		unitOfWork:                    unitOfWork,
+		confirmations:                 confirmations,
+		offers:                        offers,
 		logger:                        logger.With(zap.String("component", "AppointmentsController")),
 	}
 	g := router.Group(
 		"/appointments",
 	)
+	auth := authenticator.Authenticate()
 	g.GET("/", c.IndexEndpoint)
-	g.POST("/", c.CreateEndpoint)
+	g.POST("/", auth, RequireRole(RoleStaff, RoleAdmin), c.CreateEndpoint)
 	g.GET("/:appointment_id", c.GetEndpoint)
-	g.POST("/:appointment_id/bookings", c.AddBookingEndpoint)
+	g.PUT("/:appointment_id", auth, RequireRole(RoleStaff, RoleAdmin), c.UpdateEndpoint)
+	g.PATCH("/:appointment_id", auth, RequireRole(RoleStaff, RoleAdmin), c.PatchEndpoint)
+	g.DELETE("/:appointment_id", auth, RequireRole(RoleStaff, RoleAdmin), c.DeleteEndpoint)
+	g.POST("/:appointment_id/bookings", auth, RequireRole(RolePatient, RoleAdmin), c.AddBookingEndpoint)
+	g.DELETE("/:appointment_id/bookings/:booking_id", auth, RequireRole(RolePatient, RoleAdmin),
+		c.CancelBookingEndpoint)
+	g.POST("/:appointment_id/bookings/:booking_id/reschedule", auth, RequireRole(RolePatient, RoleAdmin),
+		c.RescheduleBookingEndpoint)
 }
 
 func extractAppointmentID(c *gin.Context) (id uuid.UUID, err error) {
 	return uuid.Parse(c.Param("appointment_id"))
 }
 
+func extractBookingID(c *gin.Context) (id uuid.UUID, err error) {
+	return uuid.Parse(c.Param("booking_id"))
+}
+
+// findBooking returns the booking of the path, it answers 404 when the booking
+// doesn't belong to the appointment of the path and 403 when it isn't the
+// booking of the authenticated patient
+func (v *AppointmentsController) findBooking(c *gin.Context) (*domain.AppointmentBooking, bool) {
+	appointmentID, err := extractAppointmentID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return nil, false
+	}
+	bookingID, err := extractBookingID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return nil, false
+	}
+	appointmentBooking, err := v.appointmentBookingsRepository.FindByID(c.Request.Context(), bookingID)
+	if err == nil && appointmentBooking.AppointmentID != appointmentID {
+		err = repository.ErrRecordNotFound
+	}
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, AppointmentBookingResponse{Error: r})
+		return nil, false
+	}
+	if !authorizePatient(c, appointmentBooking.PatientID) {
+		return nil, false
+	}
+	return appointmentBooking, true
+}
+
+// IndexEndpoint searches the upcoming appointments with a remaining seat
 func (v *AppointmentsController) IndexEndpoint(c *gin.Context) {
-	appointments, err := v.appointmentsRepository.AllAvailable()
+	var request AppointmentSearchRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentsResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	search, err := request.buildSearch()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentsResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentsResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	appointments, page, err := v.appointmentsRepository.Search(c.Request.Context(), search, pageRequest, time.Now())
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
-		c.JSON(code, AppointmentResponse{Error: r})
+		c.JSON(code, AppointmentsResponse{Error: r})
 		return
 	}
-	c.JSON(http.StatusOK, AppointmentsResponse{Appointments: appointments})
+	c.JSON(http.StatusOK, AppointmentsResponse{Appointments: appointments, PageResponse: newPageResponse(page)})
 }
 
 func (v *AppointmentsController) GetEndpoint(c *gin.Context) {
@@ -97,7 +222,7 @@ func (v *AppointmentsController) GetEndpoint(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, AppointmentResponse{Error: &ErrorResponse{Msg: err.Error()}})
 		return
 	}
-	appointment, err := v.appointmentsRepository.FindByID(appointmentID)
+	appointment, err := v.appointmentsRepository.FindByID(c.Request.Context(), appointmentID)
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, AppointmentResponse{Error: r})
@@ -112,24 +237,175 @@ func (v *AppointmentsController) CreateEndpoint(c *gin.Context) {
 		err   error
 	)
 	if err = c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, AppointmentResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		c.JSON(http.StatusBadRequest, AppointmentResponse{Error: bindingErrorResponse(err)})
 		return
 	}
 	appointment := input.buildDomain()
-	err = v.appointmentsRepository.Create(&appointment)
+	if !authorizeStaffOf(c, appointment.TreatmentCenterID) {
+		return
+	}
+	if !v.checkStartTime(c, &appointment) {
+		return
+	}
+	ctx := c.Request.Context()
+	var (
+		t       *domain.Appointment
+		offered []*domain.AppointmentBooking
+	)
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.Appointments().Create(ctx, &appointment); err != nil {
+			return err
+		}
+		offered = v.offers.In(r).Hold(ctx, appointment.ID)
+		t, err = r.Appointments().FindByID(ctx, appointment.ID)
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, AppointmentResponse{Error: r})
+		return
+	}
+	v.offers.Notify(ctx, offered)
+	c.JSON(http.StatusCreated, AppointmentResponse{Appointment: t})
+}
+
+// checkStartTime answers 400 naming the start time when an appointment doesn't
+// start in the future in the opening hours of its treatment center
+func (v *AppointmentsController) checkStartTime(c *gin.Context, appointment *domain.Appointment) bool {
+	treatmentCenter, err := v.treatmentCentersRepository.FindByID(c.Request.Context(), appointment.TreatmentCenterID)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, AppointmentResponse{Error: r})
+		return false
+	}
+	r, err := validateStartTime(appointment, treatmentCenter, time.Now())
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, AppointmentResponse{Error: r})
+		return false
+	}
+	if r != nil {
+		c.JSON(http.StatusBadRequest, AppointmentResponse{Error: r})
+		return false
+	}
+	return true
+}
+
+// findAppointment returns the appointment of the path, it answers 403 when the
+// authenticated staff isn't of its treatment center
+func (v *AppointmentsController) findAppointment(c *gin.Context) (*domain.Appointment, bool) {
+	id, err := extractAppointmentID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return nil, false
+	}
+	appointment, err := v.appointmentsRepository.FindByID(c.Request.Context(), id)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, AppointmentResponse{Error: r})
+		return nil, false
+	}
+	if !authorizeStaffOf(c, appointment.TreatmentCenterID) {
+		return nil, false
+	}
+	return appointment, true
+}
+
+// UpdateEndpoint replaces the start time and capacity of an appointment, an
+// appointment with bookings keeps its start time
+func (v *AppointmentsController) UpdateEndpoint(c *gin.Context) {
+	appointment, ok := v.findAppointment(c)
+	if !ok {
+		return
+	}
+
+	var input inputAppointment
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	v.update(c, appointment, &input)
+}
+
+// PatchEndpoint updates the start time and capacity of an appointment present
+// in a JSON merge patch, an appointment with bookings keeps its start time
+func (v *AppointmentsController) PatchEndpoint(c *gin.Context) {
+	appointment, ok := v.findAppointment(c)
+	if !ok {
+		return
+	}
+
+	var input inputAppointment
+	if err := bindMergePatch(c, appointment, &input); err != nil {
+		c.JSON(patchStatus(err), AppointmentResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	v.update(c, appointment, &input)
+}
+
+func (v *AppointmentsController) update(c *gin.Context, appointment *domain.Appointment, input *inputAppointment) {
+	startTime := appointment.StartTime
+	input.updateModel(appointment)
+	// an appointment which already took place keeps its capacity editable
+	if !appointment.StartTime.Equal(startTime) && !v.checkStartTime(c, appointment) {
+		return
+	}
+	ctx := c.Request.Context()
+	var (
+		t       *domain.Appointment
+		offered []*domain.AppointmentBooking
+	)
+	err := v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.Appointments().Update(ctx, appointment); err != nil {
+			return err
+		}
+		// a larger capacity frees seats for the waitlist
+		offered = v.offers.In(r).Hold(ctx, appointment.ID)
+		t, err = r.Appointments().FindByID(ctx, appointment.ID)
+		return err
+	})
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, AppointmentResponse{Error: r})
 		return
 	}
-	var t *domain.Appointment
-	t, err = v.appointmentsRepository.FindByID(appointment.ID)
+	v.offers.Notify(ctx, offered)
+	c.JSON(http.StatusOK, AppointmentResponse{Appointment: t})
+}
+
+// DeleteEndpoint deletes an appointment, with cancel_bookings its active bookings
+// are cancelled and their patients notified, otherwise they prevent the deletion
+func (v *AppointmentsController) DeleteEndpoint(c *gin.Context) {
+	appointment, ok := v.findAppointment(c)
+	if !ok {
+		return
+	}
+	var request DeleteRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	cancelled, err := v.appointmentsRepository.Delete(c.Request.Context(), appointment.ID, request.CancelBookings)
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, AppointmentResponse{Error: r})
 		return
 	}
-	c.JSON(http.StatusCreated, AppointmentResponse{Appointment: t})
+	notifyWithdrawn(c.Request.Context(), v.confirmations, cancelled, v.logger)
+	c.Status(http.StatusNoContent)
+}
+
+// notifyWithdrawn tells the patients of the bookings cancelled by their treatment
+// center, the bookings stay cancelled when the email can't be sent
+func notifyWithdrawn(ctx context.Context, confirmations *confirmation.Service, cancelled []*domain.PatientBooking,
+	logger *zap.Logger) {
+	for _, booking := range cancelled {
+		if err := confirmations.Withdrawn(ctx, booking); err != nil {
+			logger.Error("unable to notify withdrawn booking",
+				zap.Stringer("booking_id", booking.ID), zap.Error(err))
+		}
+	}
 }
 
 func (v *AppointmentsController) AddBookingEndpoint(c *gin.Context) {
@@ -141,25 +417,104 @@ func (v *AppointmentsController) AddBookingEndpoint(c *gin.Context) {
 
 	var input inputAppointmentBooking
 	if err = c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: bindingErrorResponse(err)})
 		return
 	}
 
 	appointmentBooking := input.buildDomain(appointmentID)
+	if !authorizePatient(c, appointmentBooking.PatientID) {
+		return
+	}
 	appointmentBooking.Status = domain.AwaitingConfirmation
-	err = v.appointmentBookingsRepository.Create(&appointmentBooking)
+	deadline := v.confirmations.Deadline()
+	appointmentBooking.ExpiresAt = &deadline
+	ctx := c.Request.Context()
+	var ab *domain.AppointmentBooking
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.AppointmentBookings().Create(ctx, &appointmentBooking); err != nil {
+			return err
+		}
+		ab, err = r.AppointmentBookings().FindByID(ctx, appointmentBooking.ID)
+		return err
+	})
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, AppointmentBookingResponse{Error: r})
 		return
 	}
 
-	var ab *domain.AppointmentBooking
-	ab, err = v.appointmentBookingsRepository.FindByID(appointmentBooking.ID)
+	// the booking stays awaiting confirmation if the email can't be sent
+	if err = v.confirmations.Request(ctx, ab); err != nil {
+		v.logger.Error("unable to request booking confirmation",
+			zap.Stringer("booking_id", ab.ID), zap.Error(err))
+	}
+	c.JSON(http.StatusCreated, AppointmentBookingResponse{AppointmentBooking: ab})
+}
+
+func (v *AppointmentsController) CancelBookingEndpoint(c *gin.Context) {
+	appointmentBooking, ok := v.findBooking(c)
+	if !ok {
+		return
+	}
+
+	// the seat goes to the waitlist along with the cancellation
+	ctx := c.Request.Context()
+	var offered []*domain.AppointmentBooking
+	err := v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		err = r.AppointmentBookings().Cancel(ctx, appointmentBooking.ID, domain.ReleaseReasonCancelled)
+		if err != nil {
+			return err
+		}
+		offered = v.offers.In(r).Hold(ctx, appointmentBooking.AppointmentID)
+		appointmentBooking, err = r.AppointmentBookings().FindByID(ctx, appointmentBooking.ID)
+		return err
+	})
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, AppointmentBookingResponse{Error: r})
 		return
 	}
-	c.JSON(http.StatusCreated, AppointmentBookingResponse{AppointmentBooking: ab})
+	v.offers.Notify(ctx, offered)
+	c.JSON(http.StatusOK, AppointmentBookingResponse{AppointmentBooking: appointmentBooking})
+}
+
+func (v *AppointmentsController) RescheduleBookingEndpoint(c *gin.Context) {
+	appointmentBooking, ok := v.findBooking(c)
+	if !ok {
+		return
+	}
+
+	var request RescheduleBookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var (
+		rescheduled *domain.AppointmentBooking
+		offered     []*domain.AppointmentBooking
+	)
+	err := v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		rescheduled, err = r.AppointmentBookings().Reschedule(ctx, appointmentBooking.ID, request.AppointmentID)
+		if err == nil && rescheduled.ID != appointmentBooking.ID {
+			offered = v.offers.In(r).Hold(ctx, appointmentBooking.AppointmentID)
+		}
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, AppointmentBookingResponse{Error: r})
+		return
+	}
+
+	v.offers.Notify(ctx, offered)
+	// the token sent for the previous booking can't confirm the new one
+	if rescheduled.ID != appointmentBooking.ID && rescheduled.Status == domain.AwaitingConfirmation {
+		if err = v.confirmations.Request(ctx, rescheduled); err != nil {
+			v.logger.Error("unable to request booking confirmation",
+				zap.Stringer("booking_id", rescheduled.ID), zap.Error(err))
+		}
+	}
+	c.JSON(http.StatusOK, AppointmentBookingResponse{AppointmentBooking: rescheduled})
 }
@@ -22,7 +22,7 @@ type AppointmentsApiIntegrationTestSuite struct {
 
 const validAppointmentJSON = `{
 	"treatment_center_id": "52b2edf2-a380-4436-9f98-b70f78f174ef",
-	"start_time": "2021-11-14T10:00:00Z"
+	"start_time": "2099-01-05T10:00:00Z"
 }`
 
 const validAppointmentBookingJSON = `{
@@ -30,30 +30,106 @@ const validAppointmentBookingJSON = `{
 }`
 
 func (suite *AppointmentsApiIntegrationTestSuite) TestAppointmentsIndexList() {
+	// the fixture appointments are in the past
 	apitest.New().Debug().
 		Handler(suite.Router).
 		Get("/v1/appointments/").
 		Expect(suite.T()).
 		Status(http.StatusOK).
-		Assert(jsonpath.Len(`$.appointments`, 7)).
-		Assert(jsonpath.Contains(`$.appointments[? @.id=="eecce415-2d4c-440d-ac90-9780a3bd3371"].treatment_center_id`,
-			"52b2edf2-a380-4436-9f98-b70f78f174ef")).
-		Assert(jsonpath.Contains(`$.appointments[? @.id=="5edac3af-6805-469e-ae94-f9610c09516a"].treatment_center_id`,
-			"10063726-d378-472c-9b50-22a48331635d")).
+		Assert(jsonpath.NotPresent(`$.appointments`)).
+		Assert(jsonpath.Equal(`$.total`, float64(0))).
+		End()
+
+	// the center opens on mondays from 08:00 to 12:00 and tuesdays from 14:00 to 16:00 in Paris
+	for _, startTime := range []string{"2099-01-05T08:00:00Z", "2099-01-05T10:00:00Z", "2099-01-06T13:00:00Z"} {
+		apitest.New().Debug().
+			Handler(suite.Router).
+			Post("/v1/appointments/").
+			Header("Authorization", suite.StaffBearer("32b2edf2-a380-4436-9f98-b70f78f1934d")).
+			JSON(fmt.Sprintf(`{"treatment_center_id": "32b2edf2-a380-4436-9f98-b70f78f1934d", "start_time": %q}`,
+				startTime)).
+			Expect(suite.T()).
+			Status(http.StatusCreated).
+			End()
+	}
+
+	var cursor string
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Get("/v1/appointments/").
+		Query("limit", "2").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.appointments`, 2)).
+		Assert(jsonpath.Equal(`$.appointments[0].start_time`, "2099-01-05T08:00:00Z")).
+		Assert(jsonpath.Equal(`$.appointments[1].start_time`, "2099-01-05T10:00:00Z")).
+		Assert(jsonpath.Equal(`$.total`, float64(3))).
+		Assert(testutils.Extract(`$.next_cursor`, &cursor)).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Get("/v1/appointments/").
+		Query("limit", "2").
+		Query("cursor", cursor).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.appointments`, 1)).
+		Assert(jsonpath.Equal(`$.appointments[0].start_time`, "2099-01-06T13:00:00Z")).
+		Assert(jsonpath.NotPresent(`$.next_cursor`)).
+		End()
+
+	// 08:00 UTC is 09:00 in Paris and 10:00 UTC 11:00
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Get("/v1/appointments/").
+		QueryParams(map[string]string{
+			"treatment_center_id": "32b2edf2-a380-4436-9f98-b70f78f1934d",
+			"from":                "2099-01-05",
+			"to":                  "2099-01-05",
+			"before":              "11:00",
+		}).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.appointments`, 1)).
+		Assert(jsonpath.Equal(`$.appointments[0].start_time`, "2099-01-05T08:00:00Z")).
+		Assert(jsonpath.Equal(`$.appointments[0].remaining_seats`, float64(1))).
 		End()
 }
 
+func (suite *AppointmentsApiIntegrationTestSuite) TestAppointmentsIndexListInvalidSearch() {
+	for _, query := range []map[string]string{
+		{"cursor": "nope"},
+		{"after": "14:00", "before": "12:00"},
+		{"treatment_center_id": "nope"},
+		{"limit": "1000"},
+		{"sort": "capacity"},
+		{"direction": "up"},
+	} {
+		apitest.New().Debug().
+			Handler(suite.Router).
+			Get("/v1/appointments/").
+			QueryParams(query).
+			Expect(suite.T()).
+			Status(http.StatusBadRequest).
+			Assert(jsonpath.Present(`$.error.message`)).
+			End()
+	}
+}
+
 func (suite *AppointmentsApiIntegrationTestSuite) TestCreateAppointment() {
 	var id string
 	apitest.New().Debug().
 		Handler(suite.Router).
 		Post("/v1/appointments/").
+		Header("Authorization", suite.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
 		JSON(validAppointmentJSON).
 		Expect(suite.T()).
 		Status(http.StatusCreated).
 		Assert(jsonpath.Present(`$.appointment.id`)).
 		Assert(testutils.Extract(`$.appointment.id`, &id)).
 		Assert(jsonpath.Equal(`$.appointment.treatment_center_id`, "52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		Assert(jsonpath.Equal(`$.appointment.capacity`, float64(1))).
 		Assert(jsonpath.Present(`$.appointment.created_at`)).
 		Assert(jsonpath.Present(`$.appointment.updated_at`)).
 		End()
@@ -73,6 +149,191 @@ func (suite *AppointmentsApiIntegrationTestSuite) TestCreateAppointment() {
 		End()
 }
 
+func (suite *AppointmentsApiIntegrationTestSuite) TestCreateAppointmentInvalidStartTime() {
+	tests := []struct {
+		name      string
+		startTime string
+		message   string
+	}{
+		{name: "Past", startTime: "2021-11-15T08:00:00Z", message: "must be in the future"},
+		{name: "ClosedDay", startTime: "2099-01-04T08:00:00Z",
+			message: "must be in the opening hours of the treatment center"},
+		// the 60 minutes slot would end after 12:00 in Paris
+		{name: "AfterClosing", startTime: "2099-01-05T10:30:00Z",
+			message: "must be in the opening hours of the treatment center"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		suite.Run(tc.name, func() {
+			apitest.New().Debug().
+				Handler(suite.Router).
+				Post("/v1/appointments/").
+				Header("Authorization", suite.StaffBearer("32b2edf2-a380-4436-9f98-b70f78f1934d")).
+				JSON(fmt.Sprintf(`{"treatment_center_id": "32b2edf2-a380-4436-9f98-b70f78f1934d", "start_time": %q}`,
+					tc.startTime)).
+				Expect(suite.T()).
+				Status(http.StatusBadRequest).
+				Assert(jsonpath.Equal(`$.error.fields[0].field`, "start_time")).
+				Assert(jsonpath.Equal(`$.error.fields[0].message`, tc.message)).
+				End()
+		})
+	}
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestPatchAppointment() {
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Patch("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371").
+		Header("Authorization", suite.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		Body(`{"capacity": 3}`).
+		ContentType("application/merge-patch+json").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment.capacity`, float64(3))).
+		Assert(jsonpath.Equal(`$.appointment.start_time`, "2021-11-13T08:00:00Z")).
+		Assert(jsonpath.Equal(`$.appointment.treatment_center_id`, "52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Patch("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371").
+		Header("Authorization", suite.StaffBearer("0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8")).
+		Body(`{"capacity": 2}`).
+		ContentType("application/merge-patch+json").
+		Expect(suite.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Patch("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371").
+		Header("Authorization", suite.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		Body(`{"capacity": -1}`).
+		ContentType("application/merge-patch+json").
+		Expect(suite.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.fields[0].field`, "capacity")).
+		Assert(jsonpath.Equal(`$.error.fields[0].message`, "must be at least 1")).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Patch("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371").
+		Header("Authorization", suite.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		Body(`{"start_time": "2021-11-13T08:30:00Z"}`).
+		ContentType("application/merge-patch+json").
+		Expect(suite.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.fields[0].field`, "start_time")).
+		Assert(jsonpath.Equal(`$.error.fields[0].message`, "must be in the future")).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestPatchBookedAppointment() {
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Patch("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350").
+		Header("Authorization", suite.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
+		Body(`{"start_time": "2099-01-05T10:00:00Z"}`).
+		ContentType("application/merge-patch+json").
+		Expect(suite.T()).
+		Status(http.StatusConflict).
+		Assert(jsonpath.Equal(`$.error.message`, "appointment has bookings")).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Patch("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350").
+		Header("Authorization", suite.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
+		Body(`{"capacity": 2}`).
+		ContentType("application/merge-patch+json").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment.capacity`, float64(2))).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestUpdateAppointment() {
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Put("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371").
+		Header("Authorization", suite.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(`{"start_time": "2099-01-05T10:00:00Z"}`).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment.start_time`, "2099-01-05T10:00:00Z")).
+		Assert(jsonpath.Equal(`$.appointment.capacity`, float64(1))).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Put("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371").
+		Header("Authorization", suite.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(`{"capacity": 2}`).
+		Expect(suite.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.fields[0].field`, "start_time")).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestDeleteAppointment() {
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Delete("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371").
+		Header("Authorization", suite.StaffBearer("0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8")).
+		Expect(suite.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Delete("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371").
+		Header("Authorization", suite.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		Expect(suite.T()).
+		Status(http.StatusNoContent).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Get("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371").
+		Expect(suite.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestDeleteBookedAppointment() {
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Delete("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350").
+		Header("Authorization", suite.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
+		Expect(suite.T()).
+		Status(http.StatusConflict).
+		Assert(jsonpath.Equal(`$.error.message`, "appointment has bookings")).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Delete("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350").
+		Header("Authorization", suite.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
+		Query("cancel_bookings", "true").
+		Expect(suite.T()).
+		Status(http.StatusNoContent).
+		End()
+
+	// the cancelled booking still tells where it was
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Get("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884/bookings").
+		Header("Authorization", suite.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.bookings[0].status`, "cancelled")).
+		Assert(jsonpath.Equal(`$.bookings[0].release_reason`, "cancelled by the treatment center")).
+		Assert(jsonpath.Equal(`$.bookings[0].start_time`, "2021-11-13T18:00:00Z")).
+		Assert(jsonpath.Equal(`$.bookings[0].treatment_center_name`, "Center in the game")).
+		End()
+}
+
 func (suite *AppointmentsApiIntegrationTestSuite) TestGetAppointmentsByID() {
 	apitest.New().Debug().
 		Handler(suite.Router).
@@ -88,10 +349,12 @@ func (suite *AppointmentsApiIntegrationTestSuite) TestGetAppointmentsByID() {
 }
 
 func (suite *AppointmentsApiIntegrationTestSuite) TestCreateAppointmentBooking() {
+	suite.Postpone("appointments", "start_time", "eecce415-2d4c-440d-ac90-9780a3bd3371")
 	var id string
 	apitest.New().Debug().
 		Handler(suite.Router).
 		Post("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371/bookings").
+		Header("Authorization", suite.PatientBearer("8152fcbe-3228-46c9-b483-edcb6317d99c")).
 		JSON(validAppointmentBookingJSON).
 		Expect(suite.T()).
 		Status(http.StatusCreated).
@@ -105,6 +368,147 @@ func (suite *AppointmentsApiIntegrationTestSuite) TestCreateAppointmentBooking()
 	suite.Assert().NotEmpty(id)
 }
 
+func (suite *AppointmentsApiIntegrationTestSuite) TestCreateAppointmentBookingAlreadyBooked() {
+	suite.Postpone("appointments", "start_time", "4cdb532d-bfe8-4af6-b9b5-d5078985a350")
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Post("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350/bookings").
+		Header("Authorization", suite.PatientBearer("8152fcbe-3228-46c9-b483-edcb6317d99c")).
+		JSON(validAppointmentBookingJSON).
+		Expect(suite.T()).
+		Status(http.StatusConflict).
+		Assert(jsonpath.Equal(`$.error.message`, "appointment is not available")).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestCreateAppointmentBookingStarted() {
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Post("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371/bookings").
+		Header("Authorization", suite.PatientBearer("8152fcbe-3228-46c9-b483-edcb6317d99c")).
+		JSON(validAppointmentBookingJSON).
+		Expect(suite.T()).
+		Status(http.StatusConflict).
+		Assert(jsonpath.Equal(`$.error.message`, "appointment has already started")).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestCancelAppointmentBooking() {
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Delete("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710").
+		Header("Authorization", suite.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment_booking.id`, "f859ae2c-e24f-46e8-9c27-4431112fc710")).
+		Assert(jsonpath.Equal(`$.appointment_booking.status`, "cancelled")).
+		Assert(jsonpath.Equal(`$.appointment_booking.release_reason`, "cancelled by the patient")).
+		Assert(jsonpath.Present(`$.appointment_booking.released_at`)).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Delete("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710").
+		Header("Authorization", suite.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		Expect(suite.T()).
+		Status(http.StatusConflict).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Get("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment.remaining_seats`, float64(1))).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestCancelAppointmentBookingOfAnotherAppointment() {
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Delete("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710").
+		Header("Authorization", suite.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		Expect(suite.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestRescheduleAppointmentBooking() {
+	suite.Postpone("appointments", "start_time", "eecce415-2d4c-440d-ac90-9780a3bd3371")
+	var id string
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Post("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710/reschedule").
+		Header("Authorization", suite.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		JSON(`{"appointment_id": "eecce415-2d4c-440d-ac90-9780a3bd3371"}`).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(testutils.Extract(`$.appointment_booking.id`, &id)).
+		Assert(jsonpath.Equal(`$.appointment_booking.appointment_id`, "eecce415-2d4c-440d-ac90-9780a3bd3371")).
+		Assert(jsonpath.Equal(`$.appointment_booking.patient_id`, "24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		Assert(jsonpath.Equal(`$.appointment_booking.status`, "confirmed")).
+		End()
+
+	suite.Assert().NotEqual("f859ae2c-e24f-46e8-9c27-4431112fc710", id)
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Get("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment.remaining_seats`, float64(1))).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestRescheduleAppointmentBookingNotAvailable() {
+	suite.Postpone("appointments", "start_time", "eecce415-2d4c-440d-ac90-9780a3bd3371")
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Post("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371/bookings").
+		Header("Authorization", suite.PatientBearer("8152fcbe-3228-46c9-b483-edcb6317d99c")).
+		JSON(validAppointmentBookingJSON).
+		Expect(suite.T()).
+		Status(http.StatusCreated).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Post("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710/reschedule").
+		Header("Authorization", suite.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		JSON(`{"appointment_id": "eecce415-2d4c-440d-ac90-9780a3bd3371"}`).
+		Expect(suite.T()).
+		Status(http.StatusConflict).
+		Assert(jsonpath.Equal(`$.error.message`, "appointment is not available")).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Get("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment.remaining_seats`, float64(0))).
+		End()
+}
+
+func (suite *AppointmentsApiIntegrationTestSuite) TestAppointmentBookingOfAnotherPatient() {
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Post("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371/bookings").
+		Header("Authorization", suite.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		JSON(validAppointmentBookingJSON).
+		Expect(suite.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	apitest.New().Debug().
+		Handler(suite.Router).
+		Delete("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710").
+		Header("Authorization", suite.PatientBearer("8152fcbe-3228-46c9-b483-edcb6317d99c")).
+		Expect(suite.T()).
+		Status(http.StatusForbidden).
+		End()
+}
+
 func TestAppointmentsApiIntegrationTestSuite(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, new(AppointmentsApiIntegrationTestSuite))
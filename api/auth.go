@@ -0,0 +1,210 @@
+package api
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type Role string
+
+const (
+	RolePatient Role = "patient"
+	RoleStaff   Role = "staff"
+	RoleAdmin   Role = "admin"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidRole  = errors.New("invalid role")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+// claimsKey is the key of the authenticated claims in the gin context
+const claimsKey = "covidvax.claims"
+
+// signingMethods are the algorithms accepted for the tokens
+var signingMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// Claims are the claims carried by the bearer tokens, a patient token
+// carries the patient id and a staff token the id of its treatment center
+type Claims struct {
+	Role              Role       `json:"role"`
+	PatientID         *uuid.UUID `json:"patient_id,omitempty"`
+	TreatmentCenterID *uuid.UUID `json:"treatment_center_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) Valid() error {
+	if err := c.RegisteredClaims.Valid(); err != nil {
+		return err
+	}
+	if c.ExpiresAt == nil {
+		return errors.New("token has no expiration")
+	}
+	switch c.Role {
+	case RoleAdmin:
+	case RolePatient:
+		if c.PatientID == nil {
+			return fmt.Errorf("%w: patient_id is required", ErrInvalidRole)
+		}
+	case RoleStaff:
+		if c.TreatmentCenterID == nil {
+			return fmt.Errorf("%w: treatment_center_id is required", ErrInvalidRole)
+		}
+	default:
+		return ErrInvalidRole
+	}
+	return nil
+}
+
+// IsPatient reports whether the claims are the ones of the patient or an admin
+func (c *Claims) IsPatient(patientID uuid.UUID) bool {
+	return c.Role == RoleAdmin || (c.Role == RolePatient && *c.PatientID == patientID)
+}
+
+// IsStaffOf reports whether the claims are the ones of a staff member of the
+// treatment center or an admin
+func (c *Claims) IsStaffOf(treatmentCenterID uuid.UUID) bool {
+	return c.Role == RoleAdmin || (c.Role == RoleStaff && *c.TreatmentCenterID == treatmentCenterID)
+}
+
+// Authenticator verifies bearer tokens against a set of public keys
+// indexed by their key id
+type Authenticator struct {
+	keys   map[string]crypto.PublicKey
+	logger *zap.Logger
+}
+
+func NewAuthenticator(keys map[string]crypto.PublicKey, logger *zap.Logger) *Authenticator {
+	return &Authenticator{
+		keys:   keys,
+		logger: logger.With(zap.String("component", "Authenticator")),
+	}
+}
+
+// LoadKeys reads the PEM encoded public keys of dir, the key id of a key
+// is its file name without the .pem extension
+func LoadKeys(dir string) (map[string]crypto.PublicKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM data", path)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys[strings.TrimSuffix(filepath.Base(path), ".pem")] = key
+	}
+	return keys, nil
+}
+
+// Verify parses the token and checks its signature and claims
+func (a *Authenticator) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(signingMethods))
+	_, err := parser.ParseWithClaims(token, claims, a.key)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// key returns the key a token is signed with, tokens without key id
+// are accepted when a single key is configured
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, nil
+		}
+	}
+	key, ok := a.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// Authenticate rejects the requests without a valid bearer token and
+// stores the claims of the token in the context
+func (a *Authenticator) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || token == c.GetHeader("Authorization") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, Response{Error: &ErrorResponse{Msg: ErrMissingToken.Error()}})
+			return
+		}
+		claims, err := a.Verify(token)
+		if err != nil {
+			a.logger.Debug("invalid token", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, Response{Error: &ErrorResponse{Msg: "invalid token"}})
+			return
+		}
+		c.Set(claimsKey, claims)
+		c.Next()
+	}
+}
+
+// RequireRole rejects the requests whose claims have none of the roles,
+// it must be used after Authenticate
+func RequireRole(roles ...Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := claimsFromContext(c)
+		for _, role := range roles {
+			if claims != nil && claims.Role == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, Response{Error: &ErrorResponse{Msg: ErrForbidden.Error()}})
+	}
+}
+
+// authorizePatient answers 403 unless the request is made by the patient or an admin
+func authorizePatient(c *gin.Context, patientID uuid.UUID) bool {
+	if claims := claimsFromContext(c); claims != nil && claims.IsPatient(patientID) {
+		return true
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, Response{Error: &ErrorResponse{Msg: ErrForbidden.Error()}})
+	return false
+}
+
+// authorizeStaffOf answers 403 unless the request is made by a staff member
+// of the treatment center or an admin
+func authorizeStaffOf(c *gin.Context, treatmentCenterID uuid.UUID) bool {
+	if claims := claimsFromContext(c); claims != nil && claims.IsStaffOf(treatmentCenterID) {
+		return true
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, Response{Error: &ErrorResponse{Msg: ErrForbidden.Error()}})
+	return false
+}
+
+func claimsFromContext(c *gin.Context) *Claims {
+	claims, _ := c.Get(claimsKey)
+	if claims == nil {
+		return nil
+	}
+	return claims.(*Claims)
+}
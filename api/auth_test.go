@@ -0,0 +1,130 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAuthenticatorVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "main.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	keys, err := LoadKeys(dir)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	authenticator := NewAuthenticator(keys, zap.NewExample())
+
+	patientID := uuid.MustParse("24e32685-0a32-4a9d-bc22-0e98cdaf5884")
+	sign := func(key *rsa.PrivateKey, kid string, claims *Claims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+	expiresAt := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "Successful",
+			token: sign(key, "main", &Claims{Role: RolePatient, PatientID: &patientID, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt}}),
+		},
+		{
+			name:  "WithoutKeyID",
+			token: sign(key, "", &Claims{Role: RoleAdmin, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt}}),
+		},
+		{
+			name:    "UnknownKeyID",
+			token:   sign(key, "other", &Claims{Role: RoleAdmin, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt}}),
+			wantErr: true,
+		},
+		{
+			name:    "OtherKey",
+			token:   sign(other, "main", &Claims{Role: RoleAdmin, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt}}),
+			wantErr: true,
+		},
+		{
+			name: "Expired",
+			token: sign(key, "main", &Claims{Role: RoleAdmin, RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}}),
+			wantErr: true,
+		},
+		{
+			name:    "WithoutExpiration",
+			token:   sign(key, "main", &Claims{Role: RoleAdmin}),
+			wantErr: true,
+		},
+		{
+			name:    "PatientWithoutID",
+			token:   sign(key, "main", &Claims{Role: RolePatient, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt}}),
+			wantErr: true,
+		},
+		{
+			name:    "UnknownRole",
+			token:   sign(key, "main", &Claims{Role: "root", RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt}}),
+			wantErr: true,
+		},
+		{
+			name:    "Unsigned",
+			token:   "eyJhbGciOiJub25lIn0.eyJyb2xlIjoiYWRtaW4ifQ.",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := authenticator.Verify(tc.token)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, claims)
+		})
+	}
+}
+
+func TestClaimsAuthorization(t *testing.T) {
+	patientID := uuid.MustParse("24e32685-0a32-4a9d-bc22-0e98cdaf5884")
+	centerID := uuid.MustParse("10063726-d378-472c-9b50-22a48331635d")
+	otherID := uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef")
+
+	admin := &Claims{Role: RoleAdmin}
+	patient := &Claims{Role: RolePatient, PatientID: &patientID}
+	staff := &Claims{Role: RoleStaff, TreatmentCenterID: &centerID}
+
+	assert.True(t, admin.IsPatient(patientID))
+	assert.True(t, admin.IsStaffOf(centerID))
+	assert.True(t, patient.IsPatient(patientID))
+	assert.False(t, patient.IsPatient(otherID))
+	assert.False(t, patient.IsStaffOf(centerID))
+	assert.True(t, staff.IsStaffOf(centerID))
+	assert.False(t, staff.IsStaffOf(otherID))
+	assert.False(t, staff.IsPatient(patientID))
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/y9mo/covidvax/confirmation"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+	"go.uber.org/zap"
+)
+
+type BookingsController struct {
+	unitOfWork    repository.UnitOfWork
+	confirmations *confirmation.Service
+	logger        *zap.Logger
+}
+
+type BookingConfirmationRequest struct {
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+func SetupBooking(router gin.IRouter,
+	unitOfWork repository.UnitOfWork,
+	confirmations *confirmation.Service,
+	logger *zap.Logger) {
+	c := BookingsController{
+		unitOfWork:    unitOfWork,
+		confirmations: confirmations,
+		logger:        logger.With(zap.String("component", "BookingsController")),
+	}
+	g := router.Group(
+		"/bookings",
+	)
+	g.GET("/confirm", c.ConfirmEndpoint)
+	g.POST("/confirm", c.ConfirmEndpoint)
+}
+
+func (v *BookingsController) ConfirmEndpoint(c *gin.Context) {
+	var request BookingConfirmationRequest
+	if err := c.ShouldBind(&request); err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	bookingID, err := v.confirmations.Verify(request.Token)
+	if err == confirmation.ErrExpiredToken {
+		c.JSON(http.StatusGone, AppointmentBookingResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var appointmentBooking *domain.AppointmentBooking
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.AppointmentBookings().Confirm(ctx, bookingID); err != nil {
+			return err
+		}
+		appointmentBooking, err = r.AppointmentBookings().FindByID(ctx, bookingID)
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, AppointmentBookingResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, AppointmentBookingResponse{AppointmentBooking: appointmentBooking})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/steinfletcher/apitest"
+	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"github.com/stretchr/testify/suite"
+	"github.com/y9mo/covidvax/testutils"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type BookingsApiIntegrationTestSuite struct {
+	ApiIntegrationSuite
+}
+
+func (s *BookingsApiIntegrationTestSuite) TestConfirmBooking() {
+	s.Postpone("appointments", "start_time", "eecce415-2d4c-440d-ac90-9780a3bd3371")
+	var id string
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371/bookings").
+		Header("Authorization", s.PatientBearer("8152fcbe-3228-46c9-b483-edcb6317d99c")).
+		JSON(validAppointmentBookingJSON).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(testutils.Extract(`$.appointment_booking.id`, &id)).
+		Assert(jsonpath.Equal(`$.appointment_booking.status`, "awaiting confirmation")).
+		End()
+
+	s.Require().NotEmpty(id)
+	token := s.Signer.Sign(uuid.MustParse(id))
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/bookings/confirm").
+		Query("token", token).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment_booking.id`, id)).
+		Assert(jsonpath.Equal(`$.appointment_booking.status`, "confirmed")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/bookings/confirm").
+		Query("token", token).
+		Expect(s.T()).
+		Status(http.StatusConflict).
+		Assert(jsonpath.Present(`$.error.message`)).
+		End()
+}
+
+func (s *BookingsApiIntegrationTestSuite) TestConfirmBookingInvalidToken() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/bookings/confirm").
+		Query("token", "invalid").
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.message`, "invalid token")).
+		End()
+}
+
+func (s *BookingsApiIntegrationTestSuite) TestConfirmUnknownBooking() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/bookings/confirm").
+		Query("token", s.Signer.Sign(uuid.MustParse("3dc1ba24-9e41-4c5a-b73f-79d9d4c2ba0e"))).
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func TestBookingsApiIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, new(BookingsApiIntegrationTestSuite))
+}
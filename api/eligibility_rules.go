@@ -0,0 +1,184 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+	"go.uber.org/zap"
+)
+
+type EligibilityRulesResponse struct {
+	EligibilityRules []*domain.EligibilityRule `json:"eligibility_rules,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type EligibilityRuleResponse struct {
+	EligibilityRule *domain.EligibilityRule `json:"eligibility_rule,omitempty"`
+	Error           *ErrorResponse          `json:"error,omitempty"`
+}
+
+type EligibilityRulesController struct {
+	eligibilityRulesRepository repository.EligibilityRules
+	unitOfWork                 repository.UnitOfWork
+	logger                     *zap.Logger
+}
+
+type inputEligibilityRule struct {
+	domain.EligibilityRule
+}
+
+func (r *inputEligibilityRule) buildModel() domain.EligibilityRule {
+	rule := domain.EligibilityRule{
+		ID: uuid.New(),
+	}
+	r.updateModel(&rule)
+	return rule
+}
+
+func (r *inputEligibilityRule) updateModel(rule *domain.EligibilityRule) {
+	rule.Name = normalizeName(r.Name)
+	rule.MinAge = r.MinAge
+	rule.RiskCategories = r.RiskCategories
+	rule.Professions = r.Professions
+	rule.EffectiveFrom = r.EffectiveFrom
+	rule.EffectiveUntil = r.EffectiveUntil
+}
+
+// SetupEligibilityRule serves the eligibility rules, anyone can read them
+// and the admins define them
+func SetupEligibilityRule(
+	router gin.IRouter,
+	eligibilityRulesRepository repository.EligibilityRules,
+	unitOfWork repository.UnitOfWork,
+	authenticator *Authenticator,
+	logger *zap.Logger) {
+	c := EligibilityRulesController{
+		eligibilityRulesRepository: eligibilityRulesRepository,
+		unitOfWork:                 unitOfWork,
+		logger:                     logger.With(zap.String("component", "EligibilityRulesController")),
+	}
+	g := router.Group("/eligibility_rules")
+	auth := authenticator.Authenticate()
+	admin := RequireRole(RoleAdmin)
+	g.GET("/", c.IndexEndpoint)
+	g.GET("/:eligibility_rule_id", c.GetEndpoint)
+	g.POST("/", auth, admin, c.CreateEndpoint)
+	g.PUT("/:eligibility_rule_id", auth, admin, c.UpdateEndpoint)
+	g.DELETE("/:eligibility_rule_id", auth, admin, c.DeleteEndpoint)
+}
+
+func extractEligibilityRuleID(c *gin.Context) (id uuid.UUID, err error) {
+	return uuid.Parse(c.Param("eligibility_rule_id"))
+}
+
+func (v *EligibilityRulesController) IndexEndpoint(c *gin.Context) {
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, EligibilityRulesResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	rules, page, err := v.eligibilityRulesRepository.All(c.Request.Context(), pageRequest)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, EligibilityRulesResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, EligibilityRulesResponse{EligibilityRules: rules, PageResponse: newPageResponse(page)})
+}
+
+func (v *EligibilityRulesController) GetEndpoint(c *gin.Context) {
+	id, err := extractEligibilityRuleID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, EligibilityRuleResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	rule, err := v.eligibilityRulesRepository.FindByID(c.Request.Context(), id)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, EligibilityRuleResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, EligibilityRuleResponse{EligibilityRule: rule})
+}
+
+func (v *EligibilityRulesController) CreateEndpoint(c *gin.Context) {
+	var input inputEligibilityRule
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, EligibilityRuleResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	rule := input.buildModel()
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, EligibilityRuleResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	ctx := c.Request.Context()
+	var created *domain.EligibilityRule
+	err := v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.EligibilityRules().Create(ctx, &rule); err != nil {
+			return err
+		}
+		created, err = r.EligibilityRules().FindByID(ctx, rule.ID)
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, EligibilityRuleResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusCreated, EligibilityRuleResponse{EligibilityRule: created})
+}
+
+// UpdateEndpoint replaces the criteria and effective dates of a rule
+func (v *EligibilityRulesController) UpdateEndpoint(c *gin.Context) {
+	id, err := extractEligibilityRuleID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, EligibilityRuleResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	var input inputEligibilityRule
+	if err = c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, EligibilityRuleResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	rule := domain.EligibilityRule{ID: id}
+	input.updateModel(&rule)
+	if err = rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, EligibilityRuleResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var updated *domain.EligibilityRule
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.EligibilityRules().Update(ctx, &rule); err != nil {
+			return err
+		}
+		updated, err = r.EligibilityRules().FindByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, EligibilityRuleResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, EligibilityRuleResponse{EligibilityRule: updated})
+}
+
+func (v *EligibilityRulesController) DeleteEndpoint(c *gin.Context) {
+	id, err := extractEligibilityRuleID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, EligibilityRuleResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if err = v.eligibilityRulesRepository.Delete(c.Request.Context(), id); err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, EligibilityRuleResponse{Error: r})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steinfletcher/apitest"
+	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"github.com/stretchr/testify/suite"
+	"github.com/y9mo/covidvax/testutils"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type EligibilityRulesApiIntegrationTestSuite struct {
+	ApiIntegrationSuite
+}
+
+const validEligibilityRuleJSON = `{
+	"name": "Elderly or health workers",
+	"min_age": 75,
+	"professions": ["nurse", "doctor"],
+	"effective_from": "2021-01-18T00:00:00Z"
+}`
+
+func (s *EligibilityRulesApiIntegrationTestSuite) TestEligibilityRulesIndexList() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/eligibility_rules/").
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.eligibility_rules`, 1)).
+		Assert(jsonpath.Equal(`$.eligibility_rules[0].name`, "Elderly and at risk")).
+		Assert(jsonpath.Equal(`$.eligibility_rules[0].min_age`, float64(75))).
+		Assert(jsonpath.Equal(`$.eligibility_rules[0].risk_categories[0]`, "dialysis")).
+		Assert(jsonpath.Equal(`$.eligibility_rules[0].effective_until`, "2021-06-01T00:00:00Z")).
+		End()
+}
+
+func (s *EligibilityRulesApiIntegrationTestSuite) TestCreateEligibilityRule() {
+	var id string
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/eligibility_rules/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(validEligibilityRuleJSON).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(testutils.Extract(`$.eligibility_rule.id`, &id)).
+		Assert(jsonpath.Equal(`$.eligibility_rule.name`, "Elderly or health workers")).
+		Assert(jsonpath.Equal(`$.eligibility_rule.professions[1]`, "doctor")).
+		Assert(jsonpath.NotPresent(`$.eligibility_rule.effective_until`)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Put(fmt.Sprintf("/v1/eligibility_rules/%s", id)).
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"name": "Elderly", "min_age": 70, "effective_from": "2021-01-18T00:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.eligibility_rule.min_age`, float64(70))).
+		Assert(jsonpath.NotPresent(`$.eligibility_rule.professions`)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Delete(fmt.Sprintf("/v1/eligibility_rules/%s", id)).
+		Header("Authorization", s.AdminBearer()).
+		Expect(s.T()).
+		Status(http.StatusNoContent).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get(fmt.Sprintf("/v1/eligibility_rules/%s", id)).
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (s *EligibilityRulesApiIntegrationTestSuite) TestCreateEligibilityRuleInvalid() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/eligibility_rules/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"name": "Nobody", "effective_from": "2021-01-18T00:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.message`, "min_age, risk_categories or professions is required")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/eligibility_rules/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"name": "Adults", "min_age": 18, "effective_from": "2021-01-18T00:00:00Z",
+			"effective_until": "2021-01-01T00:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.message`, "effective_until must be after effective_from")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/eligibility_rules/").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(validEligibilityRuleJSON).
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+}
+
+func (s *EligibilityRulesApiIntegrationTestSuite) TestBookIneligible() {
+	s.Postpone("appointments", "start_time", "eecce415-2d4c-440d-ac90-9780a3bd3371")
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/eligibility_rules/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(validEligibilityRuleJSON).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371/bookings").
+		Header("Authorization", s.PatientBearer("8152fcbe-3228-46c9-b483-edcb6317d99c")).
+		JSON(`{"patient_id": "8152fcbe-3228-46c9-b483-edcb6317d99c"}`).
+		Expect(s.T()).
+		Status(http.StatusConflict).
+		Assert(jsonpath.Equal(`$.error.message`, "patient is not eligible: Elderly or health workers")).
+		Assert(jsonpath.Equal(`$.error.rule.name`, "Elderly or health workers")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/patients/8152fcbe-3228-46c9-b483-edcb6317d99c").
+		Header("Authorization", s.AdminBearer()).
+		Body(`{"profession": "Nurse"}`).
+		ContentType("application/merge-patch+json").
+		Expect(s.T()).
+		Status(http.StatusOK).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371/bookings").
+		Header("Authorization", s.PatientBearer("8152fcbe-3228-46c9-b483-edcb6317d99c")).
+		JSON(`{"patient_id": "8152fcbe-3228-46c9-b483-edcb6317d99c"}`).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Equal(`$.appointment_booking.status`, "awaiting confirmation")).
+		End()
+}
+
+func (s *EligibilityRulesApiIntegrationTestSuite) TestRescheduleIneligible() {
+	s.Postpone("appointments", "start_time", "eecce415-2d4c-440d-ac90-9780a3bd3371")
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/eligibility_rules/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(validEligibilityRuleJSON).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710/reschedule").
+		Header("Authorization", s.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		JSON(`{"appointment_id": "eecce415-2d4c-440d-ac90-9780a3bd3371"}`).
+		Expect(s.T()).
+		Status(http.StatusConflict).
+		Assert(jsonpath.Equal(`$.error.message`, "patient is not eligible: Elderly or health workers")).
+		Assert(jsonpath.Equal(`$.error.rule.name`, "Elderly or health workers")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/appointments/4cdb532d-bfe8-4af6-b9b5-d5078985a350").
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment.remaining_seats`, float64(0))).
+		End()
+}
+
+func TestEligibilityRulesApiIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, new(EligibilityRulesApiIntegrationTestSuite))
+}
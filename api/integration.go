@@ -1,15 +1,27 @@
 package api
 
 import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/confirmation"
+	"github.com/y9mo/covidvax/mail"
 	"github.com/y9mo/covidvax/repository"
 	"github.com/y9mo/covidvax/testutils"
+	"github.com/y9mo/covidvax/waitlist"
 	"go.uber.org/zap"
 )
 
 type ApiIntegrationSuite struct {
 	testutils.IntegrationSuite
 	Router *gin.Engine
+	Signer *confirmation.Signer
+	key    *rsa.PrivateKey
 }
 
 func (s *ApiIntegrationSuite) SetupSuite() {
@@ -22,7 +34,59 @@ func (s *ApiIntegrationSuite) SetupSuite() {
 	tcr := repository.NewTreatmentCenters(s.DB(), logger)
 	ar := repository.NewAppointments(s.DB(), logger)
 	abr := repository.NewAppointmentBookings(s.DB(), logger)
+	vpr := repository.NewVaccineProducts(s.DB(), logger)
+	dr := repository.NewDoses(s.DB(), logger)
+	vlr := repository.NewVaccineLots(s.DB(), logger)
+	wr := repository.NewWaitlist(s.DB(), logger)
+	elr := repository.NewEligibilityRules(s.DB(), logger)
+	uow := repository.NewUnitOfWork(s.DB(), logger)
+
+	s.Signer = confirmation.NewSigner([]byte("test secret"), time.Hour)
+	confirmations := confirmation.NewService(s.Signer, mail.NewFileSender("", "covidvax@localhost", logger),
+		pr, ar, "http://localhost:8080", logger)
+	offers := waitlist.NewService(wr, confirmations, logger)
+
+	s.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	authenticator := NewAuthenticator(map[string]crypto.PublicKey{"test": &s.key.PublicKey}, logger)
+
+	s.Router, err = Setup(logger, pr, tcr, ar, abr, vpr, dr, vlr, wr, elr, uow, confirmations, offers, authenticator,
+		"33", 10*time.Second)
+	s.Require().NoError(err)
+}
 
-	s.Router, err = Setup(logger, pr, tcr, ar, abr)
+// Bearer returns an authorization header value for a token of role
+func (s *ApiIntegrationSuite) Bearer(role Role, patientID, treatmentCenterID string) string {
+	claims := &Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "test " + string(role),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	if patientID != "" {
+		id := uuid.MustParse(patientID)
+		claims.PatientID = &id
+	}
+	if treatmentCenterID != "" {
+		id := uuid.MustParse(treatmentCenterID)
+		claims.TreatmentCenterID = &id
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(s.key)
 	s.Require().NoError(err)
+	return "Bearer " + signed
+}
+
+func (s *ApiIntegrationSuite) AdminBearer() string {
+	return s.Bearer(RoleAdmin, "", "")
+}
+
+func (s *ApiIntegrationSuite) PatientBearer(patientID string) string {
+	return s.Bearer(RolePatient, patientID, "")
+}
+
+func (s *ApiIntegrationSuite) StaffBearer(treatmentCenterID string) string {
+	return s.Bearer(RoleStaff, "", treatmentCenterID)
 }
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/y9mo/covidvax/domain"
+)
+
+// defaultPageLimit is the size of the pages of a list without limit
+const defaultPageLimit = 50
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageQuery are the query parameters shared by the list endpoints, the sort
+// fields depend on the list and the cursor is the next_cursor of a page
+type PageQuery struct {
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=200"`
+	Cursor    string `form:"cursor"`
+	Sort      string `form:"sort"`
+	Direction string `form:"direction" binding:"omitempty,oneof=asc desc"`
+}
+
+// PageResponse is the page metadata of the lists, it is embedded in their responses
+type PageResponse struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+// bindPageRequest reads the page asked by the query of a list endpoint
+func bindPageRequest(c *gin.Context) (domain.PageRequest, error) {
+	var query PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		return domain.PageRequest{}, err
+	}
+	request := domain.PageRequest{
+		Limit:     query.Limit,
+		Sort:      query.Sort,
+		Direction: domain.SortDirection(query.Direction),
+	}
+	if request.Limit == 0 {
+		request.Limit = defaultPageLimit
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return request, err
+		}
+		request.Cursor = cursor
+	}
+	return request, request.Validate()
+}
+
+func newPageResponse(page domain.Page) *PageResponse {
+	response := &PageResponse{Total: page.Total}
+	if page.NextCursor != nil {
+		response.NextCursor = encodeCursor(page.NextCursor)
+	}
+	return response
+}
+
+// encodeCursor returns the opaque form of a cursor given to the clients
+func encodeCursor(cursor *domain.Cursor) string {
+	value, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+func decodeCursor(cursor string) (*domain.Cursor, error) {
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	decoded := &domain.Cursor{}
+	if err = json.Unmarshal(value, decoded); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return decoded, nil
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// mergePatchContentType is the media type of the JSON merge patches (RFC 7396)
+const mergePatchContentType = "application/merge-patch+json"
+
+var ErrUnsupportedPatch = errors.New("patches must be sent as " + mergePatchContentType + " or application/json")
+
+// bindMergePatch applies the JSON merge patch of the request body to the JSON
+// representation of current, then decodes and validates the result into input
+// like the binding of a full update
+func bindMergePatch(c *gin.Context, current interface{}, input interface{}) error {
+	if contentType := c.ContentType(); contentType != mergePatchContentType && contentType != binding.MIMEJSON {
+		return ErrUnsupportedPatch
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	var patch interface{}
+	if err = json.Unmarshal(body, &patch); err != nil {
+		return err
+	}
+
+	document, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var target interface{}
+	if err = json.Unmarshal(document, &target); err != nil {
+		return err
+	}
+	if document, err = json.Marshal(mergePatch(target, patch)); err != nil {
+		return err
+	}
+	return binding.JSON.BindBody(document, input)
+}
+
+// mergePatch returns the target patched as described by RFC 7396: the members
+// of a patch object replace the ones of the target, recursively for objects,
+// a null member removes the target one, any other patch replaces the target
+func mergePatch(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = mergePatch(object[name], value)
+	}
+	return object
+}
+
+// patchStatus is the status answering a patch bindMergePatch rejected
+func patchStatus(err error) int {
+	if err == ErrUnsupportedPatch {
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
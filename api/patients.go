@@ -2,17 +2,21 @@ package api
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/confirmation"
 	"github.com/y9mo/covidvax/domain"
 	"github.com/y9mo/covidvax/repository"
+	"github.com/y9mo/covidvax/waitlist"
 	"go.uber.org/zap"
 )
 
 type PatientsResponse struct {
 	Patients []*domain.Patient `json:"patients,omitempty"`
-	Error    *ErrorResponse    `json:"error,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
 }
 
 type PatientResponse struct {
@@ -20,54 +24,173 @@ type PatientResponse struct {
 	Error   *ErrorResponse  `json:"error,omitempty"`
 }
 
+type DuplicateCandidatesResponse struct {
+	Candidates []*domain.DuplicateCandidate `json:"candidates,omitempty"`
+	Error      *ErrorResponse               `json:"error,omitempty"`
+}
+
+type PatientBookingsResponse struct {
+	Bookings []*domain.PatientBooking `json:"bookings,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type DosesResponse struct {
+	Doses []*domain.Dose `json:"doses,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type DoseResponse struct {
+	Dose              *domain.Dose             `json:"dose,omitempty"`
+	CancelledBookings []*domain.PatientBooking `json:"cancelled_bookings,omitempty"`
+	Error             *ErrorResponse           `json:"error,omitempty"`
+}
+
+type WaitlistEntriesResponse struct {
+	WaitlistEntries []*domain.WaitlistEntry `json:"waitlist_entries,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type WaitlistEntryResponse struct {
+	WaitlistEntry *domain.WaitlistEntry `json:"waitlist_entry,omitempty"`
+	Error         *ErrorResponse        `json:"error,omitempty"`
+}
+
 type PatientsController struct {
-	patientsRepository repository.Patients
-	logger             *zap.Logger
+	patientsRepository            repository.Patients
+	appointmentBookingsRepository repository.AppointmentBookings
+	dosesRepository               repository.Doses
+	waitlistRepository            repository.Waitlist
+	unitOfWork                    repository.UnitOfWork
+	confirmations                 *confirmation.Service
+	offers                        *waitlist.Service
+	phones                        phoneNumbers
+	logger                        *zap.Logger
 }
 
 type inputPatient struct {
 	domain.Patient
-	ID uuid.UUID `json:"-"`
+	ID     uuid.UUID `json:"-"`
+	phones phoneNumbers
 }
 
 func (c *inputPatient) buildModel() domain.Patient {
-	return domain.Patient{
+	patient := domain.Patient{
 		ID: uuid.New(),
 	}
+	c.updateModel(&patient)
+	return patient
 }
 
 func (c *inputPatient) updateModel(patient *domain.Patient) {
-	// patient.Os = c.Os
+	patient.Email = normalizeEmail(c.Email)
+	patient.FirstName = normalizeName(c.FirstName)
+	patient.LastName = normalizeName(c.LastName)
+	patient.Phone = c.phones.normalize(c.Phone)
+	patient.BirthDate = c.BirthDate
+	patient.NationalHealthID = normalizeNationalHealthID(c.NationalHealthID)
+	patient.RiskCategories = c.RiskCategories
+	patient.Profession = strings.TrimSpace(c.Profession)
+}
+
+// MergeRequest names the duplicate patient to merge into another one
+type MergeRequest struct {
+	DuplicateID uuid.UUID `json:"duplicate_id" binding:"required"`
+}
+
+type inputDose struct {
+	domain.Dose
+}
+
+func (d *inputDose) buildModel(patientID uuid.UUID) domain.Dose {
+	return domain.Dose{
+		ID:                   uuid.New(),
+		PatientID:            patientID,
+		VaccineProductID:     d.VaccineProductID,
+		AppointmentBookingID: d.AppointmentBookingID,
+		AdministeredAt:       d.AdministeredAt,
+	}
+}
+
+type inputWaitlistEntry struct {
+	domain.WaitlistEntry
+}
+
+func (w *inputWaitlistEntry) buildModel(patientID uuid.UUID) domain.WaitlistEntry {
+	return domain.WaitlistEntry{
+		ID:                uuid.New(),
+		PatientID:         patientID,
+		TreatmentCenterID: w.TreatmentCenterID,
+		FromDate:          w.FromDate,
+		ToDate:            w.ToDate,
+	}
 }
 
 func SetupPatient(
 	router gin.IRouter,
 	patientsRepository repository.Patients,
+	appointmentBookingsRepository repository.AppointmentBookings,
+	dosesRepository repository.Doses,
+	waitlistRepository repository.Waitlist,
+	unitOfWork repository.UnitOfWork,
+	confirmations *confirmation.Service,
+	offers *waitlist.Service,
+	phones phoneNumbers,
+	authenticator *Authenticator,
 	logger *zap.Logger) {
 	c := PatientsController{
-		patientsRepository: patientsRepository,
-		logger:             logger.With(zap.String("component", "PatientsController")),
+		patientsRepository:            patientsRepository,
+		appointmentBookingsRepository: appointmentBookingsRepository,
+		dosesRepository:               dosesRepository,
+		waitlistRepository:            waitlistRepository,
+		unitOfWork:                    unitOfWork,
+		confirmations:                 confirmations,
+		offers:                        offers,
+		phones:                        phones,
+		logger:                        logger.With(zap.String("component", "PatientsController")),
 	}
-	g := router.Group("/patients")
-	g.GET("/", c.IndexEndpoint)
-	g.GET("/:patient_id", c.GetEndpoint)
-	g.POST("/", c.CreateEndpoint)
-	g.DELETE("/:patient_id", c.DeleteEndpoint)
-	g.PUT("/:patient_id", c.UpdateEndpoint)
+	g := router.Group("/patients", authenticator.Authenticate())
+	admin := RequireRole(RoleAdmin)
+	g.GET("/", admin, c.IndexEndpoint)
+	g.GET("/:patient_id", admin, c.GetEndpoint)
+	g.POST("/", admin, c.CreateEndpoint)
+	g.DELETE("/:patient_id", admin, c.DeleteEndpoint)
+	g.PUT("/:patient_id", admin, c.UpdateEndpoint)
+	g.PATCH("/:patient_id", admin, c.PatchEndpoint)
+	g.GET("/:patient_id/duplicates", admin, c.GetDuplicatesEndpoint)
+	g.POST("/:patient_id/merge", admin, c.MergeEndpoint)
+	g.GET("/:patient_id/bookings", RequireRole(RolePatient, RoleAdmin), c.GetBookingsEndpoint)
+	g.GET("/:patient_id/doses", RequireRole(RolePatient, RoleAdmin), c.GetDosesEndpoint)
+	g.POST("/:patient_id/doses", RequireRole(RoleStaff, RoleAdmin), c.AddDoseEndpoint)
+	g.GET("/:patient_id/waitlist", RequireRole(RolePatient, RoleAdmin), c.GetWaitlistEndpoint)
+	g.POST("/:patient_id/waitlist", RequireRole(RolePatient, RoleAdmin), c.JoinWaitlistEndpoint)
+	g.DELETE("/:patient_id/waitlist/:waitlist_entry_id", RequireRole(RolePatient, RoleAdmin),
+		c.WithdrawFromWaitlistEndpoint)
 }
 
 func extractPatientID(c *gin.Context) (id uuid.UUID, err error) {
 	return uuid.Parse(c.Param("patient_id"))
 }
 
+func extractWaitlistEntryID(c *gin.Context) (id uuid.UUID, err error) {
+	return uuid.Parse(c.Param("waitlist_entry_id"))
+}
+
 func (v *PatientsController) IndexEndpoint(c *gin.Context) {
-	patients, err := v.patientsRepository.All()
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, PatientsResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	patients, page, err := v.patientsRepository.All(c.Request.Context(), pageRequest)
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, PatientsResponse{Error: r})
 		return
 	}
-	c.JSON(http.StatusOK, PatientsResponse{Patients: patients})
+	c.JSON(http.StatusOK, PatientsResponse{Patients: patients, PageResponse: newPageResponse(page)})
 }
 
 func (v *PatientsController) GetEndpoint(c *gin.Context) {
@@ -77,7 +200,7 @@ func (v *PatientsController) GetEndpoint(c *gin.Context) {
 		return
 	}
 	var patient *domain.Patient
-	patient, err = v.patientsRepository.FindByID(patientID)
+	patient, err = v.patientsRepository.FindByID(c.Request.Context(), patientID)
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, PatientResponse{Error: r})
@@ -88,25 +211,24 @@ func (v *PatientsController) GetEndpoint(c *gin.Context) {
 }
 
 func (v *PatientsController) CreateEndpoint(c *gin.Context) {
-	var (
-		data inputPatient
-		err  error
-	)
+	var err error
+	data := inputPatient{phones: v.phones}
 
 	if err = c.ShouldBindJSON(&data); err != nil {
-		c.JSON(http.StatusBadRequest, PatientResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		c.JSON(http.StatusBadRequest, PatientResponse{Error: bindingErrorResponse(err)})
 		return
 	}
 
 	patient := data.buildModel()
-	if err = v.patientsRepository.Create(&patient); err != nil {
-		code, r := handleRepositoryError(err, v.logger)
-		c.JSON(code, PatientResponse{Error: r})
-		return
-	}
-
+	ctx := c.Request.Context()
 	var newPatient *domain.Patient
-	newPatient, err = v.patientsRepository.FindByID(patient.ID)
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.Patients().Create(ctx, &patient); err != nil {
+			return err
+		}
+		newPatient, err = r.Patients().FindByID(ctx, patient.ID)
+		return err
+	})
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, PatientResponse{Error: r})
@@ -116,62 +238,349 @@ func (v *PatientsController) CreateEndpoint(c *gin.Context) {
 }
 
 func (v *PatientsController) DeleteEndpoint(c *gin.Context) {
-	var patient *domain.Patient
 	id, err := extractPatientID(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, PatientResponse{Error: &ErrorResponse{Msg: err.Error()}})
 		return
 	}
 
-	patient, err = v.patientsRepository.FindByID(id)
+	ctx := c.Request.Context()
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) error {
+		patient, err := r.Patients().FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return r.Patients().Delete(ctx, patient)
+	})
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, PatientResponse{Error: r})
 		return
 	}
+	c.Status(http.StatusNoContent)
+}
+
+func (v *PatientsController) UpdateEndpoint(c *gin.Context) {
+	input := inputPatient{phones: v.phones}
+	id, err := extractPatientID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, PatientResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if err = c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, PatientResponse{Error: bindingErrorResponse(err)})
+		return
+	}
 
-	err = v.patientsRepository.Delete(patient)
+	patient, err := v.patientsRepository.FindByID(c.Request.Context(), id)
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, PatientResponse{Error: r})
 		return
 	}
-	c.Status(http.StatusNoContent)
+	v.update(c, patient, &input)
 }
 
-func (v *PatientsController) UpdateEndpoint(c *gin.Context) {
-	var input inputPatient
+// PatchEndpoint updates the fields of a patient present in a JSON merge patch
+func (v *PatientsController) PatchEndpoint(c *gin.Context) {
 	id, err := extractPatientID(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, PatientResponse{Error: &ErrorResponse{Msg: err.Error()}})
 		return
 	}
-	if err = c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, PatientResponse{Error: &ErrorResponse{Msg: err.Error()}})
+	patient, err := v.patientsRepository.FindByID(c.Request.Context(), id)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, PatientResponse{Error: r})
+		return
+	}
+
+	input := inputPatient{phones: v.phones}
+	if err = bindMergePatch(c, patient, &input); err != nil {
+		c.JSON(patchStatus(err), PatientResponse{Error: bindingErrorResponse(err)})
 		return
 	}
+	v.update(c, patient, &input)
+}
 
-	patient, err := v.patientsRepository.FindByID(id)
+func (v *PatientsController) update(c *gin.Context, patient *domain.Patient, input *inputPatient) {
+	input.updateModel(patient)
+	ctx := c.Request.Context()
+	err := v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.Patients().Update(ctx, patient); err != nil {
+			return err
+		}
+		patient, err = r.Patients().FindByID(ctx, patient.ID)
+		return err
+	})
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, PatientResponse{Error: r})
 		return
 	}
 
-	input.updateModel(patient)
-	err = v.patientsRepository.Update(patient)
+	c.JSON(http.StatusOK, PatientResponse{Patient: patient})
+}
+
+// GetDuplicatesEndpoint lists the patients who may be the same person as the patient
+func (v *PatientsController) GetDuplicatesEndpoint(c *gin.Context) {
+	patientID, err := extractPatientID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, DuplicateCandidatesResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	candidates, err := v.patientsRepository.Duplicates(c.Request.Context(), patientID)
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
-		c.JSON(code, PatientResponse{Error: r})
+		c.JSON(code, DuplicateCandidatesResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, DuplicateCandidatesResponse{Candidates: candidates})
+}
+
+// MergeEndpoint merges a duplicate into the patient, which gets its bookings,
+// doses and waitlist entries, and deletes the duplicate
+func (v *PatientsController) MergeEndpoint(c *gin.Context) {
+	patientID, err := extractPatientID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, PatientResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	var request MergeRequest
+	if err = c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, PatientResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	if request.DuplicateID == patientID {
+		c.JSON(http.StatusBadRequest, PatientResponse{Error: &ErrorResponse{Msg: "a patient can't be merged into itself"}})
 		return
 	}
 
-	patient, err = v.patientsRepository.FindByID(id)
+	ctx := c.Request.Context()
+	var patient *domain.Patient
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.Patients().Merge(ctx, patientID, request.DuplicateID); err != nil {
+			return err
+		}
+		patient, err = r.Patients().FindByID(ctx, patientID)
+		return err
+	})
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, PatientResponse{Error: r})
 		return
 	}
-
 	c.JSON(http.StatusOK, PatientResponse{Patient: patient})
 }
+
+func (v *PatientsController) GetBookingsEndpoint(c *gin.Context) {
+	patientID, err := extractPatientID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, PatientBookingsResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizePatient(c, patientID) {
+		return
+	}
+
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, PatientBookingsResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	ctx := c.Request.Context()
+	// an unknown patient is not found rather than without bookings
+	if _, err = v.patientsRepository.FindByID(ctx, patientID); err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, PatientBookingsResponse{Error: r})
+		return
+	}
+	bookings, page, err := v.appointmentBookingsRepository.AllByPatientID(ctx, patientID, pageRequest)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, PatientBookingsResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, PatientBookingsResponse{Bookings: bookings, PageResponse: newPageResponse(page)})
+}
+
+func (v *PatientsController) GetDosesEndpoint(c *gin.Context) {
+	patientID, err := extractPatientID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, DosesResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizePatient(c, patientID) {
+		return
+	}
+
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, DosesResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	ctx := c.Request.Context()
+	// an unknown patient is not found rather than without doses
+	if _, err = v.patientsRepository.FindByID(ctx, patientID); err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, DosesResponse{Error: r})
+		return
+	}
+	doses, page, err := v.dosesRepository.AllByPatientID(ctx, patientID, pageRequest)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, DosesResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, DosesResponse{Doses: doses, PageResponse: newPageResponse(page)})
+}
+
+func (v *PatientsController) AddDoseEndpoint(c *gin.Context) {
+	patientID, err := extractPatientID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, DoseResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	var input inputDose
+	if err = c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, DoseResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	// staff members record the doses of the patients booked at their treatment center
+	if claims := claimsFromContext(c); claims.Role == RoleStaff {
+		booked, err := v.appointmentBookingsRepository.BookedAt(ctx, patientID, *claims.TreatmentCenterID)
+		if err != nil {
+			code, r := handleRepositoryError(err, v.logger)
+			c.JSON(code, DoseResponse{Error: r})
+			return
+		}
+		if !booked {
+			c.JSON(http.StatusForbidden, DoseResponse{Error: &ErrorResponse{Msg: ErrForbidden.Error()}})
+			return
+		}
+	}
+
+	// the dose is recorded whatever the bookings it puts off the vaccine schedule,
+	// they are cancelled and their seats go to the waitlist along with the dose
+	dose := input.buildModel(patientID)
+	var (
+		cancelled []*domain.PatientBooking
+		offered   []*domain.AppointmentBooking
+	)
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if cancelled, err = r.Doses().Create(ctx, &dose); err != nil {
+			return err
+		}
+		for _, booking := range cancelled {
+			offered = append(offered, v.offers.In(r).Hold(ctx, booking.AppointmentID)...)
+		}
+		return nil
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, DoseResponse{Error: r})
+		return
+	}
+	v.offers.Notify(ctx, offered)
+	notifyWithdrawn(ctx, v.confirmations, cancelled, v.logger)
+	c.JSON(http.StatusCreated, DoseResponse{Dose: &dose, CancelledBookings: cancelled})
+}
+
+func (v *PatientsController) GetWaitlistEndpoint(c *gin.Context) {
+	patientID, err := extractPatientID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, WaitlistEntriesResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizePatient(c, patientID) {
+		return
+	}
+
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, WaitlistEntriesResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	ctx := c.Request.Context()
+	// an unknown patient is not found rather than without waitlist entries
+	if _, err = v.patientsRepository.FindByID(ctx, patientID); err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, WaitlistEntriesResponse{Error: r})
+		return
+	}
+	entries, page, err := v.waitlistRepository.AllByPatientID(ctx, patientID, pageRequest)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, WaitlistEntriesResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, WaitlistEntriesResponse{WaitlistEntries: entries, PageResponse: newPageResponse(page)})
+}
+
+func (v *PatientsController) JoinWaitlistEndpoint(c *gin.Context) {
+	patientID, err := extractPatientID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, WaitlistEntryResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizePatient(c, patientID) {
+		return
+	}
+	var input inputWaitlistEntry
+	if err = c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, WaitlistEntryResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	entry := input.buildModel(patientID)
+	if err = entry.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, WaitlistEntryResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+
+	if err = v.waitlistRepository.Join(c.Request.Context(), &entry); err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, WaitlistEntryResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusCreated, WaitlistEntryResponse{WaitlistEntry: &entry})
+}
+
+func (v *PatientsController) WithdrawFromWaitlistEndpoint(c *gin.Context) {
+	patientID, err := extractPatientID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, WaitlistEntryResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizePatient(c, patientID) {
+		return
+	}
+	entryID, err := extractWaitlistEntryID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, WaitlistEntryResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var entry *domain.WaitlistEntry
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		entry, err = r.Waitlist().FindByID(ctx, entryID)
+		if err == nil && entry.PatientID != patientID {
+			err = repository.ErrRecordNotFound
+		}
+		if err == nil {
+			err = r.Waitlist().Withdraw(ctx, entryID)
+		}
+		if err == nil {
+			entry, err = r.Waitlist().FindByID(ctx, entryID)
+		}
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, WaitlistEntryResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, WaitlistEntryResponse{WaitlistEntry: entry})
+}
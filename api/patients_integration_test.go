@@ -0,0 +1,490 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steinfletcher/apitest"
+	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"github.com/stretchr/testify/suite"
+	"github.com/y9mo/covidvax/testutils"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type PatientsApiIntegrationTestSuite struct {
+	ApiIntegrationSuite
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestPatientsIndexList() {
+	var cursor string
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/").
+		Header("Authorization", s.AdminBearer()).
+		QueryParams(map[string]string{"sort": "last_name", "direction": "desc", "limit": "2"}).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.patients`, 2)).
+		Assert(jsonpath.Equal(`$.patients[0].last_name`, "Two")).
+		Assert(jsonpath.Equal(`$.patients[1].last_name`, "Three")).
+		Assert(jsonpath.Equal(`$.total`, float64(6))).
+		Assert(testutils.Extract(`$.next_cursor`, &cursor)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/").
+		Header("Authorization", s.AdminBearer()).
+		QueryParams(map[string]string{"sort": "last_name", "direction": "desc", "limit": "2", "cursor": cursor}).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.patients[0].last_name`, "Six")).
+		Assert(jsonpath.Equal(`$.patients[1].last_name`, "One")).
+		End()
+
+	// a cursor only resumes the sort it was made for
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/").
+		Header("Authorization", s.AdminBearer()).
+		QueryParams(map[string]string{"sort": "email", "cursor": cursor}).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Present(`$.error.message`)).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestCreatePatient() {
+	var id string
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"email": " patient.seven@some.com", "first_name": "Patient ", "last_name": "Seven",
+			"phone": "06 11 22 33 44", "birth_date": "1980-05-17T00:00:00Z", "national_health_id": "1 80 05 75 123 456 78"}`).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(testutils.Extract(`$.patient.id`, &id)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get(fmt.Sprintf("/v1/patients/%s", id)).
+		Header("Authorization", s.AdminBearer()).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.patient.email`, "patient.seven@some.com")).
+		Assert(jsonpath.Equal(`$.patient.first_name`, "Patient")).
+		Assert(jsonpath.Equal(`$.patient.last_name`, "Seven")).
+		Assert(jsonpath.Equal(`$.patient.phone`, "+33611223344")).
+		Assert(jsonpath.Equal(`$.patient.birth_date`, "1980-05-17T00:00:00Z")).
+		Assert(jsonpath.Equal(`$.patient.national_health_id`, "180057512345678")).
+		End()
+
+	// emails are unique whatever their case
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"email": "Patient.Seven@Some.com", "first_name": "Patient", "last_name": "Seven"}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.message`, "Already exist")).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestCreatePatientInvalidFields() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"email": "patient.seven@some.com", "first_name": "Patient"}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Len(`$.error.fields`, 1)).
+		Assert(jsonpath.Equal(`$.error.fields[0].field`, "last_name")).
+		Assert(jsonpath.Equal(`$.error.fields[0].message`, "is required")).
+		End()
+
+	tests := []struct {
+		name    string
+		body    string
+		field   string
+		message string
+	}{
+		{name: "Email", body: `{"email": "patient.seven", "first_name": "Patient", "last_name": "Seven"}`,
+			field: "email", message: "must be an email address"},
+		{name: "BlankName", body: `{"email": "patient.seven@some.com", "first_name": "  ", "last_name": "Seven"}`,
+			field: "first_name", message: "is required"},
+		{name: "LongName", body: fmt.Sprintf(`{"email": "patient.seven@some.com", "first_name": "Patient",
+			"last_name": %q}`, strings.Repeat("e", 101)),
+			field: "last_name", message: "must be at most 100 characters long"},
+		{name: "Phone", body: `{"email": "patient.seven@some.com", "first_name": "Patient", "last_name": "Seven",
+			"phone": "call me"}`,
+			field: "phone", message: "must be a phone number"},
+		{name: "ShortPhone", body: `{"email": "patient.seven@some.com", "first_name": "Patient", "last_name": "Seven",
+			"phone": "+331"}`,
+			field: "phone", message: "must be a phone number"},
+		{name: "BirthDate", body: `{"email": "patient.seven@some.com", "first_name": "Patient", "last_name": "Seven",
+			"birth_date": "2999-01-01T00:00:00Z"}`,
+			field: "birth_date", message: "must be in the past"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			apitest.New().Debug().
+				Handler(s.Router).
+				Post("/v1/patients/").
+				Header("Authorization", s.AdminBearer()).
+				JSON(tc.body).
+				Expect(s.T()).
+				Status(http.StatusBadRequest).
+				Assert(jsonpath.Equal(`$.error.message`, "invalid fields")).
+				Assert(jsonpath.Len(`$.error.fields`, 1)).
+				Assert(jsonpath.Equal(`$.error.fields[0].field`, tc.field)).
+				Assert(jsonpath.Equal(`$.error.fields[0].message`, tc.message)).
+				End()
+		})
+	}
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestUpdatePatient() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Put("/v1/patients/8152fcbe-3228-46c9-b483-edcb6317d99c").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"email": "patient.one@other.com", "first_name": "Patient", "last_name": "First",
+			"phone": "0611223344"}`).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.patient.email`, "patient.one@other.com")).
+		Assert(jsonpath.Equal(`$.patient.last_name`, "First")).
+		Assert(jsonpath.Equal(`$.patient.phone`, "+33611223344")).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestPatchPatient() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/patients/8152fcbe-3228-46c9-b483-edcb6317d99c").
+		Header("Authorization", s.AdminBearer()).
+		Body(`{"last_name": "First", "phone": "0611223344"}`).
+		ContentType("application/merge-patch+json").
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.patient.email`, "patient.one@some.com")).
+		Assert(jsonpath.Equal(`$.patient.last_name`, "First")).
+		Assert(jsonpath.Equal(`$.patient.phone`, "+33611223344")).
+		End()
+
+	// null removes a member of the patient
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/patients/8152fcbe-3228-46c9-b483-edcb6317d99c").
+		Header("Authorization", s.AdminBearer()).
+		Body(`{"phone": null}`).
+		ContentType("application/merge-patch+json").
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.patient.last_name`, "First")).
+		Assert(jsonpath.NotPresent(`$.patient.phone`)).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestPatchPatientInvalid() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/patients/8152fcbe-3228-46c9-b483-edcb6317d99c").
+		Header("Authorization", s.AdminBearer()).
+		Body(`{"email": null}`).
+		ContentType("application/merge-patch+json").
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.fields[0].field`, "email")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/patients/8152fcbe-3228-46c9-b483-edcb6317d99c").
+		Header("Authorization", s.AdminBearer()).
+		Body(`{"first_name": 1}`).
+		ContentType("application/merge-patch+json").
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.fields[0].field`, "first_name")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/patients/8152fcbe-3228-46c9-b483-edcb6317d99c").
+		Header("Authorization", s.AdminBearer()).
+		Body(`last_name=First`).
+		ContentType("text/plain").
+		Expect(s.T()).
+		Status(http.StatusUnsupportedMediaType).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/patients/00000000-0000-4000-8000-000000000000").
+		Header("Authorization", s.AdminBearer()).
+		Body(`{"last_name": "First"}`).
+		ContentType("application/merge-patch+json").
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestMergeDuplicatePatient() {
+	var id string
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"email": "patient.three+vax@any.com", "first_name": "Patient", "last_name": "Thre", "phone": "0611223344"}`).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(testutils.Extract(`$.patient.id`, &id)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884/duplicates").
+		Header("Authorization", s.AdminBearer()).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.candidates`, 1)).
+		Assert(jsonpath.Equal(`$.candidates[0].id`, id)).
+		Assert(jsonpath.Equal(`$.candidates[0].reasons[0]`, "similar_email")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884/merge").
+		Header("Authorization", s.AdminBearer()).
+		JSON(fmt.Sprintf(`{"duplicate_id": %q}`, id)).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.patient.email`, "patient.three@any.com")).
+		Assert(jsonpath.Equal(`$.patient.phone`, "+33611223344")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get(fmt.Sprintf("/v1/patients/%s", id)).
+		Header("Authorization", s.AdminBearer()).
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884/duplicates").
+		Header("Authorization", s.AdminBearer()).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.NotPresent(`$.candidates`)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884/merge").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"duplicate_id": "24e32685-0a32-4a9d-bc22-0e98cdaf5884"}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884/merge").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"duplicate_id": "00000000-0000-4000-8000-000000000000"}`).
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestGetPatientBookings() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884/bookings").
+		Header("Authorization", s.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.bookings`, 1)).
+		Assert(jsonpath.Equal(`$.bookings[0].id`, "f859ae2c-e24f-46e8-9c27-4431112fc710")).
+		Assert(jsonpath.Equal(`$.bookings[0].status`, "confirmed")).
+		Assert(jsonpath.Equal(`$.bookings[0].start_time`, "2021-11-13T18:00:00Z")).
+		Assert(jsonpath.Equal(`$.bookings[0].treatment_center_name`, "Center in the game")).
+		Assert(jsonpath.Equal(`$.bookings[0].treatment_center_address`, "game time")).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestGetPatientBookingsOfAnotherPatient() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884/bookings").
+		Header("Authorization", s.PatientBearer("8152fcbe-3228-46c9-b483-edcb6317d99c")).
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884").
+		Header("Authorization", s.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestGetUnknownPatientBookings() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/00000000-0000-4000-8000-000000000000/bookings").
+		Header("Authorization", s.AdminBearer()).
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestGetUnknownPatientDoses() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/00000000-0000-4000-8000-000000000000/doses").
+		Header("Authorization", s.AdminBearer()).
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestGetUnknownPatientWaitlist() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/00000000-0000-4000-8000-000000000000/waitlist").
+		Header("Authorization", s.AdminBearer()).
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestAddPatientDose() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2/doses").
+		Header("Authorization", s.StaffBearer("0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8")).
+		JSON(`{"vaccine_product_id": "7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8", "administered_at": "2021-11-25T09:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Present(`$.dose.id`)).
+		Assert(jsonpath.Equal(`$.dose.number`, float64(2))).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2/doses").
+		Header("Authorization", s.PatientBearer("3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2")).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.doses`, 2)).
+		Assert(jsonpath.Equal(`$.doses[0].number`, float64(1))).
+		Assert(jsonpath.Equal(`$.doses[1].administered_at`, "2021-11-25T09:00:00Z")).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestAddDoseOfAnotherCenterPatient() {
+	// the patient was never booked at the center of the staff member
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2/doses").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(`{"vaccine_product_id": "7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8", "administered_at": "2021-11-25T09:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestBookFollowUpDoseTooEarly() {
+	s.Postpone("appointments", "start_time", "eecce415-2d4c-440d-ac90-9780a3bd3371")
+	s.Postpone("doses", "administered_at", "5c4b3a29-1807-46f5-a4e3-d2c1b0a9f8e7")
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/appointments/eecce415-2d4c-440d-ac90-9780a3bd3371/bookings").
+		Header("Authorization", s.PatientBearer("3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2")).
+		JSON(`{"patient_id": "3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2"}`).
+		Expect(s.T()).
+		Status(http.StatusConflict).
+		Assert(jsonpath.Equal(`$.error.message`, "too early for the next dose of the vaccine")).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestWaitlist() {
+	var entryID string
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/a1c2e3f4-5b6d-4e7f-8091-a2b3c4d5e6f7/waitlist").
+		Header("Authorization", s.PatientBearer("a1c2e3f4-5b6d-4e7f-8091-a2b3c4d5e6f7")).
+		JSON(`{"treatment_center_id": "52b2edf2-a380-4436-9f98-b70f78f174ef",
+			"from_date": "2099-01-01T00:00:00Z", "to_date": "2099-01-31T00:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Equal(`$.waitlist_entry.status`, "waiting")).
+		Assert(testutils.Extract(`$.waitlist_entry.id`, &entryID)).
+		End()
+
+	// the new appointment goes to the first eligible patient of the waitlist
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/appointments/").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(`{"treatment_center_id": "52b2edf2-a380-4436-9f98-b70f78f174ef", "start_time": "2099-01-05T09:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Equal(`$.appointment.remaining_seats`, float64(0))).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/98953c1f-e91e-494e-8935-1904fb6bb33a/waitlist").
+		Header("Authorization", s.PatientBearer("98953c1f-e91e-494e-8935-1904fb6bb33a")).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.waitlist_entries`, 1)).
+		Assert(jsonpath.Equal(`$.waitlist_entries[0].status`, "offered")).
+		Assert(jsonpath.Present(`$.waitlist_entries[0].appointment_booking_id`)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Delete("/v1/patients/a1c2e3f4-5b6d-4e7f-8091-a2b3c4d5e6f7/waitlist/"+entryID).
+		Header("Authorization", s.PatientBearer("a1c2e3f4-5b6d-4e7f-8091-a2b3c4d5e6f7")).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.waitlist_entry.status`, "withdrawn")).
+		End()
+}
+
+func (s *PatientsApiIntegrationTestSuite) TestJoinWaitlistInvalidRange() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/patients/a1c2e3f4-5b6d-4e7f-8091-a2b3c4d5e6f7/waitlist").
+		Header("Authorization", s.PatientBearer("a1c2e3f4-5b6d-4e7f-8091-a2b3c4d5e6f7")).
+		JSON(`{"treatment_center_id": "52b2edf2-a380-4436-9f98-b70f78f174ef",
+			"from_date": "2099-01-31T00:00:00Z", "to_date": "2099-01-01T00:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.message`, "to_date must not be before from_date")).
+		End()
+}
+
+func TestPatientsApiIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, new(PatientsApiIntegrationTestSuite))
+}
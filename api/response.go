@@ -3,12 +3,21 @@ package api
 import (
 	"net/http"
 
+	"github.com/y9mo/covidvax/domain"
 	"github.com/y9mo/covidvax/repository"
 	"go.uber.org/zap"
 )
 
 type ErrorResponse struct {
-	Msg string `json:"message"`
+	Msg    string       `json:"message"`
+	Fields []FieldError `json:"fields,omitempty"`
+	// Rule is the eligibility rule a patient doesn't meet to book an appointment
+	Rule *domain.EligibilityRule `json:"rule,omitempty"`
+}
+
+// Response is the body of the errors not specific to a resource
+type Response struct {
+	Error *ErrorResponse `json:"error,omitempty"`
 }
 
 // handleRepositoryError will generate an http status code and a errorResponse
@@ -22,10 +31,40 @@ func handleRepositoryError(err error, logger *zap.Logger) (int, *ErrorResponse)
 		return http.StatusBadRequest, &ErrorResponse{Msg: "Already exist"}
 	}
 
-	if err == repository.ErrInvalidID {
+	if err == repository.ErrInvalidID || err == repository.ErrUnknownLot {
+		return http.StatusBadRequest, &ErrorResponse{Msg: err.Error()}
+	}
+
+	if err == repository.ErrInvalidSort || err == repository.ErrInvalidCursor {
 		return http.StatusBadRequest, &ErrorResponse{Msg: err.Error()}
 	}
 
+	if err == repository.ErrInvalidStatusTransition || err == repository.ErrAppointmentNotAvailable ||
+		err == domain.ErrAppointmentStarted {
+		return http.StatusConflict, &ErrorResponse{Msg: err.Error()}
+	}
+
+	if err == repository.ErrAppointmentBooked || err == repository.ErrCapacityBelowBookings ||
+		err == repository.ErrTreatmentCenterBooked || err == repository.ErrPatientsConflict {
+		return http.StatusConflict, &ErrorResponse{Msg: err.Error()}
+	}
+
+	if err == repository.ErrInsufficientStock || err == repository.ErrLotUnusable {
+		return http.StatusConflict, &ErrorResponse{Msg: err.Error()}
+	}
+
+	if err == domain.ErrDoseTooEarly || err == domain.ErrDoseTooLate || err == domain.ErrSeriesCompleted {
+		return http.StatusConflict, &ErrorResponse{Msg: err.Error()}
+	}
+
+	if ineligible, ok := err.(*domain.IneligibleError); ok {
+		return http.StatusConflict, &ErrorResponse{Msg: err.Error(), Rule: ineligible.Rule}
+	}
+
+	if err == repository.ErrQueryCanceled {
+		return http.StatusServiceUnavailable, &ErrorResponse{Msg: err.Error()}
+	}
+
 	logger.Error("database Error", zap.Error(err))
 	return http.StatusInternalServerError, &ErrorResponse{Msg: "internal error"}
 }
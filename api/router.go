@@ -6,7 +6,9 @@ import (
 	ginzap "github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
 	"github.com/y9mo/covidvax"
+	"github.com/y9mo/covidvax/confirmation"
 	"github.com/y9mo/covidvax/repository"
+	"github.com/y9mo/covidvax/waitlist"
 	"go.uber.org/zap"
 )
 
@@ -16,20 +18,39 @@ func Setup(
 	tcr repository.TreatmentCenters,
 	ar repository.Appointments,
 	abr repository.AppointmentBookings,
+	vpr repository.VaccineProducts,
+	dr repository.Doses,
+	vlr repository.VaccineLots,
+	wr repository.Waitlist,
+	elr repository.EligibilityRules,
+	uow repository.UnitOfWork,
+	confirmations *confirmation.Service,
+	offers *waitlist.Service,
+	authenticator *Authenticator,
+	nationalCallingCode string,
+	dbTimeout time.Duration,
 ) (*gin.Engine, error) {
+	phones, err := newPhoneNumbers(nationalCallingCode)
+	if err != nil {
+		return nil, err
+	}
 
 	router := gin.New()
 
 	router.Use(ginzap.Ginzap(logger, time.RFC3339, true))
 	// Logs panic to error log
 	router.Use(ginzap.RecoveryWithZap(logger, true))
+	router.Use(Timeout(dbTimeout))
 
 	router.GET("/", Index)
 
 	g := router.Group("/v1")
-	SetupPatient(g, pr, logger)
-	SetupTreatmentCenter(g, tcr, ar, logger)
-	SetupAppointment(g, ar, abr, logger)
+	SetupPatient(g, pr, abr, dr, wr, uow, confirmations, offers, phones, authenticator, logger)
+	SetupTreatmentCenter(g, tcr, ar, vlr, uow, confirmations, offers, phones, authenticator, logger)
+	SetupAppointment(g, ar, tcr, abr, uow, confirmations, offers, authenticator, logger)
+	SetupBooking(g, uow, confirmations, logger)
+	SetupVaccineProduct(g, vpr, uow, authenticator, logger)
+	SetupEligibilityRule(g, elr, uow, authenticator, logger)
 	return router, nil
 }
 
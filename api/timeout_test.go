@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		wantDeadline bool
+	}{
+		{name: "Bounded", timeout: time.Second, wantDeadline: true},
+		{name: "Disabled"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var ctx context.Context
+			router := gin.New()
+			router.GET("/", Timeout(tc.timeout), func(c *gin.Context) {
+				ctx = c.Request.Context()
+				c.Status(http.StatusNoContent)
+			})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			deadline, ok := ctx.Deadline()
+			assert.Equal(t, tc.wantDeadline, ok)
+			if tc.wantDeadline {
+				assert.WithinDuration(t, time.Now().Add(tc.timeout), deadline, tc.timeout)
+				// the context is released once the request is served
+				assert.ErrorIs(t, ctx.Err(), context.Canceled)
+			}
+		})
+	}
+}
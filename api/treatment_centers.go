@@ -1,19 +1,32 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/confirmation"
 	"github.com/y9mo/covidvax/domain"
 	"github.com/y9mo/covidvax/repository"
+	"github.com/y9mo/covidvax/scheduling"
+	"github.com/y9mo/covidvax/waitlist"
 	"go.uber.org/zap"
 )
 
 type TreatmentCentersResponse struct {
 	TreatmentCenters []*domain.TreatmentCenter `json:"treatment_centers,omitempty"`
-	Error            *ErrorResponse            `json:"error,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type NearbyTreatmentCentersResponse struct {
+	TreatmentCenters []*domain.NearbyTreatmentCenter `json:"treatment_centers,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
 }
 
 type TreatmentCenterResponse struct {
@@ -28,29 +41,179 @@ type TreatmentCenterAppointmentResponse struct {
 
 type TreatmentCenterAppointmentsResponse struct {
 	Appointments []*domain.Appointment `json:"appointments,omitempty"`
-	Error        *ErrorResponse        `json:"error,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type VaccineLotsResponse struct {
+	VaccineLots []*domain.VaccineLot `json:"vaccine_lots,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type VaccineLotResponse struct {
+	VaccineLot *domain.VaccineLot `json:"vaccine_lot,omitempty"`
+	Error      *ErrorResponse     `json:"error,omitempty"`
 }
 
 type TreatmentCentersController struct {
 	treatmentCentersRepository repository.TreatmentCenters
 	appointmentsRepository     repository.Appointments
+	vaccineLotsRepository      repository.VaccineLots
+	unitOfWork                 repository.UnitOfWork
+	confirmations              *confirmation.Service
+	offers                     *waitlist.Service
+	generator                  *scheduling.Generator
+	phones                     phoneNumbers
 	logger                     *zap.Logger
 }
 
 type inputTreatmentCenter struct {
 	domain.TreatmentCenter
+	phones phoneNumbers
 }
 
 func (t *inputTreatmentCenter) buildModel() domain.TreatmentCenter {
-	return domain.TreatmentCenter{
-		ID:   uuid.New(),
-		Name: t.Name,
+	treatmentCenter := domain.TreatmentCenter{
+		ID: uuid.New(),
+	}
+	t.updateModel(&treatmentCenter)
+	schedule := inputTreatmentCenterSchedule{
+		Timezone:     t.Timezone,
+		SlotDuration: t.SlotDuration,
+		SlotCapacity: t.SlotCapacity,
+		OpeningHours: t.OpeningHours,
+		Closures:     t.Closures,
 	}
+	schedule.updateModel(&treatmentCenter)
+	return treatmentCenter
 }
 
+// updateModel sets the details of a treatment center, its schedule is left
+// to inputTreatmentCenterSchedule
 func (t *inputTreatmentCenter) updateModel(treatmentCenter *domain.TreatmentCenter) {
-	treatmentCenter.Name = t.Name
+	treatmentCenter.Name = normalizeName(t.Name)
 	treatmentCenter.Address = t.Address
+	treatmentCenter.Phone = t.phones.normalize(t.Phone)
+	treatmentCenter.Latitude = t.Latitude
+	treatmentCenter.Longitude = t.Longitude
+}
+
+type inputTreatmentCenterSchedule struct {
+	Timezone     string                 `json:"timezone"`
+	SlotDuration int                    `json:"slot_duration" binding:"omitempty,min=1"`
+	SlotCapacity int                    `json:"slot_capacity" binding:"omitempty,min=1"`
+	OpeningHours []*domain.OpeningHours `json:"opening_hours" binding:"dive"`
+	Closures     []*domain.Closure      `json:"closures" binding:"dive"`
+}
+
+const (
+	defaultTimezone     = "UTC"
+	defaultSlotDuration = 10
+	defaultSlotCapacity = 1
+)
+
+func (t *inputTreatmentCenterSchedule) updateModel(treatmentCenter *domain.TreatmentCenter) {
+	treatmentCenter.Timezone = t.Timezone
+	if treatmentCenter.Timezone == "" {
+		treatmentCenter.Timezone = defaultTimezone
+	}
+	treatmentCenter.SlotDuration = t.SlotDuration
+	if treatmentCenter.SlotDuration == 0 {
+		treatmentCenter.SlotDuration = defaultSlotDuration
+	}
+	treatmentCenter.SlotCapacity = t.SlotCapacity
+	if treatmentCenter.SlotCapacity == 0 {
+		treatmentCenter.SlotCapacity = defaultSlotCapacity
+	}
+
+	treatmentCenter.OpeningHours = make([]*domain.OpeningHours, 0, len(t.OpeningHours))
+	for _, hours := range t.OpeningHours {
+		treatmentCenter.OpeningHours = append(treatmentCenter.OpeningHours, &domain.OpeningHours{
+			ID:                uuid.New(),
+			TreatmentCenterID: treatmentCenter.ID,
+			Weekday:           hours.Weekday,
+			OpensAt:           hours.OpensAt,
+			ClosesAt:          hours.ClosesAt,
+		})
+	}
+	treatmentCenter.Closures = make([]*domain.Closure, 0, len(t.Closures))
+	for _, closure := range t.Closures {
+		treatmentCenter.Closures = append(treatmentCenter.Closures, &domain.Closure{
+			ID:                uuid.New(),
+			TreatmentCenterID: treatmentCenter.ID,
+			Date:              closure.Date,
+			Reason:            closure.Reason,
+		})
+	}
+}
+
+// validateSchedule checks the parts of a schedule the binding can't
+func validateSchedule(treatmentCenter *domain.TreatmentCenter) error {
+	if _, err := treatmentCenter.Location(); err != nil {
+		return err
+	}
+	for _, hours := range treatmentCenter.OpeningHours {
+		if err := hours.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type inputVaccineLot struct {
+	domain.VaccineLot
+}
+
+func (t *inputVaccineLot) buildModel(treatmentCenterID uuid.UUID) domain.VaccineLot {
+	return domain.VaccineLot{
+		ID:                uuid.New(),
+		TreatmentCenterID: treatmentCenterID,
+		VaccineProductID:  t.VaccineProductID,
+		LotNumber:         t.LotNumber,
+		Quantity:          t.Quantity,
+		ExpiresOn:         t.ExpiresOn,
+		ReceivedAt:        currentDaytime(),
+	}
+}
+
+type GenerateAppointmentsRequest struct {
+	From *time.Time `form:"from" time_format:"2006-01-02" time_utc:"1" binding:"required"`
+	To   *time.Time `form:"to" time_format:"2006-01-02" time_utc:"1" binding:"required"`
+}
+
+const defaultNearbyRadiusKm = 20
+
+// NearbyTreatmentCentersRequest are the query parameters of the nearby search,
+// near is the point formatted as lat,lng and available_until a day in UTC, included
+type NearbyTreatmentCentersRequest struct {
+	Near           string     `form:"near"`
+	RadiusKm       float64    `form:"radius_km" binding:"omitempty,gt=0,max=1000"`
+	AvailableUntil *time.Time `form:"available_until" time_format:"2006-01-02" time_utc:"1"`
+}
+
+func (r *NearbyTreatmentCentersRequest) buildSearch() (domain.NearbySearch, error) {
+	search := domain.NearbySearch{RadiusKm: r.RadiusKm}
+	if search.RadiusKm == 0 {
+		search.RadiusKm = defaultNearbyRadiusKm
+	}
+	if r.AvailableUntil != nil {
+		until := r.AvailableUntil.AddDate(0, 0, 1)
+		search.AvailableUntil = &until
+	}
+	invalid := errors.New("near must be formatted as lat,lng")
+	parts := strings.Split(r.Near, ",")
+	if len(parts) != 2 {
+		return search, invalid
+	}
+	var err error
+	if search.Latitude, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil {
+		return search, invalid
+	}
+	if search.Longitude, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+		return search, invalid
+	}
+	return search, search.Validate()
 }
 
 type TreatmentCenterAppointmentRequest struct {
@@ -61,19 +224,42 @@ func SetupTreatmentCenter(
 	router gin.IRouter,
 	treatmentCentersRepository repository.TreatmentCenters,
 	appointmentsRepository repository.Appointments,
+	vaccineLotsRepository repository.VaccineLots,
+	unitOfWork repository.UnitOfWork,
+	confirmations *confirmation.Service,
+	offers *waitlist.Service,
+	phones phoneNumbers,
+	authenticator *Authenticator,
 	logger *zap.Logger) {
 	c := TreatmentCentersController{
 		treatmentCentersRepository: treatmentCentersRepository,
 		appointmentsRepository:     appointmentsRepository,
+		vaccineLotsRepository:      vaccineLotsRepository,
+		unitOfWork:                 unitOfWork,
+		confirmations:              confirmations,
+		offers:                     offers,
+		generator:                  scheduling.NewGenerator(treatmentCentersRepository, appointmentsRepository, logger),
+		phones:                     phones,
 		logger:                     logger.With(zap.String("component", "TreatmentCentersController")),
 	}
 	g := router.Group(
 		"/treatment_centers",
 	)
+	auth := authenticator.Authenticate()
 	g.GET("/", c.IndexEndpoint)
-	g.POST("/", c.CreateEndpoint)
+	g.POST("/", auth, RequireRole(RoleAdmin), c.CreateEndpoint)
 	g.GET("/:treatment_center_id", c.GetEndpoint)
-	g.GET("/:treatment_center_id/bookings", c.GetBookedAppointmentsEndpoint)
+	g.PUT("/:treatment_center_id", auth, RequireRole(RoleStaff, RoleAdmin), c.UpdateEndpoint)
+	g.PATCH("/:treatment_center_id", auth, RequireRole(RoleStaff, RoleAdmin), c.PatchEndpoint)
+	g.DELETE("/:treatment_center_id", auth, RequireRole(RoleAdmin), c.DeleteEndpoint)
+	g.GET("/:treatment_center_id/bookings", auth, RequireRole(RoleStaff, RoleAdmin), c.GetBookedAppointmentsEndpoint)
+	g.PUT("/:treatment_center_id/schedule", auth, RequireRole(RoleStaff, RoleAdmin), c.UpdateScheduleEndpoint)
+	g.POST("/:treatment_center_id/appointments/generate", auth, RequireRole(RoleStaff, RoleAdmin),
+		c.GenerateAppointmentsEndpoint)
+	g.POST("/:treatment_center_id/bookings/:booking_id/outcome", auth, RequireRole(RoleStaff, RoleAdmin),
+		c.RecordOutcomeEndpoint)
+	g.GET("/:treatment_center_id/stock", auth, RequireRole(RoleStaff, RoleAdmin), c.GetStockEndpoint)
+	g.POST("/:treatment_center_id/stock/deliveries", auth, RequireRole(RoleStaff, RoleAdmin), c.ReceiveDeliveryEndpoint)
 }
 
 func extractTreatmentCenterID(c *gin.Context) (id uuid.UUID, err error) {
@@ -86,13 +272,51 @@ func currentDaytime() *time.Time {
 }
 
 func (v *TreatmentCentersController) IndexEndpoint(c *gin.Context) {
-	treatmentCenters, err := v.treatmentCentersRepository.All()
+	if c.Query("near") != "" {
+		v.nearby(c)
+		return
+	}
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCentersResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	treatmentCenters, page, err := v.treatmentCentersRepository.All(c.Request.Context(), pageRequest)
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
-		c.JSON(code, TreatmentCenterResponse{Error: r})
+		c.JSON(code, TreatmentCentersResponse{Error: r})
 		return
 	}
-	c.JSON(http.StatusOK, TreatmentCentersResponse{TreatmentCenters: treatmentCenters})
+	c.JSON(http.StatusOK, TreatmentCentersResponse{TreatmentCenters: treatmentCenters, PageResponse: newPageResponse(page)})
+}
+
+// nearby lists the treatment centers around a point, the closest first, with
+// their next available appointment
+func (v *TreatmentCentersController) nearby(c *gin.Context) {
+	var request NearbyTreatmentCentersRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, NearbyTreatmentCentersResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	search, err := request.buildSearch()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NearbyTreatmentCentersResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NearbyTreatmentCentersResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	treatmentCenters, page, err := v.treatmentCentersRepository.Nearby(c.Request.Context(), search, pageRequest,
+		time.Now())
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, NearbyTreatmentCentersResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, NearbyTreatmentCentersResponse{TreatmentCenters: treatmentCenters,
+		PageResponse: newPageResponse(page)})
 }
 
 func (v *TreatmentCentersController) GetEndpoint(c *gin.Context) {
@@ -101,7 +325,7 @@ func (v *TreatmentCentersController) GetEndpoint(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: &ErrorResponse{Msg: err.Error()}})
 		return
 	}
-	treatmentCenter, err := v.treatmentCentersRepository.FindByID(id)
+	treatmentCenter, err := v.treatmentCentersRepository.FindByID(c.Request.Context(), id)
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, TreatmentCenterResponse{Error: r})
@@ -111,30 +335,138 @@ func (v *TreatmentCentersController) GetEndpoint(c *gin.Context) {
 }
 
 func (v *TreatmentCentersController) CreateEndpoint(c *gin.Context) {
-	var (
-		input inputTreatmentCenter
-		err   error
-	)
+	var err error
+	input := inputTreatmentCenter{phones: v.phones}
 
 	if err = c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: bindingErrorResponse(err)})
 		return
 	}
 	treatmentCenter := input.buildModel()
-	err = v.treatmentCentersRepository.Create(&treatmentCenter)
+	if err = treatmentCenter.ValidateCoordinates(); err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if err = validateSchedule(&treatmentCenter); err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	ctx := c.Request.Context()
+	var t *domain.TreatmentCenter
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.TreatmentCenters().Create(ctx, &treatmentCenter); err != nil {
+			return err
+		}
+		t, err = r.TreatmentCenters().FindByID(ctx, treatmentCenter.ID)
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, TreatmentCenterResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusCreated, TreatmentCenterResponse{TreatmentCenter: t})
+}
+
+// findTreatmentCenter returns the treatment center of the path, it answers 403
+// when the authenticated staff isn't of the center
+func (v *TreatmentCentersController) findTreatmentCenter(c *gin.Context) (*domain.TreatmentCenter, bool) {
+	id, err := extractTreatmentCenterID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return nil, false
+	}
+	if !authorizeStaffOf(c, id) {
+		return nil, false
+	}
+	treatmentCenter, err := v.treatmentCentersRepository.FindByID(c.Request.Context(), id)
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, TreatmentCenterResponse{Error: r})
+		return nil, false
+	}
+	return treatmentCenter, true
+}
+
+// UpdateEndpoint replaces the details of a treatment center, its schedule is
+// updated through UpdateScheduleEndpoint
+func (v *TreatmentCentersController) UpdateEndpoint(c *gin.Context) {
+	treatmentCenter, ok := v.findTreatmentCenter(c)
+	if !ok {
+		return
+	}
+
+	input := inputTreatmentCenter{phones: v.phones}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	v.update(c, treatmentCenter, &input)
+}
+
+// PatchEndpoint updates the details of a treatment center present in a JSON
+// merge patch, its schedule is updated through UpdateScheduleEndpoint
+func (v *TreatmentCentersController) PatchEndpoint(c *gin.Context) {
+	treatmentCenter, ok := v.findTreatmentCenter(c)
+	if !ok {
+		return
+	}
+
+	input := inputTreatmentCenter{phones: v.phones}
+	if err := bindMergePatch(c, treatmentCenter, &input); err != nil {
+		c.JSON(patchStatus(err), TreatmentCenterResponse{Error: bindingErrorResponse(err)})
 		return
 	}
+	v.update(c, treatmentCenter, &input)
+}
+
+func (v *TreatmentCentersController) update(c *gin.Context, treatmentCenter *domain.TreatmentCenter,
+	input *inputTreatmentCenter) {
+	input.updateModel(treatmentCenter)
+	if err := treatmentCenter.ValidateCoordinates(); err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	ctx := c.Request.Context()
 	var t *domain.TreatmentCenter
-	t, err = v.treatmentCentersRepository.FindByID(treatmentCenter.ID)
+	err := v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.TreatmentCenters().Update(ctx, treatmentCenter); err != nil {
+			return err
+		}
+		t, err = r.TreatmentCenters().FindByID(ctx, treatmentCenter.ID)
+		return err
+	})
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, TreatmentCenterResponse{Error: r})
 		return
 	}
-	c.JSON(http.StatusCreated, TreatmentCenterResponse{TreatmentCenter: t})
+	c.JSON(http.StatusOK, TreatmentCenterResponse{TreatmentCenter: t})
+}
+
+// DeleteEndpoint deletes a treatment center along with its upcoming appointments,
+// with cancel_bookings their active bookings are cancelled and their patients
+// notified, otherwise they prevent the deletion
+func (v *TreatmentCentersController) DeleteEndpoint(c *gin.Context) {
+	treatmentCenter, ok := v.findTreatmentCenter(c)
+	if !ok {
+		return
+	}
+	var request DeleteRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	cancelled, err := v.treatmentCentersRepository.Delete(c.Request.Context(), treatmentCenter.ID,
+		request.CancelBookings, time.Now())
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, TreatmentCenterResponse{Error: r})
+		return
+	}
+	notifyWithdrawn(c.Request.Context(), v.confirmations, cancelled, v.logger)
+	c.Status(http.StatusNoContent)
 }
 
 func (v *TreatmentCentersController) GetBookedAppointmentsEndpoint(c *gin.Context) {
@@ -143,22 +475,220 @@ func (v *TreatmentCentersController) GetBookedAppointmentsEndpoint(c *gin.Contex
 		c.JSON(http.StatusBadRequest, TreatmentCenterAppointmentsResponse{Error: &ErrorResponse{Msg: err.Error()}})
 		return
 	}
+	if !authorizeStaffOf(c, id) {
+		return
+	}
 	var request TreatmentCenterAppointmentRequest
 	if err := c.ShouldBind(&request); err != nil {
-		c.JSON(http.StatusBadRequest, TreatmentCenterAppointmentsResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		c.JSON(http.StatusBadRequest, TreatmentCenterAppointmentsResponse{Error: bindingErrorResponse(err)})
 		return
 	}
 
 	if request.Date == nil {
 		request.Date = currentDaytime()
 	}
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterAppointmentsResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	treatmentCenterAppointments, page, err := v.appointmentsRepository.AllBookedByTreatmentCenterIDForDate(
+		c.Request.Context(), id, *request.Date, pageRequest)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, TreatmentCenterAppointmentsResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, TreatmentCenterAppointmentsResponse{Appointments: treatmentCenterAppointments,
+		PageResponse: newPageResponse(page)})
+}
+
+func (v *TreatmentCentersController) UpdateScheduleEndpoint(c *gin.Context) {
+	var input inputTreatmentCenterSchedule
+	id, err := extractTreatmentCenterID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizeStaffOf(c, id) {
+		return
+	}
+	if err = c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	treatmentCenter := domain.TreatmentCenter{ID: id}
+	input.updateModel(&treatmentCenter)
+	if err = validateSchedule(&treatmentCenter); err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var t *domain.TreatmentCenter
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.TreatmentCenters().UpdateSchedule(ctx, &treatmentCenter); err != nil {
+			return err
+		}
+		t, err = r.TreatmentCenters().FindByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, TreatmentCenterResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, TreatmentCenterResponse{TreatmentCenter: t})
+}
 
-	treatmentCenterAppointments, err := v.appointmentsRepository.AllBookedByTreatmentCenterIDForDate(id,
-		*request.Date)
+func (v *TreatmentCentersController) GenerateAppointmentsEndpoint(c *gin.Context) {
+	id, err := extractTreatmentCenterID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterAppointmentsResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizeStaffOf(c, id) {
+		return
+	}
+	var request GenerateAppointmentsRequest
+	if err = c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, TreatmentCenterAppointmentsResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	// the waitlisted patients are offered the appointments created
+	ctx := c.Request.Context()
+	var (
+		appointments []*domain.Appointment
+		offered      []*domain.AppointmentBooking
+	)
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		appointments, err = v.generator.In(r).Generate(ctx, id, *request.From, *request.To)
+		if err != nil {
+			return err
+		}
+		created := make([]uuid.UUID, 0, len(appointments))
+		for _, appointment := range appointments {
+			created = append(created, appointment.ID)
+		}
+		offered = v.offers.In(r).Hold(ctx, created...)
+		return nil
+	})
+	if err == scheduling.ErrInvalidRange || err == scheduling.ErrInvalidSlotDuration {
+		c.JSON(http.StatusBadRequest, TreatmentCenterAppointmentsResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
 	if err != nil {
 		code, r := handleRepositoryError(err, v.logger)
 		c.JSON(code, TreatmentCenterAppointmentsResponse{Error: r})
 		return
 	}
-	c.JSON(http.StatusOK, TreatmentCenterAppointmentsResponse{Appointments: treatmentCenterAppointments})
+	v.offers.Notify(ctx, offered)
+	c.JSON(http.StatusCreated, TreatmentCenterAppointmentsResponse{Appointments: appointments})
+}
+
+func (v *TreatmentCentersController) RecordOutcomeEndpoint(c *gin.Context) {
+	id, err := extractTreatmentCenterID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizeStaffOf(c, id) {
+		return
+	}
+	bookingID, err := extractBookingID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	var outcome domain.BookingOutcome
+	if err = c.ShouldBindJSON(&outcome); err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	// the staff member recording the outcome is assumed to have administered the dose
+	if outcome.AdministeredBy == "" && outcome.Status == domain.Administered {
+		outcome.AdministeredBy = claimsFromContext(c).Subject
+	}
+	if err = outcome.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, AppointmentBookingResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var appointmentBooking *domain.AppointmentBooking
+	err = v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		appointmentBooking, err = r.AppointmentBookings().FindByID(ctx, bookingID)
+		if err != nil {
+			return err
+		}
+		appointment, err := r.Appointments().FindByID(ctx, appointmentBooking.AppointmentID)
+		if err == nil && appointment.TreatmentCenterID != id {
+			err = repository.ErrRecordNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err = r.AppointmentBookings().RecordOutcome(ctx, bookingID, &outcome, time.Now().UTC()); err != nil {
+			return err
+		}
+		appointmentBooking, err = r.AppointmentBookings().FindByID(ctx, bookingID)
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, AppointmentBookingResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, AppointmentBookingResponse{AppointmentBooking: appointmentBooking})
+}
+
+func (v *TreatmentCentersController) GetStockEndpoint(c *gin.Context) {
+	id, err := extractTreatmentCenterID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, VaccineLotsResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizeStaffOf(c, id) {
+		return
+	}
+
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, VaccineLotsResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	vaccineLots, page, err := v.vaccineLotsRepository.AllByTreatmentCenterID(c.Request.Context(), id, pageRequest)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, VaccineLotsResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, VaccineLotsResponse{VaccineLots: vaccineLots, PageResponse: newPageResponse(page)})
+}
+
+func (v *TreatmentCentersController) ReceiveDeliveryEndpoint(c *gin.Context) {
+	id, err := extractTreatmentCenterID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, VaccineLotResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	if !authorizeStaffOf(c, id) {
+		return
+	}
+	var input inputVaccineLot
+	if err = c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, VaccineLotResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+
+	vaccineLot := input.buildModel(id)
+	if err = v.vaccineLotsRepository.Receive(c.Request.Context(), &vaccineLot); err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, VaccineLotResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusCreated, VaccineLotResponse{VaccineLot: &vaccineLot})
 }
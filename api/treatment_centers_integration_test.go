@@ -49,17 +49,59 @@ func (s *TreatmentCentersApiIntegrationTestSuite) TestTreatmentCentersIndexList(
 		End()
 }
 
+func (s *TreatmentCentersApiIntegrationTestSuite) TestNearbyTreatmentCenters() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/treatment_centers/").
+		Query("near", "48.8566,2.3522").
+		Query("radius_km", "30").
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.treatment_centers`, 2)).
+		Assert(jsonpath.Equal(`$.treatment_centers[0].id`, "52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		Assert(jsonpath.Equal(`$.treatment_centers[0].distance_km`, float64(0))).
+		Assert(jsonpath.Equal(`$.treatment_centers[1].id`, "32b2edf2-a380-4436-9f98-b70f78f1934d")).
+		Assert(jsonpath.Equal(`$.treatment_centers[1].name`, "Center of Light")).
+		Assert(jsonpath.Present(`$.treatment_centers[1].distance_km`)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/treatment_centers/").
+		Query("near", "48.8566,2.3522").
+		Query("available_until", "2099-12-31").
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.NotPresent(`$.treatment_centers`)).
+		End()
+}
+
+func (s *TreatmentCentersApiIntegrationTestSuite) TestNearbyTreatmentCentersInvalidSearch() {
+	for _, near := range []string{"48.8566", "north,2.3522", "91,2.3522"} {
+		apitest.New().Debug().
+			Handler(s.Router).
+			Get("/v1/treatment_centers/").
+			Query("near", near).
+			Expect(s.T()).
+			Status(http.StatusBadRequest).
+			Assert(jsonpath.Present(`$.error.message`)).
+			End()
+	}
+}
+
 func (s *TreatmentCentersApiIntegrationTestSuite) TestCreateTreatmentCenter() {
 	var id string
 	apitest.New().Debug().
 		Handler(s.Router).
 		Post("/v1/treatment_centers/").
+		Header("Authorization", s.AdminBearer()).
 		JSON(validTreatmentCenterJSON).
 		Expect(s.T()).
 		Status(http.StatusCreated).
 		Assert(jsonpath.Present(`$.treatment_center.id`)).
 		Assert(testutils.Extract(`$.treatment_center.id`, &id)).
 		Assert(jsonpath.Equal(`$.treatment_center.name`, "Center for test")).
+		Assert(jsonpath.Equal(`$.treatment_center.phone`, "+33102030405")).
 		Assert(jsonpath.Present(`$.treatment_center.created_at`)).
 		Assert(jsonpath.Present(`$.treatment_center.updated_at`)).
 		End()
@@ -79,6 +121,114 @@ func (s *TreatmentCentersApiIntegrationTestSuite) TestCreateTreatmentCenter() {
 		End()
 }
 
+func (s *TreatmentCentersApiIntegrationTestSuite) TestPatchTreatmentCenter() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/treatment_centers/52b2edf2-a380-4436-9f98-b70f78f174ef").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		Body(`{"address": "next door", "phone": "0102030406"}`).
+		ContentType("application/merge-patch+json").
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.treatment_center.name`, "Center Two")).
+		Assert(jsonpath.Equal(`$.treatment_center.address`, "next door")).
+		Assert(jsonpath.Equal(`$.treatment_center.phone`, "+33102030406")).
+		Assert(jsonpath.Equal(`$.treatment_center.latitude`, 48.8566)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/treatment_centers/52b2edf2-a380-4436-9f98-b70f78f174ef").
+		Header("Authorization", s.StaffBearer("0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8")).
+		Body(`{"address": "far away"}`).
+		ContentType("application/merge-patch+json").
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	// the coordinates of a treatment center are removed together
+	apitest.New().Debug().
+		Handler(s.Router).
+		Patch("/v1/treatment_centers/52b2edf2-a380-4436-9f98-b70f78f174ef").
+		Header("Authorization", s.AdminBearer()).
+		Body(`{"latitude": null}`).
+		ContentType("application/merge-patch+json").
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		End()
+}
+
+func (s *TreatmentCentersApiIntegrationTestSuite) TestUpdateTreatmentCenter() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Put("/v1/treatment_centers/52b2edf2-a380-4436-9f98-b70f78f174ef").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(`{"name": "Center Two", "address": "next door", "phone": "0102030406"}`).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.treatment_center.address`, "next door")).
+		Assert(jsonpath.Equal(`$.treatment_center.phone`, "+33102030406")).
+		Assert(jsonpath.NotPresent(`$.treatment_center.latitude`)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Put("/v1/treatment_centers/52b2edf2-a380-4436-9f98-b70f78f174ef").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(`{"name": "Center Two", "phone": "0102030406"}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.fields[0].field`, "address")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Put("/v1/treatment_centers/52b2edf2-a380-4436-9f98-b70f78f174ef").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(`{"name": "Center Two", "address": "next door", "phone": "+44 20 7946 0958 ext 1"}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.fields[0].field`, "phone")).
+		Assert(jsonpath.Equal(`$.error.fields[0].message`, "must be a phone number")).
+		End()
+}
+
+func (s *TreatmentCentersApiIntegrationTestSuite) TestDeleteTreatmentCenter() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Delete("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d").
+		Header("Authorization", s.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Delete("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d").
+		Header("Authorization", s.AdminBearer()).
+		Expect(s.T()).
+		Status(http.StatusNoContent).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d").
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+
+	// the past bookings keep their treatment center
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/patients/24e32685-0a32-4a9d-bc22-0e98cdaf5884/bookings").
+		Header("Authorization", s.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.bookings[0].status`, "confirmed")).
+		Assert(jsonpath.Equal(`$.bookings[0].treatment_center_name`, "Center in the game")).
+		End()
+}
+
 func (s *TreatmentCentersApiIntegrationTestSuite) TestGetTreatmentCentersByID() {
 	apitest.New().Debug().
 		Handler(s.Router).
@@ -97,6 +247,7 @@ func (s *TreatmentCentersApiIntegrationTestSuite) TestGetTreatmentCenterBookings
 	apitest.New().Debug().
 		Handler(s.Router).
 		Get("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d/bookings").
+		Header("Authorization", s.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
 		QueryParams(map[string]string{"date": "2021-11-13"}).
 		Expect(s.T()).
 		Status(http.StatusOK).
@@ -109,6 +260,186 @@ func (s *TreatmentCentersApiIntegrationTestSuite) TestGetTreatmentCenterBookings
 		End()
 }
 
+func (s *TreatmentCentersApiIntegrationTestSuite) TestGetTreatmentCenterBookingsUnauthorized() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d/bookings").
+		Expect(s.T()).
+		Status(http.StatusUnauthorized).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d/bookings").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d/bookings").
+		Header("Authorization", s.PatientBearer("24e32685-0a32-4a9d-bc22-0e98cdaf5884")).
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+}
+
+func (s *TreatmentCentersApiIntegrationTestSuite) TestRecordBookingOutcome() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/treatment_centers/52b2edf2-a380-4436-9f98-b70f78f174ef/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710/outcome").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(`{"status": "no show"}`).
+		Expect(s.T()).
+		Status(http.StatusNotFound).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710/outcome").
+		Header("Authorization", s.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
+		JSON(`{"status": "administered", "lot_number": "FK5618"}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Equal(`$.error.message`, "vaccine_product_id is required to record an administration")).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710/outcome").
+		Header("Authorization", s.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
+		JSON(`{"status": "administered", "lot_number": "FK5618",
+			"vaccine_product_id": "7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8"}`).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.appointment_booking.status`, "administered")).
+		Assert(jsonpath.Equal(`$.appointment_booking.lot_number`, "FK5618")).
+		Assert(jsonpath.Equal(`$.appointment_booking.administered_by`, "test staff")).
+		Assert(jsonpath.Present(`$.appointment_booking.outcome_recorded_at`)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/treatment_centers/10063726-d378-472c-9b50-22a48331635d/bookings/f859ae2c-e24f-46e8-9c27-4431112fc710/outcome").
+		Header("Authorization", s.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
+		JSON(`{"status": "refused"}`).
+		Expect(s.T()).
+		Status(http.StatusConflict).
+		End()
+}
+
+const validTreatmentCenterScheduleJSON = `{
+	"timezone": "Europe/Paris",
+	"slot_duration": 30,
+	"slot_capacity": 4,
+	"opening_hours": [
+		{"weekday": 3, "opens_at": "09:00", "closes_at": "11:00"}
+	],
+	"closures": [
+		{"date": "2021-12-29T00:00:00Z", "reason": "Inventory"}
+	]
+}`
+
+func (s *TreatmentCentersApiIntegrationTestSuite) TestUpdateTreatmentCenterSchedule() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Put("/v1/treatment_centers/52b2edf2-a380-4436-9f98-b70f78f174ef/schedule").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(validTreatmentCenterScheduleJSON).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal(`$.treatment_center.timezone`, "Europe/Paris")).
+		Assert(jsonpath.Equal(`$.treatment_center.slot_duration`, float64(30))).
+		Assert(jsonpath.Equal(`$.treatment_center.slot_capacity`, float64(4))).
+		Assert(jsonpath.Len(`$.treatment_center.opening_hours`, 1)).
+		Assert(jsonpath.Equal(`$.treatment_center.opening_hours[0].opens_at`, "09:00")).
+		Assert(jsonpath.Len(`$.treatment_center.closures`, 1)).
+		End()
+}
+
+func (s *TreatmentCentersApiIntegrationTestSuite) TestUpdateTreatmentCenterInvalidSchedule() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Put("/v1/treatment_centers/52b2edf2-a380-4436-9f98-b70f78f174ef/schedule").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(`{"slot_duration": 30, "opening_hours": [{"weekday": 3, "opens_at": "11:00", "closes_at": "09:00"}]}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		Assert(jsonpath.Present(`$.error.message`)).
+		End()
+}
+
+func (s *TreatmentCentersApiIntegrationTestSuite) TestReceiveStockDelivery() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/treatment_centers/0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8/stock/deliveries").
+		Header("Authorization", s.StaffBearer("10063726-d378-472c-9b50-22a48331635d")).
+		JSON(`{"vaccine_product_id": "7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8", "lot_number": "FF2593",
+			"quantity": 30, "expires_on": "2099-12-31T00:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/treatment_centers/0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8/stock/deliveries").
+		Header("Authorization", s.StaffBearer("0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8")).
+		JSON(`{"vaccine_product_id": "7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8", "lot_number": "FF2593",
+			"quantity": 30, "expires_on": "2099-12-31T00:00:00Z"}`).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Present(`$.vaccine_lot.id`)).
+		Assert(jsonpath.Equal(`$.vaccine_lot.treatment_center_id`, "0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8")).
+		Assert(jsonpath.Equal(`$.vaccine_lot.quantity`, float64(30))).
+		Assert(jsonpath.Present(`$.vaccine_lot.received_at`)).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/treatment_centers/0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8/stock").
+		Header("Authorization", s.StaffBearer("0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8")).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.vaccine_lots`, 1)).
+		Assert(jsonpath.Equal(`$.vaccine_lots[0].lot_number`, "FF2593")).
+		End()
+}
+
+func (s *TreatmentCentersApiIntegrationTestSuite) TestGenerateTreatmentCenterAppointments() {
+	generate := func() *apitest.Response {
+		return apitest.New().Debug().
+			Handler(s.Router).
+			Post("/v1/treatment_centers/32b2edf2-a380-4436-9f98-b70f78f1934d/appointments/generate").
+			Header("Authorization", s.StaffBearer("32b2edf2-a380-4436-9f98-b70f78f1934d")).
+			QueryParams(map[string]string{"from": "2021-12-20", "to": "2021-12-28"}).
+			Expect(s.T()).
+			Status(http.StatusCreated)
+	}
+
+	generate().
+		Assert(jsonpath.Len(`$.appointments`, 10)).
+		Assert(jsonpath.Contains(`$.appointments[*].start_time`, "2021-12-20T07:00:00Z")).
+		Assert(jsonpath.Contains(`$.appointments[*].start_time`, "2021-12-28T14:00:00Z")).
+		Assert(jsonpath.Equal(`$.appointments[0].capacity`, float64(2))).
+		End()
+
+	generate().
+		Assert(jsonpath.NotPresent(`$.appointments`)).
+		End()
+}
+
+func (s *TreatmentCentersApiIntegrationTestSuite) TestGenerateTreatmentCenterAppointmentsInvalidRange() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/treatment_centers/32b2edf2-a380-4436-9f98-b70f78f1934d/appointments/generate").
+		Header("Authorization", s.StaffBearer("32b2edf2-a380-4436-9f98-b70f78f1934d")).
+		QueryParams(map[string]string{"from": "2021-12-28", "to": "2021-12-20"}).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		End()
+}
+
 func TestTreatmentCentersApiIntegrationTestSuite(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, new(TreatmentCentersApiIntegrationTestSuite))
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+	"go.uber.org/zap"
+)
+
+type VaccineProductsResponse struct {
+	VaccineProducts []*domain.VaccineProduct `json:"vaccine_products,omitempty"`
+	*PageResponse
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type VaccineProductResponse struct {
+	VaccineProduct *domain.VaccineProduct `json:"vaccine_product,omitempty"`
+	Error          *ErrorResponse         `json:"error,omitempty"`
+}
+
+type VaccineProductsController struct {
+	vaccineProductsRepository repository.VaccineProducts
+	unitOfWork                repository.UnitOfWork
+	logger                    *zap.Logger
+}
+
+type inputVaccineProduct struct {
+	domain.VaccineProduct
+}
+
+func (t *inputVaccineProduct) buildModel() domain.VaccineProduct {
+	return domain.VaccineProduct{
+		ID:                  uuid.New(),
+		Name:                t.Name,
+		Doses:               t.Doses,
+		MinIntervalDays:     t.MinIntervalDays,
+		MaxIntervalDays:     t.MaxIntervalDays,
+		BoosterIntervalDays: t.BoosterIntervalDays,
+	}
+}
+
+func SetupVaccineProduct(
+	router gin.IRouter,
+	vaccineProductsRepository repository.VaccineProducts,
+	unitOfWork repository.UnitOfWork,
+	authenticator *Authenticator,
+	logger *zap.Logger) {
+	c := VaccineProductsController{
+		vaccineProductsRepository: vaccineProductsRepository,
+		unitOfWork:                unitOfWork,
+		logger:                    logger.With(zap.String("component", "VaccineProductsController")),
+	}
+	g := router.Group("/vaccine_products")
+	g.GET("/", c.IndexEndpoint)
+	g.POST("/", authenticator.Authenticate(), RequireRole(RoleAdmin), c.CreateEndpoint)
+	g.GET("/:vaccine_product_id", c.GetEndpoint)
+}
+
+func extractVaccineProductID(c *gin.Context) (id uuid.UUID, err error) {
+	return uuid.Parse(c.Param("vaccine_product_id"))
+}
+
+func (v *VaccineProductsController) IndexEndpoint(c *gin.Context) {
+	pageRequest, err := bindPageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, VaccineProductsResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	vaccineProducts, page, err := v.vaccineProductsRepository.All(c.Request.Context(), pageRequest)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, VaccineProductsResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, VaccineProductsResponse{VaccineProducts: vaccineProducts, PageResponse: newPageResponse(page)})
+}
+
+func (v *VaccineProductsController) GetEndpoint(c *gin.Context) {
+	id, err := extractVaccineProductID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, VaccineProductResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	vaccineProduct, err := v.vaccineProductsRepository.FindByID(c.Request.Context(), id)
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, VaccineProductResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusOK, VaccineProductResponse{VaccineProduct: vaccineProduct})
+}
+
+func (v *VaccineProductsController) CreateEndpoint(c *gin.Context) {
+	var input inputVaccineProduct
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, VaccineProductResponse{Error: bindingErrorResponse(err)})
+		return
+	}
+	vaccineProduct := input.buildModel()
+	if err := vaccineProduct.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, VaccineProductResponse{Error: &ErrorResponse{Msg: err.Error()}})
+		return
+	}
+	ctx := c.Request.Context()
+	var t *domain.VaccineProduct
+	err := v.unitOfWork.Do(ctx, func(r repository.Repositories) (err error) {
+		if err = r.VaccineProducts().Create(ctx, &vaccineProduct); err != nil {
+			return err
+		}
+		t, err = r.VaccineProducts().FindByID(ctx, vaccineProduct.ID)
+		return err
+	})
+	if err != nil {
+		code, r := handleRepositoryError(err, v.logger)
+		c.JSON(code, VaccineProductResponse{Error: r})
+		return
+	}
+	c.JSON(http.StatusCreated, VaccineProductResponse{VaccineProduct: t})
+}
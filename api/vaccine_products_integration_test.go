@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steinfletcher/apitest"
+	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"github.com/stretchr/testify/suite"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type VaccineProductsApiIntegrationTestSuite struct {
+	ApiIntegrationSuite
+}
+
+const validVaccineProductJSON = `{
+	"name": "Spikevax",
+	"doses": 2,
+	"min_interval_days": 28,
+	"max_interval_days": 49,
+	"booster_interval_days": 150
+}`
+
+func (s *VaccineProductsApiIntegrationTestSuite) TestVaccineProductsIndexList() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Get("/v1/vaccine_products/").
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len(`$.vaccine_products`, 2)).
+		Assert(jsonpath.Equal(`$.vaccine_products[0].name`, "Comirnaty")).
+		Assert(jsonpath.Equal(`$.vaccine_products[0].doses`, float64(2))).
+		Assert(jsonpath.Equal(`$.vaccine_products[1].name`, "Janssen")).
+		Assert(jsonpath.NotPresent(`$.vaccine_products[1].booster_interval_days`)).
+		End()
+}
+
+func (s *VaccineProductsApiIntegrationTestSuite) TestCreateVaccineProduct() {
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/vaccine_products/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(validVaccineProductJSON).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Present(`$.vaccine_product.id`)).
+		Assert(jsonpath.Equal(`$.vaccine_product.name`, "Spikevax")).
+		Assert(jsonpath.Equal(`$.vaccine_product.max_interval_days`, float64(49))).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/vaccine_products/").
+		Header("Authorization", s.AdminBearer()).
+		JSON(`{"name": "Inverted", "doses": 2, "min_interval_days": 28, "max_interval_days": 21}`).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		End()
+
+	apitest.New().Debug().
+		Handler(s.Router).
+		Post("/v1/vaccine_products/").
+		Header("Authorization", s.StaffBearer("52b2edf2-a380-4436-9f98-b70f78f174ef")).
+		JSON(validVaccineProductJSON).
+		Expect(s.T()).
+		Status(http.StatusForbidden).
+		End()
+}
+
+func TestVaccineProductsApiIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, new(VaccineProductsApiIntegrationTestSuite))
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/y9mo/covidvax/domain"
+)
+
+// FieldError names a field of a request body that is invalid, nested fields
+// are joined with dots and list items indexed like opening_hours[0].opens_at
+type FieldError struct {
+	Field string `json:"field"`
+	Msg   string `json:"message"`
+}
+
+func init() {
+	// the validation errors name the fields like their JSON or query parameter
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "" {
+				name = field.Tag.Get("form")
+			}
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+		v.RegisterStructValidation(validatePatient, inputPatient{})
+		v.RegisterStructValidation(validateTreatmentCenter, inputTreatmentCenter{})
+	}
+}
+
+// maxNameLength is the length in characters of the longest patient or treatment center name
+const maxNameLength = 100
+
+var (
+	e164        = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	callingCode = regexp.MustCompile(`^[1-9][0-9]{0,2}$`)
+)
+
+// phoneNumbers writes the phone numbers of the requests in the E.164 format,
+// nationalCallingCode is the country calling code of the ones written without
+// one, with their national leading 0, they are invalid when it is empty
+type phoneNumbers struct {
+	nationalCallingCode string
+}
+
+// newPhoneNumbers takes the country calling code of the phone numbers written
+// in their national format, 33 for France, an empty one rejects them
+func newPhoneNumbers(nationalCallingCode string) (phoneNumbers, error) {
+	code := strings.TrimPrefix(nationalCallingCode, "+")
+	if code != "" && !callingCode.MatchString(code) {
+		return phoneNumbers{}, fmt.Errorf("invalid country calling code %q", nationalCallingCode)
+	}
+	return phoneNumbers{nationalCallingCode: code}, nil
+}
+
+// validatePatient checks the format of the email, names and phone of a patient and
+// its birth date is past, the missing ones are left to their required binding
+func validatePatient(sl validator.StructLevel) {
+	patient := sl.Current().Interface().(inputPatient)
+	validateEmail(sl, patient.Email, "email", "Email")
+	validateName(sl, patient.FirstName, "first_name", "FirstName")
+	validateName(sl, patient.LastName, "last_name", "LastName")
+	validatePhone(sl, patient.phones, patient.Phone, "phone", "Phone")
+	if patient.BirthDate != nil && patient.BirthDate.After(time.Now()) {
+		sl.ReportError(patient.BirthDate, "birth_date", "BirthDate", "past", "")
+	}
+}
+
+// validateTreatmentCenter checks the format of the name and phone of a treatment center
+func validateTreatmentCenter(sl validator.StructLevel) {
+	treatmentCenter := sl.Current().Interface().(inputTreatmentCenter)
+	validateName(sl, treatmentCenter.Name, "name", "Name")
+	validatePhone(sl, treatmentCenter.phones, treatmentCenter.Phone, "phone", "Phone")
+}
+
+func validateEmail(sl validator.StructLevel, email, field, structField string) {
+	if email == "" {
+		return
+	}
+	if sl.Validator().Var(normalizeEmail(email), "email,max=320") != nil {
+		sl.ReportError(email, field, structField, "email", "")
+	}
+}
+
+func validateName(sl validator.StructLevel, name, field, structField string) {
+	if name == "" {
+		return
+	}
+	switch length := utf8.RuneCountInString(strings.TrimSpace(name)); {
+	case length == 0:
+		sl.ReportError(name, field, structField, "required", "")
+	case length > maxNameLength:
+		sl.ReportError(name, field, structField, "max", fmt.Sprint(maxNameLength))
+	}
+}
+
+func validatePhone(sl validator.StructLevel, phones phoneNumbers, phone, field, structField string) {
+	if phone != "" && phones.normalize(phone) == "" {
+		sl.ReportError(phone, field, structField, "phone", "")
+	}
+}
+
+// normalizeEmail drops the spaces around an email
+func normalizeEmail(email string) string {
+	return strings.TrimSpace(email)
+}
+
+// normalizeName drops the spaces around a name
+func normalizeName(name string) string {
+	return strings.TrimSpace(name)
+}
+
+// normalizeNationalHealthID drops the spaces of a national health identifier,
+// a blank one is no identifier
+func normalizeNationalHealthID(id *string) *string {
+	if id == nil {
+		return nil
+	}
+	normalized := strings.Join(strings.Fields(*id), "")
+	if normalized == "" {
+		return nil
+	}
+	return &normalized
+}
+
+// normalize returns a phone number in the E.164 format, +<country code><number>,
+// or an empty string when it isn't a phone number. Spaces, dots, dashes and
+// parentheses are dropped, 00 starts an international number and 0 a national one.
+func (p phoneNumbers) normalize(phone string) string {
+	var digits strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		switch {
+		case r >= '0' && r <= '9', r == '+' && i == 0:
+			digits.WriteRune(r)
+		case strings.ContainsRune(" .-()", r):
+		default:
+			return ""
+		}
+	}
+	number := digits.String()
+	switch {
+	case strings.HasPrefix(number, "+"):
+	case strings.HasPrefix(number, "00"):
+		number = "+" + number[2:]
+	case strings.HasPrefix(number, "0") && p.nationalCallingCode != "":
+		number = "+" + p.nationalCallingCode + number[1:]
+	default:
+		return ""
+	}
+	if !e164.MatchString(number) {
+		return ""
+	}
+	return number
+}
+
+// validateStartTime checks an appointment starts after now in a slot of the
+// opening hours of its treatment center, it returns the response naming the
+// start time when it doesn't
+func validateStartTime(appointment *domain.Appointment, treatmentCenter *domain.TreatmentCenter,
+	now time.Time) (*ErrorResponse, error) {
+	var msg string
+	if !appointment.StartTime.After(now) {
+		msg = "must be in the future"
+	} else if fits, err := treatmentCenter.Fits(appointment.StartTime); err != nil {
+		return nil, err
+	} else if !fits {
+		msg = "must be in the opening hours of the treatment center"
+	}
+	if msg == "" {
+		return nil, nil
+	}
+	return &ErrorResponse{Msg: "invalid fields", Fields: []FieldError{{Field: "start_time", Msg: msg}}}, nil
+}
+
+// bindingErrorResponse turns an error of the binding of a request body into
+// an error response naming the invalid fields when it can
+func bindingErrorResponse(err error) *ErrorResponse {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		response := &ErrorResponse{Msg: "invalid fields"}
+		for _, fieldError := range validationErrors {
+			response.Fields = append(response.Fields, FieldError{
+				Field: fieldPath(fieldError.Namespace()),
+				Msg:   validationMessage(fieldError),
+			})
+		}
+		return response
+	}
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return &ErrorResponse{Msg: "invalid fields", Fields: []FieldError{{
+			Field: typeError.Field,
+			Msg:   fmt.Sprintf("must be a %s", typeError.Type),
+		}}}
+	}
+	return &ErrorResponse{Msg: err.Error()}
+}
+
+// fieldPath drops from the namespace of a validation error the struct it starts
+// with and the embedded structs, which are named after their Go type
+func fieldPath(namespace string) string {
+	parts := strings.Split(namespace, ".")[1:]
+	path := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" && unicode.IsUpper(rune(part[0])) {
+			continue
+		}
+		path = append(path, part)
+	}
+	return strings.Join(path, ".")
+}
+
+func validationMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		if fieldError.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fieldError.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldError.Param())
+	case "max", "lte":
+		if fieldError.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fieldError.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldError.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldError.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fieldError.Param())
+	case "uuid":
+		return "must be a uuid"
+	case "email":
+		return "must be an email address"
+	case "phone":
+		return "must be a phone number"
+	case "past":
+		return "must be in the past"
+	default:
+		return fmt.Sprintf("failed the %s validation", fieldError.Tag())
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/y9mo/covidvax/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name  string
+		phone string
+		want  string
+	}{
+		{name: "E164", phone: "+33611223344", want: "+33611223344"},
+		{name: "National", phone: "06 11 22 33 44", want: "+33611223344"},
+		{name: "Dots", phone: "06.11.22.33.44", want: "+33611223344"},
+		{name: "International", phone: "0044 20 7946 0958", want: "+442079460958"},
+		{name: "Parentheses", phone: "+1 (415) 555-0100", want: "+14155550100"},
+		{name: "Letters", phone: "call me"},
+		{name: "PlusInside", phone: "06+11223344"},
+		{name: "NoPrefix", phone: "611223344"},
+		{name: "TooShort", phone: "+331"},
+		{name: "TooLong", phone: "+3361122334455667"},
+		{name: "ZeroCountryCode", phone: "+0611223344"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, phoneNumbers{nationalCallingCode: "33"}.normalize(tc.phone))
+		})
+	}
+}
+
+func TestNationalCallingCode(t *testing.T) {
+	german, err := newPhoneNumbers("+49")
+	require.NoError(t, err)
+	assert.Equal(t, "+4930123456", german.normalize("030 123456"))
+	assert.Equal(t, "+33611223344", german.normalize("+33 6 11 22 33 44"))
+
+	none, err := newPhoneNumbers("")
+	require.NoError(t, err)
+	assert.Equal(t, "", none.normalize("06 11 22 33 44"))
+	assert.Equal(t, "+442079460958", none.normalize("0044 20 7946 0958"))
+
+	_, err = newPhoneNumbers("0")
+	assert.Error(t, err)
+	_, err = newPhoneNumbers("1234")
+	assert.Error(t, err)
+
+	// the binding validates a phone with the calling code of its input
+	center := domain.TreatmentCenter{Name: "Center", Address: "Somewhere", Phone: "0102030405"}
+	assert.NoError(t, binding.Validator.ValidateStruct(&inputTreatmentCenter{TreatmentCenter: center,
+		phones: german}))
+	assert.Error(t, binding.Validator.ValidateStruct(&inputTreatmentCenter{TreatmentCenter: center,
+		phones: none}))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/y9mo/covidvax/repository"
+	"github.com/y9mo/covidvax/scheduling"
+)
+
+const generateSlotsUsage = "usage: covidvax generate-slots <treatment_center_id> <from YYYY-MM-DD> <to YYYY-MM-DD>"
+
+// generateSlots creates the appointments of a treatment center
+// from its opening hours for a range of days
+func generateSlots(ctx context.Context, args []string,
+	tcr repository.TreatmentCenters,
+	ar repository.Appointments,
+	logger *zap.Logger) error {
+	if len(args) != 3 {
+		return errors.New(generateSlotsUsage)
+	}
+	treatmentCenterID, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid treatment center id: %w", err)
+	}
+	from, err := time.Parse("2006-01-02", args[1])
+	if err != nil {
+		return fmt.Errorf("invalid from date: %w", err)
+	}
+	to, err := time.Parse("2006-01-02", args[2])
+	if err != nil {
+		return fmt.Errorf("invalid to date: %w", err)
+	}
+
+	created, err := scheduling.NewGenerator(tcr, ar, logger).Generate(ctx, treatmentCenterID, from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d appointments created\n", len(created))
+	return nil
+}
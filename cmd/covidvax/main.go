@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata"
 
 	goflag "flag"
 
@@ -16,16 +22,41 @@ import (
 
 	"github.com/y9mo/covidvax"
 	"github.com/y9mo/covidvax/api"
+	"github.com/y9mo/covidvax/confirmation"
+	"github.com/y9mo/covidvax/mail"
+	"github.com/y9mo/covidvax/reaper"
 	"github.com/y9mo/covidvax/repository"
+	"github.com/y9mo/covidvax/repository/memory"
+	"github.com/y9mo/covidvax/waitlist"
 )
 
 /// Prefix for environments variables
 const prefix string = "COVIDVAX"
 
 type Config struct {
-	Development  bool   `mapstructure:"dev"`
-	PgConnection string `mapstructure:"pg-connection"`
-	Listen       string `mapstructure:"listen"`
+	Development        bool          `mapstructure:"dev"`
+	PgConnection       string        `mapstructure:"pg-connection"`
+	SQLiteConnection   string        `mapstructure:"sqlite-connection"`
+	Listen             string        `mapstructure:"listen"`
+	PublicURL          string        `mapstructure:"public-url"`
+	ConfirmationSecret string        `mapstructure:"confirmation-secret"`
+	ConfirmationTTL    time.Duration `mapstructure:"confirmation-ttl"`
+	ReaperInterval     time.Duration `mapstructure:"reaper-interval"`
+	SMTPHost           string        `mapstructure:"smtp-host"`
+	SMTPPort           int           `mapstructure:"smtp-port"`
+	SMTPUsername       string        `mapstructure:"smtp-username"`
+	SMTPPassword       string        `mapstructure:"smtp-password"`
+	MailFrom           string        `mapstructure:"mail-from"`
+	MailDir            string        `mapstructure:"mail-dir"`
+	IAMKeysDir         string        `mapstructure:"iam-keys-dir"`
+	ReminderLeadTimes  []string      `mapstructure:"reminder-lead-times"`
+	ReminderInterval   time.Duration `mapstructure:"reminder-interval"`
+	SMSGatewayURL      string        `mapstructure:"sms-gateway-url"`
+	SMSGatewayToken    string        `mapstructure:"sms-gateway-token"`
+	SMSFrom            string        `mapstructure:"sms-from"`
+	DBTimeout          time.Duration `mapstructure:"db-timeout"`
+	Storage            string        `mapstructure:"storage"`
+	NationalPhoneCode  string        `mapstructure:"national-phone-code"`
 }
 
 func GetConfig() (Config, error) {
@@ -33,7 +64,32 @@ func GetConfig() (Config, error) {
 	pflag.String("pg-connection",
 		"host=127.0.0.1 port=5432 user=admin dbname=covidvax password=admin-pwd sslmode=disable",
 		"postgresql connection string")
+	pflag.String("sqlite-connection", "covidvax.db", "sqlite database file, or :memory:")
 	pflag.String("listen", ":8080", "listen address")
+	pflag.String("public-url", "http://localhost:8080", "url the api is reachable at, used in emails")
+	pflag.String("confirmation-secret", "", "secret used to sign booking confirmation tokens")
+	pflag.Duration("confirmation-ttl", 24*time.Hour,
+		"delay to confirm a booking before it is released, also the validity of confirmation tokens")
+	pflag.Duration("reaper-interval", time.Minute, "interval between two releases of unconfirmed bookings")
+	pflag.String("smtp-host", "", "smtp server host, emails are only logged when empty")
+	pflag.Int("smtp-port", 587, "smtp server port")
+	pflag.String("smtp-username", "", "smtp username")
+	pflag.String("smtp-password", "", "smtp password")
+	pflag.String("mail-from", "covidvax@localhost", "sender address of emails")
+	pflag.String("mail-dir", "", "directory where emails are written when no smtp host is set")
+	pflag.String("iam-keys-dir", "/docker.d/iam", "directory of the PEM public keys verifying the bearer tokens")
+	pflag.StringSlice("reminder-lead-times", []string{"24h", "2h"},
+		"delays before their appointment the patients are reminded of their confirmed bookings")
+	pflag.Duration("reminder-interval", 5*time.Minute,
+		"interval between two reminder runs of the server, 0 disables them for the reminders command")
+	pflag.String("sms-gateway-url", "", "url of the sms gateway, no sms are sent when empty")
+	pflag.String("sms-gateway-token", "", "bearer token of the sms gateway")
+	pflag.String("sms-from", "covidvax", "sender of the sms")
+	pflag.Duration("db-timeout", 10*time.Second,
+		"delay after which the database queries of a request are cancelled, 0 disables it")
+	pflag.String("storage", "postgres", "storage of the data, postgres, sqlite or memory which loses it on exit")
+	pflag.String("national-phone-code", "33",
+		"country calling code of the phone numbers written with a leading 0, they are rejected when empty")
 
 	pflag.CommandLine.AddGoFlagSet(goflag.CommandLine)
 	pflag.Parse()
@@ -67,28 +123,146 @@ func main() {
 	}
 	logger.Sugar().Debugf("%+v", config)
 
-	logger.Sugar().Debugf("connecting to %s", config.PgConnection)
-	db, err := gorm.Open("postgres", config.PgConnection)
+	repositories, rr, uow, err := initRepositories(config, logger)
 	if err != nil {
-		logger.Sugar().Fatalf("failed to connect to the database: %s", err)
+		logger.Sugar().Fatalf("storage: %s", err)
 	}
+	pr := repositories.Patients()
+	tcr := repositories.TreatmentCenters()
+	ar := repositories.Appointments()
+	abr := repositories.AppointmentBookings()
+	vpr := repositories.VaccineProducts()
+	dr := repositories.Doses()
+	vlr := repositories.VaccineLots()
+	wr := repositories.Waitlist()
+	elr := repositories.EligibilityRules()
 
-	pr := repository.NewPatients(db, logger)
-	tcr := repository.NewTreatmentCenters(db, logger)
-	ar := repository.NewAppointments(db, logger)
-	abr := repository.NewAppointmentBookings(db, logger)
+	reminders, err := newReminderScheduler(config, pr, rr, logger)
+	if err != nil {
+		logger.Sugar().Fatalf("reminders: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch command := pflag.Arg(0); command {
+	case "":
+	case "generate-slots":
+		if err := generateSlots(ctx, pflag.Args()[1:], tcr, ar, logger); err != nil {
+			logger.Sugar().Fatalf("generate-slots: %s", err)
+		}
+		return
+	case "reminders":
+		if err := sendReminders(ctx, reminders, logger); err != nil {
+			logger.Sugar().Fatalf("reminders: %s", err)
+		}
+		return
+	default:
+		logger.Sugar().Fatalf("unknown command %q", command)
+	}
+
+	secret, err := confirmationSecret(config)
+	if err != nil {
+		logger.Sugar().Fatalf("confirmation secret: %s", err)
+	}
+	confirmations := confirmation.NewService(
+		confirmation.NewSigner(secret, config.ConfirmationTTL),
+		initMailSender(config, logger),
+		pr, ar, config.PublicURL, logger)
+	offers := waitlist.NewService(wr, confirmations, logger)
 
-	router, err := api.Setup(logger, pr, tcr, ar, abr)
+	keys, err := api.LoadKeys(config.IAMKeysDir)
+	if err != nil {
+		logger.Sugar().Fatalf("iam keys: %s", err)
+	}
+	if len(keys) == 0 {
+		logger.Sugar().Warnf("no iam key in %s, authenticated endpoints reject every request", config.IAMKeysDir)
+	}
+
+	router, err := api.Setup(logger, pr, tcr, ar, abr, vpr, dr, vlr, wr, elr, uow, confirmations, offers,
+		api.NewAuthenticator(keys, logger), config.NationalPhoneCode, config.DBTimeout)
 	if err != nil {
 		logger.Sugar().Fatalf("router setup: %s", err)
 	}
 
+	go reaper.New(uow, offers, config.ReaperInterval, logger).Run(ctx)
+	if config.ReminderInterval > 0 {
+		go reminders.Run(ctx)
+	}
+
 	srv := http.Server{Addr: config.Listen, Handler: router}
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Sugar().Errorf("shutdown: %s", err)
+		}
+	}()
 
 	logger.Sugar().Infof("listening on %s", config.Listen)
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		logger.Sugar().Fatalf("listen: %s", err)
 	}
+	<-closed
+}
+
+// initRepositories returns the repositories of the configured storage
+func initRepositories(config Config, logger *zap.Logger) (repository.Repositories, repository.Reminders,
+	repository.UnitOfWork, error) {
+	var db *gorm.DB
+	var err error
+	switch config.Storage {
+	case "postgres":
+		logger.Sugar().Debugf("connecting to %s", config.PgConnection)
+		db, err = gorm.Open("postgres", config.PgConnection)
+	case "sqlite":
+		logger.Sugar().Debugf("opening %s", config.SQLiteConnection)
+		db, err = repository.OpenSQLite(config.SQLiteConnection)
+	case "memory":
+		logger.Sugar().Warnf("in memory storage, the data is lost on exit")
+		store := memory.NewStore()
+		return memory.NewRepositories(store), memory.NewReminders(store), memory.NewUnitOfWork(store), nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown storage %q", config.Storage)
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to connect to the database: %w", err)
+	}
+	return repository.NewRepositories(db, logger), repository.NewReminders(db, logger),
+		repository.NewUnitOfWork(db, logger), nil
+}
+
+func initMailSender(config Config, logger *zap.Logger) mail.Sender {
+	if config.SMTPHost == "" {
+		logger.Sugar().Infof("no smtp host, emails are logged")
+		return mail.NewFileSender(config.MailDir, config.MailFrom, logger)
+	}
+	return mail.NewSMTPSender(mail.SMTPConfig{
+		Host:     config.SMTPHost,
+		Port:     config.SMTPPort,
+		Username: config.SMTPUsername,
+		Password: config.SMTPPassword,
+		From:     config.MailFrom,
+	})
+}
+
+// confirmationSecret returns the configured secret, in development mode
+// a random one is generated when none is set
+func confirmationSecret(config Config) ([]byte, error) {
+	if config.ConfirmationSecret != "" {
+		return []byte(config.ConfirmationSecret), nil
+	}
+	if !config.Development {
+		return nil, fmt.Errorf("confirmation-secret is required")
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
 }
 
 func initLog(development bool) (logger *zap.Logger, err error) {
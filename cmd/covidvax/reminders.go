@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/y9mo/covidvax/mail"
+	"github.com/y9mo/covidvax/notifier"
+	"github.com/y9mo/covidvax/reminder"
+	"github.com/y9mo/covidvax/repository"
+)
+
+// newReminderScheduler builds the reminder scheduler from the configuration
+func newReminderScheduler(config Config,
+	pr repository.Patients,
+	rr repository.Reminders,
+	logger *zap.Logger) (*reminder.Scheduler, error) {
+	leadTimes := make([]time.Duration, 0, len(config.ReminderLeadTimes))
+	for _, value := range config.ReminderLeadTimes {
+		leadTime, err := time.ParseDuration(value)
+		if err != nil || leadTime < time.Minute {
+			return nil, fmt.Errorf("invalid reminder lead time %q", value)
+		}
+		leadTimes = append(leadTimes, leadTime)
+	}
+	n := initNotifier(config, initMailSender(config, logger), logger)
+	return reminder.New(rr, pr, n, leadTimes, config.ReminderInterval, logger), nil
+}
+
+// sendReminders sends the reminders due now, for deployments running it
+// periodically instead of the scheduler of the server
+func sendReminders(ctx context.Context, scheduler *reminder.Scheduler, logger *zap.Logger) error {
+	sent, err := scheduler.Remind(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	logger.Sugar().Infof("%d reminders sent", sent)
+	return nil
+}
+
+// initNotifier notifies by email and by sms when a gateway is set, without smtp
+// host nor sms gateway the notifications are logged and written in mail-dir
+func initNotifier(config Config, sender mail.Sender, logger *zap.Logger) notifier.Notifier {
+	var notifiers []notifier.Notifier
+	if config.SMTPHost != "" {
+		notifiers = append(notifiers, notifier.NewEmail(sender))
+	}
+	if config.SMSGatewayURL != "" {
+		notifiers = append(notifiers, notifier.NewSMS(notifier.SMSConfig{
+			URL:   config.SMSGatewayURL,
+			Token: config.SMSGatewayToken,
+			From:  config.SMSFrom,
+		}))
+	}
+	switch len(notifiers) {
+	case 0:
+		logger.Sugar().Infof("no smtp host nor sms gateway, notifications are logged")
+		return notifier.NewFile(config.MailDir, logger)
+	case 1:
+		return notifiers[0]
+	default:
+		return notifier.NewMulti(logger, notifiers...)
+	}
+}
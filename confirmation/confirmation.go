@@ -0,0 +1,141 @@
+package confirmation
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/mail"
+	"github.com/y9mo/covidvax/repository"
+	"go.uber.org/zap"
+)
+
+const confirmPath = "/v1/bookings/confirm"
+
+// Service sends booking confirmation requests to patients by email
+// and verifies the tokens they send back, it also emails them the waitlist
+// offers and the bookings cancelled by their treatment center
+type Service struct {
+	signer                 *Signer
+	sender                 mail.Sender
+	patientsRepository     repository.Patients
+	appointmentsRepository repository.Appointments
+	baseURL                string
+	logger                 *zap.Logger
+}
+
+func NewService(
+	signer *Signer,
+	sender mail.Sender,
+	patientsRepository repository.Patients,
+	appointmentsRepository repository.Appointments,
+	baseURL string,
+	logger *zap.Logger) *Service {
+	return &Service{
+		signer:                 signer,
+		sender:                 sender,
+		patientsRepository:     patientsRepository,
+		appointmentsRepository: appointmentsRepository,
+		baseURL:                strings.TrimSuffix(baseURL, "/"),
+		logger:                 logger.With(zap.String("component", "ConfirmationService")),
+	}
+}
+
+// Request emails the patient of the booking a link to confirm it
+func (s *Service) Request(ctx context.Context, booking *domain.AppointmentBooking) error {
+	patient, err := s.patientsRepository.FindByID(ctx, booking.PatientID)
+	if err != nil {
+		return fmt.Errorf("unable to find patient %s: %w", booking.PatientID, err)
+	}
+	appointment, err := s.appointmentsRepository.FindByID(ctx, booking.AppointmentID)
+	if err != nil {
+		return fmt.Errorf("unable to find appointment %s: %w", booking.AppointmentID, err)
+	}
+
+	link := fmt.Sprintf("%s%s?token=%s", s.baseURL, confirmPath, url.QueryEscape(s.signer.Sign(booking.ID)))
+	body := fmt.Sprintf(`Hello %s %s,
+
+You booked a vaccination appointment on %s.
+Please confirm it by following this link:
+
+%s
+
+Without confirmation the appointment will be released.
+`, patient.FirstName, patient.LastName, appointment.StartTime.UTC().Format(time.RFC1123), link)
+
+	s.logger.Debug("requesting booking confirmation", zap.Stringer("booking_id", booking.ID))
+	return s.sender.Send(mail.Message{
+		To:      patient.Email,
+		Subject: "Confirm your vaccination appointment",
+		Body:    body,
+	})
+}
+
+// Offer emails the patient of a booking made from the waitlist a link to accept it
+func (s *Service) Offer(ctx context.Context, booking *domain.AppointmentBooking) error {
+	patient, err := s.patientsRepository.FindByID(ctx, booking.PatientID)
+	if err != nil {
+		return fmt.Errorf("unable to find patient %s: %w", booking.PatientID, err)
+	}
+	appointment, err := s.appointmentsRepository.FindByID(ctx, booking.AppointmentID)
+	if err != nil {
+		return fmt.Errorf("unable to find appointment %s: %w", booking.AppointmentID, err)
+	}
+
+	link := fmt.Sprintf("%s%s?token=%s", s.baseURL, confirmPath, url.QueryEscape(s.signer.Sign(booking.ID)))
+	body := fmt.Sprintf(`Hello %s %s,
+
+A vaccination appointment on %s is available for you.
+It is held for you until %s, accept it by following this link:
+
+%s
+
+Without answer the appointment will be offered to the next patient of the waitlist.
+`, patient.FirstName, patient.LastName, appointment.StartTime.UTC().Format(time.RFC1123),
+		booking.ExpiresAt.UTC().Format(time.RFC1123), link)
+
+	s.logger.Debug("offering waitlisted appointment", zap.Stringer("booking_id", booking.ID))
+	return s.sender.Send(mail.Message{
+		To:      patient.Email,
+		Subject: "A vaccination appointment is available",
+		Body:    body,
+	})
+}
+
+// Withdrawn emails the patient of a booking cancelled without asking, because
+// its appointment or treatment center was deleted or it no longer follows the
+// vaccine schedule, the email gives its release reason
+func (s *Service) Withdrawn(ctx context.Context, booking *domain.PatientBooking) error {
+	patient, err := s.patientsRepository.FindByID(ctx, booking.PatientID)
+	if err != nil {
+		return fmt.Errorf("unable to find patient %s: %w", booking.PatientID, err)
+	}
+
+	body := fmt.Sprintf(`Hello %s %s,
+
+Your vaccination appointment on %s at %s, %s was %s.
+Please book another appointment.
+`, patient.FirstName, patient.LastName, booking.StartTime.UTC().Format(time.RFC1123),
+		booking.TreatmentCenterName, booking.TreatmentCenterAddress, booking.ReleaseReason)
+
+	s.logger.Debug("notifying withdrawn booking", zap.Stringer("booking_id", booking.ID))
+	return s.sender.Send(mail.Message{
+		To:      patient.Email,
+		Subject: "Your vaccination appointment is cancelled",
+		Body:    body,
+	})
+}
+
+// Deadline returns the date a booking made now must be confirmed before
+func (s *Service) Deadline() time.Time {
+	return time.Now().Add(s.signer.TTL())
+}
+
+// Verify returns the booking id carried by a confirmation token
+func (s *Service) Verify(token string) (uuid.UUID, error) {
+	return s.signer.Verify(token)
+}
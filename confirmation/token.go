@@ -0,0 +1,80 @@
+package confirmation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+var ErrExpiredToken = errors.New("expired token")
+
+// payloadSize is the size of a token payload: an uuid followed by
+// the expiration date as an unix timestamp
+const payloadSize = 16 + 8
+
+var encoding = base64.RawURLEncoding
+
+// Signer generates and verifies HMAC signed tokens carrying an id
+// and an expiration date
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewSigner(secret []byte, ttl time.Duration) *Signer {
+	return &Signer{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// TTL is the validity of the tokens
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// Sign returns a token for id valid for the signer ttl
+func (s *Signer) Sign(id uuid.UUID) string {
+	payload := make([]byte, payloadSize)
+	copy(payload, id[:])
+	binary.BigEndian.PutUint64(payload[16:], uint64(s.now().Add(s.ttl).Unix()))
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(s.mac(payload))
+}
+
+// Verify checks the token signature and expiration and returns the id it carries
+func (s *Signer) Verify(token string) (uuid.UUID, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return uuid.Nil, ErrInvalidToken
+	}
+	payload, err := encoding.DecodeString(parts[0])
+	if err != nil || len(payload) != payloadSize {
+		return uuid.Nil, ErrInvalidToken
+	}
+	signature, err := encoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, s.mac(payload)) {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	expiresAt := time.Unix(int64(binary.BigEndian.Uint64(payload[16:])), 0)
+	if s.now().After(expiresAt) {
+		return uuid.Nil, ErrExpiredToken
+	}
+
+	id, err := uuid.FromBytes(payload[:16])
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return id, nil
+}
+
+func (s *Signer) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,46 @@
+package confirmation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner(t *testing.T) {
+	id := uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710")
+	now := time.Date(2021, 11, 13, 8, 0, 0, 0, time.UTC)
+
+	signer := NewSigner([]byte("secret"), time.Hour)
+	signer.now = func() time.Time { return now }
+	token := signer.Sign(id)
+
+	other := NewSigner([]byte("other secret"), time.Hour)
+	other.now = signer.now
+
+	tests := []struct {
+		name    string
+		signer  *Signer
+		token   string
+		at      time.Time
+		wantID  uuid.UUID
+		wantErr error
+	}{
+		{name: "Successful", signer: signer, token: token, at: now.Add(59 * time.Minute), wantID: id},
+		{name: "Expired", signer: signer, token: token, at: now.Add(61 * time.Minute), wantErr: ErrExpiredToken},
+		{name: "OtherSecret", signer: other, token: token, at: now, wantErr: ErrInvalidToken},
+		{name: "Tampered", signer: signer, token: "A" + token[1:], at: now, wantErr: ErrInvalidToken},
+		{name: "Malformed", signer: signer, token: "not a token", at: now, wantErr: ErrInvalidToken},
+		{name: "Empty", signer: signer, token: "", at: now, wantErr: ErrInvalidToken},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.signer.now = func() time.Time { return tc.at }
+			gotID, err := tc.signer.Verify(tc.token)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantID, gotID)
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// Package db holds the schema migrations, those of Postgres in migrations are
+// applied by the migrate container and those of SQLite in sqlite by the server
+package db
+
+import "embed"
+
+// SQLiteMigrationsDir is the directory of the SQLite migrations
+const SQLiteMigrationsDir = "sqlite"
+
+// SQLiteMigrations embeds the SQLite migrations, they follow the Postgres ones
+// with the same versions
+//
+//go:embed sqlite/*.sql
+var SQLiteMigrations embed.FS
@@ -0,0 +1,24 @@
+BEGIN;
+
+DROP INDEX IF EXISTS appointment_bookings_expires_at_index;
+DROP INDEX IF EXISTS appointment_bookings_appointment_id_patient_id_uindex;
+
+DELETE FROM appointment_bookings WHERE status = 'expired';
+
+ALTER TABLE appointment_bookings
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS released_at,
+    DROP COLUMN IF EXISTS release_reason;
+
+ALTER TYPE appointment_status RENAME TO appointment_status_old;
+CREATE TYPE appointment_status AS ENUM ('awaiting confirmation', 'confirmed');
+ALTER TABLE appointment_bookings
+    ALTER COLUMN status TYPE appointment_status USING status::text::appointment_status;
+DROP TYPE appointment_status_old;
+
+CREATE UNIQUE INDEX appointment_bookings_appointment_id_patient_id_uindex
+    ON appointment_bookings (appointment_id, patient_id);
+
+COMMIT;
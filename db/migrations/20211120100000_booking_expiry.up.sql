@@ -0,0 +1,22 @@
+BEGIN;
+
+ALTER TYPE appointment_status ADD VALUE IF NOT EXISTS 'expired';
+
+ALTER TABLE appointment_bookings
+    ADD COLUMN created_at      timestamptz DEFAULT NOW(),
+    ADD COLUMN updated_at      timestamptz,
+    ADD COLUMN expires_at      timestamptz,
+    ADD COLUMN released_at     timestamptz,
+    ADD COLUMN release_reason  text;
+
+-- a released booking must not prevent the patient from booking the slot again
+DROP INDEX IF EXISTS appointment_bookings_appointment_id_patient_id_uindex;
+CREATE UNIQUE INDEX appointment_bookings_appointment_id_patient_id_uindex
+    ON appointment_bookings (appointment_id, patient_id)
+    WHERE status IN ('awaiting confirmation', 'confirmed');
+
+CREATE INDEX appointment_bookings_expires_at_index
+    ON appointment_bookings (expires_at)
+    WHERE status = 'awaiting confirmation';
+
+COMMIT;
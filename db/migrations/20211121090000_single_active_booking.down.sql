@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS appointment_bookings_appointment_id_uindex;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- an appointment can only be held by one booking at a time
+CREATE UNIQUE INDEX appointment_bookings_appointment_id_uindex
+    ON appointment_bookings (appointment_id)
+    WHERE status IN ('awaiting confirmation', 'confirmed');
+
+COMMIT;
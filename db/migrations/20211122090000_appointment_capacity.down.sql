@@ -0,0 +1,23 @@
+BEGIN;
+
+-- merged appointments aren't split back, bookings beyond the first seat are released
+UPDATE appointment_bookings
+SET status = 'expired', released_at = NOW(), release_reason = 'appointment capacity removed'
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, row_number() OVER (PARTITION BY appointment_id ORDER BY created_at, id) AS seat
+        FROM appointment_bookings
+        WHERE status IN ('awaiting confirmation', 'confirmed')
+    ) seats
+    WHERE seat > 1
+);
+
+CREATE UNIQUE INDEX appointment_bookings_appointment_id_uindex
+    ON appointment_bookings (appointment_id)
+    WHERE status IN ('awaiting confirmation', 'confirmed');
+
+DROP INDEX IF EXISTS appointments_treatment_center_id_start_time_uindex;
+
+ALTER TABLE appointments DROP COLUMN IF EXISTS capacity;
+
+COMMIT;
@@ -0,0 +1,46 @@
+BEGIN;
+
+ALTER TABLE appointments
+    ADD COLUMN capacity integer NOT NULL DEFAULT 1 CHECK (capacity > 0);
+
+-- identical single seat appointments of a center are merged into the oldest one
+CREATE TEMPORARY TABLE appointment_merges ON COMMIT DROP AS
+SELECT id,
+       first_value(id) OVER (PARTITION BY treatment_center_id, start_time ORDER BY created_at, id) AS kept_id
+FROM appointments;
+
+-- an appointment is now held by as many bookings as its capacity, the merged
+-- appointments bring their bookings to the one kept
+DROP INDEX IF EXISTS appointment_bookings_appointment_id_uindex;
+
+-- a patient keeps a single booking of the merged appointments, the first one
+UPDATE appointment_bookings
+SET status = 'expired', released_at = NOW(), release_reason = 'duplicate appointment merged'
+WHERE id IN (
+    SELECT id FROM (
+        SELECT ab.id, row_number() OVER (PARTITION BY m.kept_id, ab.patient_id ORDER BY ab.created_at, ab.id) AS rank
+        FROM appointment_bookings ab
+        JOIN appointment_merges m ON m.id = ab.appointment_id
+        WHERE ab.status IN ('awaiting confirmation', 'confirmed')
+    ) bookings
+    WHERE rank > 1
+);
+
+UPDATE appointment_bookings ab
+SET appointment_id = m.kept_id
+FROM appointment_merges m
+WHERE ab.appointment_id = m.id AND m.id <> m.kept_id;
+
+UPDATE appointments a
+SET capacity = m.seats
+FROM (SELECT kept_id, count(*) AS seats FROM appointment_merges GROUP BY kept_id) m
+WHERE a.id = m.kept_id;
+
+DELETE FROM appointments a
+USING appointment_merges m
+WHERE a.id = m.id AND m.id <> m.kept_id;
+
+CREATE UNIQUE INDEX appointments_treatment_center_id_start_time_uindex
+    ON appointments (treatment_center_id, start_time);
+
+COMMIT;
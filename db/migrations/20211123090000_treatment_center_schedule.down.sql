@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP TABLE IF EXISTS treatment_center_closures;
+DROP TABLE IF EXISTS treatment_center_opening_hours;
+
+ALTER TABLE treatment_centers
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS slot_duration,
+    DROP COLUMN IF EXISTS slot_capacity;
+
+COMMIT;
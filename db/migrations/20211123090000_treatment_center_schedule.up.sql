@@ -0,0 +1,30 @@
+BEGIN;
+
+ALTER TABLE treatment_centers
+    ADD COLUMN timezone       text NOT NULL DEFAULT 'UTC',
+    ADD COLUMN slot_duration  integer NOT NULL DEFAULT 10 CHECK (slot_duration > 0),
+    ADD COLUMN slot_capacity  integer NOT NULL DEFAULT 1 CHECK (slot_capacity > 0);
+
+CREATE TABLE IF NOT EXISTS treatment_center_opening_hours (
+    id                    uuid NOT NULL PRIMARY KEY,
+    treatment_center_id   uuid NOT NULL REFERENCES treatment_centers (id) ON DELETE CASCADE,
+    weekday               smallint NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    opens_at              varchar (5) NOT NULL,
+    closes_at             varchar (5) NOT NULL,
+    CHECK (opens_at < closes_at)
+);
+
+CREATE INDEX treatment_center_opening_hours_treatment_center_id_index
+    ON treatment_center_opening_hours (treatment_center_id);
+
+CREATE TABLE IF NOT EXISTS treatment_center_closures (
+    id                    uuid NOT NULL PRIMARY KEY,
+    treatment_center_id   uuid NOT NULL REFERENCES treatment_centers (id) ON DELETE CASCADE,
+    date                  date NOT NULL,
+    reason                text NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX treatment_center_closures_treatment_center_id_date_uindex
+    ON treatment_center_closures (treatment_center_id, date);
+
+COMMIT;
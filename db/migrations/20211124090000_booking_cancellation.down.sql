@@ -0,0 +1,22 @@
+BEGIN;
+
+DROP INDEX IF EXISTS appointment_bookings_expires_at_index;
+DROP INDEX IF EXISTS appointment_bookings_appointment_id_patient_id_uindex;
+
+UPDATE appointment_bookings SET status = 'expired' WHERE status = 'cancelled';
+
+ALTER TYPE appointment_status RENAME TO appointment_status_old;
+CREATE TYPE appointment_status AS ENUM ('awaiting confirmation', 'confirmed', 'expired');
+ALTER TABLE appointment_bookings
+    ALTER COLUMN status TYPE appointment_status USING status::text::appointment_status;
+DROP TYPE appointment_status_old;
+
+CREATE UNIQUE INDEX appointment_bookings_appointment_id_patient_id_uindex
+    ON appointment_bookings (appointment_id, patient_id)
+    WHERE status IN ('awaiting confirmation', 'confirmed');
+
+CREATE INDEX appointment_bookings_expires_at_index
+    ON appointment_bookings (expires_at)
+    WHERE status = 'awaiting confirmation';
+
+COMMIT;
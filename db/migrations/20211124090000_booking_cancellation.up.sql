@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TYPE appointment_status ADD VALUE IF NOT EXISTS 'cancelled';
+
+COMMIT;
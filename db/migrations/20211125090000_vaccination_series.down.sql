@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS doses;
+DROP TABLE IF EXISTS vaccine_products;
+
+COMMIT;
@@ -0,0 +1,27 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS vaccine_products (
+    id                      uuid NOT NULL PRIMARY KEY,
+    name                    text NOT NULL UNIQUE,
+    doses                   smallint NOT NULL CHECK (doses > 0),
+    min_interval_days       integer NOT NULL DEFAULT 0 CHECK (min_interval_days >= 0),
+    max_interval_days       integer CHECK (max_interval_days >= min_interval_days),
+    booster_interval_days   integer CHECK (booster_interval_days > 0),
+    created_at              timestamptz DEFAULT NOW(),
+    updated_at              timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS doses (
+    id                      uuid NOT NULL PRIMARY KEY,
+    patient_id              uuid NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    vaccine_product_id      uuid NOT NULL REFERENCES vaccine_products (id),
+    appointment_booking_id  uuid UNIQUE REFERENCES appointment_bookings (id),
+    number                  smallint NOT NULL CHECK (number > 0),
+    administered_at         timestamptz NOT NULL,
+    created_at              timestamptz DEFAULT NOW(),
+    updated_at              timestamptz
+);
+
+CREATE UNIQUE INDEX doses_patient_id_number_uindex ON doses (patient_id, number);
+
+COMMIT;
@@ -0,0 +1,27 @@
+BEGIN;
+
+ALTER TABLE appointment_bookings
+    DROP COLUMN IF EXISTS lot_number,
+    DROP COLUMN IF EXISTS administered_by,
+    DROP COLUMN IF EXISTS outcome_recorded_at;
+
+DROP INDEX IF EXISTS appointment_bookings_expires_at_index;
+DROP INDEX IF EXISTS appointment_bookings_appointment_id_patient_id_uindex;
+
+UPDATE appointment_bookings SET status = 'confirmed' WHERE status IN ('administered', 'no show', 'refused');
+
+ALTER TYPE appointment_status RENAME TO appointment_status_old;
+CREATE TYPE appointment_status AS ENUM ('awaiting confirmation', 'confirmed', 'expired', 'cancelled');
+ALTER TABLE appointment_bookings
+    ALTER COLUMN status TYPE appointment_status USING status::text::appointment_status;
+DROP TYPE appointment_status_old;
+
+CREATE UNIQUE INDEX appointment_bookings_appointment_id_patient_id_uindex
+    ON appointment_bookings (appointment_id, patient_id)
+    WHERE status IN ('awaiting confirmation', 'confirmed');
+
+CREATE INDEX appointment_bookings_expires_at_index
+    ON appointment_bookings (expires_at)
+    WHERE status = 'awaiting confirmation';
+
+COMMIT;
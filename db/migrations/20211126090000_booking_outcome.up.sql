@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TYPE appointment_status ADD VALUE IF NOT EXISTS 'administered';
+ALTER TYPE appointment_status ADD VALUE IF NOT EXISTS 'no show';
+ALTER TYPE appointment_status ADD VALUE IF NOT EXISTS 'refused';
+
+ALTER TABLE appointment_bookings
+    ADD COLUMN lot_number           text,
+    ADD COLUMN administered_by      text,
+    ADD COLUMN outcome_recorded_at  timestamptz;
+
+COMMIT;
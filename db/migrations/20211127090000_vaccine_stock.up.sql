@@ -0,0 +1,18 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS vaccine_lots (
+    id                    uuid NOT NULL PRIMARY KEY,
+    treatment_center_id   uuid NOT NULL REFERENCES treatment_centers (id) ON DELETE CASCADE,
+    vaccine_product_id    uuid NOT NULL REFERENCES vaccine_products (id),
+    lot_number            text NOT NULL,
+    quantity              integer NOT NULL CHECK (quantity >= 0),
+    expires_on            date NOT NULL,
+    received_at           timestamptz NOT NULL DEFAULT NOW(),
+    created_at            timestamptz DEFAULT NOW(),
+    updated_at            timestamptz
+);
+
+CREATE UNIQUE INDEX vaccine_lots_treatment_center_id_vaccine_product_id_lot_number_uindex
+    ON vaccine_lots (treatment_center_id, vaccine_product_id, lot_number);
+
+COMMIT;
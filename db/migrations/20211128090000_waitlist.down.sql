@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS waitlist_entries;
+DROP TYPE IF EXISTS waitlist_status;
+
+COMMIT;
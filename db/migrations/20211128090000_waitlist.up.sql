@@ -0,0 +1,25 @@
+BEGIN;
+
+CREATE TYPE waitlist_status AS ENUM ('waiting', 'offered', 'fulfilled', 'lapsed', 'withdrawn');
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id                      uuid NOT NULL PRIMARY KEY,
+    patient_id              uuid NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    treatment_center_id     uuid NOT NULL REFERENCES treatment_centers (id) ON DELETE CASCADE,
+    from_date               date NOT NULL,
+    to_date                 date NOT NULL CHECK (to_date >= from_date),
+    status                  waitlist_status NOT NULL DEFAULT 'waiting',
+    appointment_booking_id  uuid REFERENCES appointment_bookings (id),
+    offered_at              timestamptz,
+    created_at              timestamptz DEFAULT NOW(),
+    updated_at              timestamptz
+);
+
+-- a patient waits once per treatment center
+CREATE UNIQUE INDEX waitlist_entries_patient_id_treatment_center_id_uindex
+    ON waitlist_entries (patient_id, treatment_center_id) WHERE status IN ('waiting', 'offered');
+
+CREATE INDEX waitlist_entries_treatment_center_id_created_at_index
+    ON waitlist_entries (treatment_center_id, created_at) WHERE status = 'waiting';
+
+COMMIT;
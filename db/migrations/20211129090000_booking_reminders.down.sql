@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS booking_reminders;
+ALTER TABLE patients DROP COLUMN IF EXISTS phone;
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS phone text;
+
+CREATE TABLE IF NOT EXISTS booking_reminders (
+    appointment_booking_id  uuid NOT NULL REFERENCES appointment_bookings (id) ON DELETE CASCADE,
+    lead_minutes            integer NOT NULL CHECK (lead_minutes > 0),
+    sent_at                 timestamptz NOT NULL,
+    PRIMARY KEY (appointment_booking_id, lead_minutes)
+);
+
+COMMIT;
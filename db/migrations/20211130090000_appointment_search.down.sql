@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS appointments_start_time_id_index;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS appointments_start_time_id_index ON appointments (start_time, id);
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX IF EXISTS treatment_centers_location_index;
+
+ALTER TABLE treatment_centers
+    DROP CONSTRAINT IF EXISTS treatment_centers_location_check,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS longitude;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE treatment_centers
+    ADD COLUMN IF NOT EXISTS latitude  double precision CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN IF NOT EXISTS longitude double precision CHECK (longitude BETWEEN -180 AND 180),
+    ADD CONSTRAINT treatment_centers_location_check CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- the nearby searches first narrow the centers to a bounding box
+CREATE INDEX IF NOT EXISTS treatment_centers_location_index ON treatment_centers (latitude, longitude);
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- the deleted appointments holding a start time taken again can't be restored
+DELETE FROM appointments a
+WHERE a.deleted_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM appointment_bookings ab WHERE ab.appointment_id = a.id)
+  AND EXISTS (SELECT 1 FROM appointments other
+              WHERE other.treatment_center_id = a.treatment_center_id
+                AND other.start_time = a.start_time AND other.id <> a.id);
+
+DROP INDEX IF EXISTS appointments_treatment_center_id_start_time_uindex;
+CREATE UNIQUE INDEX appointments_treatment_center_id_start_time_uindex
+    ON appointments (treatment_center_id, start_time);
+
+ALTER TABLE appointments DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE treatment_centers DROP COLUMN IF EXISTS deleted_at;
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+-- deleted treatment centers and appointments are kept for the bookings referencing them
+ALTER TABLE treatment_centers
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+ALTER TABLE appointments
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+-- a deleted appointment frees its start time
+DROP INDEX IF EXISTS appointments_treatment_center_id_start_time_uindex;
+CREATE UNIQUE INDEX appointments_treatment_center_id_start_time_uindex
+    ON appointments (treatment_center_id, start_time)
+    WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP INDEX IF EXISTS patients_lower_last_name_index;
+
+DROP INDEX IF EXISTS patients_national_health_id_uindex;
+
+DROP INDEX IF EXISTS patients_lower_email_uindex;
+ALTER TABLE patients ADD CONSTRAINT patients_email_key UNIQUE (email);
+
+ALTER TABLE patients
+    DROP COLUMN IF EXISTS national_health_id,
+    DROP COLUMN IF EXISTS birth_date;
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+ALTER TABLE patients
+    ADD COLUMN IF NOT EXISTS birth_date date,
+    ADD COLUMN IF NOT EXISTS national_health_id text;
+
+-- emails are unique whatever their case, the patients sharing one must be merged first
+ALTER TABLE patients DROP CONSTRAINT IF EXISTS patients_email_key;
+CREATE UNIQUE INDEX patients_lower_email_uindex ON patients (lower(email));
+
+CREATE UNIQUE INDEX patients_national_health_id_uindex
+    ON patients (national_health_id) WHERE national_health_id IS NOT NULL;
+
+CREATE INDEX patients_lower_last_name_index ON patients (lower(last_name));
+
+COMMIT;
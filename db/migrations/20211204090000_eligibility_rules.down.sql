@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS eligibility_rules;
+
+ALTER TABLE patients
+    DROP COLUMN IF EXISTS profession,
+    DROP COLUMN IF EXISTS risk_categories;
+
+COMMIT;
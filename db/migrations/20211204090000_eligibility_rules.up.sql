@@ -0,0 +1,22 @@
+BEGIN;
+
+ALTER TABLE patients
+    ADD COLUMN IF NOT EXISTS risk_categories jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS profession text;
+
+-- a patient meets a rule with any of its criteria and must meet every rule in effect to book
+CREATE TABLE IF NOT EXISTS eligibility_rules (
+    id                      uuid NOT NULL PRIMARY KEY,
+    name                    text NOT NULL,
+    min_age                 smallint CHECK (min_age >= 0),
+    risk_categories         jsonb NOT NULL DEFAULT '[]',
+    professions             jsonb NOT NULL DEFAULT '[]',
+    effective_from          timestamptz NOT NULL,
+    effective_until         timestamptz CHECK (effective_until > effective_from),
+    created_at              timestamptz DEFAULT NOW(),
+    updated_at              timestamptz
+);
+
+CREATE INDEX eligibility_rules_effective_from_index ON eligibility_rules (effective_from);
+
+COMMIT;
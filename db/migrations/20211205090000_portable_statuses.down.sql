@@ -0,0 +1,36 @@
+BEGIN;
+
+DROP INDEX IF EXISTS appointment_bookings_appointment_id_patient_id_uindex;
+DROP INDEX IF EXISTS appointment_bookings_expires_at_index;
+DROP INDEX IF EXISTS waitlist_entries_patient_id_treatment_center_id_uindex;
+DROP INDEX IF EXISTS waitlist_entries_treatment_center_id_created_at_index;
+
+CREATE TYPE appointment_status AS ENUM
+    ('awaiting confirmation', 'confirmed', 'expired', 'cancelled', 'administered', 'no show', 'refused');
+ALTER TABLE appointment_bookings
+    DROP CONSTRAINT IF EXISTS appointment_bookings_status_check,
+    ALTER COLUMN status TYPE appointment_status USING status::appointment_status;
+
+CREATE TYPE waitlist_status AS ENUM ('waiting', 'offered', 'fulfilled', 'lapsed', 'withdrawn');
+ALTER TABLE waitlist_entries
+    DROP CONSTRAINT IF EXISTS waitlist_entries_status_check,
+    ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE waitlist_entries
+    ALTER COLUMN status TYPE waitlist_status USING status::waitlist_status,
+    ALTER COLUMN status SET DEFAULT 'waiting';
+
+CREATE UNIQUE INDEX appointment_bookings_appointment_id_patient_id_uindex
+    ON appointment_bookings (appointment_id, patient_id)
+    WHERE status IN ('awaiting confirmation', 'confirmed');
+
+CREATE INDEX appointment_bookings_expires_at_index
+    ON appointment_bookings (expires_at)
+    WHERE status = 'awaiting confirmation';
+
+CREATE UNIQUE INDEX waitlist_entries_patient_id_treatment_center_id_uindex
+    ON waitlist_entries (patient_id, treatment_center_id) WHERE status IN ('waiting', 'offered');
+
+CREATE INDEX waitlist_entries_treatment_center_id_created_at_index
+    ON waitlist_entries (treatment_center_id, created_at) WHERE status = 'waiting';
+
+COMMIT;
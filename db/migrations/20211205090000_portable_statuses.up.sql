@@ -0,0 +1,37 @@
+BEGIN;
+
+-- the statuses are checked text rather than enums so that the schema also runs on SQLite
+DROP INDEX IF EXISTS appointment_bookings_appointment_id_patient_id_uindex;
+DROP INDEX IF EXISTS appointment_bookings_expires_at_index;
+DROP INDEX IF EXISTS waitlist_entries_patient_id_treatment_center_id_uindex;
+DROP INDEX IF EXISTS waitlist_entries_treatment_center_id_created_at_index;
+
+ALTER TABLE appointment_bookings
+    ALTER COLUMN status TYPE text USING status::text,
+    ADD CONSTRAINT appointment_bookings_status_check CHECK (status IN
+        ('awaiting confirmation', 'confirmed', 'expired', 'cancelled', 'administered', 'no show', 'refused'));
+DROP TYPE appointment_status;
+
+ALTER TABLE waitlist_entries ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE waitlist_entries
+    ALTER COLUMN status TYPE text USING status::text,
+    ALTER COLUMN status SET DEFAULT 'waiting',
+    ADD CONSTRAINT waitlist_entries_status_check CHECK (status IN
+        ('waiting', 'offered', 'fulfilled', 'lapsed', 'withdrawn'));
+DROP TYPE waitlist_status;
+
+CREATE UNIQUE INDEX appointment_bookings_appointment_id_patient_id_uindex
+    ON appointment_bookings (appointment_id, patient_id)
+    WHERE status IN ('awaiting confirmation', 'confirmed');
+
+CREATE INDEX appointment_bookings_expires_at_index
+    ON appointment_bookings (expires_at)
+    WHERE status = 'awaiting confirmation';
+
+CREATE UNIQUE INDEX waitlist_entries_patient_id_treatment_center_id_uindex
+    ON waitlist_entries (patient_id, treatment_center_id) WHERE status IN ('waiting', 'offered');
+
+CREATE INDEX waitlist_entries_treatment_center_id_created_at_index
+    ON waitlist_entries (treatment_center_id, created_at) WHERE status = 'waiting';
+
+COMMIT;
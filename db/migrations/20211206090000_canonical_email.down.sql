@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS patients_canonical_email_index;
+
+ALTER TABLE patients DROP COLUMN IF EXISTS canonical_email;
+
+COMMIT;
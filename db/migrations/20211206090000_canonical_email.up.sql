@@ -0,0 +1,12 @@
+BEGIN;
+
+-- the email lowered without the dots and +tag of its local part, like
+-- domain.CanonicalEmail, finds the patients who may be duplicates through its index
+ALTER TABLE patients
+    ADD COLUMN canonical_email text GENERATED ALWAYS AS (
+        replace(split_part(split_part(lower(email), '@', 1), '+', 1), '.', '') || '@' || split_part(lower(email), '@', 2)
+    ) STORED;
+
+CREATE INDEX patients_canonical_email_index ON patients (canonical_email);
+
+COMMIT;
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/y9mo/covidvax/testutils"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationsIntegrationTestSuite struct {
+	testutils.IntegrationSuite
+}
+
+// SetupSuite starts the database without migrating it, each test migrates it to
+// the version it seeds
+func (s *MigrationsIntegrationTestSuite) SetupSuite() {
+	s.IntegrationSuite.SetupPostgres(context.Background())
+	s.IntegrationSuite.InitSQLClient()
+}
+
+func (s *MigrationsIntegrationTestSuite) SetupTest() {}
+
+func (s *MigrationsIntegrationTestSuite) TearDownTest() {}
+
+func (s *MigrationsIntegrationTestSuite) exec(query string, args ...interface{}) {
+	s.Require().NoError(s.IntegrationSuite.DB().Exec(query, args...).Error)
+}
+
+func (s *MigrationsIntegrationTestSuite) TestAppointmentCapacity() {
+	s.IntegrationSuite.MigrateTo(20211121090000)
+
+	s.exec(`INSERT INTO treatment_centers (id, name, address, phone)
+		VALUES ('52b2edf2-a380-4436-9f98-b70f78f174ef', 'Center', 'Somewhere', '0422420033')`)
+	s.exec(`INSERT INTO patients (id, email, first_name, last_name) VALUES
+		('8152fcbe-3228-46c9-b483-edcb6317d99c', 'one@some.com', 'One', 'Patient'),
+		('d93f7ecc-816f-4124-b41e-dcfa58f03761', 'two@some.com', 'Two', 'Patient'),
+		('24e32685-0a32-4a9d-bc22-0e98cdaf5884', 'three@some.com', 'Three', 'Patient')`)
+	// two booked slots at 08:00 and two slots at 09:00 booked by the same patient
+	s.exec(`INSERT INTO appointments (id, treatment_center_id, start_time, created_at) VALUES
+		('eecce415-2d4c-440d-ac90-9780a3bd3371', '52b2edf2-a380-4436-9f98-b70f78f174ef', '2021-11-13 08:00:00+00', '2021-11-12 14:00:00+00'),
+		('eab38294-8b76-410c-8058-3e152e64dced', '52b2edf2-a380-4436-9f98-b70f78f174ef', '2021-11-13 08:00:00+00', '2021-11-12 15:00:00+00'),
+		('b7269eb2-b5f9-46a5-ae79-87916c67e50a', '52b2edf2-a380-4436-9f98-b70f78f174ef', '2021-11-13 09:00:00+00', '2021-11-12 14:00:00+00'),
+		('96261e44-6d19-4d12-af0b-f28f56f665e2', '52b2edf2-a380-4436-9f98-b70f78f174ef', '2021-11-13 09:00:00+00', '2021-11-12 15:00:00+00')`)
+	s.exec(`INSERT INTO appointment_bookings (id, appointment_id, patient_id, status, created_at) VALUES
+		('f859ae2c-e24f-46e8-9c27-4431112fc710', 'eecce415-2d4c-440d-ac90-9780a3bd3371', '8152fcbe-3228-46c9-b483-edcb6317d99c', 'confirmed', '2021-11-12 16:00:00+00'),
+		('7d3e5f1a-9b2c-4d6e-8f0a-1b3c5d7e9f2a', 'eab38294-8b76-410c-8058-3e152e64dced', 'd93f7ecc-816f-4124-b41e-dcfa58f03761', 'awaiting confirmation', '2021-11-12 16:00:00+00'),
+		('1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d', 'b7269eb2-b5f9-46a5-ae79-87916c67e50a', '24e32685-0a32-4a9d-bc22-0e98cdaf5884', 'confirmed', '2021-11-12 16:00:00+00'),
+		('2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e', '96261e44-6d19-4d12-af0b-f28f56f665e2', '24e32685-0a32-4a9d-bc22-0e98cdaf5884', 'confirmed', '2021-11-12 17:00:00+00')`)
+
+	s.IntegrationSuite.MigrateTo(20211122090000)
+
+	type appointment struct {
+		ID       string
+		Capacity int
+	}
+	var appointments []appointment
+	err := s.IntegrationSuite.DB().Raw(`SELECT id, capacity FROM appointments ORDER BY start_time`).
+		Scan(&appointments).Error
+	s.Require().NoError(err)
+	s.Assert().Equal([]appointment{
+		{ID: "eecce415-2d4c-440d-ac90-9780a3bd3371", Capacity: 2},
+		{ID: "b7269eb2-b5f9-46a5-ae79-87916c67e50a", Capacity: 2},
+	}, appointments)
+
+	type booking struct {
+		ID            string
+		AppointmentID string
+		Status        string
+	}
+	var bookings []booking
+	err = s.IntegrationSuite.DB().Raw(`SELECT id, appointment_id, status FROM appointment_bookings
+		ORDER BY created_at, id`).Scan(&bookings).Error
+	s.Require().NoError(err)
+	s.Assert().Equal([]booking{
+		{ID: "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d", AppointmentID: "b7269eb2-b5f9-46a5-ae79-87916c67e50a", Status: "confirmed"},
+		{ID: "7d3e5f1a-9b2c-4d6e-8f0a-1b3c5d7e9f2a", AppointmentID: "eecce415-2d4c-440d-ac90-9780a3bd3371", Status: "awaiting confirmation"},
+		{ID: "f859ae2c-e24f-46e8-9c27-4431112fc710", AppointmentID: "eecce415-2d4c-440d-ac90-9780a3bd3371", Status: "confirmed"},
+		{ID: "2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e", AppointmentID: "b7269eb2-b5f9-46a5-ae79-87916c67e50a", Status: "expired"},
+	}, bookings)
+}
+
+func TestMigrationsIntegrationTestSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping MigrationsIntegrationTest in short mode.")
+		return
+	}
+	t.Parallel()
+	suite.Run(t, new(MigrationsIntegrationTestSuite))
+}
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS eligibility_rules;
+DROP TABLE IF EXISTS booking_reminders;
+DROP TABLE IF EXISTS waitlist_entries;
+DROP TABLE IF EXISTS vaccine_lots;
+DROP TABLE IF EXISTS doses;
+DROP TABLE IF EXISTS vaccine_products;
+DROP TABLE IF EXISTS appointment_bookings;
+DROP TABLE IF EXISTS appointments;
+DROP TABLE IF EXISTS treatment_center_closures;
+DROP TABLE IF EXISTS treatment_center_opening_hours;
+DROP TABLE IF EXISTS treatment_centers;
+DROP TABLE IF EXISTS patients;
@@ -0,0 +1,189 @@
+-- the schema of the Postgres migrations up to 20211205090000 in SQLite, the uuids
+-- and lists are text and the times are datetime text in UTC
+
+CREATE TABLE patients (
+    id                  text NOT NULL PRIMARY KEY,
+    email               text NOT NULL,
+    first_name          text NOT NULL,
+    last_name           text NOT NULL,
+    phone               text,
+    birth_date          date,
+    national_health_id  text,
+    risk_categories     text NOT NULL DEFAULT '[]',
+    profession          text,
+    created_at          datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at          datetime
+);
+
+-- emails are unique whatever their case, the patients sharing one must be merged first
+CREATE UNIQUE INDEX patients_lower_email_uindex ON patients (lower(email));
+
+CREATE UNIQUE INDEX patients_national_health_id_uindex
+    ON patients (national_health_id) WHERE national_health_id IS NOT NULL;
+
+CREATE INDEX patients_lower_last_name_index ON patients (lower(last_name));
+
+CREATE TABLE treatment_centers (
+    id             text NOT NULL PRIMARY KEY,
+    name           text NOT NULL,
+    address        text NOT NULL,
+    phone          text NOT NULL,
+    timezone       text NOT NULL DEFAULT 'UTC',
+    slot_duration  integer NOT NULL DEFAULT 10 CHECK (slot_duration > 0),
+    slot_capacity  integer NOT NULL DEFAULT 1 CHECK (slot_capacity > 0),
+    latitude       real CHECK (latitude BETWEEN -90 AND 90),
+    longitude      real CHECK (longitude BETWEEN -180 AND 180),
+    created_at     datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at     datetime,
+    deleted_at     datetime,
+    CONSTRAINT treatment_centers_location_check CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+-- the nearby searches first narrow the centers to a bounding box
+CREATE INDEX treatment_centers_location_index ON treatment_centers (latitude, longitude);
+
+CREATE TABLE treatment_center_opening_hours (
+    id                    text NOT NULL PRIMARY KEY,
+    treatment_center_id   text NOT NULL REFERENCES treatment_centers (id) ON DELETE CASCADE,
+    weekday               integer NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    opens_at              text NOT NULL,
+    closes_at             text NOT NULL,
+    CHECK (opens_at < closes_at)
+);
+
+CREATE INDEX treatment_center_opening_hours_treatment_center_id_index
+    ON treatment_center_opening_hours (treatment_center_id);
+
+CREATE TABLE treatment_center_closures (
+    id                    text NOT NULL PRIMARY KEY,
+    treatment_center_id   text NOT NULL REFERENCES treatment_centers (id) ON DELETE CASCADE,
+    date                  date NOT NULL,
+    reason                text NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX treatment_center_closures_treatment_center_id_date_uindex
+    ON treatment_center_closures (treatment_center_id, date);
+
+CREATE TABLE appointments (
+    id                    text NOT NULL PRIMARY KEY,
+    treatment_center_id   text REFERENCES treatment_centers (id),
+    start_time            datetime,
+    capacity              integer NOT NULL DEFAULT 1 CHECK (capacity > 0),
+    created_at            datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at            datetime,
+    deleted_at            datetime
+);
+
+-- a deleted appointment frees its start time
+CREATE UNIQUE INDEX appointments_treatment_center_id_start_time_uindex
+    ON appointments (treatment_center_id, start_time)
+    WHERE deleted_at IS NULL;
+
+CREATE INDEX appointments_start_time_id_index ON appointments (start_time, id);
+
+CREATE TABLE appointment_bookings (
+    id                    text NOT NULL PRIMARY KEY,
+    appointment_id        text REFERENCES appointments (id),
+    patient_id            text REFERENCES patients (id),
+    status                text NOT NULL CHECK (status IN
+        ('awaiting confirmation', 'confirmed', 'expired', 'cancelled', 'administered', 'no show', 'refused')),
+    created_at            datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at            datetime,
+    expires_at            datetime,
+    released_at           datetime,
+    release_reason        text,
+    lot_number            text,
+    administered_by       text,
+    outcome_recorded_at   datetime
+);
+
+-- a released booking must not prevent the patient from booking the slot again
+CREATE UNIQUE INDEX appointment_bookings_appointment_id_patient_id_uindex
+    ON appointment_bookings (appointment_id, patient_id)
+    WHERE status IN ('awaiting confirmation', 'confirmed');
+
+CREATE INDEX appointment_bookings_expires_at_index
+    ON appointment_bookings (expires_at)
+    WHERE status = 'awaiting confirmation';
+
+CREATE TABLE vaccine_products (
+    id                      text NOT NULL PRIMARY KEY,
+    name                    text NOT NULL UNIQUE,
+    doses                   integer NOT NULL CHECK (doses > 0),
+    min_interval_days       integer NOT NULL DEFAULT 0 CHECK (min_interval_days >= 0),
+    max_interval_days       integer CHECK (max_interval_days >= min_interval_days),
+    booster_interval_days   integer CHECK (booster_interval_days > 0),
+    created_at              datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at              datetime
+);
+
+CREATE TABLE doses (
+    id                      text NOT NULL PRIMARY KEY,
+    patient_id              text NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    vaccine_product_id      text NOT NULL REFERENCES vaccine_products (id),
+    appointment_booking_id  text UNIQUE REFERENCES appointment_bookings (id),
+    number                  integer NOT NULL CHECK (number > 0),
+    administered_at         datetime NOT NULL,
+    created_at              datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at              datetime
+);
+
+CREATE UNIQUE INDEX doses_patient_id_number_uindex ON doses (patient_id, number);
+
+CREATE TABLE vaccine_lots (
+    id                    text NOT NULL PRIMARY KEY,
+    treatment_center_id   text NOT NULL REFERENCES treatment_centers (id) ON DELETE CASCADE,
+    vaccine_product_id    text NOT NULL REFERENCES vaccine_products (id),
+    lot_number            text NOT NULL,
+    quantity              integer NOT NULL CHECK (quantity >= 0),
+    expires_on            date NOT NULL,
+    received_at           datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at            datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at            datetime
+);
+
+CREATE UNIQUE INDEX vaccine_lots_treatment_center_id_vaccine_product_id_lot_number_uindex
+    ON vaccine_lots (treatment_center_id, vaccine_product_id, lot_number);
+
+CREATE TABLE waitlist_entries (
+    id                      text NOT NULL PRIMARY KEY,
+    patient_id              text NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    treatment_center_id     text NOT NULL REFERENCES treatment_centers (id) ON DELETE CASCADE,
+    from_date               date NOT NULL,
+    to_date                 date NOT NULL CHECK (to_date >= from_date),
+    status                  text NOT NULL DEFAULT 'waiting' CHECK (status IN
+        ('waiting', 'offered', 'fulfilled', 'lapsed', 'withdrawn')),
+    appointment_booking_id  text REFERENCES appointment_bookings (id),
+    offered_at              datetime,
+    created_at              datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at              datetime
+);
+
+-- a patient waits once per treatment center
+CREATE UNIQUE INDEX waitlist_entries_patient_id_treatment_center_id_uindex
+    ON waitlist_entries (patient_id, treatment_center_id) WHERE status IN ('waiting', 'offered');
+
+CREATE INDEX waitlist_entries_treatment_center_id_created_at_index
+    ON waitlist_entries (treatment_center_id, created_at) WHERE status = 'waiting';
+
+CREATE TABLE booking_reminders (
+    appointment_booking_id  text NOT NULL REFERENCES appointment_bookings (id) ON DELETE CASCADE,
+    lead_minutes            integer NOT NULL CHECK (lead_minutes > 0),
+    sent_at                 datetime NOT NULL,
+    PRIMARY KEY (appointment_booking_id, lead_minutes)
+);
+
+-- a patient meets a rule with any of its criteria and must meet every rule in effect to book
+CREATE TABLE eligibility_rules (
+    id                      text NOT NULL PRIMARY KEY,
+    name                    text NOT NULL,
+    min_age                 integer CHECK (min_age >= 0),
+    risk_categories         text NOT NULL DEFAULT '[]',
+    professions             text NOT NULL DEFAULT '[]',
+    effective_from          datetime NOT NULL,
+    effective_until         datetime CHECK (effective_until > effective_from),
+    created_at              datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at              datetime
+);
+
+CREATE INDEX eligibility_rules_effective_from_index ON eligibility_rules (effective_from);
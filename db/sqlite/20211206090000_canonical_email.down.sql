@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS patients_canonical_email_index;
+
+ALTER TABLE patients DROP COLUMN canonical_email;
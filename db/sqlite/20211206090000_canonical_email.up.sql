@@ -0,0 +1,10 @@
+-- the canonical email of the Postgres migration, SQLite only adds virtual generated columns
+-- and has no split_part: the local part ends at the first + or @
+
+ALTER TABLE patients
+    ADD COLUMN canonical_email text GENERATED ALWAYS AS (
+        replace(substr(lower(email), 1, min(instr(lower(email), '@'), instr(lower(email) || '+', '+')) - 1), '.', '')
+        || substr(lower(email), instr(lower(email), '@'))
+    ) VIRTUAL;
+
+CREATE INDEX patients_canonical_email_index ON patients (canonical_email);
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,20 +13,155 @@ type AppointmentStatus string
 var (
 	Confirmed            AppointmentStatus = "confirmed"
 	AwaitingConfirmation AppointmentStatus = "awaiting confirmation"
+	Expired              AppointmentStatus = "expired"
+	Cancelled            AppointmentStatus = "cancelled"
+	Administered         AppointmentStatus = "administered"
+	NoShow               AppointmentStatus = "no show"
+	Refused              AppointmentStatus = "refused"
 )
 
+// ActiveStatuses are the statuses of a booking still to be honoured,
+// it can be confirmed, cancelled or rescheduled
+var ActiveStatuses = []AppointmentStatus{AwaitingConfirmation, Confirmed}
+
+// HoldingStatuses are the statuses of a booking holding a seat of its appointment
+var HoldingStatuses = []AppointmentStatus{AwaitingConfirmation, Confirmed, Administered}
+
+// OutcomeStatuses are the statuses recording what happened at the appointment
+var OutcomeStatuses = []AppointmentStatus{Administered, NoShow, Refused}
+
+// Active reports whether a booking with this status is still to be honoured
+func (s AppointmentStatus) Active() bool {
+	return s.in(ActiveStatuses)
+}
+
+func (s AppointmentStatus) in(statuses []AppointmentStatus) bool {
+	for _, status := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrAppointmentStarted is returned when booking an appointment that has
+// already started
+var ErrAppointmentStarted = errors.New("appointment has already started")
+
+// ReleaseReasonUnconfirmed is recorded on bookings released because
+// they weren't confirmed in time
+const ReleaseReasonUnconfirmed = "not confirmed in time"
+
+// ReleaseReasonCancelled is recorded on bookings cancelled by their patient
+const ReleaseReasonCancelled = "cancelled by the patient"
+
+// ReleaseReasonRescheduled is recorded on bookings moved to another appointment
+const ReleaseReasonRescheduled = "rescheduled"
+
+// ReleaseReasonWithdrawn is recorded on bookings cancelled because their
+// appointment or treatment center was deleted
+const ReleaseReasonWithdrawn = "cancelled by the treatment center"
+
+// ReleaseReasonOffSchedule is recorded on bookings cancelled because a dose
+// administered since leaves them out of the vaccine schedule
+const ReleaseReasonOffSchedule = "cancelled as it no longer follows the vaccine schedule"
+
 type Appointment struct {
 	ID                uuid.UUID       `json:"id" gorm:"primary_key"`
 	TreatmentCenterID uuid.UUID       `json:"treatment_center_id"`
 	TreatmentCenter   TreatmentCenter `json:"-" binding:"-" gorm:"association_autoupdate:false;association_autocreate:false"`
 	StartTime         time.Time       `json:"start_time" binding:"required"`
+	Capacity          int             `json:"capacity" binding:"omitempty,min=1"`
+	RemainingSeats    int             `json:"remaining_seats" binding:"-" gorm:"-"`
 	CreatedAt         *time.Time      `json:"created_at"`
 	UpdatedAt         *time.Time      `json:"updated_at"`
+	DeletedAt         *time.Time      `json:"-" binding:"-"`
+}
+
+// AppointmentSearch narrows a search of the available appointments, the nil
+// fields don't filter. The times of day are read on the wall clock of the
+// treatment center of the appointments.
+type AppointmentSearch struct {
+	TreatmentCenterID *uuid.UUID
+	// From and To bound the start time, To excluded
+	From *time.Time
+	To   *time.Time
+	// After and Before bound the time of day of the start time, Before excluded
+	After  *Clock
+	Before *Clock
+}
+
+// Validate checks the ranges of the search
+func (s *AppointmentSearch) Validate() error {
+	if s.From != nil && s.To != nil && !s.From.Before(*s.To) {
+		return errors.New("from must be before to")
+	}
+	var after, before time.Duration
+	var err error
+	if s.After != nil {
+		if after, err = s.After.Duration(); err != nil {
+			return err
+		}
+	}
+	if s.Before != nil {
+		if before, err = s.Before.Duration(); err != nil {
+			return err
+		}
+	}
+	if s.After != nil && s.Before != nil && before <= after {
+		return fmt.Errorf("before %s must be after after %s", *s.Before, *s.After)
+	}
+	return nil
 }
 
 type AppointmentBooking struct {
-	ID            uuid.UUID         `json:"id"`
-	AppointmentID uuid.UUID         `json:"appointment_id" gorm:"association_foreignkey:ID"`
-	PatientID     uuid.UUID         `json:"patient_id" gorm:"association_foreignkey:ID"`
-	Status        AppointmentStatus `json:"status"`
+	ID                uuid.UUID         `json:"id"`
+	AppointmentID     uuid.UUID         `json:"appointment_id" gorm:"association_foreignkey:ID"`
+	PatientID         uuid.UUID         `json:"patient_id" gorm:"association_foreignkey:ID"`
+	Status            AppointmentStatus `json:"status"`
+	ExpiresAt         *time.Time        `json:"expires_at,omitempty"`
+	ReleasedAt        *time.Time        `json:"released_at,omitempty"`
+	ReleaseReason     string            `json:"release_reason,omitempty"`
+	LotNumber         string            `json:"lot_number,omitempty"`
+	AdministeredBy    string            `json:"administered_by,omitempty"`
+	OutcomeRecordedAt *time.Time        `json:"outcome_recorded_at,omitempty"`
+	CreatedAt         *time.Time        `json:"created_at"`
+	UpdatedAt         *time.Time        `json:"updated_at"`
+}
+
+// BookingOutcome is what happened at the appointment of a confirmed booking,
+// an administration records the vaccine, its lot and who administered it
+type BookingOutcome struct {
+	Status           AppointmentStatus `json:"status" binding:"required"`
+	VaccineProductID *uuid.UUID        `json:"vaccine_product_id"`
+	LotNumber        string            `json:"lot_number"`
+	AdministeredBy   string            `json:"administered_by"`
+}
+
+// Validate checks the status is an outcome and an administration is complete
+func (o *BookingOutcome) Validate() error {
+	if !o.Status.in(OutcomeStatuses) {
+		return fmt.Errorf("status must be one of %q, %q or %q", Administered, NoShow, Refused)
+	}
+	if o.Status != Administered {
+		return nil
+	}
+	if o.VaccineProductID == nil {
+		return errors.New("vaccine_product_id is required to record an administration")
+	}
+	if o.LotNumber == "" {
+		return errors.New("lot_number is required to record an administration")
+	}
+	if o.AdministeredBy == "" {
+		return errors.New("administered_by is required to record an administration")
+	}
+	return nil
+}
+
+// BookingReminder records the reminder sent for a booking ahead of its
+// appointment, a booking is reminded once per lead time
+type BookingReminder struct {
+	AppointmentBookingID uuid.UUID `json:"appointment_booking_id" gorm:"primary_key"`
+	LeadMinutes          int       `json:"lead_minutes" gorm:"primary_key"`
+	SentAt               time.Time `json:"sent_at"`
 }
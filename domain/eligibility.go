@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StringList is a list of strings stored as a JSON array
+type StringList []string
+
+// Value stores a nil list as an empty array
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	default:
+		return fmt.Errorf("can't scan a %T into a string list", value)
+	}
+}
+
+// contains reports whether the list holds one of values, whatever their case
+func (l StringList) contains(values ...string) bool {
+	for _, item := range l {
+		for _, value := range values {
+			if strings.EqualFold(item, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EligibilityRule is a condition the patients must meet to book an appointment
+// while the rule is in effect, from EffectiveFrom until EffectiveUntil excluded.
+// A patient meets the rule with any of its criteria: being at least MinAge on
+// the day of the appointment, having one of the risk categories or one of the
+// professions.
+type EligibilityRule struct {
+	ID             uuid.UUID  `json:"id" gorm:"primary_key"`
+	Name           string     `json:"name" binding:"required"`
+	MinAge         *int       `json:"min_age,omitempty" binding:"omitempty,min=0"`
+	RiskCategories StringList `json:"risk_categories,omitempty"`
+	Professions    StringList `json:"professions,omitempty"`
+	EffectiveFrom  time.Time  `json:"effective_from" binding:"required"`
+	EffectiveUntil *time.Time `json:"effective_until,omitempty"`
+	CreatedAt      *time.Time `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+}
+
+// Validate checks the rule has a criterion and its effective dates form a range
+func (r *EligibilityRule) Validate() error {
+	if r.MinAge == nil && len(r.RiskCategories) == 0 && len(r.Professions) == 0 {
+		return errors.New("min_age, risk_categories or professions is required")
+	}
+	if r.EffectiveUntil != nil && !r.EffectiveUntil.After(r.EffectiveFrom) {
+		return errors.New("effective_until must be after effective_from")
+	}
+	return nil
+}
+
+// Allows reports whether the patient meets a criterion of the rule for an
+// appointment starting at start
+func (r *EligibilityRule) Allows(patient *Patient, start time.Time) bool {
+	if r.MinAge != nil && patient.BirthDate != nil && Age(*patient.BirthDate, start) >= *r.MinAge {
+		return true
+	}
+	if r.RiskCategories.contains(patient.RiskCategories...) {
+		return true
+	}
+	return patient.Profession != "" && r.Professions.contains(patient.Profession)
+}
+
+// FailedEligibilityRule returns the first of the rules the patient doesn't meet
+// for an appointment starting at start, nil when the patient meets them all
+func FailedEligibilityRule(rules []*EligibilityRule, patient *Patient, start time.Time) *EligibilityRule {
+	for _, rule := range rules {
+		if !rule.Allows(patient, start) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// IneligibleError refuses a booking to a patient who doesn't meet Rule
+type IneligibleError struct {
+	Rule *EligibilityRule
+}
+
+func (e *IneligibleError) Error() string {
+	return "patient is not eligible: " + e.Rule.Name
+}
+
+// Age is the age in years on a date of someone born on birthDate
+func Age(birthDate, date time.Time) int {
+	by, bm, bd := birthDate.Date()
+	y, m, d := date.Date()
+	age := y - by
+	if m < bm || (m == bm && d < bd) {
+		age--
+	}
+	return age
+}
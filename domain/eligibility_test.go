@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAge(t *testing.T) {
+	birthDate := time.Date(1946, 3, 15, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 74, Age(birthDate, time.Date(2021, 3, 14, 10, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 75, Age(birthDate, time.Date(2021, 3, 15, 10, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 75, Age(birthDate, time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)))
+}
+
+func TestEligibilityRuleAllows(t *testing.T) {
+	minAge := 75
+	rule := &EligibilityRule{
+		Name:           "Phase 1",
+		MinAge:         &minAge,
+		RiskCategories: StringList{"immunocompromised", "dialysis"},
+		Professions:    StringList{"nurse", "doctor"},
+	}
+	start := time.Date(2021, 3, 15, 10, 0, 0, 0, time.UTC)
+	old := time.Date(1946, 3, 15, 0, 0, 0, 0, time.UTC)
+	young := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		patient *Patient
+		want    bool
+	}{
+		{name: "OldEnough", patient: &Patient{BirthDate: &old}, want: true},
+		{name: "TooYoung", patient: &Patient{BirthDate: &young}},
+		{name: "NoBirthDate", patient: &Patient{}},
+		{name: "RiskCategory", patient: &Patient{BirthDate: &young, RiskCategories: StringList{"Dialysis"}}, want: true},
+		{name: "OtherRiskCategory", patient: &Patient{RiskCategories: StringList{"asthma"}}},
+		{name: "Profession", patient: &Patient{BirthDate: &young, Profession: "Nurse"}, want: true},
+		{name: "OtherProfession", patient: &Patient{Profession: "teacher"}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, rule.Allows(tc.patient, start))
+		})
+	}
+}
+
+func TestFailedEligibilityRule(t *testing.T) {
+	adults, elderly := 18, 75
+	rules := []*EligibilityRule{
+		{Name: "Adults", MinAge: &adults},
+		{Name: "Elderly or at risk", MinAge: &elderly, RiskCategories: StringList{"dialysis"}},
+	}
+	start := time.Date(2021, 3, 15, 10, 0, 0, 0, time.UTC)
+	birthDate := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	child := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, FailedEligibilityRule(nil, &Patient{}, start))
+	assert.Nil(t, FailedEligibilityRule(rules, &Patient{BirthDate: &birthDate, RiskCategories: StringList{"dialysis"}},
+		start))
+	assert.Equal(t, rules[1], FailedEligibilityRule(rules, &Patient{BirthDate: &birthDate}, start))
+	assert.Equal(t, rules[0], FailedEligibilityRule(rules, &Patient{BirthDate: &child}, start))
+}
+
+func TestEligibilityRuleValidate(t *testing.T) {
+	minAge := 18
+	from := time.Date(2021, 1, 18, 0, 0, 0, 0, time.UTC)
+	before := from.AddDate(0, 0, -1)
+
+	assert.NoError(t, (&EligibilityRule{MinAge: &minAge, EffectiveFrom: from}).Validate())
+	assert.Error(t, (&EligibilityRule{EffectiveFrom: from}).Validate())
+	assert.Error(t, (&EligibilityRule{MinAge: &minAge, EffectiveFrom: from, EffectiveUntil: &before}).Validate())
+}
+
+func TestStringList(t *testing.T) {
+	value, err := StringList(nil).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "[]", value)
+
+	value, err = StringList{"nurse", "doctor"}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `["nurse","doctor"]`, value)
+
+	var list StringList
+	require.NoError(t, list.Scan([]byte(`["nurse"]`)))
+	assert.Equal(t, StringList{"nurse"}, list)
+	require.NoError(t, list.Scan(nil))
+	assert.Nil(t, list)
+	assert.Error(t, list.Scan(1))
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type SortDirection string
+
+var (
+	Ascending  SortDirection = "asc"
+	Descending SortDirection = "desc"
+)
+
+// PageRequest asks for a page of a list sorted by one of its fields, the empty
+// sort and direction are the defaults of the list and a zero limit asks for
+// the whole list. The cursor resumes the list after the last item of the
+// previous page.
+type PageRequest struct {
+	Limit     int
+	Sort      string
+	Direction SortDirection
+	Cursor    *Cursor
+}
+
+// Validate checks the limit and direction of the request
+func (r *PageRequest) Validate() error {
+	if r.Limit < 0 {
+		return fmt.Errorf("invalid limit %d", r.Limit)
+	}
+	if r.Direction != "" && r.Direction != Ascending && r.Direction != Descending {
+		return fmt.Errorf("invalid direction %q, expected asc or desc", r.Direction)
+	}
+	return nil
+}
+
+// Cursor is the position of an item in a sorted list, by the value of its
+// sort field then its id. It is only valid for the sort it was made for.
+type Cursor struct {
+	Sort      string        `json:"sort"`
+	Direction SortDirection `json:"direction"`
+	Value     string        `json:"value"`
+	ID        uuid.UUID     `json:"id"`
+}
+
+// Page describes a page of a list, the next cursor is nil on the last page
+// and the total counts the items of every page
+type Page struct {
+	NextCursor *Cursor
+	Total      int64
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,7 +12,129 @@ type Patient struct {
 	Email               string                `json:"email" binding:"required"`
 	FirstName           string                `json:"first_name" binding:"required"`
 	LastName            string                `json:"last_name" binding:"required"`
-	AppointmentBookings []*AppointmentBooking `json:"_" binding:"-"`
+	Phone               string                `json:"phone,omitempty"`
+	BirthDate           *time.Time            `json:"birth_date,omitempty"`
+	NationalHealthID    *string               `json:"national_health_id,omitempty"`
+	RiskCategories      StringList            `json:"risk_categories,omitempty"`
+	Profession          string                `json:"profession,omitempty"`
+	AppointmentBookings []*AppointmentBooking `json:"-" binding:"-"`
+	Doses               []*Dose               `json:"-" binding:"-"`
 	CreatedAt           *time.Time            `json:"created_at"`
 	UpdatedAt           *time.Time            `json:"updated_at"`
 }
+
+// PatientBooking is a booking of a patient along with its appointment
+// start time and treatment center
+type PatientBooking struct {
+	AppointmentBooking
+	StartTime              time.Time `json:"start_time"`
+	TreatmentCenterID      uuid.UUID `json:"treatment_center_id"`
+	TreatmentCenterName    string    `json:"treatment_center_name"`
+	TreatmentCenterAddress string    `json:"treatment_center_address"`
+}
+
+// The reasons a patient may be a duplicate of another one
+const (
+	DuplicateNationalHealthID = "national_health_id"
+	DuplicateNameAndBirthDate = "name_and_birth_date"
+	DuplicateEmail            = "similar_email"
+)
+
+// maxEmailDistance is the number of characters the local parts of two emails of
+// the same person may differ by, a typo or a swap of two letters
+const maxEmailDistance = 2
+
+// DuplicateCandidate is a patient that may be the same person as another one,
+// along with the reasons it was matched
+type DuplicateCandidate struct {
+	*Patient
+	Reasons []string `json:"reasons"`
+}
+
+// DuplicateReasons returns why other may be the same person as the patient: the
+// same national health identifier, the same name and birth date or a similar
+// email. It returns nothing when they don't look alike.
+func (p *Patient) DuplicateReasons(other *Patient) (reasons []string) {
+	if p.NationalHealthID != nil && other.NationalHealthID != nil &&
+		*p.NationalHealthID == *other.NationalHealthID {
+		reasons = append(reasons, DuplicateNationalHealthID)
+	}
+	if p.BirthDate != nil && other.BirthDate != nil && sameDate(*p.BirthDate, *other.BirthDate) &&
+		strings.EqualFold(strings.TrimSpace(p.FirstName), strings.TrimSpace(other.FirstName)) &&
+		strings.EqualFold(strings.TrimSpace(p.LastName), strings.TrimSpace(other.LastName)) {
+		reasons = append(reasons, DuplicateNameAndBirthDate)
+	}
+	if similarEmails(p.Email, other.Email) {
+		reasons = append(reasons, DuplicateEmail)
+	}
+	return reasons
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// similarEmails reports whether two emails of the same domain only differ by
+// their case or a few characters, the dots and +tags of their local part ignored.
+// The same local part on other domains is too common a name to match.
+func similarEmails(a, b string) bool {
+	a, b = CanonicalEmail(a), CanonicalEmail(b)
+	if a == "" || b == "" {
+		return false
+	}
+	atA, atB := strings.LastIndex(a, "@"), strings.LastIndex(b, "@")
+	if a[atA:] != b[atB:] {
+		return false
+	}
+	return distance(a[:atA], b[:atB]) <= maxEmailDistance
+}
+
+// CanonicalEmail lowers an email and drops the dots and +tag of its local part,
+// it is empty when the email has no local part
+func CanonicalEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return ""
+	}
+	local, domain := email[:at], email[at:]
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+	return strings.ReplaceAll(local, ".", "") + domain
+}
+
+// distance is the Levenshtein distance between two strings, the number of
+// characters to insert, delete or replace to turn one into the other
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatientDuplicateReasons(t *testing.T) {
+	birthDate := time.Date(1980, 5, 17, 0, 0, 0, 0, time.UTC)
+	otherBirthDate := time.Date(1981, 5, 17, 0, 0, 0, 0, time.UTC)
+	nhi := "180057512345678"
+	otherNHI := "280057512345678"
+	patient := &Patient{
+		Email:            "jane.doe@some.com",
+		FirstName:        "Jane",
+		LastName:         "Doe",
+		BirthDate:        &birthDate,
+		NationalHealthID: &nhi,
+	}
+
+	tests := []struct {
+		name  string
+		other *Patient
+		want  []string
+	}{
+		{name: "SameNationalHealthID",
+			other: &Patient{Email: "someone@other.com", FirstName: "J", LastName: "D", NationalHealthID: &nhi},
+			want:  []string{DuplicateNationalHealthID}},
+		{name: "SameNameAndBirthDate",
+			other: &Patient{Email: "someone@other.com", FirstName: "jane ", LastName: "DOE", BirthDate: &birthDate},
+			want:  []string{DuplicateNameAndBirthDate}},
+		{name: "OtherBirthDate",
+			other: &Patient{Email: "someone@other.com", FirstName: "Jane", LastName: "Doe", BirthDate: &otherBirthDate}},
+		{name: "NoBirthDate",
+			other: &Patient{Email: "someone@other.com", FirstName: "Jane", LastName: "Doe"}},
+		{name: "EmailCase",
+			other: &Patient{Email: "Jane.Doe@Some.com", FirstName: "Jane", LastName: "Smith"},
+			want:  []string{DuplicateEmail}},
+		{name: "EmailTypo",
+			other: &Patient{Email: "jane.deo@some.com", FirstName: "Jane", LastName: "Smith"},
+			want:  []string{DuplicateEmail}},
+		{name: "EmailTag",
+			other: &Patient{Email: "janedoe+vax@some.com", FirstName: "Jane", LastName: "Smith"},
+			want:  []string{DuplicateEmail}},
+		{name: "EmailOtherDomain",
+			other: &Patient{Email: "janedoe@other.org", FirstName: "Jane", LastName: "Smith"}},
+		{name: "OtherEmail",
+			other: &Patient{Email: "john.smith@some.com", FirstName: "John", LastName: "Smith"}},
+		{name: "Everything",
+			other: &Patient{Email: "jane.doe@some.fr", FirstName: "Jane", LastName: "Doe", BirthDate: &birthDate,
+				NationalHealthID: &nhi},
+			want: []string{DuplicateNationalHealthID, DuplicateNameAndBirthDate}},
+		{name: "OtherNationalHealthID",
+			other: &Patient{Email: "someone@other.com", FirstName: "Jane", LastName: "Doe",
+				NationalHealthID: &otherNHI}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, patient.DuplicateReasons(tc.other))
+		})
+	}
+}
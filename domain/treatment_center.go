@@ -1,17 +1,171 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type TreatmentCenter struct {
-	ID           uuid.UUID      `json:"id" gorm:"primary_key"`
-	Name         string         `json:"name" binding:"required"`
-	Address      string         `json:"address" binding:"required"`
-	Phone        string         `json:"phone" binding:"required"`
-	Appointments []*Appointment `json:"-" binding:"-"`
-	CreatedAt    *time.Time     `json:"created_at"`
-	UpdatedAt    *time.Time     `json:"updated_at"`
+	ID           uuid.UUID       `json:"id" gorm:"primary_key"`
+	Name         string          `json:"name" binding:"required"`
+	Address      string          `json:"address" binding:"required"`
+	Phone        string          `json:"phone" binding:"required"`
+	Latitude     *float64        `json:"latitude,omitempty" binding:"omitempty,min=-90,max=90"`
+	Longitude    *float64        `json:"longitude,omitempty" binding:"omitempty,min=-180,max=180"`
+	Timezone     string          `json:"timezone"`
+	SlotDuration int             `json:"slot_duration" binding:"omitempty,min=1"`
+	SlotCapacity int             `json:"slot_capacity" binding:"omitempty,min=1"`
+	OpeningHours []*OpeningHours `json:"opening_hours,omitempty" binding:"dive"`
+	Closures     []*Closure      `json:"closures,omitempty" binding:"dive"`
+	Appointments []*Appointment  `json:"-" binding:"-"`
+	CreatedAt    *time.Time      `json:"created_at"`
+	UpdatedAt    *time.Time      `json:"updated_at"`
+	DeletedAt    *time.Time      `json:"-" binding:"-"`
+}
+
+// Location returns the time zone the opening hours are expressed in
+func (t *TreatmentCenter) Location() (*time.Location, error) {
+	if t.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(t.Timezone)
+}
+
+// Fits reports whether a slot starting at start fits in the opening hours of
+// the treatment center, out of its closures. A center without opening hours
+// takes appointments at any time.
+func (t *TreatmentCenter) Fits(start time.Time) (bool, error) {
+	if len(t.OpeningHours) == 0 {
+		return true, nil
+	}
+	loc, err := t.Location()
+	if err != nil {
+		return false, err
+	}
+	local := start.In(loc)
+	for _, closure := range t.Closures {
+		if closure.Date.Format("2006-01-02") == local.Format("2006-01-02") {
+			return false, nil
+		}
+	}
+	begins := TimeOfDay(local)
+	ends := begins + time.Duration(t.SlotDuration)*time.Minute
+	for _, hours := range t.OpeningHours {
+		if hours.Weekday != local.Weekday() {
+			continue
+		}
+		opens, err := hours.OpensAt.Duration()
+		if err != nil {
+			return false, err
+		}
+		closes, err := hours.ClosesAt.Duration()
+		if err != nil {
+			return false, err
+		}
+		if opens <= begins && ends <= closes {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ValidateCoordinates checks the treatment center is located by both its
+// latitude and longitude or by neither
+func (t *TreatmentCenter) ValidateCoordinates() error {
+	if (t.Latitude == nil) != (t.Longitude == nil) {
+		return errors.New("latitude and longitude must be set together")
+	}
+	return nil
+}
+
+// NearbySearch looks for the treatment centers within a radius around a point,
+// AvailableUntil keeps those with an available appointment before it
+type NearbySearch struct {
+	Latitude       float64
+	Longitude      float64
+	RadiusKm       float64
+	AvailableUntil *time.Time
+}
+
+// Validate checks the point and the radius of the search
+func (s *NearbySearch) Validate() error {
+	if s.Latitude < -90 || s.Latitude > 90 || s.Longitude < -180 || s.Longitude > 180 {
+		return fmt.Errorf("invalid point %g,%g", s.Latitude, s.Longitude)
+	}
+	if s.RadiusKm <= 0 {
+		return errors.New("radius must be positive")
+	}
+	return nil
+}
+
+// NearbyTreatmentCenter is a treatment center found by a nearby search with
+// its distance to the point and its next available appointment, if any
+type NearbyTreatmentCenter struct {
+	TreatmentCenter
+	DistanceKm        float64    `json:"distance_km"`
+	NextAppointmentID *uuid.UUID `json:"next_appointment_id,omitempty"`
+	NextAppointmentAt *time.Time `json:"next_appointment_at,omitempty"`
+}
+
+// OpeningHours are the hours a treatment center is open on a day of the week
+type OpeningHours struct {
+	ID                uuid.UUID    `json:"-" gorm:"primary_key"`
+	TreatmentCenterID uuid.UUID    `json:"-"`
+	Weekday           time.Weekday `json:"weekday" binding:"min=0,max=6"`
+	OpensAt           Clock        `json:"opens_at" binding:"required"`
+	ClosesAt          Clock        `json:"closes_at" binding:"required"`
+}
+
+func (OpeningHours) TableName() string {
+	return "treatment_center_opening_hours"
+}
+
+// Validate checks the hours are well formed and the center closes after it opens
+func (o *OpeningHours) Validate() error {
+	opens, err := o.OpensAt.Duration()
+	if err != nil {
+		return err
+	}
+	closes, err := o.ClosesAt.Duration()
+	if err != nil {
+		return err
+	}
+	if closes <= opens {
+		return fmt.Errorf("closes_at %s must be after opens_at %s", o.ClosesAt, o.OpensAt)
+	}
+	return nil
+}
+
+// Closure is a day a treatment center is closed regardless of its opening hours
+type Closure struct {
+	ID                uuid.UUID `json:"-" gorm:"primary_key"`
+	TreatmentCenterID uuid.UUID `json:"-"`
+	Date              time.Time `json:"date" binding:"required"`
+	Reason            string    `json:"reason"`
+}
+
+func (Closure) TableName() string {
+	return "treatment_center_closures"
+}
+
+// Clock is a wall clock time formatted as 15:04
+type Clock string
+
+// TimeOfDay returns the wall clock of t as a duration, unlike the time elapsed
+// since midnight it is not an hour off on the days the clocks change
+func TimeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+}
+
+// Duration returns the time elapsed since midnight
+func (c Clock) Duration() (time.Duration, error) {
+	t, err := time.Parse("15:04", string(c))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", c)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
 }
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreatmentCenterFits(t *testing.T) {
+	center := &TreatmentCenter{
+		Timezone:     "Europe/Paris",
+		SlotDuration: 30,
+		OpeningHours: []*OpeningHours{
+			{Weekday: time.Monday, OpensAt: "08:00", ClosesAt: "12:00"},
+			{Weekday: time.Monday, OpensAt: "14:00", ClosesAt: "18:00"},
+		},
+		Closures: []*Closure{{Date: time.Date(2021, 12, 27, 0, 0, 0, 0, time.UTC)}},
+	}
+	sunday := &TreatmentCenter{
+		Timezone:     "Europe/Paris",
+		SlotDuration: 30,
+		OpeningHours: []*OpeningHours{{Weekday: time.Sunday, OpensAt: "08:00", ClosesAt: "12:00"}},
+	}
+
+	tests := []struct {
+		name   string
+		center *TreatmentCenter
+		start  time.Time
+		want   bool
+	}{
+		// Paris is UTC+1 in winter
+		{name: "Opening", center: center, start: time.Date(2021, 12, 6, 7, 0, 0, 0, time.UTC), want: true},
+		{name: "LastSlot", center: center, start: time.Date(2021, 12, 6, 10, 30, 0, 0, time.UTC), want: true},
+		{name: "Afternoon", center: center, start: time.Date(2021, 12, 6, 13, 0, 0, 0, time.UTC), want: true},
+		{name: "BeforeOpening", center: center, start: time.Date(2021, 12, 6, 6, 45, 0, 0, time.UTC)},
+		{name: "EndsAfterClosing", center: center, start: time.Date(2021, 12, 6, 10, 45, 0, 0, time.UTC)},
+		{name: "Lunch", center: center, start: time.Date(2021, 12, 6, 11, 30, 0, 0, time.UTC)},
+		{name: "ClosedWeekday", center: center, start: time.Date(2021, 12, 7, 7, 0, 0, 0, time.UTC)},
+		{name: "Closure", center: center, start: time.Date(2021, 12, 27, 7, 0, 0, 0, time.UTC)},
+		// Paris is UTC+2 in summer
+		{name: "Summer", center: center, start: time.Date(2021, 6, 7, 6, 0, 0, 0, time.UTC), want: true},
+		// the clocks of Paris change on these sundays
+		{name: "SpringForwardOpening", center: sunday, start: time.Date(2021, 3, 28, 6, 0, 0, 0, time.UTC), want: true},
+		{name: "SpringForwardBeforeOpening", center: sunday, start: time.Date(2021, 3, 28, 5, 30, 0, 0, time.UTC)},
+		{name: "FallBackLastSlot", center: sunday, start: time.Date(2021, 10, 31, 10, 30, 0, 0, time.UTC), want: true},
+		{name: "FallBackEndsAfterClosing", center: sunday, start: time.Date(2021, 10, 31, 10, 45, 0, 0, time.UTC)},
+		{name: "NoOpeningHours", center: &TreatmentCenter{}, start: time.Date(2021, 12, 5, 3, 0, 0, 0, time.UTC),
+			want: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.center.Fits(tc.start)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
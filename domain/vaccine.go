@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDoseTooEarly    = errors.New("too early for the next dose of the vaccine")
+	ErrDoseTooLate     = errors.New("too late for the next dose of the vaccine")
+	ErrSeriesCompleted = errors.New("vaccination series is completed")
+)
+
+// VaccineProduct is a vaccine and its schedule: the number of doses of the
+// primary series, the interval between them and the interval before a booster
+type VaccineProduct struct {
+	ID                  uuid.UUID  `json:"id" gorm:"primary_key"`
+	Name                string     `json:"name" binding:"required"`
+	Doses               int        `json:"doses" binding:"required,min=1"`
+	MinIntervalDays     int        `json:"min_interval_days" binding:"min=0"`
+	MaxIntervalDays     *int       `json:"max_interval_days,omitempty" binding:"omitempty,min=0"`
+	BoosterIntervalDays *int       `json:"booster_interval_days,omitempty" binding:"omitempty,min=1"`
+	CreatedAt           *time.Time `json:"created_at"`
+	UpdatedAt           *time.Time `json:"updated_at"`
+}
+
+// Validate checks the parts of a product the binding can't
+func (p *VaccineProduct) Validate() error {
+	if p.MaxIntervalDays != nil && *p.MaxIntervalDays < p.MinIntervalDays {
+		return errors.New("max_interval_days must not be less than min_interval_days")
+	}
+	return nil
+}
+
+// CheckNextDose returns an error when a dose at date doesn't follow the
+// product schedule after the last dose of a patient
+func (p *VaccineProduct) CheckNextDose(last *Dose, at time.Time) error {
+	if last.Number < p.Doses {
+		if at.Before(last.AdministeredAt.AddDate(0, 0, p.MinIntervalDays)) {
+			return ErrDoseTooEarly
+		}
+		if p.MaxIntervalDays != nil && at.After(last.AdministeredAt.AddDate(0, 0, *p.MaxIntervalDays)) {
+			return ErrDoseTooLate
+		}
+		return nil
+	}
+	if p.BoosterIntervalDays == nil {
+		return ErrSeriesCompleted
+	}
+	if at.Before(last.AdministeredAt.AddDate(0, 0, *p.BoosterIntervalDays)) {
+		return ErrDoseTooEarly
+	}
+	return nil
+}
+
+// Dose is a vaccine dose administered to a patient, doses are numbered
+// from 1 in the order they are administered, boosters included
+type Dose struct {
+	ID                   uuid.UUID  `json:"id" gorm:"primary_key"`
+	PatientID            uuid.UUID  `json:"patient_id"`
+	VaccineProductID     uuid.UUID  `json:"vaccine_product_id" binding:"required"`
+	AppointmentBookingID *uuid.UUID `json:"appointment_booking_id,omitempty"`
+	Number               int        `json:"number" binding:"-"`
+	AdministeredAt       time.Time  `json:"administered_at" binding:"required"`
+	CreatedAt            *time.Time `json:"created_at"`
+	UpdatedAt            *time.Time `json:"updated_at"`
+}
+
+// VaccineLot is a delivery of doses of a vaccine product to a treatment center,
+// its quantity is the number of doses left
+type VaccineLot struct {
+	ID                uuid.UUID  `json:"id" gorm:"primary_key"`
+	TreatmentCenterID uuid.UUID  `json:"treatment_center_id"`
+	VaccineProductID  uuid.UUID  `json:"vaccine_product_id" binding:"required"`
+	LotNumber         string     `json:"lot_number" binding:"required"`
+	Quantity          int        `json:"quantity" binding:"required,min=1"`
+	ExpiresOn         time.Time  `json:"expires_on" binding:"required"`
+	ReceivedAt        *time.Time `json:"received_at"`
+	CreatedAt         *time.Time `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+}
+
+// Expired reports whether the lot can't be administered at date anymore
+func (l *VaccineLot) Expired(at time.Time) bool {
+	y, m, d := l.ExpiresOn.Date()
+	return !at.Before(time.Date(y, m, d+1, 0, 0, 0, 0, at.Location()))
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaccineProductCheckNextDose(t *testing.T) {
+	maxInterval := 42
+	booster := 180
+	twoDoses := &VaccineProduct{Name: "two doses", Doses: 2, MinIntervalDays: 21, MaxIntervalDays: &maxInterval,
+		BoosterIntervalDays: &booster}
+	singleDose := &VaccineProduct{Name: "single dose", Doses: 1}
+
+	administeredAt := time.Date(2021, 11, 1, 10, 0, 0, 0, time.UTC)
+	first := &Dose{Number: 1, AdministeredAt: administeredAt}
+	second := &Dose{Number: 2, AdministeredAt: administeredAt}
+
+	tests := []struct {
+		name    string
+		product *VaccineProduct
+		last    *Dose
+		at      time.Time
+		wantErr error
+	}{
+		{name: "SecondDose", product: twoDoses, last: first, at: administeredAt.AddDate(0, 0, 21)},
+		{name: "SecondDoseTooEarly", product: twoDoses, last: first, at: administeredAt.AddDate(0, 0, 20),
+			wantErr: ErrDoseTooEarly},
+		{name: "SecondDoseLatest", product: twoDoses, last: first, at: administeredAt.AddDate(0, 0, 42)},
+		{name: "SecondDoseTooLate", product: twoDoses, last: first, at: administeredAt.AddDate(0, 0, 43),
+			wantErr: ErrDoseTooLate},
+		{name: "Booster", product: twoDoses, last: second, at: administeredAt.AddDate(0, 0, 180)},
+		{name: "BoosterTooEarly", product: twoDoses, last: second, at: administeredAt.AddDate(0, 0, 179),
+			wantErr: ErrDoseTooEarly},
+		{name: "SeriesCompleted", product: singleDose, last: first, at: administeredAt.AddDate(1, 0, 0),
+			wantErr: ErrSeriesCompleted},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantErr, tc.product.CheckNextDose(tc.last, tc.at))
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WaitlistStatus string
+
+var (
+	Waiting   WaitlistStatus = "waiting"
+	Offered   WaitlistStatus = "offered"
+	Fulfilled WaitlistStatus = "fulfilled"
+	Lapsed    WaitlistStatus = "lapsed"
+	Withdrawn WaitlistStatus = "withdrawn"
+)
+
+// WaitlistEntry is the interest of a patient for an appointment of a treatment
+// center between two dates, when a seat frees up it is offered to the patient
+// as a booking awaiting confirmation
+type WaitlistEntry struct {
+	ID                   uuid.UUID      `json:"id" gorm:"primary_key"`
+	PatientID            uuid.UUID      `json:"patient_id"`
+	TreatmentCenterID    uuid.UUID      `json:"treatment_center_id" binding:"required"`
+	FromDate             time.Time      `json:"from_date" binding:"required"`
+	ToDate               time.Time      `json:"to_date" binding:"required"`
+	Status               WaitlistStatus `json:"status"`
+	AppointmentBookingID *uuid.UUID     `json:"appointment_booking_id,omitempty"`
+	OfferedAt            *time.Time     `json:"offered_at,omitempty"`
+	CreatedAt            *time.Time     `json:"created_at"`
+	UpdatedAt            *time.Time     `json:"updated_at"`
+}
+
+// Validate checks the dates of the entry form a range
+func (e *WaitlistEntry) Validate() error {
+	if e.ToDate.Before(e.FromDate) {
+		return errors.New("to_date must not be before from_date")
+	}
+	return nil
+}
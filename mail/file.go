@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// fileSender is a stand-in for local runs, it logs every message
+// and writes it in dir when dir isn't empty
+type fileSender struct {
+	dir    string
+	from   string
+	logger *zap.Logger
+}
+
+func NewFileSender(dir string, from string, logger *zap.Logger) Sender {
+	return fileSender{
+		dir:    dir,
+		from:   from,
+		logger: logger.With(zap.String("component", "FileSender")),
+	}
+}
+
+func (s fileSender) Send(msg Message) error {
+	s.logger.Info("mail",
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+	if s.dir == "" {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New())
+	if err := ioutil.WriteFile(filepath.Join(s.dir, name), format(s.from, msg), 0644); err != nil {
+		return fmt.Errorf("unable to write mail to %s: %w", s.dir, err)
+	}
+	return nil
+}
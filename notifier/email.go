@@ -0,0 +1,25 @@
+package notifier
+
+import (
+	"github.com/y9mo/covidvax/mail"
+)
+
+// email notifies by email through a mail sender
+type email struct {
+	sender mail.Sender
+}
+
+func NewEmail(sender mail.Sender) Notifier {
+	return email{sender: sender}
+}
+
+func (e email) Notify(notification Notification) error {
+	if notification.To.Email == "" {
+		return ErrNoAddress
+	}
+	return e.sender.Send(mail.Message{
+		To:      notification.To.Email,
+		Subject: notification.Subject,
+		Body:    notification.Body,
+	})
+}
@@ -0,0 +1,48 @@
+package notifier
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// file is a stand-in for local runs, it logs every notification
+// and writes it in dir when dir isn't empty
+type file struct {
+	dir    string
+	logger *zap.Logger
+}
+
+func NewFile(dir string, logger *zap.Logger) Notifier {
+	return file{
+		dir:    dir,
+		logger: logger.With(zap.String("component", "FileNotifier")),
+	}
+}
+
+func (f file) Notify(notification Notification) error {
+	f.logger.Info("notification",
+		zap.String("to", notification.To.Name),
+		zap.String("subject", notification.Subject),
+		zap.String("body", notification.Body),
+	)
+	if f.dir == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s <%s> %s\n", notification.To.Name, notification.To.Email, notification.To.Phone)
+	fmt.Fprintf(&b, "Subject: %s\n\n", notification.Subject)
+	b.WriteString(notification.Body)
+
+	name := fmt.Sprintf("%s-%s.txt", time.Now().UTC().Format("20060102T150405"), uuid.New())
+	if err := ioutil.WriteFile(filepath.Join(f.dir, name), []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("unable to write notification to %s: %w", f.dir, err)
+	}
+	return nil
+}
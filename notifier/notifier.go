@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// ErrNoAddress is returned by a notifier when the recipient has no address on its channel
+var ErrNoAddress = errors.New("recipient has no address on the channel")
+
+// Recipient is who a notification is sent to, each notifier
+// uses the address of its channel
+type Recipient struct {
+	Name  string
+	Email string
+	Phone string
+}
+
+// Notification is a plain text message to a patient
+type Notification struct {
+	To      Recipient
+	Subject string
+	Body    string
+}
+
+// Notifier delivers notifications on a channel
+type Notifier interface {
+	Notify(notification Notification) error
+}
+
+// multi notifies on several channels
+type multi struct {
+	notifiers []Notifier
+	logger    *zap.Logger
+}
+
+// NewMulti returns a notifier delivering on every channel, a notification
+// is delivered when one of the channels succeeds so it isn't sent again
+// on the channels which already delivered it
+func NewMulti(logger *zap.Logger, notifiers ...Notifier) Notifier {
+	return multi{
+		notifiers: notifiers,
+		logger:    logger.With(zap.String("component", "MultiNotifier")),
+	}
+}
+
+func (m multi) Notify(notification Notification) error {
+	var last error
+	delivered := false
+	for _, n := range m.notifiers {
+		err := n.Notify(notification)
+		if err == nil {
+			delivered = true
+			continue
+		}
+		if err != ErrNoAddress {
+			m.logger.Warn("unable to notify", zap.String("subject", notification.Subject), zap.Error(err))
+		}
+		last = err
+	}
+	if delivered {
+		return nil
+	}
+	if last == nil {
+		return ErrNoAddress
+	}
+	return last
+}
@@ -0,0 +1,74 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var notification = Notification{
+	To:      Recipient{Name: "Jane Doe", Email: "jane@example.com", Phone: "+33601020304"},
+	Subject: "Reminder",
+	Body:    "Your appointment is tomorrow",
+}
+
+func TestSMS(t *testing.T) {
+	var got smsMessage
+	var authorization string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.To == "+33600000000" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer gateway.Close()
+
+	n := NewSMS(SMSConfig{URL: gateway.URL, Token: "secret", From: "covidvax"})
+	require.NoError(t, n.Notify(notification))
+	assert.Equal(t, smsMessage{From: "covidvax", To: "+33601020304", Text: "Your appointment is tomorrow"}, got)
+	assert.Equal(t, "Bearer secret", authorization)
+
+	unreachable := notification
+	unreachable.To.Phone = "+33600000000"
+	assert.Error(t, n.Notify(unreachable))
+
+	noPhone := notification
+	noPhone.To.Phone = ""
+	assert.Equal(t, ErrNoAddress, n.Notify(noPhone))
+}
+
+type notifierFunc func(notification Notification) error
+
+func (f notifierFunc) Notify(notification Notification) error {
+	return f(notification)
+}
+
+func TestMulti(t *testing.T) {
+	failing := notifierFunc(func(Notification) error { return errors.New("unavailable") })
+	succeeding := notifierFunc(func(Notification) error { return nil })
+	noAddress := notifierFunc(func(Notification) error { return ErrNoAddress })
+
+	tests := []struct {
+		name      string
+		notifiers []Notifier
+		wantErr   bool
+	}{
+		{name: "OneDelivered", notifiers: []Notifier{failing, succeeding}},
+		{name: "NoneDelivered", notifiers: []Notifier{noAddress, failing}, wantErr: true},
+		{name: "NoAddress", notifiers: []Notifier{noAddress}, wantErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewMulti(zap.NewNop(), tc.notifiers...).Notify(notification)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
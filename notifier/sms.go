@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type SMSConfig struct {
+	// URL the messages are posted to
+	URL string
+	// Token is sent as a bearer token when not empty
+	Token string
+	// From is the sender name or number
+	From string
+}
+
+// sms notifies by text message through an http gateway accepting
+// a json message {"from", "to", "text"}
+type sms struct {
+	config SMSConfig
+	client *http.Client
+}
+
+func NewSMS(config SMSConfig) Notifier {
+	return sms{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type smsMessage struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+func (s sms) Notify(notification Notification) error {
+	if notification.To.Phone == "" {
+		return ErrNoAddress
+	}
+	body, err := json.Marshal(smsMessage{
+		From: s.config.From,
+		To:   notification.To.Phone,
+		Text: notification.Body,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send sms to %s: %w", notification.To.Phone, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unable to send sms to %s: gateway answered %s", notification.To.Phone, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package reaper
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+	"github.com/y9mo/covidvax/waitlist"
+	"go.uber.org/zap"
+)
+
+// Reaper periodically releases the bookings which weren't confirmed in time
+// so their appointments become available again and offers them to the waitlist
+type Reaper struct {
+	unitOfWork repository.UnitOfWork
+	offers     *waitlist.Service
+	interval   time.Duration
+	logger     *zap.Logger
+}
+
+func New(unitOfWork repository.UnitOfWork,
+	offers *waitlist.Service,
+	interval time.Duration,
+	logger *zap.Logger) *Reaper {
+	return &Reaper{
+		unitOfWork: unitOfWork,
+		offers:     offers,
+		interval:   interval,
+		logger:     logger.With(zap.String("component", "Reaper")),
+	}
+}
+
+// Run reaps every interval until ctx is done
+func (r *Reaper) Run(ctx context.Context) {
+	r.logger.Info("starting", zap.Duration("interval", r.interval))
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("stopping")
+			return
+		case <-ticker.C:
+			if _, err := r.Reap(ctx); err != nil {
+				r.logger.Error("unable to expire bookings", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Reap expires the bookings awaiting confirmation past their deadline
+// and offers their seats to the next waitlisted patients, the seats are
+// held for them along with the expiry so none is freed without its offer
+func (r *Reaper) Reap(ctx context.Context) ([]*domain.AppointmentBooking, error) {
+	var expired, offered []*domain.AppointmentBooking
+	err := r.unitOfWork.Do(ctx, func(repositories repository.Repositories) (err error) {
+		expired, err = repositories.AppointmentBookings().ExpireUnconfirmed(ctx, time.Now().UTC(),
+			domain.ReleaseReasonUnconfirmed)
+		if err != nil {
+			return err
+		}
+		freed := make([]uuid.UUID, 0, len(expired))
+		for _, booking := range expired {
+			freed = append(freed, booking.AppointmentID)
+		}
+		offered = r.offers.In(repositories).Hold(ctx, freed...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, booking := range expired {
+		r.logger.Info("booking expired",
+			zap.Stringer("booking_id", booking.ID),
+			zap.Stringer("appointment_id", booking.AppointmentID))
+	}
+	r.offers.Notify(ctx, offered)
+	return expired, nil
+}
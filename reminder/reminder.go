@@ -0,0 +1,123 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/notifier"
+	"github.com/y9mo/covidvax/repository"
+	"go.uber.org/zap"
+)
+
+// Scheduler reminds the patients of their confirmed bookings ahead of their
+// appointments, once per lead time. A booking confirmed after a lead time
+// passed only gets the reminders of the shorter lead times.
+type Scheduler struct {
+	remindersRepository repository.Reminders
+	patientsRepository  repository.Patients
+	notifier            notifier.Notifier
+	leadTimes           []time.Duration
+	interval            time.Duration
+	logger              *zap.Logger
+}
+
+func New(remindersRepository repository.Reminders,
+	patientsRepository repository.Patients,
+	notifier notifier.Notifier,
+	leadTimes []time.Duration,
+	interval time.Duration,
+	logger *zap.Logger) *Scheduler {
+	sorted := append([]time.Duration(nil), leadTimes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &Scheduler{
+		remindersRepository: remindersRepository,
+		patientsRepository:  patientsRepository,
+		notifier:            notifier,
+		leadTimes:           sorted,
+		interval:            interval,
+		logger:              logger.With(zap.String("component", "ReminderScheduler")),
+	}
+}
+
+// Run reminds every interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	s.logger.Info("starting", zap.Duration("interval", s.interval), zap.Durations("lead_times", s.leadTimes))
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("stopping")
+			return
+		case <-ticker.C:
+			if _, err := s.Remind(ctx, time.Now().UTC()); err != nil {
+				s.logger.Error("unable to send reminders", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Remind sends the reminders due at now and returns how many were sent, the
+// shortest lead times go first so a booking due for several gets a single reminder
+func (s *Scheduler) Remind(ctx context.Context, now time.Time) (int, error) {
+	sent := 0
+	for _, leadTime := range s.leadTimes {
+		bookings, err := s.remindersRepository.Due(ctx, now, leadTime)
+		if err != nil {
+			return sent, err
+		}
+		for _, booking := range bookings {
+			reminder := &domain.BookingReminder{
+				AppointmentBookingID: booking.ID,
+				LeadMinutes:          int(leadTime.Minutes()),
+				SentAt:               now,
+			}
+			// the reminder is recorded first so it is never sent twice
+			claimed, err := s.remindersRepository.Claim(ctx, reminder)
+			if err != nil {
+				return sent, err
+			}
+			if !claimed {
+				continue
+			}
+			if err = s.send(ctx, booking); err != nil {
+				s.logger.Error("unable to send reminder", zap.Stringer("booking_id", booking.ID), zap.Error(err))
+				if err = s.remindersRepository.Release(ctx, reminder); err != nil {
+					return sent, err
+				}
+				continue
+			}
+			s.logger.Info("reminder sent",
+				zap.Stringer("booking_id", booking.ID),
+				zap.Duration("lead_time", leadTime))
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (s *Scheduler) send(ctx context.Context, booking *domain.PatientBooking) error {
+	patient, err := s.patientsRepository.FindByID(ctx, booking.PatientID)
+	if err != nil {
+		return fmt.Errorf("unable to find patient %s: %w", booking.PatientID, err)
+	}
+	body := fmt.Sprintf(`Hello %s %s,
+
+Your vaccination appointment is on %s at %s, %s.
+If you can't come, please cancel it so the dose goes to another patient.
+`, patient.FirstName, patient.LastName, booking.StartTime.UTC().Format(time.RFC1123),
+		booking.TreatmentCenterName, booking.TreatmentCenterAddress)
+
+	return s.notifier.Notify(notifier.Notification{
+		To: notifier.Recipient{
+			Name:  patient.FirstName + " " + patient.LastName,
+			Email: patient.Email,
+			Phone: patient.Phone,
+		},
+		Subject: "Reminder of your vaccination appointment",
+		Body:    body,
+	})
+}
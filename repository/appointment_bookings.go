@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/y9mo/covidvax/domain"
@@ -8,10 +11,18 @@ import (
 )
 
 type AppointmentBookings interface {
-	Create(appointmentBooking *domain.AppointmentBooking) error
-	Update(appointmentBooking *domain.AppointmentBooking) error
-	Delete(appointmentBooking *domain.AppointmentBooking) error
-	FindByID(id uuid.UUID) (*domain.AppointmentBooking, error)
+	Create(ctx context.Context, appointmentBooking *domain.AppointmentBooking) error
+	Update(ctx context.Context, appointmentBooking *domain.AppointmentBooking) error
+	Delete(ctx context.Context, appointmentBooking *domain.AppointmentBooking) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.AppointmentBooking, error)
+	AllByPatientID(ctx context.Context, patientID uuid.UUID,
+		request domain.PageRequest) ([]*domain.PatientBooking, domain.Page, error)
+	Confirm(ctx context.Context, id uuid.UUID) error
+	ExpireUnconfirmed(ctx context.Context, now time.Time, reason string) ([]*domain.AppointmentBooking, error)
+	Cancel(ctx context.Context, id uuid.UUID, reason string) error
+	Reschedule(ctx context.Context, id uuid.UUID, appointmentID uuid.UUID) (*domain.AppointmentBooking, error)
+	RecordOutcome(ctx context.Context, id uuid.UUID, outcome *domain.BookingOutcome, at time.Time) error
+	BookedAt(ctx context.Context, patientID uuid.UUID, treatmentCenterID uuid.UUID) (bool, error)
 }
 
 type appointmentBookings struct {
@@ -23,24 +34,71 @@ func NewAppointmentBookings(db *gorm.DB, logger *zap.Logger) AppointmentBookings
 	return appointmentBookings{db: db, logger: logger}
 }
 
-func (r appointmentBookings) Create(appointmentBooking *domain.AppointmentBooking) error {
-	err := r.db.Debug().Create(appointmentBooking).Error
+// Create books a seat of an appointment, it fails with domain.ErrAppointmentStarted
+// when the appointment has already started, with ErrAppointmentNotAvailable
+// when every seat of the appointment is already held, with one of the domain
+// dose errors when the appointment doesn't fit the patient vaccine schedule and
+// with a domain.IneligibleError when the patient doesn't meet a rule in effect
+func (r appointmentBookings) Create(ctx context.Context, appointmentBooking *domain.AppointmentBooking) error {
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		return book(tx, appointmentBooking)
+	})
 	return handleGormError(err, r.logger)
 }
 
-func (r appointmentBookings) Update(appointmentBooking *domain.AppointmentBooking) error {
-	err := r.db.Debug().Update(appointmentBooking).Error
+// book inserts a booking if its appointment is still to start and has a
+// remaining seat, its date follows the vaccine schedule of the patient and the
+// patient meets the eligibility rules in effect, tx must be a transaction
+func book(tx *gorm.DB, appointmentBooking *domain.AppointmentBooking) error {
+	// concurrent bookings of the same appointment wait for each other
+	appointment := domain.Appointment{}
+	err := forUpdate(tx, "FOR UPDATE").
+		Where("id = ?", appointmentBooking.AppointmentID).First(&appointment).Error
+	if err != nil {
+		return err
+	}
+	if !appointment.StartTime.After(time.Now()) {
+		return domain.ErrAppointmentStarted
+	}
+
+	var holders []uuid.UUID
+	err = tx.Model(&domain.AppointmentBooking{}).
+		Where("appointment_id = ? AND status IN (?)", appointmentBooking.AppointmentID, domain.HoldingStatuses).
+		Pluck("patient_id", &holders).Error
+	if err != nil {
+		return err
+	}
+	for _, holder := range holders {
+		if holder == appointmentBooking.PatientID {
+			return ErrUniqueConstraintFailure
+		}
+	}
+	if len(holders) >= appointment.Capacity {
+		return ErrAppointmentNotAvailable
+	}
+	if err = checkDoseSchedule(tx, appointmentBooking.PatientID, appointment.StartTime); err != nil {
+		return err
+	}
+	if err = checkEligibility(tx, appointmentBooking.PatientID, appointment.StartTime); err != nil {
+		return err
+	}
+
+	return tx.Create(appointmentBooking).Error
+}
+
+func (r appointmentBookings) Update(ctx context.Context, appointmentBooking *domain.AppointmentBooking) error {
+	err := withContext(ctx, r.db).Debug().Update(appointmentBooking).Error
 	return handleGormError(err, r.logger)
 }
 
-func (r appointmentBookings) Delete(appointmentBooking *domain.AppointmentBooking) error {
-	err := r.db.Debug().Delete(appointmentBooking).Error
+func (r appointmentBookings) Delete(ctx context.Context, appointmentBooking *domain.AppointmentBooking) error {
+	err := withContext(ctx, r.db).Debug().Delete(appointmentBooking).Error
 	return handleGormError(err, r.logger)
 }
 
-func (r appointmentBookings) FindByID(id uuid.UUID) (*domain.AppointmentBooking, error) {
+func (r appointmentBookings) FindByID(ctx context.Context, id uuid.UUID) (*domain.AppointmentBooking, error) {
 	appointmentBooking := domain.AppointmentBooking{}
-	err := r.db.Debug().Where("id = ?", id).Find(&appointmentBooking).Error
+	err := withContext(ctx, r.db).Debug().Where("id = ?", id).Find(&appointmentBooking).Error
 	err = handleGormError(err, r.logger)
 	if err != nil {
 		return nil, err
@@ -48,11 +106,237 @@ func (r appointmentBookings) FindByID(id uuid.UUID) (*domain.AppointmentBooking,
 	return &appointmentBooking, nil
 }
 
-func (r appointmentBookings) All() (result []*domain.AppointmentBooking, err error) {
-	err = r.db.Debug().Find(&result).Error
+// Confirm moves a booking awaiting confirmation to confirmed,
+// any other status will return ErrInvalidStatusTransition.
+// Confirming a booking offered from the waitlist fulfills the waitlist entry.
+func (r appointmentBookings) Confirm(ctx context.Context, id uuid.UUID) error {
+	var confirmed int64
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		result := tx.Model(&domain.AppointmentBooking{}).
+			Where("id = ? AND status = ?", id, domain.AwaitingConfirmation).
+			Update("status", domain.Confirmed)
+		if result.Error != nil {
+			return result.Error
+		}
+		confirmed = result.RowsAffected
+		return settleOffers(tx, []uuid.UUID{id}, domain.Fulfilled)
+	})
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return err
+	}
+	if confirmed == 0 {
+		if _, err = r.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrInvalidStatusTransition
+	}
+	return nil
+}
+
+// ExpireUnconfirmed releases the bookings still awaiting confirmation
+// after their expiration date and returns them, the waitlist offers among
+// them lapse
+func (r appointmentBookings) ExpireUnconfirmed(ctx context.Context, now time.Time,
+	reason string) (result []*domain.AppointmentBooking, err error) {
+	err = transaction(ctx, r.db, func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		err := forUpdate(tx, "FOR UPDATE").Model(&domain.AppointmentBooking{}).
+			Where("status = ? AND expires_at < ?", domain.AwaitingConfirmation, now).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		err = tx.Model(&domain.AppointmentBooking{}).Where("id IN (?)", ids).UpdateColumns(map[string]interface{}{
+			"status":         domain.Expired,
+			"released_at":    now,
+			"release_reason": reason,
+			"updated_at":     now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err = tx.Where("id IN (?)", ids).Order("expires_at, id").Find(&result).Error; err != nil {
+			return err
+		}
+		return settleOffers(tx, ids, domain.Lapsed)
+	})
 	err = handleGormError(err, r.logger)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
+
+// Cancel releases an active booking, the booking is kept with its reason
+func (r appointmentBookings) Cancel(ctx context.Context, id uuid.UUID, reason string) error {
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		return release(tx, id, domain.Cancelled, reason)
+	})
+	return handleGormError(err, r.logger)
+}
+
+// Reschedule atomically cancels an active booking and books the same patient
+// on another appointment, the new booking is returned
+func (r appointmentBookings) Reschedule(ctx context.Context, id uuid.UUID,
+	appointmentID uuid.UUID) (*domain.AppointmentBooking, error) {
+	var rescheduled *domain.AppointmentBooking
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		current := domain.AppointmentBooking{}
+		err := forUpdate(tx, "FOR UPDATE").Where("id = ?", id).First(&current).Error
+		if err != nil {
+			return err
+		}
+		if !current.Status.Active() {
+			return ErrInvalidStatusTransition
+		}
+		if current.AppointmentID == appointmentID {
+			rescheduled = &current
+			return nil
+		}
+
+		if err = release(tx, id, domain.Cancelled, domain.ReleaseReasonRescheduled); err != nil {
+			return err
+		}
+		rescheduled = &domain.AppointmentBooking{
+			ID:            uuid.New(),
+			AppointmentID: appointmentID,
+			PatientID:     current.PatientID,
+			Status:        current.Status,
+			ExpiresAt:     current.ExpiresAt,
+		}
+		return book(tx, rescheduled)
+	})
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(ctx, rescheduled.ID)
+}
+
+// RecordOutcome records what happened at the appointment of a confirmed booking,
+// an administration also records the dose received by the patient and takes it
+// from the lot of the treatment center
+func (r appointmentBookings) RecordOutcome(ctx context.Context, id uuid.UUID, outcome *domain.BookingOutcome,
+	at time.Time) error {
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		current := domain.AppointmentBooking{}
+		err := forUpdate(tx, "FOR UPDATE").Where("id = ?", id).First(&current).Error
+		if err != nil {
+			return err
+		}
+		if current.Status != domain.Confirmed {
+			return ErrInvalidStatusTransition
+		}
+
+		err = tx.Model(&current).Updates(map[string]interface{}{
+			"status":              outcome.Status,
+			"lot_number":          outcome.LotNumber,
+			"administered_by":     outcome.AdministeredBy,
+			"outcome_recorded_at": at,
+		}).Error
+		if err != nil || outcome.Status != domain.Administered {
+			return err
+		}
+		err = useDose(tx, current.AppointmentID, *outcome.VaccineProductID, outcome.LotNumber, at)
+		if err != nil {
+			return err
+		}
+		return administer(tx, &domain.Dose{
+			ID:                   uuid.New(),
+			PatientID:            current.PatientID,
+			VaccineProductID:     *outcome.VaccineProductID,
+			AppointmentBookingID: &current.ID,
+			AdministeredAt:       at,
+		})
+	})
+	return handleGormError(err, r.logger)
+}
+
+// release moves an active booking to a released status, a waitlist
+// offer of the booking lapses
+func release(tx *gorm.DB, id uuid.UUID, status domain.AppointmentStatus, reason string) error {
+	now := time.Now().UTC()
+	result := tx.Model(&domain.AppointmentBooking{}).
+		Where("id = ? AND status IN (?)", id, domain.ActiveStatuses).
+		Updates(map[string]interface{}{
+			"status":         status,
+			"released_at":    now,
+			"release_reason": reason,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int
+		if err := tx.Model(&domain.AppointmentBooking{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrRecordNotFound
+		}
+		return ErrInvalidStatusTransition
+	}
+	return settleOffers(tx, []uuid.UUID{id}, domain.Lapsed)
+}
+
+// patientBookingsSorting sorts the bookings of a patient by appointment by
+// default, the most recent first
+var patientBookingsSorting = sorting{
+	fields: map[string]sortField{
+		"start_time": {column: "a.start_time"},
+		"created_at": {column: "appointment_bookings.created_at"},
+	},
+	idColumn:         "appointment_bookings.id",
+	defaultSort:      "start_time",
+	defaultDirection: domain.Descending,
+}
+
+// AllByPatientID returns the bookings of a patient
+func (r appointmentBookings) AllByPatientID(ctx context.Context, patientID uuid.UUID,
+	request domain.PageRequest) (result []*domain.PatientBooking, page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	query := patientBookings(db).Where("appointment_bookings.patient_id = ?", patientID)
+	page, err = paginate(db, query, request, patientBookingsSorting, &result)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// patientBookings selects the bookings along with their appointment
+// start time and treatment center, a is the appointment
+func patientBookings(db *gorm.DB) *gorm.DB {
+	return db.Table("appointment_bookings").
+		Select("appointment_bookings.*, a.start_time, a.treatment_center_id, " +
+			"tc.name AS treatment_center_name, tc.address AS treatment_center_address").
+		Joins("JOIN appointments a ON a.id = appointment_bookings.appointment_id").
+		Joins("JOIN treatment_centers tc ON tc.id = a.treatment_center_id")
+}
+
+func (r appointmentBookings) All(ctx context.Context) (result []*domain.AppointmentBooking, err error) {
+	err = withContext(ctx, r.db).Debug().Find(&result).Error
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BookedAt tells whether the patient has a booking, whatever its status, at an
+// appointment of the treatment center
+func (r appointmentBookings) BookedAt(ctx context.Context, patientID uuid.UUID,
+	treatmentCenterID uuid.UUID) (bool, error) {
+	var count int
+	err := withContext(ctx, r.db).Debug().Model(&domain.AppointmentBooking{}).
+		Joins("JOIN appointments ON appointments.id = appointment_bookings.appointment_id").
+		Where("appointment_bookings.patient_id = ? AND appointments.treatment_center_id = ?", patientID,
+			treatmentCenterID).
+		Count(&count).Error
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
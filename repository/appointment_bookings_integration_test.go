@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/y9mo/covidvax/domain"
@@ -14,11 +17,23 @@ import (
 type AppointmentBookingsIntegrationTestSuite struct {
 	testutils.IntegrationSuite
 	appointmentsRepository AppointmentBookings
+	availabilities         Appointments
 }
 
 func (s *AppointmentBookingsIntegrationTestSuite) SetupSuite() {
 	s.IntegrationSuite.SetupSuite()
 	s.appointmentsRepository = NewAppointmentBookings(s.IntegrationSuite.DB(), zap.NewExample())
+	s.availabilities = NewAppointments(s.IntegrationSuite.DB(), zap.NewExample())
+}
+
+// SetupTest postpones the fixture appointments the tests book, along with the
+// dose of the patient due a follow-up, as only appointments to come are booked
+func (s *AppointmentBookingsIntegrationTestSuite) SetupTest() {
+	s.IntegrationSuite.SetupTest()
+	s.IntegrationSuite.Postpone("appointments", "start_time", "eecce415-2d4c-440d-ac90-9780a3bd3371",
+		"eab38294-8b76-410c-8058-3e152e64dced", "b7269eb2-b5f9-46a5-ae79-87916c67e50a",
+		"96261e44-6d19-4d12-af0b-f28f56f665e2", "4cdb532d-bfe8-4af6-b9b5-d5078985a350")
+	s.IntegrationSuite.Postpone("doses", "administered_at", "5c4b3a29-1807-46f5-a4e3-d2c1b0a9f8e7")
 }
 
 func (s *AppointmentBookingsIntegrationTestSuite) TearDownSuite() {
@@ -48,7 +63,7 @@ func (s *AppointmentBookingsIntegrationTestSuite) TestCreate() {
 			id:   uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"),
 			appointment: &domain.AppointmentBooking{
 				ID:            uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"),
-				AppointmentID: uuid.MustParse("dcea3eae-3004-4d3d-95b6-abc02ecb026d"),
+				AppointmentID: uuid.MustParse("eab38294-8b76-410c-8058-3e152e64dced"),
 				PatientID:     uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"),
 				Status:        domain.AwaitingConfirmation,
 			},
@@ -65,15 +80,48 @@ func (s *AppointmentBookingsIntegrationTestSuite) TestCreate() {
 			},
 			wantErr: ErrUniqueConstraintFailure,
 		},
+		{
+			name: "BookedByAnotherPatient",
+			id:   uuid.MustParse("9a4f5c1d-2e3b-4c6d-8e7f-0a1b2c3d4e5f"),
+			appointment: &domain.AppointmentBooking{
+				ID:            uuid.MustParse("9a4f5c1d-2e3b-4c6d-8e7f-0a1b2c3d4e5f"),
+				AppointmentID: uuid.MustParse("4cdb532d-bfe8-4af6-b9b5-d5078985a350"),
+				PatientID:     uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+				Status:        domain.AwaitingConfirmation,
+			},
+			wantErr: ErrAppointmentNotAvailable,
+		},
+		{
+			name: "UnknownAppointment",
+			id:   uuid.MustParse("2c5e0f7a-8b1d-4e3c-a6f9-7d2b1e0c4a58"),
+			appointment: &domain.AppointmentBooking{
+				ID:            uuid.MustParse("2c5e0f7a-8b1d-4e3c-a6f9-7d2b1e0c4a58"),
+				AppointmentID: uuid.MustParse("dcea3eae-3004-4d3d-95b6-abc02ecb026d"),
+				PatientID:     uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+				Status:        domain.AwaitingConfirmation,
+			},
+			wantErr: ErrRecordNotFound,
+		},
+		{
+			name: "DoseTooEarly",
+			id:   uuid.MustParse("8e2d4f6a-0c1b-4a3e-9d5f-7b9c1e3a5d7f"),
+			appointment: &domain.AppointmentBooking{
+				ID:            uuid.MustParse("8e2d4f6a-0c1b-4a3e-9d5f-7b9c1e3a5d7f"),
+				AppointmentID: uuid.MustParse("eab38294-8b76-410c-8058-3e152e64dced"),
+				PatientID:     uuid.MustParse("3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2"),
+				Status:        domain.AwaitingConfirmation,
+			},
+			wantErr: domain.ErrDoseTooEarly,
+		},
 	}
 	for _, tc := range tests {
 		tc := tc
 		s.Run(tc.name, func() {
-			err := s.appointmentsRepository.Create(tc.appointment)
+			err := s.appointmentsRepository.Create(context.Background(), tc.appointment)
 			if tc.wantErr != nil {
 				s.Assert().Equal(tc.wantErr, err)
 			} else {
-				gotAppointmentBooking, err := s.appointmentsRepository.FindByID(tc.id)
+				gotAppointmentBooking, err := s.appointmentsRepository.FindByID(context.Background(), tc.id)
 				s.Assert().NoError(err)
 
 				s.Assert().Equal(tc.wantErr, err)
@@ -85,11 +133,460 @@ func (s *AppointmentBookingsIntegrationTestSuite) TestCreate() {
 	}
 }
 
-func TestAppointmentBookingsIntegrationTestSuite(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skipping AppointmentBookingsIntegrationTest in short mode.")
-		return
+func (s *AppointmentBookingsIntegrationTestSuite) TestConfirm() {
+	awaiting := &domain.AppointmentBooking{
+		ID:            uuid.MustParse("5d0a3b1e-54c7-4c8e-8a4e-3e4b50c6a0b2"),
+		AppointmentID: uuid.MustParse("eab38294-8b76-410c-8058-3e152e64dced"),
+		PatientID:     uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+		Status:        domain.AwaitingConfirmation,
 	}
+	s.Require().NoError(s.appointmentsRepository.Create(context.Background(), awaiting))
+
+	tests := []struct {
+		name    string
+		id      uuid.UUID
+		wantErr error
+	}{
+		{
+			name:    "Successful",
+			id:      awaiting.ID,
+			wantErr: nil,
+		},
+		{
+			name:    "AlreadyConfirmed",
+			id:      awaiting.ID,
+			wantErr: ErrInvalidStatusTransition,
+		},
+		{
+			name:    "NotFound",
+			id:      uuid.MustParse("3dc1ba24-9e41-4c5a-b73f-79d9d4c2ba0e"),
+			wantErr: ErrRecordNotFound,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			err := s.appointmentsRepository.Confirm(context.Background(), tc.id)
+			s.Assert().Equal(tc.wantErr, err)
+			if tc.wantErr == nil {
+				got, err := s.appointmentsRepository.FindByID(context.Background(), tc.id)
+				s.Assert().NoError(err)
+				s.Assert().Equal(domain.Confirmed, got.Status)
+			}
+		})
+	}
+}
+
+func (s *AppointmentBookingsIntegrationTestSuite) TestCreateUpToCapacity() {
+	appointmentID := uuid.MustParse("96261e44-6d19-4d12-af0b-f28f56f665e2")
+	tests := []struct {
+		name          string
+		patientID     uuid.UUID
+		wantErr       error
+		wantRemaining int
+	}{
+		{
+			name:          "FirstSeat",
+			patientID:     uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"),
+			wantErr:       nil,
+			wantRemaining: 1,
+		},
+		{
+			name:          "LastSeat",
+			patientID:     uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+			wantErr:       nil,
+			wantRemaining: 0,
+		},
+		{
+			name:          "Full",
+			patientID:     uuid.MustParse("24e32685-0a32-4a9d-bc22-0e98cdaf5884"),
+			wantErr:       ErrAppointmentNotAvailable,
+			wantRemaining: 0,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			err := s.appointmentsRepository.Create(context.Background(), &domain.AppointmentBooking{
+				ID:            uuid.New(),
+				AppointmentID: appointmentID,
+				PatientID:     tc.patientID,
+				Status:        domain.AwaitingConfirmation,
+			})
+			s.Assert().Equal(tc.wantErr, err)
+
+			appointment, err := s.availabilities.FindByID(context.Background(), appointmentID)
+			s.Require().NoError(err)
+			s.Assert().Equal(2, appointment.Capacity)
+			s.Assert().Equal(tc.wantRemaining, appointment.RemainingSeats)
+		})
+	}
+
+	available, err := s.availabilities.AllAvailable(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Len(available, 6)
+}
+
+func (s *AppointmentBookingsIntegrationTestSuite) TestExpireUnconfirmed() {
+	now := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+	overdue := &domain.AppointmentBooking{
+		ID:            uuid.MustParse("5d0a3b1e-54c7-4c8e-8a4e-3e4b50c6a0b2"),
+		AppointmentID: uuid.MustParse("eab38294-8b76-410c-8058-3e152e64dced"),
+		PatientID:     uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+		Status:        domain.AwaitingConfirmation,
+		ExpiresAt:     &past,
+	}
+	pending := &domain.AppointmentBooking{
+		ID:            uuid.MustParse("0b7e8a7c-3f4e-4a3d-9d39-8c7f5a0f6e11"),
+		AppointmentID: uuid.MustParse("b7269eb2-b5f9-46a5-ae79-87916c67e50a"),
+		PatientID:     uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+		Status:        domain.AwaitingConfirmation,
+		ExpiresAt:     &future,
+	}
+	s.Require().NoError(s.appointmentsRepository.Create(context.Background(), overdue))
+	s.Require().NoError(s.appointmentsRepository.Create(context.Background(), pending))
+
+	available, err := s.availabilities.AllAvailable(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Len(available, 5)
+
+	expired, err := s.appointmentsRepository.ExpireUnconfirmed(context.Background(),
+		now, domain.ReleaseReasonUnconfirmed)
+	s.Require().NoError(err)
+	s.Require().Len(expired, 1)
+	s.Assert().Equal(overdue.ID, expired[0].ID)
+	s.Assert().Equal(domain.Expired, expired[0].Status)
+
+	got, err := s.appointmentsRepository.FindByID(context.Background(), overdue.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.Expired, got.Status)
+	s.Assert().Equal(domain.ReleaseReasonUnconfirmed, got.ReleaseReason)
+	s.Assert().NotNil(got.ReleasedAt)
+
+	got, err = s.appointmentsRepository.FindByID(context.Background(), pending.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.AwaitingConfirmation, got.Status)
+
+	available, err = s.availabilities.AllAvailable(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Len(available, 6)
+
+	s.Assert().Equal(ErrInvalidStatusTransition, s.appointmentsRepository.Confirm(context.Background(), overdue.ID))
+}
+
+func (s *AppointmentBookingsIntegrationTestSuite) TestCreateConcurrently() {
+	appointmentID := uuid.MustParse("eab38294-8b76-410c-8058-3e152e64dced")
+	patientIDs := []uuid.UUID{
+		uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"),
+		uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+		uuid.MustParse("24e32685-0a32-4a9d-bc22-0e98cdaf5884"),
+		uuid.MustParse("6f0bd1a3-7d0a-4f5e-9a61-2b1c3d4e5f60"),
+		uuid.MustParse("a1c2e3f4-5b6d-4e7f-8091-a2b3c4d5e6f7"),
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(patientIDs))
+	for _, patientID := range patientIDs {
+		wg.Add(1)
+		go func(patientID uuid.UUID) {
+			defer wg.Done()
+			errs <- s.appointmentsRepository.Create(context.Background(), &domain.AppointmentBooking{
+				ID:            uuid.New(),
+				AppointmentID: appointmentID,
+				PatientID:     patientID,
+				Status:        domain.AwaitingConfirmation,
+			})
+		}(patientID)
+	}
+	wg.Wait()
+	close(errs)
+
+	var booked, rejected int
+	for err := range errs {
+		switch err {
+		case nil:
+			booked++
+		case ErrAppointmentNotAvailable:
+			rejected++
+		default:
+			s.Failf("unexpected error", "%s", err)
+		}
+	}
+	s.Assert().Equal(1, booked)
+	s.Assert().Equal(len(patientIDs)-1, rejected)
+}
+
+func (s *AppointmentBookingsIntegrationTestSuite) TestCancel() {
+	id := uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710")
+	s.Require().NoError(s.appointmentsRepository.Cancel(context.Background(), id, domain.ReleaseReasonCancelled))
+
+	got, err := s.appointmentsRepository.FindByID(context.Background(), id)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.Cancelled, got.Status)
+	s.Assert().Equal(domain.ReleaseReasonCancelled, got.ReleaseReason)
+	s.Assert().NotNil(got.ReleasedAt)
+
+	available, err := s.availabilities.AllAvailable(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Len(available, 8)
+
+	s.Assert().Equal(ErrInvalidStatusTransition, s.appointmentsRepository.Cancel(context.Background(),
+		id, domain.ReleaseReasonCancelled))
+	s.Assert().Equal(ErrRecordNotFound,
+		s.appointmentsRepository.Cancel(context.Background(),
+			uuid.MustParse("3dc1ba24-9e41-4c5a-b73f-79d9d4c2ba0e"), domain.ReleaseReasonCancelled))
+}
+
+func (s *AppointmentBookingsIntegrationTestSuite) TestReschedule() {
+	id := uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710")
+	tests := []struct {
+		name          string
+		appointmentID uuid.UUID
+		wantErr       error
+	}{
+		{
+			name:          "UnknownAppointment",
+			appointmentID: uuid.MustParse("3dc1ba24-9e41-4c5a-b73f-79d9d4c2ba0e"),
+			wantErr:       ErrRecordNotFound,
+		},
+		{
+			name:          "Successful",
+			appointmentID: uuid.MustParse("eab38294-8b76-410c-8058-3e152e64dced"),
+			wantErr:       nil,
+		},
+		{
+			name:          "AlreadyRescheduled",
+			appointmentID: uuid.MustParse("b7269eb2-b5f9-46a5-ae79-87916c67e50a"),
+			wantErr:       ErrInvalidStatusTransition,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			got, err := s.appointmentsRepository.Reschedule(context.Background(), id, tc.appointmentID)
+			s.Assert().Equal(tc.wantErr, err)
+			if tc.wantErr != nil {
+				return
+			}
+			s.Assert().NotEqual(id, got.ID)
+			s.Assert().Equal(tc.appointmentID, got.AppointmentID)
+			s.Assert().Equal(domain.Confirmed, got.Status)
+
+			previous, err := s.appointmentsRepository.FindByID(context.Background(), id)
+			s.Require().NoError(err)
+			s.Assert().Equal(domain.Cancelled, previous.Status)
+			s.Assert().Equal(domain.ReleaseReasonRescheduled, previous.ReleaseReason)
+		})
+	}
+
+	available, err := s.availabilities.AllAvailable(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Len(available, 7)
+}
+
+func (s *AppointmentBookingsIntegrationTestSuite) TestAllByPatientID() {
+	patientID := uuid.MustParse("24e32685-0a32-4a9d-bc22-0e98cdaf5884")
+	s.Require().NoError(s.appointmentsRepository.Create(context.Background(), &domain.AppointmentBooking{
+		ID:            uuid.MustParse("c3b1f2a4-8d6e-4f0a-b2c4-d6e8f0a2b4c6"),
+		AppointmentID: uuid.MustParse("eecce415-2d4c-440d-ac90-9780a3bd3371"),
+		PatientID:     patientID,
+		Status:        domain.AwaitingConfirmation,
+	}))
+
+	bookings, page, err := s.appointmentsRepository.AllByPatientID(context.Background(),
+		patientID, domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(bookings, 2)
+	s.Assert().Equal(int64(2), page.Total)
+	s.Assert().Nil(page.NextCursor)
+
+	s.Assert().Equal(uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"), bookings[0].ID)
+	s.Assert().Equal(domain.Confirmed, bookings[0].Status)
+	s.Assert().Equal(uuid.MustParse("10063726-d378-472c-9b50-22a48331635d"), bookings[0].TreatmentCenterID)
+	s.Assert().Equal("Center in the game", bookings[0].TreatmentCenterName)
+	s.Assert().Equal("game time", bookings[0].TreatmentCenterAddress)
+	appointment, err := s.availabilities.FindByID(context.Background(),
+		uuid.MustParse("4cdb532d-bfe8-4af6-b9b5-d5078985a350"))
+	s.Require().NoError(err)
+	s.Assert().True(appointment.StartTime.Equal(bookings[0].StartTime))
+
+	s.Assert().Equal(uuid.MustParse("c3b1f2a4-8d6e-4f0a-b2c4-d6e8f0a2b4c6"), bookings[1].ID)
+	s.Assert().Equal(domain.AwaitingConfirmation, bookings[1].Status)
+	s.Assert().Equal("Center Two", bookings[1].TreatmentCenterName)
+
+	bookings, page, err = s.appointmentsRepository.AllByPatientID(context.Background(),
+		patientID, domain.PageRequest{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(bookings, 1)
+	s.Assert().Equal(uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"), bookings[0].ID)
+	s.Require().NotNil(page.NextCursor)
+	cursor := page.NextCursor
+
+	bookings, page, err = s.appointmentsRepository.AllByPatientID(context.Background(), patientID,
+		domain.PageRequest{Limit: 1, Cursor: cursor})
+	s.Require().NoError(err)
+	s.Require().Len(bookings, 1)
+	s.Assert().Equal(uuid.MustParse("c3b1f2a4-8d6e-4f0a-b2c4-d6e8f0a2b4c6"), bookings[0].ID)
+	s.Assert().Nil(page.NextCursor)
+	s.Assert().Equal(int64(2), page.Total)
+
+	_, _, err = s.appointmentsRepository.AllByPatientID(context.Background(), patientID,
+		domain.PageRequest{Sort: "created_at", Cursor: cursor})
+	s.Assert().Equal(ErrInvalidCursor, err)
+	_, _, err = s.appointmentsRepository.AllByPatientID(context.Background(),
+		patientID, domain.PageRequest{Sort: "status"})
+	s.Assert().Equal(ErrInvalidSort, err)
+
+	bookings, _, err = s.appointmentsRepository.AllByPatientID(context.Background(),
+		uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"),
+		domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Assert().Empty(bookings)
+}
+
+func (s *AppointmentBookingsIntegrationTestSuite) TestCreateFollowUpDose() {
+	patientID := uuid.MustParse("3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2")
+	first := domain.Dose{}
+	s.Require().NoError(s.IntegrationSuite.DB().Where("patient_id = ?", patientID).First(&first).Error)
+	tests := []struct {
+		name    string
+		days    int
+		wantErr error
+	}{
+		{name: "TooLate", days: 43, wantErr: domain.ErrDoseTooLate},
+		{name: "Successful", days: 28, wantErr: nil},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			appointment := &domain.Appointment{
+				ID:                uuid.New(),
+				TreatmentCenterID: uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
+				StartTime:         first.AdministeredAt.AddDate(0, 0, tc.days),
+				Capacity:          1,
+			}
+			s.Require().NoError(s.availabilities.Create(context.Background(), appointment))
+
+			err := s.appointmentsRepository.Create(context.Background(), &domain.AppointmentBooking{
+				ID:            uuid.New(),
+				AppointmentID: appointment.ID,
+				PatientID:     patientID,
+				Status:        domain.AwaitingConfirmation,
+			})
+			s.Assert().Equal(tc.wantErr, err)
+		})
+	}
+}
+
+func (s *AppointmentBookingsIntegrationTestSuite) TestRecordOutcome() {
+	at := time.Date(2021, 11, 13, 18, 5, 0, 0, time.UTC)
+	productID := uuid.MustParse("7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8")
+	awaiting := &domain.AppointmentBooking{
+		ID:            uuid.MustParse("6a8c0e2b-4d5f-4a7b-9c1d-3e5f7a9b1c3d"),
+		AppointmentID: uuid.MustParse("eab38294-8b76-410c-8058-3e152e64dced"),
+		PatientID:     uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+		Status:        domain.AwaitingConfirmation,
+	}
+	s.Require().NoError(s.appointmentsRepository.Create(context.Background(), awaiting))
+
+	tests := []struct {
+		name    string
+		id      uuid.UUID
+		outcome *domain.BookingOutcome
+		wantErr error
+	}{
+		{
+			name:    "NotConfirmed",
+			id:      awaiting.ID,
+			outcome: &domain.BookingOutcome{Status: domain.NoShow},
+			wantErr: ErrInvalidStatusTransition,
+		},
+		{
+			name:    "UnknownBooking",
+			id:      uuid.MustParse("3dc1ba24-9e41-4c5a-b73f-79d9d4c2ba0e"),
+			outcome: &domain.BookingOutcome{Status: domain.NoShow},
+			wantErr: ErrRecordNotFound,
+		},
+		{
+			name: "UnknownLot",
+			id:   uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"),
+			outcome: &domain.BookingOutcome{
+				Status:           domain.Administered,
+				VaccineProductID: &productID,
+				LotNumber:        "EW2239",
+			},
+			wantErr: ErrUnknownLot,
+		},
+		{
+			name: "ExpiredLot",
+			id:   uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"),
+			outcome: &domain.BookingOutcome{
+				Status:           domain.Administered,
+				VaccineProductID: &productID,
+				LotNumber:        "EL0140",
+			},
+			wantErr: ErrLotUnusable,
+		},
+		{
+			name: "Administered",
+			id:   uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"),
+			outcome: &domain.BookingOutcome{
+				Status:           domain.Administered,
+				VaccineProductID: &productID,
+				LotNumber:        "FK5618",
+				AdministeredBy:   "nurse.one",
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "AlreadyRecorded",
+			id:      uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"),
+			outcome: &domain.BookingOutcome{Status: domain.Refused},
+			wantErr: ErrInvalidStatusTransition,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			s.Assert().Equal(tc.wantErr, s.appointmentsRepository.RecordOutcome(context.Background(),
+				tc.id, tc.outcome, at))
+		})
+	}
+
+	got, err := s.appointmentsRepository.FindByID(context.Background(),
+		uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"))
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.Administered, got.Status)
+	s.Assert().Equal("FK5618", got.LotNumber)
+	s.Assert().Equal("nurse.one", got.AdministeredBy)
+	s.Assert().NotNil(got.OutcomeRecordedAt)
+
+	lots, _, err := NewVaccineLots(s.IntegrationSuite.DB(), zap.NewExample()).
+		AllByTreatmentCenterID(context.Background(),
+			uuid.MustParse("10063726-d378-472c-9b50-22a48331635d"), domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(lots, 2)
+	s.Assert().Equal("FK5618", lots[1].LotNumber)
+	s.Assert().Equal(9, lots[1].Quantity)
+
+	doses, _, err := NewDoses(s.DB(), zap.NewExample()).AllByPatientID(context.Background(),
+		got.PatientID, domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(doses, 1)
+	s.Assert().Equal(1, doses[0].Number)
+	s.Assert().Equal(&got.ID, doses[0].AppointmentBookingID)
+
+	// the administered booking still holds its seat
+	appointment, err := s.availabilities.FindByID(context.Background(), got.AppointmentID)
+	s.Require().NoError(err)
+	s.Assert().Equal(0, appointment.RemainingSeats)
+}
+
+func TestAppointmentBookingsIntegrationTestSuite(t *testing.T) {
 	t.Parallel()
-	suite.Run(t, new(AppointmentBookingsIntegrationTestSuite))
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &AppointmentBookingsIntegrationTestSuite{IntegrationSuite: backend}
+	})
 }
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,14 +12,18 @@ import (
 )
 
 type Appointments interface {
-	Create(appointment *domain.Appointment) error
-	Update(appointment *domain.Appointment) error
-	Delete(appointment *domain.Appointment) error
-	FindByID(id uuid.UUID) (*domain.Appointment, error)
-	All() (result []*domain.Appointment, err error)
-	AllByTreatmentCenterID(treatmentCenterID uuid.UUID) (result []*domain.Appointment, err error)
-	AllAvailable() (result []*domain.Appointment, err error)
-	AllBookedByTreatmentCenterIDForDate(treatmentCenterID uuid.UUID, date time.Time) (result []*domain.Appointment, err error)
+	Create(ctx context.Context, appointment *domain.Appointment) error
+	Update(ctx context.Context, appointment *domain.Appointment) error
+	Delete(ctx context.Context, id uuid.UUID, cancelBookings bool) ([]*domain.PatientBooking, error)
+	CreateMissing(ctx context.Context, appointments []*domain.Appointment) ([]*domain.Appointment, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.Appointment, error)
+	All(ctx context.Context) (result []*domain.Appointment, err error)
+	AllByTreatmentCenterID(ctx context.Context, treatmentCenterID uuid.UUID) (result []*domain.Appointment, err error)
+	AllAvailable(ctx context.Context) (result []*domain.Appointment, err error)
+	Search(ctx context.Context, search domain.AppointmentSearch, request domain.PageRequest,
+		now time.Time) (result []*domain.Appointment, page domain.Page, err error)
+	AllBookedByTreatmentCenterIDForDate(ctx context.Context, treatmentCenterID uuid.UUID, date time.Time,
+		request domain.PageRequest) (result []*domain.Appointment, page domain.Page, err error)
 }
 
 type appointments struct {
@@ -29,69 +35,311 @@ func NewAppointments(db *gorm.DB, logger *zap.Logger) Appointments {
 	return appointments{db: db, logger: logger}
 }
 
-func (r appointments) Create(appointment *domain.Appointment) error {
-	err := r.db.Debug().Create(appointment).Error
+// Create opens an appointment, it fails with ErrInsufficientStock when the
+// treatment center hasn't enough doses for the seats of its upcoming appointments
+func (r appointments) Create(ctx context.Context, appointment *domain.Appointment) error {
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := lockTreatmentCenter(tx, appointment.TreatmentCenterID); err != nil {
+			return err
+		}
+		if err := tx.Create(appointment).Error; err != nil {
+			return err
+		}
+		return checkStock(tx, appointment.TreatmentCenterID, upcoming(appointment.StartTime))
+	})
 	return handleGormError(err, r.logger)
 }
 
-func (r appointments) Update(appointment *domain.Appointment) error {
-	err := r.db.Debug().Update(appointment).Error
-	return handleGormError(err, r.logger)
+// upcoming returns the date from which the appointments still need doses,
+// an appointment opened in the past counts as upcoming
+func upcoming(startTime time.Time) time.Time {
+	if now := time.Now(); now.Before(startTime) {
+		return now
+	}
+	return startTime
 }
 
-func (r appointments) Delete(appointment *domain.Appointment) error {
-	err := r.db.Debug().Delete(appointment).Error
+// Update saves the start time and capacity of an appointment. The start time of
+// an appointment holding bookings doesn't change, its capacity can't drop below
+// the seats held and the stock of its treatment center must cover the seats.
+func (r appointments) Update(ctx context.Context, appointment *domain.Appointment) error {
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		current := domain.Appointment{}
+		if err := tx.Where("id = ?", appointment.ID).First(&current).Error; err != nil {
+			return err
+		}
+		if err := lockTreatmentCenter(tx, current.TreatmentCenterID); err != nil {
+			return err
+		}
+		held, err := heldSeats(tx, appointment.ID)
+		if err != nil {
+			return err
+		}
+		if held > 0 && !current.StartTime.Equal(appointment.StartTime) {
+			return ErrAppointmentBooked
+		}
+		if appointment.Capacity < held {
+			return ErrCapacityBelowBookings
+		}
+
+		since := current.StartTime
+		if appointment.StartTime.Before(since) {
+			since = appointment.StartTime
+		}
+		err = tx.Model(&current).Updates(map[string]interface{}{
+			"start_time": appointment.StartTime,
+			"capacity":   appointment.Capacity,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return checkStock(tx, current.TreatmentCenterID, upcoming(since))
+	})
 	return handleGormError(err, r.logger)
 }
 
-func (r appointments) FindByID(id uuid.UUID) (*domain.Appointment, error) {
-	appointment := domain.Appointment{}
-	err := r.db.Debug().Where("id = ?", id).Find(&appointment).Error
+// heldSeats counts the seats of an appointment held by its bookings
+func heldSeats(tx *gorm.DB, appointmentID uuid.UUID) (held int, err error) {
+	err = tx.Model(&domain.AppointmentBooking{}).
+		Where("appointment_id = ? AND status IN (?)", appointmentID, domain.HoldingStatuses).
+		Count(&held).Error
+	return held, err
+}
+
+// Delete deletes an appointment, it is kept for its past bookings but can't be
+// found or booked anymore. An appointment with active bookings fails with
+// ErrAppointmentBooked unless cancelBookings, they are then cancelled and returned.
+func (r appointments) Delete(ctx context.Context, id uuid.UUID,
+	cancelBookings bool) (cancelled []*domain.PatientBooking, err error) {
+	err = transaction(ctx, r.db, func(tx *gorm.DB) error {
+		current := domain.Appointment{}
+		if err := tx.Where("id = ?", id).First(&current).Error; err != nil {
+			return err
+		}
+		if err := lockTreatmentCenter(tx, current.TreatmentCenterID); err != nil {
+			return err
+		}
+		var err error
+		cancelled, err = withdrawBookings(tx, cancelBookings, ErrAppointmentBooked, "a.id = ?", id)
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&current).Error
+	})
 	err = handleGormError(err, r.logger)
 	if err != nil {
 		return nil, err
 	}
-	return &appointment, nil
+	return cancelled, nil
+}
+
+// withdrawBookings cancels the active bookings of the appointments matching the
+// condition, a is the appointment, and returns them. When there are some and
+// they may not be cancelled it fails with booked.
+func withdrawBookings(tx *gorm.DB, cancel bool, booked error, condition string,
+	args ...interface{}) ([]*domain.PatientBooking, error) {
+	var ids []uuid.UUID
+	err := patientBookings(tx).Where("appointment_bookings.status IN (?)", domain.ActiveStatuses).
+		Where(condition, args...).Pluck("appointment_bookings.id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	if !cancel {
+		return nil, booked
+	}
+	for _, id := range ids {
+		if err = release(tx, id, domain.Cancelled, domain.ReleaseReasonWithdrawn); err != nil {
+			return nil, err
+		}
+	}
+	var cancelled []*domain.PatientBooking
+	err = patientBookings(tx).Where("appointment_bookings.id IN (?)", ids).
+		Order("a.start_time, appointment_bookings.id").Scan(&cancelled).Error
+	return cancelled, err
 }
 
-func (r appointments) All() (result []*domain.Appointment, err error) {
-	err = r.db.Debug().Find(&result).Error
+// CreateMissing creates the appointments whose treatment center has no appointment
+// starting at the same time yet and returns those created, like Create it fails
+// with ErrInsufficientStock when doses are lacking
+func (r appointments) CreateMissing(ctx context.Context,
+	appointments []*domain.Appointment) (created []*domain.Appointment, err error) {
+	err = transaction(ctx, r.db, func(tx *gorm.DB) error {
+		locked := map[uuid.UUID]bool{}
+		since := map[uuid.UUID]time.Time{}
+		for _, appointment := range appointments {
+			if !locked[appointment.TreatmentCenterID] {
+				if err := lockTreatmentCenter(tx, appointment.TreatmentCenterID); err != nil {
+					return err
+				}
+				locked[appointment.TreatmentCenterID] = true
+			}
+			result := tx.Set("gorm:insert_option",
+				"ON CONFLICT (treatment_center_id, start_time) WHERE deleted_at IS NULL DO NOTHING").
+				Create(appointment)
+			// postgres returns no id when the insert is skipped, sqlite affects no row
+			if result.Error == sql.ErrNoRows {
+				continue
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			created = append(created, appointment)
+			if first, ok := since[appointment.TreatmentCenterID]; !ok || appointment.StartTime.Before(first) {
+				since[appointment.TreatmentCenterID] = appointment.StartTime
+			}
+		}
+		for treatmentCenterID, first := range since {
+			if err := checkStock(tx, treatmentCenterID, upcoming(first)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	err = handleGormError(err, r.logger)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return created, nil
 }
 
-func (r appointments) AllByTreatmentCenterID(treatmentCenterID uuid.UUID) (result []*domain.Appointment, err error) {
-	err = r.db.Debug().Where("treatment_center_id = ?", treatmentCenterID).Find(&result).Error
+func (r appointments) FindByID(ctx context.Context, id uuid.UUID) (*domain.Appointment, error) {
+	row := appointmentSeats{}
+	err := withSeats(withContext(ctx, r.db).Debug()).Where("appointments.id = ?", id).Scan(&row).Error
 	err = handleGormError(err, r.logger)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return row.toDomain(), nil
 }
 
-func (r appointments) AllAvailable() (result []*domain.Appointment, err error) {
-	err = r.db.Debug().Joins("LEFT JOIN appointment_bookings ab on appointments.id = ab.appointment_id").
-		Where("ab.appointment_id is NULL").Find(&result).Error
+func (r appointments) All(ctx context.Context) (result []*domain.Appointment, err error) {
+	var rows []appointmentSeats
+	err = withSeats(withContext(ctx, r.db).Debug()).Scan(&rows).Error
 	err = handleGormError(err, r.logger)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return toDomainAppointments(rows), nil
 }
 
-func (r appointments) AllBookedByTreatmentCenterIDForDate(treatmentCenterID uuid.UUID,
-	date time.Time) (result []*domain.Appointment, err error) {
-	err = r.db.Debug().Joins("LEFT JOIN appointment_bookings ab on appointments.id = ab.appointment_id").
-		Where("ab.appointment_id is NOT NULL AND treatment_center_id = ?", treatmentCenterID).
-		Where("date_trunc('day', appointments.start_time) = date_trunc('day', ?::timestamptz)", date).
-		Find(&result).Error
+func (r appointments) AllByTreatmentCenterID(ctx context.Context,
+	treatmentCenterID uuid.UUID) (result []*domain.Appointment, err error) {
+	var rows []appointmentSeats
+	err = withSeats(withContext(ctx, r.db).Debug()).
+		Where("appointments.treatment_center_id = ?", treatmentCenterID).Scan(&rows).Error
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return toDomainAppointments(rows), nil
+}
 
+// AllAvailable returns the appointments with at least one remaining seat,
+// including the past ones
+func (r appointments) AllAvailable(ctx context.Context) (result []*domain.Appointment, err error) {
+	var rows []appointmentSeats
+	err = withSeats(withContext(ctx, r.db).Debug()).Having("count(ab.id) < appointments.capacity").Scan(&rows).Error
 	err = handleGormError(err, r.logger)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return toDomainAppointments(rows), nil
+}
+
+// appointmentsSorting sorts the appointments by start time
+var appointmentsSorting = sorting{
+	fields: map[string]sortField{
+		"start_time": {column: "appointments.start_time"},
+	},
+	idColumn:    "appointments.id",
+	defaultSort: "start_time",
+}
+
+// Search returns the appointments starting after now with at least one remaining
+// seat and matching the search
+func (r appointments) Search(ctx context.Context, search domain.AppointmentSearch, request domain.PageRequest,
+	now time.Time) (result []*domain.Appointment, page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	query := withSeats(db).
+		Joins("JOIN treatment_centers tc ON tc.id = appointments.treatment_center_id").
+		Where("appointments.start_time > ?", now).
+		Having("count(ab.id) < appointments.capacity")
+	if search.TreatmentCenterID != nil {
+		query = query.Where("appointments.treatment_center_id = ?", *search.TreatmentCenterID)
+	}
+	if search.From != nil {
+		query = query.Where("appointments.start_time >= ?", *search.From)
+	}
+	if search.To != nil {
+		query = query.Where("appointments.start_time < ?", *search.To)
+	}
+	if search.After != nil {
+		query = query.Where(startClock(db, ">="), string(*search.After))
+	}
+	if search.Before != nil {
+		query = query.Where(startClock(db, "<"), string(*search.Before))
+	}
+
+	var rows []appointmentSeats
+	page, err = paginate(db, query, request, appointmentsSorting, &rows)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return toDomainAppointments(rows), page, nil
+}
+
+// AllBookedByTreatmentCenterIDForDate returns the appointments of a treatment center
+// for a given day, in UTC, with at least one seat booked
+func (r appointments) AllBookedByTreatmentCenterIDForDate(ctx context.Context, treatmentCenterID uuid.UUID,
+	date time.Time,
+	request domain.PageRequest) (result []*domain.Appointment, page domain.Page, err error) {
+	day := date.UTC().Truncate(24 * time.Hour)
+	db := withContext(ctx, r.db).Debug()
+	query := withSeats(db).
+		Where("appointments.treatment_center_id = ?", treatmentCenterID).
+		Where("appointments.start_time >= ? AND appointments.start_time < ?", day, day.AddDate(0, 0, 1)).
+		Having("count(ab.id) > 0")
+
+	var rows []appointmentSeats
+	page, err = paginate(db, query, request, appointmentsSorting, &rows)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return toDomainAppointments(rows), page, nil
+}
+
+// appointmentSeats is an appointment along with its number of booked seats
+type appointmentSeats struct {
+	domain.Appointment
+	BookedSeats int
+}
+
+func (a appointmentSeats) toDomain() *domain.Appointment {
+	appointment := a.Appointment
+	appointment.RemainingSeats = appointment.Capacity - a.BookedSeats
+	return &appointment
+}
+
+func toDomainAppointments(rows []appointmentSeats) []*domain.Appointment {
+	result := make([]*domain.Appointment, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, row.toDomain())
+	}
+	return result
+}
+
+// withSeats selects the appointments not deleted along with the number of
+// seats held by bookings
+func withSeats(db *gorm.DB) *gorm.DB {
+	return db.Table("appointments").
+		Select("appointments.*, count(ab.id) AS booked_seats").
+		Joins("LEFT JOIN appointment_bookings ab on appointments.id = ab.appointment_id AND ab.status IN (?)",
+			domain.HoldingStatuses).
+		Where("appointments.deleted_at IS NULL").
+		Group("appointments.id")
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -40,6 +41,7 @@ func (s *AppointmentsIntegrationTestSuite) TestCreate() {
 				ID:                uuid.MustParse("d93f7ecc-816f-4124-b41e-dcfa58f03761"),
 				TreatmentCenterID: uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
 				StartTime:         time.Date(2021, 11, 11, 8, 0, 0, 0, time.UTC),
+				Capacity:          3,
 			},
 			wantErr: nil,
 		},
@@ -50,6 +52,18 @@ func (s *AppointmentsIntegrationTestSuite) TestCreate() {
 				ID:                uuid.MustParse("eecce415-2d4c-440d-ac90-9780a3bd3371"),
 				TreatmentCenterID: uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
 				StartTime:         time.Date(2021, 11, 11, 8, 0, 0, 0, time.UTC),
+				Capacity:          1,
+			},
+			wantErr: ErrUniqueConstraintFailure,
+		},
+		{
+			name: "SlotAlreadyExist",
+			id:   uuid.MustParse("7be1c0d4-1f2a-4c3b-9e8d-6a5f4b3c2d1e"),
+			appointment: &domain.Appointment{
+				ID:                uuid.MustParse("7be1c0d4-1f2a-4c3b-9e8d-6a5f4b3c2d1e"),
+				TreatmentCenterID: uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
+				StartTime:         time.Date(2021, 11, 13, 8, 0, 0, 0, time.UTC),
+				Capacity:          1,
 			},
 			wantErr: ErrUniqueConstraintFailure,
 		},
@@ -57,42 +71,266 @@ func (s *AppointmentsIntegrationTestSuite) TestCreate() {
 	for _, tc := range tests {
 		tc := tc
 		s.Run(tc.name, func() {
-			err := s.appointmentsRepository.Create(tc.appointment)
+			err := s.appointmentsRepository.Create(context.Background(), tc.appointment)
 			if tc.wantErr != nil {
 				s.Assert().Equal(tc.wantErr, err)
 			} else {
-				gotAppointment, err := s.appointmentsRepository.FindByID(tc.id)
+				gotAppointment, err := s.appointmentsRepository.FindByID(context.Background(), tc.id)
 				s.Assert().NoError(err)
 
 				s.Assert().Equal(tc.wantErr, err)
 				s.Assert().Equal(tc.appointment.ID, gotAppointment.ID)
 				s.Assert().Equal(tc.appointment.TreatmentCenterID, gotAppointment.TreatmentCenterID)
+				s.Assert().Equal(tc.appointment.Capacity, gotAppointment.Capacity)
+				s.Assert().Equal(tc.appointment.Capacity, gotAppointment.RemainingSeats)
 			}
 		})
 	}
 }
 
+func (s *AppointmentsIntegrationTestSuite) TestUpdate() {
+	bookedID := uuid.MustParse("4cdb532d-bfe8-4af6-b9b5-d5078985a350")
+	bookedStartTime := time.Date(2021, 11, 13, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		appointment *domain.Appointment
+		wantErr     error
+	}{
+		{
+			name: "Successful",
+			appointment: &domain.Appointment{
+				ID:        uuid.MustParse("eecce415-2d4c-440d-ac90-9780a3bd3371"),
+				StartTime: time.Date(2021, 11, 13, 8, 30, 0, 0, time.UTC),
+				Capacity:  3,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "BookedCapacity",
+			appointment: &domain.Appointment{
+				ID:        bookedID,
+				StartTime: bookedStartTime,
+				Capacity:  2,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "BookedStartTime",
+			appointment: &domain.Appointment{
+				ID:        bookedID,
+				StartTime: bookedStartTime.Add(time.Hour),
+				Capacity:  1,
+			},
+			wantErr: ErrAppointmentBooked,
+		},
+		{
+			name: "CapacityBelowBookings",
+			appointment: &domain.Appointment{
+				ID:        bookedID,
+				StartTime: bookedStartTime,
+				Capacity:  0,
+			},
+			wantErr: ErrCapacityBelowBookings,
+		},
+		{
+			name: "InsufficientStock",
+			appointment: &domain.Appointment{
+				ID:        bookedID,
+				StartTime: bookedStartTime,
+				Capacity:  20,
+			},
+			wantErr: ErrInsufficientStock,
+		},
+		{
+			name: "NotFound",
+			appointment: &domain.Appointment{
+				ID:        uuid.MustParse("d93f7ecc-816f-4124-b41e-dcfa58f03761"),
+				StartTime: bookedStartTime,
+				Capacity:  1,
+			},
+			wantErr: ErrRecordNotFound,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			err := s.appointmentsRepository.Update(context.Background(), tc.appointment)
+			s.Assert().Equal(tc.wantErr, err)
+			if tc.wantErr != nil {
+				return
+			}
+			gotAppointment, err := s.appointmentsRepository.FindByID(context.Background(), tc.appointment.ID)
+			s.Assert().NoError(err)
+			s.Assert().True(tc.appointment.StartTime.Equal(gotAppointment.StartTime))
+			s.Assert().Equal(tc.appointment.Capacity, gotAppointment.Capacity)
+		})
+	}
+}
+
+func (s *AppointmentsIntegrationTestSuite) TestDelete() {
+	// an appointment without bookings frees its start time
+	id := uuid.MustParse("eecce415-2d4c-440d-ac90-9780a3bd3371")
+	cancelled, err := s.appointmentsRepository.Delete(context.Background(), id, false)
+	s.Require().NoError(err)
+	s.Assert().Empty(cancelled)
+	_, err = s.appointmentsRepository.FindByID(context.Background(), id)
+	s.Assert().Equal(ErrRecordNotFound, err)
+	err = s.appointmentsRepository.Create(context.Background(), &domain.Appointment{
+		ID:                uuid.MustParse("d93f7ecc-816f-4124-b41e-dcfa58f03761"),
+		TreatmentCenterID: uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
+		StartTime:         time.Date(2021, 11, 13, 8, 0, 0, 0, time.UTC),
+		Capacity:          1,
+	})
+	s.Assert().NoError(err)
+
+	_, err = s.appointmentsRepository.Delete(context.Background(), id, false)
+	s.Assert().Equal(ErrRecordNotFound, err)
+
+	// the active bookings are only cancelled when asked to
+	bookedID := uuid.MustParse("4cdb532d-bfe8-4af6-b9b5-d5078985a350")
+	_, err = s.appointmentsRepository.Delete(context.Background(), bookedID, false)
+	s.Assert().Equal(ErrAppointmentBooked, err)
+	_, err = s.appointmentsRepository.FindByID(context.Background(), bookedID)
+	s.Assert().NoError(err)
+
+	cancelled, err = s.appointmentsRepository.Delete(context.Background(), bookedID, true)
+	s.Require().NoError(err)
+	s.Require().Len(cancelled, 1)
+	s.Assert().Equal(uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"), cancelled[0].ID)
+	s.Assert().Equal(domain.Cancelled, cancelled[0].Status)
+	s.Assert().Equal(domain.ReleaseReasonWithdrawn, cancelled[0].ReleaseReason)
+	s.Assert().Equal("Center in the game", cancelled[0].TreatmentCenterName)
+	_, err = s.appointmentsRepository.FindByID(context.Background(), bookedID)
+	s.Assert().Equal(ErrRecordNotFound, err)
+}
+
 func (s *AppointmentsIntegrationTestSuite) TestAllAvailable() {
-	r, err := s.appointmentsRepository.AllAvailable()
+	r, err := s.appointmentsRepository.AllAvailable(context.Background())
 	s.Assert().NoError(err)
 	s.Assert().Len(r, 7)
 }
 
+func (s *AppointmentsIntegrationTestSuite) TestSearch() {
+	treatmentCenterID := uuid.MustParse("32b2edf2-a380-4436-9f98-b70f78f1934d")
+	first := &domain.Appointment{
+		ID:                uuid.MustParse("8d9eafb0-c1d2-43e4-b5f6-b7c8d9e0f1a2"),
+		TreatmentCenterID: treatmentCenterID,
+		StartTime:         time.Date(2099, 1, 4, 8, 0, 0, 0, time.UTC),
+		Capacity:          1,
+	}
+	second := &domain.Appointment{
+		ID:                uuid.MustParse("9eafb0c1-d2e3-44f5-a6b7-c8d9e0f1a2b3"),
+		TreatmentCenterID: treatmentCenterID,
+		StartTime:         time.Date(2099, 1, 4, 13, 0, 0, 0, time.UTC),
+		Capacity:          1,
+	}
+	for _, appointment := range []*domain.Appointment{first, second} {
+		s.Require().NoError(s.appointmentsRepository.Create(context.Background(), appointment))
+	}
+	now := time.Date(2021, 11, 13, 10, 30, 0, 0, time.UTC)
+	noon := domain.Clock("12:00")
+
+	tests := []struct {
+		name    string
+		search  domain.AppointmentSearch
+		page    domain.PageRequest
+		wantIDs []uuid.UUID
+	}{
+		{
+			name: "Upcoming",
+			page: domain.PageRequest{Limit: 2},
+			wantIDs: []uuid.UUID{
+				uuid.MustParse("96261e44-6d19-4d12-af0b-f28f56f665e2"),
+				uuid.MustParse("cf3101c4-e848-499b-9d40-24eff4479cf2"),
+			},
+		},
+		{
+			name: "AfterCursor",
+			page: domain.PageRequest{
+				Cursor: &domain.Cursor{
+					Sort:      "start_time",
+					Direction: domain.Ascending,
+					Value:     "2021-11-13T11:00:00Z",
+					ID:        uuid.MustParse("cf3101c4-e848-499b-9d40-24eff4479cf2"),
+				},
+			},
+			wantIDs: []uuid.UUID{first.ID, second.ID},
+		},
+		{
+			name:    "Descending",
+			page:    domain.PageRequest{Limit: 2, Sort: "start_time", Direction: domain.Descending},
+			wantIDs: []uuid.UUID{second.ID, first.ID},
+		},
+		{
+			name:    "TreatmentCenterMorning",
+			search:  domain.AppointmentSearch{TreatmentCenterID: &treatmentCenterID, Before: &noon},
+			wantIDs: []uuid.UUID{first.ID},
+		},
+		{
+			name:    "TreatmentCenterAfternoon",
+			search:  domain.AppointmentSearch{TreatmentCenterID: &treatmentCenterID, After: &noon},
+			wantIDs: []uuid.UUID{second.ID},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			r, _, err := s.appointmentsRepository.Search(context.Background(), tc.search, tc.page, now)
+			s.Require().NoError(err)
+			var ids []uuid.UUID
+			for _, appointment := range r {
+				ids = append(ids, appointment.ID)
+			}
+			s.Assert().Equal(tc.wantIDs, ids)
+		})
+	}
+}
+
 func (s *AppointmentsIntegrationTestSuite) TestAllBookedByTreatmentCenterForDate() {
-	r, err := s.appointmentsRepository.AllBookedByTreatmentCenterIDForDate(
+	r, _, err := s.appointmentsRepository.AllBookedByTreatmentCenterIDForDate(context.Background(),
 		uuid.MustParse("10063726-d378-472c-9b50-22a48331635d"),
-		time.Date(2021, 11, 13, 0, 0, 0, 0, time.UTC))
+		time.Date(2021, 11, 13, 0, 0, 0, 0, time.UTC), domain.PageRequest{})
 	s.Assert().NoError(err)
 	s.Assert().Len(r, 1)
 	s.Assert().Equal(uuid.MustParse("4cdb532d-bfe8-4af6-b9b5-d5078985a350"), r[0].ID)
 	s.Assert().Equal(uuid.MustParse("10063726-d378-472c-9b50-22a48331635d"), r[0].TreatmentCenterID)
+	s.Assert().Equal(0, r[0].RemainingSeats)
 }
 
-func TestAppointmentsIntegrationTestSuite(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skipping AppointmentsIntegrationTest in short mode.")
-		return
+func (s *AppointmentsIntegrationTestSuite) TestCreateMissing() {
+	treatmentCenterID := uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef")
+	appointments := []*domain.Appointment{
+		{
+			ID:                uuid.MustParse("0e1f2a3b-4c5d-4e6f-8a9b-0c1d2e3f4a5b"),
+			TreatmentCenterID: treatmentCenterID,
+			StartTime:         time.Date(2021, 11, 13, 8, 0, 0, 0, time.UTC),
+			Capacity:          1,
+		},
+		{
+			ID:                uuid.MustParse("1f2a3b4c-5d6e-4f70-9b0c-1d2e3f4a5b6c"),
+			TreatmentCenterID: treatmentCenterID,
+			StartTime:         time.Date(2021, 11, 13, 12, 0, 0, 0, time.UTC),
+			Capacity:          1,
+		},
 	}
+
+	created, err := s.appointmentsRepository.CreateMissing(context.Background(), appointments)
+	s.Require().NoError(err)
+	s.Require().Len(created, 1)
+	s.Assert().Equal(appointments[1].ID, created[0].ID)
+
+	created, err = s.appointmentsRepository.CreateMissing(context.Background(), appointments)
+	s.Require().NoError(err)
+	s.Assert().Empty(created)
+
+	r, err := s.appointmentsRepository.AllByTreatmentCenterID(context.Background(), treatmentCenterID)
+	s.Require().NoError(err)
+	s.Assert().Len(r, 5)
+}
+
+func TestAppointmentsIntegrationTestSuite(t *testing.T) {
 	t.Parallel()
-	suite.Run(t, new(AppointmentsIntegrationTestSuite))
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &AppointmentsIntegrationTestSuite{IntegrationSuite: backend}
+	})
 }
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"unsafe"
+
+	"github.com/jinzhu/gorm"
+)
+
+// gorm v1 runs its queries without a context, contextDB and contextTx run
+// them with one so the driver cancels them in Postgres when it is done
+
+type contextDB struct {
+	*sql.DB
+	ctx context.Context
+}
+
+func (c contextDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.DB.ExecContext(c.ctx, query, args...)
+}
+
+func (c contextDB) Prepare(query string) (*sql.Stmt, error) {
+	return c.DB.PrepareContext(c.ctx, query)
+}
+
+func (c contextDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.DB.QueryContext(c.ctx, query, args...)
+}
+
+func (c contextDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.DB.QueryRowContext(c.ctx, query, args...)
+}
+
+// Begin starts the transactions gorm runs the creations and updates in
+func (c contextDB) Begin() (*sql.Tx, error) {
+	return c.DB.BeginTx(c.ctx, nil)
+}
+
+type contextTx struct {
+	*sql.Tx
+	ctx context.Context
+}
+
+func (c contextTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.Tx.ExecContext(c.ctx, query, args...)
+}
+
+func (c contextTx) Prepare(query string) (*sql.Stmt, error) {
+	return c.Tx.PrepareContext(c.ctx, query)
+}
+
+func (c contextTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.Tx.QueryContext(c.ctx, query, args...)
+}
+
+func (c contextTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.Tx.QueryRowContext(c.ctx, query, args...)
+}
+
+// commonDBField is the connection of a gorm handle, gorm v1 only sets it when
+// it opens a handle or begins a transaction
+var commonDBField = func() reflect.StructField {
+	field, ok := reflect.TypeOf((*gorm.DB)(nil)).Elem().FieldByName("db")
+	if !ok || field.Type != reflect.TypeOf((*gorm.SQLCommon)(nil)).Elem() {
+		panic("repository: gorm.DB has no db connection field")
+	}
+	return field
+}()
+
+// withCommonDB returns a clone of db running its queries on conn. Unlike a
+// handle opened on conn, the clone keeps the settings of db: its log mode,
+// logger, callbacks and table names.
+func withCommonDB(db *gorm.DB, conn gorm.SQLCommon) *gorm.DB {
+	clone := db.New()
+	field := reflect.ValueOf(clone).Elem().FieldByIndex(commonDBField.Index)
+	reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(&conn).Elem())
+	clone.Dialect().SetDB(conn)
+	return clone
+}
+
+// withContext returns a db running its queries with ctx, a db already
+// bound to a context or a transaction is returned as is
+func withContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	sqlDB, ok := db.CommonDB().(*sql.DB)
+	if !ok {
+		return db
+	}
+	return withCommonDB(db, contextDB{DB: sqlDB, ctx: ctx})
+}
+
+// transaction runs fc in a transaction whose queries run with ctx, it commits
+// when fc returns nil and rolls back otherwise. The transaction is rolled
+// back as well when ctx is done before the commit.
+func transaction(ctx context.Context, db *gorm.DB, fc func(tx *gorm.DB) error) (err error) {
+	var sqlDB *sql.DB
+	switch conn := db.CommonDB().(type) {
+	case *sql.DB:
+		sqlDB = conn
+	case contextDB:
+		sqlDB = conn.DB
+	default:
+		return savepoint(db, fc)
+	}
+	sqlTx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	tx := withCommonDB(db, contextTx{Tx: sqlTx, ctx: ctx})
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			_ = sqlTx.Rollback()
+		}
+	}()
+	err = fc(tx.Debug())
+	if err == nil {
+		err = sqlTx.Commit()
+	}
+	panicked = false
+	return err
+}
+
+// savepoint runs fc in a transaction already started, like a unit of work, the
+// changes of fc alone are rolled back when it fails so the transaction goes on
+func savepoint(tx *gorm.DB, fc func(tx *gorm.DB) error) error {
+	if err := tx.Exec("SAVEPOINT nested").Error; err != nil {
+		return err
+	}
+	if err := fc(tx); err != nil {
+		if rollbackErr := tx.Exec("ROLLBACK TO SAVEPOINT nested").Error; rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+	return tx.Exec("RELEASE SAVEPOINT nested").Error
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/testutils"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type ContextIntegrationTestSuite struct {
+	testutils.IntegrationSuite
+	patientsRepository Patients
+}
+
+func (s *ContextIntegrationTestSuite) SetupSuite() {
+	s.IntegrationSuite.SetupSuite()
+	s.patientsRepository = NewPatients(s.IntegrationSuite.DB(), zap.NewExample())
+}
+
+func (s *ContextIntegrationTestSuite) TearDownSuite() {
+	s.IntegrationSuite.TearDownSuite()
+}
+
+func (s *ContextIntegrationTestSuite) TestCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.patientsRepository.FindByID(ctx, uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"))
+	s.Assert().Equal(ErrQueryCanceled, err)
+	err = s.patientsRepository.Merge(ctx, uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"),
+		uuid.MustParse("d93f7ecc-816f-4124-b41e-dcfa58f03761"))
+	s.Assert().Equal(ErrQueryCanceled, err)
+}
+
+func (s *ContextIntegrationTestSuite) TestTimeout() {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// the query is cancelled in postgres instead of running until its end
+	start := time.Now()
+	err := withContext(ctx, s.IntegrationSuite.DB()).Exec("SELECT pg_sleep(10)").Error
+	s.Assert().Equal(ErrQueryCanceled, handleGormError(err, zap.NewExample()))
+	s.Assert().Less(time.Since(start), 5*time.Second)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = transaction(ctx, s.IntegrationSuite.DB(), func(tx *gorm.DB) error {
+		return tx.Exec("SELECT pg_sleep(10)").Error
+	})
+	s.Assert().Equal(ErrQueryCanceled, handleGormError(err, zap.NewExample()))
+	s.Assert().Less(time.Since(start), 10*time.Second)
+
+	// the connection is still usable
+	_, err = s.patientsRepository.FindByID(context.Background(), uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"))
+	s.Assert().NoError(err)
+}
+
+func (s *ContextIntegrationTestSuite) TestWriteTimeout() {
+	// another transaction holds the table so that the insert waits for it
+	lock := s.IntegrationSuite.DB().Begin()
+	s.Require().NoError(lock.Exec("LOCK TABLE patients IN EXCLUSIVE MODE").Error)
+	defer lock.Rollback()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := s.patientsRepository.Create(ctx, &domain.Patient{
+		ID:        uuid.MustParse("5b0c2e8e-6a5f-4d1b-9a57-2f7f0c1a3e44"),
+		Email:     "patient.timeout@some.com",
+		FirstName: "Patient",
+		LastName:  "Timeout",
+	})
+	s.Assert().Equal(ErrQueryCanceled, err)
+	s.Assert().Less(time.Since(start), 5*time.Second)
+}
+
+func TestContextIntegrationTestSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping ContextIntegrationTest in short mode.")
+		return
+	}
+	t.Parallel()
+	suite.Run(t, new(ContextIntegrationTestSuite))
+}
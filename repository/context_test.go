@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y9mo/covidvax/domain"
+	"go.uber.org/zap"
+)
+
+func TestContextKeepsSettings(t *testing.T) {
+	db, err := OpenSQLite(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	queries := 0
+	db.Callback().Query().After("gorm:query").Register("test:count", func(*gorm.Scope) { queries++ })
+	db.BlockGlobalUpdate(true)
+
+	var patients []*domain.Patient
+	require.NoError(t, withContext(context.Background(), db).Find(&patients).Error)
+	assert.Equal(t, 1, queries)
+	err = withContext(context.Background(), db).Model(&domain.Patient{}).Update("phone", "").Error
+	assert.EqualError(t, err, "missing WHERE clause while updating")
+
+	err = transaction(context.Background(), db, func(tx *gorm.DB) error {
+		return tx.Find(&patients).Error
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, queries)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = withContext(ctx, db).Find(&patients).Error
+	assert.Equal(t, ErrQueryCanceled, handleGormError(err, zap.NewExample()))
+}
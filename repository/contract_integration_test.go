@@ -0,0 +1,33 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/y9mo/covidvax/repository"
+	"github.com/y9mo/covidvax/repository/repositorytest"
+	"github.com/y9mo/covidvax/testutils"
+	"go.uber.org/zap"
+)
+
+func TestPostgresContract(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping PostgresContract in short mode.")
+		return
+	}
+	t.Parallel()
+	postgres := &testutils.IntegrationSuite{}
+	postgres.SetT(t)
+	postgres.SetupSuite()
+	defer postgres.TearDownSuite()
+
+	logger := zap.NewExample()
+	suite.Run(t, &repositorytest.ContractSuite{NewBackend: func() repositorytest.Backend {
+		postgres.Cleanup()
+		return repositorytest.Backend{
+			Repositories: repository.NewRepositories(postgres.DB(), logger),
+			Reminders:    repository.NewReminders(postgres.DB(), logger),
+			UnitOfWork:   repository.NewUnitOfWork(postgres.DB(), logger),
+		}
+	}})
+}
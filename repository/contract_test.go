@@ -0,0 +1,26 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/y9mo/covidvax/repository"
+	"github.com/y9mo/covidvax/repository/repositorytest"
+	"go.uber.org/zap"
+)
+
+func TestSQLiteContract(t *testing.T) {
+	t.Parallel()
+	logger := zap.NewNop()
+	suite.Run(t, &repositorytest.ContractSuite{NewBackend: func() repositorytest.Backend {
+		db, err := repository.OpenSQLite(":memory:")
+		if err != nil {
+			panic(err)
+		}
+		return repositorytest.Backend{
+			Repositories: repository.NewRepositories(db, logger),
+			Reminders:    repository.NewReminders(db, logger),
+			UnitOfWork:   repository.NewUnitOfWork(db, logger),
+		}
+	}})
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/y9mo/covidvax/domain"
+	"go.uber.org/zap"
+)
+
+type Doses interface {
+	Create(ctx context.Context, dose *domain.Dose) ([]*domain.PatientBooking, error)
+	AllByPatientID(ctx context.Context, patientID uuid.UUID, request domain.PageRequest) ([]*domain.Dose,
+		domain.Page, error)
+}
+type doses struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewDoses(db *gorm.DB, logger *zap.Logger) Doses {
+	return doses{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create records a dose as the next one of its patient. The dose was given
+// whatever the bookings of the patient: the next active ones that don't follow
+// the vaccine schedule after the dose are cancelled and returned.
+func (r doses) Create(ctx context.Context, dose *domain.Dose) (cancelled []*domain.PatientBooking, err error) {
+	err = transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := administer(tx, dose); err != nil {
+			return err
+		}
+		var err error
+		cancelled, err = cancelOffSchedule(tx, dose)
+		return err
+	})
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// administer numbers the dose after the last one of its patient and inserts it,
+// concurrent inserts for a patient fail on the (patient_id, number) unique index
+func administer(tx *gorm.DB, dose *domain.Dose) error {
+	var last struct{ Number int }
+	err := tx.Model(&domain.Dose{}).Select("COALESCE(MAX(number), 0) AS number").
+		Where("patient_id = ?", dose.PatientID).Scan(&last).Error
+	if err != nil {
+		return err
+	}
+	dose.Number = last.Number + 1
+	return tx.Create(dose).Error
+}
+
+// dosesSorting sorts the doses by number by default
+var dosesSorting = sorting{
+	fields: map[string]sortField{
+		"number":          {column: "doses.number"},
+		"administered_at": {column: "doses.administered_at"},
+	},
+	idColumn:    "doses.id",
+	defaultSort: "number",
+}
+
+func (r doses) AllByPatientID(ctx context.Context, patientID uuid.UUID,
+	request domain.PageRequest) (result []*domain.Dose, page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	query := db.Model(&domain.Dose{}).Where("patient_id = ?", patientID)
+	page, err = paginate(db, query, request, dosesSorting, &result)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// checkDoseSchedule returns an error when a dose at date would break the
+// schedule of the vaccine the patient last received
+func checkDoseSchedule(tx *gorm.DB, patientID uuid.UUID, at time.Time) error {
+	last := domain.Dose{}
+	err := tx.Where("patient_id = ?", patientID).Order("number DESC").First(&last).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	product := domain.VaccineProduct{}
+	if err = tx.Where("id = ?", last.VaccineProductID).First(&product).Error; err != nil {
+		return err
+	}
+	return product.CheckNextDose(&last, at)
+}
+
+// cancelOffSchedule cancels the active bookings of the patient after a dose
+// until the first one following the schedule of the vaccine of the dose, and
+// returns them
+func cancelOffSchedule(tx *gorm.DB, dose *domain.Dose) ([]*domain.PatientBooking, error) {
+	var next []*domain.PatientBooking
+	err := patientBookings(tx).
+		Where("appointment_bookings.patient_id = ? AND appointment_bookings.status IN (?)", dose.PatientID,
+			domain.ActiveStatuses).
+		Where("a.start_time > ?", dose.AdministeredAt).
+		Order("a.start_time, appointment_bookings.id").Scan(&next).Error
+	if err != nil || len(next) == 0 {
+		return nil, err
+	}
+	product := domain.VaccineProduct{}
+	if err = tx.Where("id = ?", dose.VaccineProductID).First(&product).Error; err != nil {
+		return nil, err
+	}
+	var ids []uuid.UUID
+	for _, booking := range next {
+		if product.CheckNextDose(dose, booking.StartTime) == nil {
+			break
+		}
+		if err = release(tx, booking.ID, domain.Cancelled, domain.ReleaseReasonOffSchedule); err != nil {
+			return nil, err
+		}
+		ids = append(ids, booking.ID)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var cancelled []*domain.PatientBooking
+	err = patientBookings(tx).Where("appointment_bookings.id IN (?)", ids).
+		Order("a.start_time, appointment_bookings.id").Scan(&cancelled).Error
+	return cancelled, err
+}
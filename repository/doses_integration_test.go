@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/testutils"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type DosesIntegrationTestSuite struct {
+	testutils.IntegrationSuite
+	dosesRepository Doses
+}
+
+func (s *DosesIntegrationTestSuite) SetupSuite() {
+	s.IntegrationSuite.SetupSuite()
+	s.dosesRepository = NewDoses(s.IntegrationSuite.DB(), zap.NewExample())
+}
+
+func (s *DosesIntegrationTestSuite) TearDownSuite() {
+	s.IntegrationSuite.TearDownSuite()
+}
+
+func (s *DosesIntegrationTestSuite) TestCreate() {
+	tests := []struct {
+		name       string
+		dose       *domain.Dose
+		wantNumber int
+		wantErr    error
+	}{
+		{
+			name: "FirstDose",
+			dose: &domain.Dose{
+				ID:               uuid.MustParse("1b3d5f7a-9c0e-4a2b-8d4f-6a8c0e2b4d6f"),
+				PatientID:        uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"),
+				VaccineProductID: uuid.MustParse("0f9e8d7c-6b5a-4493-8271-605f4e3d2c1b"),
+				AdministeredAt:   time.Date(2021, 11, 20, 9, 0, 0, 0, time.UTC),
+			},
+			wantNumber: 1,
+		},
+		{
+			name: "SecondDose",
+			dose: &domain.Dose{
+				ID:               uuid.MustParse("2c4e6a8b-0d1f-4b3c-9e5a-7b9d1f3c5e7a"),
+				PatientID:        uuid.MustParse("3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2"),
+				VaccineProductID: uuid.MustParse("7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8"),
+				AdministeredAt:   time.Date(2021, 11, 25, 9, 0, 0, 0, time.UTC),
+			},
+			wantNumber: 2,
+		},
+		{
+			name: "UnknownProduct",
+			dose: &domain.Dose{
+				ID:               uuid.MustParse("3d5f7b9c-1e2a-4c4d-8f6b-8c0e2a4d6f8b"),
+				PatientID:        uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+				VaccineProductID: uuid.MustParse("4f6a8c0e-2b3d-4d5e-9a7c-9d1f3b5e7a9c"),
+				AdministeredAt:   time.Date(2021, 11, 25, 9, 0, 0, 0, time.UTC),
+			},
+			wantErr: ErrInvalidID,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			_, err := s.dosesRepository.Create(context.Background(), tc.dose)
+			if tc.wantErr != nil {
+				s.Assert().Equal(tc.wantErr, err)
+				return
+			}
+			s.Require().NoError(err)
+			s.Assert().Equal(tc.wantNumber, tc.dose.Number)
+		})
+	}
+}
+
+func (s *DosesIntegrationTestSuite) TestAllByPatientID() {
+	patientID := uuid.MustParse("3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2")
+	_, err := s.dosesRepository.Create(context.Background(), &domain.Dose{
+		ID:               uuid.New(),
+		PatientID:        patientID,
+		VaccineProductID: uuid.MustParse("7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8"),
+		AdministeredAt:   time.Date(2021, 11, 25, 9, 0, 0, 0, time.UTC),
+	})
+	s.Require().NoError(err)
+
+	doses, _, err := s.dosesRepository.AllByPatientID(context.Background(), patientID, domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(doses, 2)
+	s.Assert().Equal(1, doses[0].Number)
+	s.Assert().Equal(2, doses[1].Number)
+	s.Assert().Equal(uuid.MustParse("7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8"), doses[1].VaccineProductID)
+}
+
+func TestDosesIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &DosesIntegrationTestSuite{IntegrationSuite: backend}
+	})
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/y9mo/covidvax/domain"
+	"go.uber.org/zap"
+)
+
+type EligibilityRules interface {
+	Create(ctx context.Context, rule *domain.EligibilityRule) error
+	Update(ctx context.Context, rule *domain.EligibilityRule) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.EligibilityRule, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	All(ctx context.Context, request domain.PageRequest) ([]*domain.EligibilityRule, domain.Page, error)
+	Effective(ctx context.Context, at time.Time) ([]*domain.EligibilityRule, error)
+}
+type eligibilityRules struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewEligibilityRules(db *gorm.DB, logger *zap.Logger) EligibilityRules {
+	return eligibilityRules{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r eligibilityRules) Create(ctx context.Context, rule *domain.EligibilityRule) error {
+	err := withContext(ctx, r.db).Debug().Create(rule).Error
+	return handleGormError(err, r.logger)
+}
+
+// Update saves the fields of a rule, empty ones included
+func (r eligibilityRules) Update(ctx context.Context, rule *domain.EligibilityRule) error {
+	db := withContext(ctx, r.db).Debug()
+	result := db.Model(&domain.EligibilityRule{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+		"name":            rule.Name,
+		"min_age":         rule.MinAge,
+		"risk_categories": rule.RiskCategories,
+		"professions":     rule.Professions,
+		"effective_from":  rule.EffectiveFrom,
+		"effective_until": rule.EffectiveUntil,
+	})
+	if err := handleGormError(result.Error, r.logger); err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r eligibilityRules) FindByID(ctx context.Context, id uuid.UUID) (*domain.EligibilityRule, error) {
+	rule := domain.EligibilityRule{}
+	err := withContext(ctx, r.db).Debug().Where("id = ?", id).Find(&rule).Error
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r eligibilityRules) Delete(ctx context.Context, id uuid.UUID) error {
+	result := withContext(ctx, r.db).Debug().Where("id = ?", id).Delete(&domain.EligibilityRule{})
+	if err := handleGormError(result.Error, r.logger); err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// eligibilityRulesSorting sorts the rules by the date they take effect by default
+var eligibilityRulesSorting = sorting{
+	fields: map[string]sortField{
+		"effective_from": {column: "eligibility_rules.effective_from"},
+		"name":           {column: "eligibility_rules.name"},
+		"created_at":     {column: "eligibility_rules.created_at"},
+	},
+	idColumn:    "eligibility_rules.id",
+	defaultSort: "effective_from",
+}
+
+func (r eligibilityRules) All(ctx context.Context,
+	request domain.PageRequest) (result []*domain.EligibilityRule, page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	page, err = paginate(db, db.Model(&domain.EligibilityRule{}), request,
+		eligibilityRulesSorting, &result)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// Effective returns the rules in effect at a time, by the date they took effect
+func (r eligibilityRules) Effective(ctx context.Context, at time.Time) ([]*domain.EligibilityRule, error) {
+	result, err := effectiveRules(withContext(ctx, r.db).Debug(), at)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func effectiveRules(db *gorm.DB, at time.Time) (result []*domain.EligibilityRule, err error) {
+	err = db.Where("effective_from <= ? AND (effective_until IS NULL OR effective_until > ?)", at, at).
+		Order("effective_from, id").
+		Find(&result).Error
+	return result, err
+}
+
+// checkEligibility returns a domain.IneligibleError when the patient doesn't
+// meet a rule in effect for an appointment starting at start
+func checkEligibility(tx *gorm.DB, patientID uuid.UUID, start time.Time) error {
+	rules, err := effectiveRules(tx, time.Now())
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	patient := domain.Patient{}
+	err = tx.Where("id = ?", patientID).First(&patient).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrInvalidID
+	}
+	if err != nil {
+		return err
+	}
+	if rule := domain.FailedEligibilityRule(rules, &patient, start); rule != nil {
+		return &domain.IneligibleError{Rule: rule}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/testutils"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type EligibilityRulesIntegrationTestSuite struct {
+	testutils.IntegrationSuite
+	eligibilityRulesRepository EligibilityRules
+}
+
+func (s *EligibilityRulesIntegrationTestSuite) SetupSuite() {
+	s.IntegrationSuite.SetupSuite()
+	s.eligibilityRulesRepository = NewEligibilityRules(s.IntegrationSuite.DB(), zap.NewExample())
+}
+
+func (s *EligibilityRulesIntegrationTestSuite) TearDownSuite() {
+	s.IntegrationSuite.TearDownSuite()
+}
+
+func (s *EligibilityRulesIntegrationTestSuite) TestFindByID() {
+	got, err := s.eligibilityRulesRepository.FindByID(context.Background(),
+		uuid.MustParse("0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"))
+	s.Require().NoError(err)
+	s.Assert().Equal("Elderly and at risk", got.Name)
+	s.Require().NotNil(got.MinAge)
+	s.Assert().Equal(75, *got.MinAge)
+	s.Assert().Equal(domain.StringList{"dialysis", "immunocompromised"}, got.RiskCategories)
+	s.Assert().Equal(domain.StringList{"nurse", "doctor"}, got.Professions)
+	s.Require().NotNil(got.EffectiveUntil)
+
+	_, err = s.eligibilityRulesRepository.FindByID(context.Background(),
+		uuid.MustParse("d93f7ecc-816f-4124-b41e-dcfa58f03761"))
+	s.Assert().Equal(ErrRecordNotFound, err)
+}
+
+func (s *EligibilityRulesIntegrationTestSuite) TestEffective() {
+	adults := 18
+	rule := &domain.EligibilityRule{
+		ID:            uuid.MustParse("5d6e7f80-9a1b-4c2d-8e3f-4a5b6c7d8e9f"),
+		Name:          "Adults",
+		MinAge:        &adults,
+		EffectiveFrom: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	s.Require().NoError(s.eligibilityRulesRepository.Create(context.Background(), rule))
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want []string
+	}{
+		{name: "Before", at: time.Date(2021, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{name: "First", at: time.Date(2021, 1, 18, 0, 0, 0, 0, time.UTC), want: []string{"Elderly and at risk"}},
+		{name: "Until", at: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), want: []string{"Adults"}},
+		{name: "Open", at: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC), want: []string{"Adults"}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			got, err := s.eligibilityRulesRepository.Effective(context.Background(), tc.at)
+			s.Require().NoError(err)
+			var names []string
+			for _, rule := range got {
+				names = append(names, rule.Name)
+			}
+			s.Assert().Equal(tc.want, names)
+		})
+	}
+}
+
+func (s *EligibilityRulesIntegrationTestSuite) TestUpdateAndDelete() {
+	id := uuid.MustParse("0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d")
+	rule := &domain.EligibilityRule{
+		ID:            id,
+		Name:          "Health workers",
+		Professions:   domain.StringList{"nurse"},
+		EffectiveFrom: time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC),
+	}
+	s.Require().NoError(s.eligibilityRulesRepository.Update(context.Background(), rule))
+
+	got, err := s.eligibilityRulesRepository.FindByID(context.Background(), id)
+	s.Require().NoError(err)
+	s.Assert().Equal("Health workers", got.Name)
+	s.Assert().Nil(got.MinAge)
+	s.Assert().Empty(got.RiskCategories)
+	s.Assert().Nil(got.EffectiveUntil)
+
+	s.Require().NoError(s.eligibilityRulesRepository.Delete(context.Background(), id))
+	_, err = s.eligibilityRulesRepository.FindByID(context.Background(), id)
+	s.Assert().Equal(ErrRecordNotFound, err)
+	s.Assert().Equal(ErrRecordNotFound, s.eligibilityRulesRepository.Delete(context.Background(), id))
+	s.Assert().Equal(ErrRecordNotFound, s.eligibilityRulesRepository.Update(context.Background(), rule))
+}
+
+func TestEligibilityRulesIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &EligibilityRulesIntegrationTestSuite{IntegrationSuite: backend}
+	})
+}
@@ -1,19 +1,35 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
 	"github.com/jinzhu/gorm"
 	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 	"go.uber.org/zap"
 )
 
 const pqUniqueConstraintError = "23505"
+const pqForeignKeyViolationError = "23503"
+const pqQueryCanceledError = "57014"
 
 var ErrRecordNotFound = errors.New("record not found")
 var ErrUniqueConstraintFailure = errors.New("record already exist")
 var ErrInvalidID = errors.New("invalid id")
+var ErrInvalidStatusTransition = errors.New("invalid status transition")
+var ErrAppointmentNotAvailable = errors.New("appointment is not available")
+var ErrInsufficientStock = errors.New("not enough vaccine doses in stock")
+var ErrUnknownLot = errors.New("unknown vaccine lot")
+var ErrLotUnusable = errors.New("vaccine lot is expired or out of stock")
+var ErrAppointmentBooked = errors.New("appointment has bookings")
+var ErrTreatmentCenterBooked = errors.New("treatment center has upcoming bookings")
+var ErrCapacityBelowBookings = errors.New("capacity is below the seats booked")
+var ErrPatientsConflict = errors.New("patients are booked on the same appointment or have a dose of the same number")
+var ErrInvalidSort = errors.New("invalid sort field")
+var ErrInvalidCursor = errors.New("cursor doesn't match the sort")
+var ErrQueryCanceled = errors.New("query canceled or timed out")
 
 func handleGormError(err error, logger *zap.Logger) error {
 	fmt.Println(err)
@@ -21,6 +37,9 @@ func handleGormError(err error, logger *zap.Logger) error {
 	case gorm.ErrRecordNotFound:
 		return ErrRecordNotFound
 	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrQueryCanceled
+	}
 
 	switch v := err.(type) {
 	case *pq.Error:
@@ -32,9 +51,19 @@ func handleGormError(err error, logger *zap.Logger) error {
 		switch v.Code {
 		case pqUniqueConstraintError:
 			return ErrUniqueConstraintFailure
+		case pqForeignKeyViolationError:
+			return ErrInvalidID
+		case pqQueryCanceledError:
+			return ErrQueryCanceled
 		default:
 			return v
 		}
+	case sqlite3.Error:
+		logger.Debug("SQLiteError",
+			zap.Int("SQLiteCode", int(v.Code)),
+			zap.Int("SQLiteExtendedCode", int(v.ExtendedCode)),
+		)
+		return sqliteError(v)
 	default:
 		return err
 	}
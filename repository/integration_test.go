@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+	"github.com/y9mo/covidvax/testutils"
+)
+
+// runOnBackends runs an integration suite against an in-memory SQLite database,
+// then against Postgres unless in short mode
+func runOnBackends(t *testing.T, newSuite func(backend testutils.IntegrationSuite) suite.TestingSuite) {
+	t.Run("SQLite", func(t *testing.T) {
+		suite.Run(t, newSuite(testutils.IntegrationSuite{Open: func() (*gorm.DB, error) {
+			return OpenSQLite(":memory:")
+		}}))
+	})
+	t.Run("Postgres", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skipping the Postgres integration tests in short mode.")
+			return
+		}
+		suite.Run(t, newSuite(testutils.IntegrationSuite{}))
+	})
+}
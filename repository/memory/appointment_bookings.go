@@ -0,0 +1,342 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type appointmentBookings struct {
+	db db
+}
+
+func NewAppointmentBookings(store *Store) repository.AppointmentBookings {
+	return appointmentBookings{db: store}
+}
+
+// Create books a seat of an appointment, it fails with domain.ErrAppointmentStarted
+// when the appointment has already started, with ErrAppointmentNotAvailable
+// when every seat of the appointment is already held, with one of the domain
+// dose errors when the appointment doesn't fit the patient vaccine schedule and
+// with a domain.IneligibleError when the patient doesn't meet a rule in effect
+func (r appointmentBookings) Create(ctx context.Context, appointmentBooking *domain.AppointmentBooking) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		return book(t, appointmentBooking)
+	})
+}
+
+// book inserts a booking if its appointment is still to start and has a
+// remaining seat, its date follows the vaccine schedule of the patient and the
+// patient meets the eligibility rules in effect
+func book(t *tables, appointmentBooking *domain.AppointmentBooking) error {
+	appointment, err := findAppointment(t, appointmentBooking.AppointmentID)
+	if err != nil {
+		return err
+	}
+	if !appointment.StartTime.After(time.Now()) {
+		return domain.ErrAppointmentStarted
+	}
+
+	held := 0
+	for _, booking := range t.bookings {
+		if booking.AppointmentID != appointment.ID || !holding(booking.Status) {
+			continue
+		}
+		if booking.PatientID == appointmentBooking.PatientID {
+			return repository.ErrUniqueConstraintFailure
+		}
+		held++
+	}
+	if held >= appointment.Capacity {
+		return repository.ErrAppointmentNotAvailable
+	}
+	if err = checkDoseSchedule(t, appointmentBooking.PatientID, appointment.StartTime); err != nil {
+		return err
+	}
+
+	patient, ok := t.patients[appointmentBooking.PatientID]
+	if !ok {
+		return repository.ErrInvalidID
+	}
+	if err = checkEligibility(t, &patient, appointment.StartTime); err != nil {
+		return err
+	}
+	if _, ok := t.bookings[appointmentBooking.ID]; ok {
+		return repository.ErrUniqueConstraintFailure
+	}
+	appointmentBooking.CreatedAt, appointmentBooking.UpdatedAt = created(appointmentBooking.CreatedAt,
+		appointmentBooking.UpdatedAt)
+	t.setBooking(appointmentBooking.ID, *appointmentBooking)
+	return nil
+}
+
+// Update saves every field of a booking
+func (r appointmentBookings) Update(ctx context.Context, appointmentBooking *domain.AppointmentBooking) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		if _, ok := t.bookings[appointmentBooking.ID]; !ok {
+			return nil
+		}
+		appointmentBooking.UpdatedAt = now()
+		t.setBooking(appointmentBooking.ID, *appointmentBooking)
+		return nil
+	})
+}
+
+// Delete deletes a booking with its reminders, a booking referenced by a dose
+// or a waitlist entry fails with ErrInvalidID
+func (r appointmentBookings) Delete(ctx context.Context, appointmentBooking *domain.AppointmentBooking) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		id := appointmentBooking.ID
+		for _, dose := range t.doses {
+			if dose.AppointmentBookingID != nil && *dose.AppointmentBookingID == id {
+				return repository.ErrInvalidID
+			}
+		}
+		for _, entry := range t.waitlist {
+			if entry.AppointmentBookingID != nil && *entry.AppointmentBookingID == id {
+				return repository.ErrInvalidID
+			}
+		}
+		for key := range t.reminders {
+			if key.appointmentBookingID == id {
+				t.deleteReminder(key)
+			}
+		}
+		t.deleteBooking(id)
+		return nil
+	})
+}
+
+func (r appointmentBookings) FindByID(ctx context.Context, id uuid.UUID) (booking *domain.AppointmentBooking,
+	err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		found, ok := t.bookings[id]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		booking = &found
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+// Confirm moves a booking awaiting confirmation to confirmed,
+// any other status will return ErrInvalidStatusTransition.
+// Confirming a booking offered from the waitlist fulfills the waitlist entry.
+func (r appointmentBookings) Confirm(ctx context.Context, id uuid.UUID) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		booking, ok := t.bookings[id]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		if booking.Status != domain.AwaitingConfirmation {
+			return repository.ErrInvalidStatusTransition
+		}
+		booking.Status = domain.Confirmed
+		booking.UpdatedAt = now()
+		t.setBooking(id, booking)
+		settleOffers(t, []uuid.UUID{id}, domain.Fulfilled)
+		return nil
+	})
+}
+
+// ExpireUnconfirmed releases the bookings still awaiting confirmation
+// after their expiration date and returns them, the waitlist offers among
+// them lapse
+func (r appointmentBookings) ExpireUnconfirmed(ctx context.Context, now time.Time,
+	reason string) (result []*domain.AppointmentBooking, err error) {
+	err = r.db.transaction(ctx, func(t *tables) error {
+		var ids []uuid.UUID
+		for id, booking := range t.bookings {
+			booking := booking
+			if booking.Status != domain.AwaitingConfirmation || booking.ExpiresAt == nil ||
+				!booking.ExpiresAt.Before(now) {
+				continue
+			}
+			releasedAt := now
+			booking.Status = domain.Expired
+			booking.ReleasedAt = &releasedAt
+			booking.ReleaseReason = reason
+			booking.UpdatedAt = &releasedAt
+			t.setBooking(id, booking)
+			result = append(result, &booking)
+			ids = append(ids, id)
+		}
+		settleOffers(t, ids, domain.Lapsed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return compareTimes(result[i].ExpiresAt, result[i].ID, result[j].ExpiresAt, result[j].ID) < 0
+	})
+	return result, nil
+}
+
+// Cancel releases an active booking, the booking is kept with its reason
+func (r appointmentBookings) Cancel(ctx context.Context, id uuid.UUID, reason string) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		return release(t, id, domain.Cancelled, reason)
+	})
+}
+
+// Reschedule atomically cancels an active booking and books the same patient
+// on another appointment, the new booking is returned
+func (r appointmentBookings) Reschedule(ctx context.Context, id uuid.UUID,
+	appointmentID uuid.UUID) (*domain.AppointmentBooking, error) {
+	var rescheduled *domain.AppointmentBooking
+	err := r.db.transaction(ctx, func(t *tables) error {
+		current, ok := t.bookings[id]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		if !current.Status.Active() {
+			return repository.ErrInvalidStatusTransition
+		}
+		if current.AppointmentID == appointmentID {
+			rescheduled = &current
+			return nil
+		}
+
+		if err := release(t, id, domain.Cancelled, domain.ReleaseReasonRescheduled); err != nil {
+			return err
+		}
+		rescheduled = &domain.AppointmentBooking{
+			ID:            uuid.New(),
+			AppointmentID: appointmentID,
+			PatientID:     current.PatientID,
+			Status:        current.Status,
+			ExpiresAt:     current.ExpiresAt,
+		}
+		return book(t, rescheduled)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(ctx, rescheduled.ID)
+}
+
+// RecordOutcome records what happened at the appointment of a confirmed booking,
+// an administration also records the dose received by the patient and takes it
+// from the lot of the treatment center
+func (r appointmentBookings) RecordOutcome(ctx context.Context, id uuid.UUID, outcome *domain.BookingOutcome,
+	at time.Time) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		current, ok := t.bookings[id]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		if current.Status != domain.Confirmed {
+			return repository.ErrInvalidStatusTransition
+		}
+
+		recordedAt := at
+		current.Status = outcome.Status
+		current.LotNumber = outcome.LotNumber
+		current.AdministeredBy = outcome.AdministeredBy
+		current.OutcomeRecordedAt = &recordedAt
+		current.UpdatedAt = now()
+		t.setBooking(id, current)
+		if outcome.Status != domain.Administered {
+			return nil
+		}
+		err := useDose(t, current.AppointmentID, *outcome.VaccineProductID, outcome.LotNumber, at)
+		if err != nil {
+			return err
+		}
+		return administer(t, &domain.Dose{
+			ID:                   uuid.New(),
+			PatientID:            current.PatientID,
+			VaccineProductID:     *outcome.VaccineProductID,
+			AppointmentBookingID: &current.ID,
+			AdministeredAt:       at,
+		})
+	})
+}
+
+// release moves an active booking to a released status, a waitlist
+// offer of the booking lapses
+func release(t *tables, id uuid.UUID, status domain.AppointmentStatus, reason string) error {
+	booking, ok := t.bookings[id]
+	if !ok {
+		return repository.ErrRecordNotFound
+	}
+	if !booking.Status.Active() {
+		return repository.ErrInvalidStatusTransition
+	}
+	booking.Status = status
+	booking.ReleasedAt = now()
+	booking.ReleaseReason = reason
+	booking.UpdatedAt = booking.ReleasedAt
+	t.setBooking(id, booking)
+	settleOffers(t, []uuid.UUID{id}, domain.Lapsed)
+	return nil
+}
+
+// patientBookingsSorting sorts the bookings of a patient by appointment by
+// default, the most recent first
+var patientBookingsSorting = sorting{
+	fields: map[string]sortField{
+		"start_time": func(item interface{}) interface{} { return item.(*domain.PatientBooking).StartTime },
+		"created_at": func(item interface{}) interface{} { return item.(*domain.PatientBooking).CreatedAt },
+	},
+	defaultSort:      "start_time",
+	defaultDirection: domain.Descending,
+}
+
+// AllByPatientID returns the bookings of a patient
+func (r appointmentBookings) AllByPatientID(ctx context.Context, patientID uuid.UUID,
+	request domain.PageRequest) (result []*domain.PatientBooking, page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, booking := range t.bookings {
+			if booking.PatientID == patientID {
+				result = append(result, patientBooking(t, booking))
+			}
+		}
+		page, err = paginate(&result, request, patientBookingsSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// patientBooking returns a booking along with its appointment start time and
+// treatment center, deleted or not
+func patientBooking(t *tables, booking domain.AppointmentBooking) *domain.PatientBooking {
+	appointment := t.appointments[booking.AppointmentID]
+	treatmentCenter := t.treatmentCenters[appointment.TreatmentCenterID]
+	return &domain.PatientBooking{
+		AppointmentBooking:     booking,
+		StartTime:              appointment.StartTime,
+		TreatmentCenterID:      treatmentCenter.ID,
+		TreatmentCenterName:    treatmentCenter.Name,
+		TreatmentCenterAddress: treatmentCenter.Address,
+	}
+}
+
+// BookedAt tells whether the patient has a booking, whatever its status, at an
+// appointment of the treatment center
+func (r appointmentBookings) BookedAt(ctx context.Context, patientID uuid.UUID,
+	treatmentCenterID uuid.UUID) (booked bool, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, booking := range t.bookings {
+			if booking.PatientID == patientID &&
+				t.appointments[booking.AppointmentID].TreatmentCenterID == treatmentCenterID {
+				booked = true
+				return nil
+			}
+		}
+		return nil
+	})
+	return booked, err
+}
@@ -0,0 +1,372 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type appointments struct {
+	db db
+}
+
+func NewAppointments(store *Store) repository.Appointments {
+	return appointments{db: store}
+}
+
+// Create opens an appointment, it fails with ErrInsufficientStock when the
+// treatment center hasn't enough doses for the seats of its upcoming appointments
+func (r appointments) Create(ctx context.Context, appointment *domain.Appointment) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		if _, err := lockTreatmentCenter(t, appointment.TreatmentCenterID); err != nil {
+			return err
+		}
+		if err := insertAppointment(t, appointment); err != nil {
+			return err
+		}
+		return checkStock(t, appointment.TreatmentCenterID, upcoming(appointment.StartTime))
+	})
+}
+
+// insertAppointment stores an appointment, a treatment center has one
+// appointment not deleted at a time
+func insertAppointment(t *tables, appointment *domain.Appointment) error {
+	if _, ok := t.appointments[appointment.ID]; ok {
+		return repository.ErrUniqueConstraintFailure
+	}
+	if startsAt(t, appointment.TreatmentCenterID, appointment.StartTime, appointment.ID) {
+		return repository.ErrUniqueConstraintFailure
+	}
+	appointment.CreatedAt, appointment.UpdatedAt = created(appointment.CreatedAt, appointment.UpdatedAt)
+	row := *appointment
+	row.TreatmentCenter, row.RemainingSeats = domain.TreatmentCenter{}, 0
+	t.setAppointment(row.ID, row)
+	return nil
+}
+
+// startsAt reports whether an appointment of the treatment center other than
+// except and not deleted starts at startTime
+func startsAt(t *tables, treatmentCenterID uuid.UUID, startTime time.Time, except uuid.UUID) bool {
+	for _, other := range t.appointments {
+		if other.ID != except && other.DeletedAt == nil && other.TreatmentCenterID == treatmentCenterID &&
+			other.StartTime.Equal(startTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// upcoming returns the date from which the appointments still need doses,
+// an appointment opened in the past counts as upcoming
+func upcoming(startTime time.Time) time.Time {
+	if now := time.Now(); now.Before(startTime) {
+		return now
+	}
+	return startTime
+}
+
+// Update saves the start time and capacity of an appointment. The start time of
+// an appointment holding bookings doesn't change, its capacity can't drop below
+// the seats held and the stock of its treatment center must cover the seats.
+func (r appointments) Update(ctx context.Context, appointment *domain.Appointment) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		current, err := findAppointment(t, appointment.ID)
+		if err != nil {
+			return err
+		}
+		if _, err = lockTreatmentCenter(t, current.TreatmentCenterID); err != nil {
+			return err
+		}
+		held := heldSeats(t, appointment.ID)
+		if held > 0 && !current.StartTime.Equal(appointment.StartTime) {
+			return repository.ErrAppointmentBooked
+		}
+		if appointment.Capacity < held {
+			return repository.ErrCapacityBelowBookings
+		}
+		if startsAt(t, current.TreatmentCenterID, appointment.StartTime, current.ID) {
+			return repository.ErrUniqueConstraintFailure
+		}
+
+		since := current.StartTime
+		if appointment.StartTime.Before(since) {
+			since = appointment.StartTime
+		}
+		current.StartTime = appointment.StartTime
+		current.Capacity = appointment.Capacity
+		current.UpdatedAt = now()
+		t.setAppointment(current.ID, current)
+		return checkStock(t, current.TreatmentCenterID, upcoming(since))
+	})
+}
+
+// findAppointment returns an appointment not deleted
+func findAppointment(t *tables, id uuid.UUID) (domain.Appointment, error) {
+	appointment, ok := t.appointments[id]
+	if !ok || appointment.DeletedAt != nil {
+		return appointment, repository.ErrRecordNotFound
+	}
+	return appointment, nil
+}
+
+// heldSeats counts the seats of an appointment held by its bookings
+func heldSeats(t *tables, appointmentID uuid.UUID) (held int) {
+	for _, booking := range t.bookings {
+		if booking.AppointmentID == appointmentID && holding(booking.Status) {
+			held++
+		}
+	}
+	return held
+}
+
+// holding reports whether a booking with this status holds a seat
+func holding(status domain.AppointmentStatus) bool {
+	for _, s := range domain.HoldingStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete deletes an appointment, it is kept for its past bookings but can't be
+// found or booked anymore. An appointment with active bookings fails with
+// ErrAppointmentBooked unless cancelBookings, they are then cancelled and returned.
+func (r appointments) Delete(ctx context.Context, id uuid.UUID,
+	cancelBookings bool) (cancelled []*domain.PatientBooking, err error) {
+	err = r.db.transaction(ctx, func(t *tables) error {
+		current, err := findAppointment(t, id)
+		if err != nil {
+			return err
+		}
+		if _, err = lockTreatmentCenter(t, current.TreatmentCenterID); err != nil {
+			return err
+		}
+		cancelled, err = withdrawBookings(t, cancelBookings, repository.ErrAppointmentBooked,
+			func(appointment domain.Appointment) bool { return appointment.ID == id })
+		if err != nil {
+			return err
+		}
+		current.DeletedAt = now()
+		t.setAppointment(id, current)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// withdrawBookings cancels the active bookings of the appointments matching and
+// returns them. When there are some and they may not be cancelled it fails with
+// booked.
+func withdrawBookings(t *tables, cancel bool, booked error,
+	matching func(appointment domain.Appointment) bool) ([]*domain.PatientBooking, error) {
+	var cancelled []*domain.PatientBooking
+	for _, booking := range t.bookings {
+		if booking.Status.Active() && matching(t.appointments[booking.AppointmentID]) {
+			if !cancel {
+				return nil, booked
+			}
+			if err := release(t, booking.ID, domain.Cancelled, domain.ReleaseReasonWithdrawn); err != nil {
+				return nil, err
+			}
+			cancelled = append(cancelled, patientBooking(t, t.bookings[booking.ID]))
+		}
+	}
+	sort.Slice(cancelled, func(i, j int) bool {
+		return compareTimes(&cancelled[i].StartTime, cancelled[i].ID, &cancelled[j].StartTime, cancelled[j].ID) < 0
+	})
+	return cancelled, nil
+}
+
+// CreateMissing creates the appointments whose treatment center has no appointment
+// starting at the same time yet and returns those created, like Create it fails
+// with ErrInsufficientStock when doses are lacking
+func (r appointments) CreateMissing(ctx context.Context,
+	appointments []*domain.Appointment) (created []*domain.Appointment, err error) {
+	err = r.db.transaction(ctx, func(t *tables) error {
+		since := map[uuid.UUID]time.Time{}
+		for _, appointment := range appointments {
+			if _, err := lockTreatmentCenter(t, appointment.TreatmentCenterID); err != nil {
+				return err
+			}
+			if startsAt(t, appointment.TreatmentCenterID, appointment.StartTime, appointment.ID) {
+				continue
+			}
+			if err := insertAppointment(t, appointment); err != nil {
+				return err
+			}
+			created = append(created, appointment)
+			if first, ok := since[appointment.TreatmentCenterID]; !ok || appointment.StartTime.Before(first) {
+				since[appointment.TreatmentCenterID] = appointment.StartTime
+			}
+		}
+		for treatmentCenterID, first := range since {
+			if err := checkStock(t, treatmentCenterID, upcoming(first)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r appointments) FindByID(ctx context.Context, id uuid.UUID) (appointment *domain.Appointment, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		found, err := findAppointment(t, id)
+		if err != nil {
+			return err
+		}
+		appointment = withSeats(t, found)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+func (r appointments) All(ctx context.Context) (result []*domain.Appointment, err error) {
+	return r.filter(ctx, func(t *tables, appointment *domain.Appointment) bool { return true })
+}
+
+func (r appointments) AllByTreatmentCenterID(ctx context.Context,
+	treatmentCenterID uuid.UUID) (result []*domain.Appointment, err error) {
+	return r.filter(ctx, func(t *tables, appointment *domain.Appointment) bool {
+		return appointment.TreatmentCenterID == treatmentCenterID
+	})
+}
+
+// AllAvailable returns the appointments with at least one remaining seat,
+// including the past ones
+func (r appointments) AllAvailable(ctx context.Context) (result []*domain.Appointment, err error) {
+	return r.filter(ctx, func(t *tables, appointment *domain.Appointment) bool {
+		return appointment.RemainingSeats > 0
+	})
+}
+
+// filter returns the appointments not deleted which are kept, by start time
+func (r appointments) filter(ctx context.Context,
+	keep func(t *tables, appointment *domain.Appointment) bool) (result []*domain.Appointment, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		result = appointmentsWithSeats(t, keep)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return compareTimes(&result[i].StartTime, result[i].ID, &result[j].StartTime, result[j].ID) < 0
+	})
+	return result, nil
+}
+
+// appointmentsSorting sorts the appointments by start time
+var appointmentsSorting = sorting{
+	fields: map[string]sortField{
+		"start_time": func(item interface{}) interface{} { return item.(*domain.Appointment).StartTime },
+	},
+	defaultSort: "start_time",
+}
+
+// Search returns the appointments starting after now with at least one remaining
+// seat and matching the search
+func (r appointments) Search(ctx context.Context, search domain.AppointmentSearch, request domain.PageRequest,
+	now time.Time) (result []*domain.Appointment, page domain.Page, err error) {
+	var after, before time.Duration
+	if search.After != nil {
+		if after, err = search.After.Duration(); err != nil {
+			return nil, page, err
+		}
+	}
+	if search.Before != nil {
+		if before, err = search.Before.Duration(); err != nil {
+			return nil, page, err
+		}
+	}
+
+	err = r.db.view(ctx, func(t *tables) error {
+		result = appointmentsWithSeats(t, func(t *tables, appointment *domain.Appointment) bool {
+			if !appointment.StartTime.After(now) || appointment.RemainingSeats <= 0 {
+				return false
+			}
+			treatmentCenter, ok := t.treatmentCenters[appointment.TreatmentCenterID]
+			if !ok {
+				return false
+			}
+			if search.TreatmentCenterID != nil && appointment.TreatmentCenterID != *search.TreatmentCenterID {
+				return false
+			}
+			if search.From != nil && appointment.StartTime.Before(*search.From) {
+				return false
+			}
+			if search.To != nil && !appointment.StartTime.Before(*search.To) {
+				return false
+			}
+			if search.After == nil && search.Before == nil {
+				return true
+			}
+			loc, err := treatmentCenter.Location()
+			if err != nil {
+				return false
+			}
+			clock := domain.TimeOfDay(appointment.StartTime.In(loc))
+			return (search.After == nil || clock >= after) && (search.Before == nil || clock < before)
+		})
+		page, err = paginate(&result, request, appointmentsSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// AllBookedByTreatmentCenterIDForDate returns the appointments of a treatment center
+// for a given day with at least one seat booked
+func (r appointments) AllBookedByTreatmentCenterIDForDate(ctx context.Context, treatmentCenterID uuid.UUID,
+	day time.Time,
+	request domain.PageRequest) (result []*domain.Appointment, page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		result = appointmentsWithSeats(t, func(t *tables, appointment *domain.Appointment) bool {
+			return appointment.TreatmentCenterID == treatmentCenterID &&
+				date(appointment.StartTime.UTC()).Equal(date(day.UTC())) &&
+				appointment.RemainingSeats < appointment.Capacity
+		})
+		page, err = paginate(&result, request, appointmentsSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// appointmentsWithSeats returns the appointments not deleted along with their
+// remaining seats which are kept
+func appointmentsWithSeats(t *tables,
+	keep func(t *tables, appointment *domain.Appointment) bool) (result []*domain.Appointment) {
+	for _, appointment := range t.appointments {
+		if appointment.DeletedAt != nil {
+			continue
+		}
+		if withSeats := withSeats(t, appointment); keep(t, withSeats) {
+			result = append(result, withSeats)
+		}
+	}
+	return result
+}
+
+// withSeats returns an appointment along with its remaining seats
+func withSeats(t *tables, appointment domain.Appointment) *domain.Appointment {
+	appointment.RemainingSeats = appointment.Capacity - heldSeats(t, appointment.ID)
+	return &appointment
+}
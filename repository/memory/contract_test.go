@@ -0,0 +1,21 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/y9mo/covidvax/repository/memory"
+	"github.com/y9mo/covidvax/repository/repositorytest"
+)
+
+func TestMemoryContract(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &repositorytest.ContractSuite{NewBackend: func() repositorytest.Backend {
+		store := memory.NewStore()
+		return repositorytest.Backend{
+			Repositories: memory.NewRepositories(store),
+			Reminders:    memory.NewReminders(store),
+			UnitOfWork:   memory.NewUnitOfWork(store),
+		}
+	}})
+}
@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type doses struct {
+	db db
+}
+
+func NewDoses(store *Store) repository.Doses {
+	return doses{db: store}
+}
+
+// Create records a dose as the next one of its patient. The dose was given
+// whatever the bookings of the patient: the next active ones that don't follow
+// the vaccine schedule after the dose are cancelled and returned.
+func (r doses) Create(ctx context.Context, dose *domain.Dose) (cancelled []*domain.PatientBooking, err error) {
+	err = r.db.transaction(ctx, func(t *tables) error {
+		if err := administer(t, dose); err != nil {
+			return err
+		}
+		var err error
+		cancelled, err = cancelOffSchedule(t, dose)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// administer numbers the dose after the last one of its patient and inserts it
+func administer(t *tables, dose *domain.Dose) error {
+	if _, ok := t.patients[dose.PatientID]; !ok {
+		return repository.ErrInvalidID
+	}
+	if _, ok := t.vaccineProducts[dose.VaccineProductID]; !ok {
+		return repository.ErrInvalidID
+	}
+	if _, ok := t.doses[dose.ID]; ok {
+		return repository.ErrUniqueConstraintFailure
+	}
+	if dose.AppointmentBookingID != nil {
+		if _, ok := t.bookings[*dose.AppointmentBookingID]; !ok {
+			return repository.ErrInvalidID
+		}
+	}
+	last := 0
+	for _, other := range t.doses {
+		if other.AppointmentBookingID != nil && dose.AppointmentBookingID != nil &&
+			*other.AppointmentBookingID == *dose.AppointmentBookingID {
+			return repository.ErrUniqueConstraintFailure
+		}
+		if other.PatientID == dose.PatientID && other.Number > last {
+			last = other.Number
+		}
+	}
+	dose.Number = last + 1
+	dose.CreatedAt, dose.UpdatedAt = created(dose.CreatedAt, dose.UpdatedAt)
+	t.setDose(dose.ID, *dose)
+	return nil
+}
+
+// dosesSorting sorts the doses by number by default
+var dosesSorting = sorting{
+	fields: map[string]sortField{
+		"number":          func(item interface{}) interface{} { return item.(*domain.Dose).Number },
+		"administered_at": func(item interface{}) interface{} { return item.(*domain.Dose).AdministeredAt },
+	},
+	defaultSort: "number",
+}
+
+func (r doses) AllByPatientID(ctx context.Context, patientID uuid.UUID,
+	request domain.PageRequest) (result []*domain.Dose, page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, dose := range t.doses {
+			dose := dose
+			if dose.PatientID == patientID {
+				result = append(result, &dose)
+			}
+		}
+		page, err = paginate(&result, request, dosesSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// checkDoseSchedule returns an error when a dose at date would break the
+// schedule of the vaccine the patient last received
+func checkDoseSchedule(t *tables, patientID uuid.UUID, at time.Time) error {
+	var last *domain.Dose
+	for _, dose := range t.doses {
+		dose := dose
+		if dose.PatientID == patientID && (last == nil || dose.Number > last.Number) {
+			last = &dose
+		}
+	}
+	if last == nil {
+		return nil
+	}
+	product, ok := t.vaccineProducts[last.VaccineProductID]
+	if !ok {
+		return repository.ErrRecordNotFound
+	}
+	return product.CheckNextDose(last, at)
+}
+
+// cancelOffSchedule cancels the active bookings of the patient after a dose
+// until the first one following the schedule of the vaccine of the dose, and
+// returns them
+func cancelOffSchedule(t *tables, dose *domain.Dose) ([]*domain.PatientBooking, error) {
+	var next []*domain.PatientBooking
+	for _, booking := range t.bookings {
+		if booking.PatientID == dose.PatientID && booking.Status.Active() &&
+			t.appointments[booking.AppointmentID].StartTime.After(dose.AdministeredAt) {
+			next = append(next, patientBooking(t, booking))
+		}
+	}
+	sort.Slice(next, func(i, j int) bool {
+		return compareTimes(&next[i].StartTime, next[i].ID, &next[j].StartTime, next[j].ID) < 0
+	})
+	product := t.vaccineProducts[dose.VaccineProductID]
+	var cancelled []*domain.PatientBooking
+	for _, booking := range next {
+		if product.CheckNextDose(dose, booking.StartTime) == nil {
+			break
+		}
+		if err := release(t, booking.ID, domain.Cancelled, domain.ReleaseReasonOffSchedule); err != nil {
+			return nil, err
+		}
+		cancelled = append(cancelled, patientBooking(t, t.bookings[booking.ID]))
+	}
+	return cancelled, nil
+}
@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type eligibilityRules struct {
+	db db
+}
+
+func NewEligibilityRules(store *Store) repository.EligibilityRules {
+	return eligibilityRules{db: store}
+}
+
+func (r eligibilityRules) Create(ctx context.Context, rule *domain.EligibilityRule) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		if _, ok := t.eligibilityRules[rule.ID]; ok {
+			return repository.ErrUniqueConstraintFailure
+		}
+		rule.CreatedAt, rule.UpdatedAt = created(rule.CreatedAt, rule.UpdatedAt)
+		t.setEligibilityRule(rule.ID, *rule)
+		return nil
+	})
+}
+
+// Update saves the fields of a rule, empty ones included
+func (r eligibilityRules) Update(ctx context.Context, rule *domain.EligibilityRule) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		current, ok := t.eligibilityRules[rule.ID]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		current.Name = rule.Name
+		current.MinAge = rule.MinAge
+		current.RiskCategories = rule.RiskCategories
+		current.Professions = rule.Professions
+		current.EffectiveFrom = rule.EffectiveFrom
+		current.EffectiveUntil = rule.EffectiveUntil
+		current.UpdatedAt = now()
+		t.setEligibilityRule(rule.ID, current)
+		return nil
+	})
+}
+
+func (r eligibilityRules) FindByID(ctx context.Context, id uuid.UUID) (rule *domain.EligibilityRule, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		found, ok := t.eligibilityRules[id]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		rule = &found
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r eligibilityRules) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		if _, ok := t.eligibilityRules[id]; !ok {
+			return repository.ErrRecordNotFound
+		}
+		t.deleteEligibilityRule(id)
+		return nil
+	})
+}
+
+// eligibilityRulesSorting sorts the rules by the date they take effect by default
+var eligibilityRulesSorting = sorting{
+	fields: map[string]sortField{
+		"effective_from": func(item interface{}) interface{} { return item.(*domain.EligibilityRule).EffectiveFrom },
+		"name":           func(item interface{}) interface{} { return item.(*domain.EligibilityRule).Name },
+		"created_at":     func(item interface{}) interface{} { return item.(*domain.EligibilityRule).CreatedAt },
+	},
+	defaultSort: "effective_from",
+}
+
+func (r eligibilityRules) All(ctx context.Context,
+	request domain.PageRequest) (result []*domain.EligibilityRule, page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, rule := range t.eligibilityRules {
+			rule := rule
+			result = append(result, &rule)
+		}
+		page, err = paginate(&result, request, eligibilityRulesSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// Effective returns the rules in effect at a time, by the date they took effect
+func (r eligibilityRules) Effective(ctx context.Context, at time.Time) (result []*domain.EligibilityRule, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		result = effectiveRules(t, at)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func effectiveRules(t *tables, at time.Time) (result []*domain.EligibilityRule) {
+	for _, rule := range t.eligibilityRules {
+		rule := rule
+		if !rule.EffectiveFrom.After(at) && (rule.EffectiveUntil == nil || rule.EffectiveUntil.After(at)) {
+			result = append(result, &rule)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return compareTimes(&result[i].EffectiveFrom, result[i].ID, &result[j].EffectiveFrom, result[j].ID) < 0
+	})
+	return result
+}
+
+// checkEligibility returns a domain.IneligibleError when the patient doesn't
+// meet a rule in effect for an appointment starting at start
+func checkEligibility(t *tables, patient *domain.Patient, start time.Time) error {
+	if rule := domain.FailedEligibilityRule(effectiveRules(t, time.Now()), patient, start); rule != nil {
+		return &domain.IneligibleError{Rule: rule}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+// sortField returns the value an item is sorted by: a time, a string or a
+// number. The items are pointers to domain values with an ID field.
+type sortField func(item interface{}) interface{}
+
+// sorting lists the fields a list can be sorted by, the id breaks the ties
+type sorting struct {
+	fields           map[string]sortField
+	defaultSort      string
+	defaultDirection domain.SortDirection
+}
+
+// paginate keeps in items, a pointer to a slice of pointers, the page asked by the
+// request, it sorts and pages like the postgres repositories and its cursors are
+// interchangeable with theirs
+func paginate(items interface{}, request domain.PageRequest, sorting sorting) (page domain.Page, err error) {
+	sortName, direction := request.Sort, request.Direction
+	if sortName == "" {
+		sortName = sorting.defaultSort
+	}
+	// the default sort keeps its direction unless the request gives one
+	if direction == "" && sortName == sorting.defaultSort {
+		direction = sorting.defaultDirection
+	}
+	if direction == "" {
+		direction = domain.Ascending
+	}
+	field, ok := sorting.fields[sortName]
+	if !ok {
+		return page, repository.ErrInvalidSort
+	}
+	if request.Cursor != nil && (request.Cursor.Sort != sortName || request.Cursor.Direction != direction) {
+		return page, repository.ErrInvalidCursor
+	}
+
+	list := reflect.ValueOf(items).Elem()
+	page.Total = int64(list.Len())
+	sign := 1
+	if direction == domain.Descending {
+		sign = -1
+	}
+	sort.SliceStable(list.Interface(), func(i, j int) bool {
+		a, b := list.Index(i).Interface(), list.Index(j).Interface()
+		c, compareErr := compareItems(field(a), itemID(a), field(b), itemID(b))
+		if compareErr != nil {
+			err = compareErr
+		}
+		return sign*c < 0
+	})
+	if err != nil {
+		return page, err
+	}
+
+	if request.Cursor != nil {
+		kept := reflect.MakeSlice(list.Type(), 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			item := list.Index(i).Interface()
+			value, err := parseCursorValue(field(item), request.Cursor.Value)
+			if err != nil {
+				return page, err
+			}
+			c, err := compareItems(field(item), itemID(item), value, request.Cursor.ID)
+			if err != nil {
+				return page, err
+			}
+			if sign*c > 0 {
+				kept = reflect.Append(kept, list.Index(i))
+			}
+		}
+		list.Set(kept)
+	}
+
+	if request.Limit == 0 || list.Len() <= request.Limit {
+		return page, nil
+	}
+	list.Set(list.Slice(0, request.Limit))
+	last := list.Index(request.Limit - 1).Interface()
+	page.NextCursor = &domain.Cursor{
+		Sort:      sortName,
+		Direction: direction,
+		Value:     cursorValue(field(last)),
+		ID:        itemID(last),
+	}
+	return page, nil
+}
+
+func itemID(item interface{}) uuid.UUID {
+	return reflect.ValueOf(item).Elem().FieldByName("ID").Interface().(uuid.UUID)
+}
+
+// compareItems compares two items by their sort value then their id, the
+// missing values come last like the nulls of postgres. It fails with
+// ErrInvalidSort on values that are not times, strings or numbers.
+func compareItems(a interface{}, aID uuid.UUID, b interface{}, bID uuid.UUID) (int, error) {
+	c, err := compareValues(a, b)
+	if err != nil || c != 0 {
+		return c, err
+	}
+	return bytes.Compare(aID[:], bID[:]), nil
+}
+
+// compareTimes compares two items by a time then their id like compareItems
+func compareTimes(a *time.Time, aID uuid.UUID, b *time.Time, bID uuid.UUID) int {
+	// times always compare
+	c, _ := compareItems(a, aID, b, bID)
+	return c
+}
+
+func compareValues(a, b interface{}) (int, error) {
+	if t, ok := a.(*time.Time); ok {
+		a = nilTime(t)
+	}
+	if t, ok := b.(*time.Time); ok {
+		b = nilTime(t)
+	}
+	switch {
+	case a == nil && b == nil:
+		return 0, nil
+	case a == nil:
+		return 1, nil
+	case b == nil:
+		return -1, nil
+	}
+	switch v := a.(type) {
+	case time.Time:
+		if w, ok := b.(time.Time); ok {
+			switch {
+			case v.Before(w):
+				return -1, nil
+			case v.After(w):
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if w, ok := b.(string); ok {
+			return strings.Compare(v, w), nil
+		}
+	case int:
+		if w, ok := b.(int); ok {
+			return v - w, nil
+		}
+	case float64:
+		if w, ok := b.(float64); ok {
+			switch {
+			case v < w:
+				return -1, nil
+			case v > w:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, repository.ErrInvalidSort
+}
+
+// nilTime turns a missing time into an untyped nil
+func nilTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// cursorValue formats the value of a sort field like the postgres repositories
+func cursorValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseCursorValue reads the value of a cursor as the type of sample
+func parseCursorValue(sample interface{}, value string) (interface{}, error) {
+	var parsed interface{}
+	var err error
+	switch sample.(type) {
+	case time.Time, *time.Time:
+		parsed, err = time.Parse(time.RFC3339Nano, value)
+	case int:
+		parsed, err = strconv.Atoi(value)
+	case float64:
+		parsed, err = strconv.ParseFloat(value, 64)
+	default:
+		parsed = value
+	}
+	if err != nil {
+		return nil, repository.ErrInvalidCursor
+	}
+	return parsed, nil
+}
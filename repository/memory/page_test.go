@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+func TestPaginateUnsortableField(t *testing.T) {
+	sorting := sorting{
+		fields: map[string]sortField{
+			"name":      func(item interface{}) interface{} { return item.(*domain.Patient).FirstName },
+			"has_phone": func(item interface{}) interface{} { return item.(*domain.Patient).Phone != "" },
+		},
+		defaultSort: "name",
+	}
+	patients := []*domain.Patient{{ID: uuid.New(), FirstName: "Grace"}, {ID: uuid.New(), FirstName: "Ada"}}
+
+	_, err := paginate(&patients, domain.PageRequest{Sort: "has_phone"}, sorting)
+	assert.Equal(t, repository.ErrInvalidSort, err)
+
+	_, err = paginate(&patients, domain.PageRequest{}, sorting)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ada", patients[0].FirstName)
+}
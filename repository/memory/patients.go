@@ -0,0 +1,285 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type patients struct {
+	db db
+}
+
+func NewPatients(store *Store) repository.Patients {
+	return patients{db: store}
+}
+
+func (r patients) Create(ctx context.Context, patient *domain.Patient) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		if _, ok := t.patients[patient.ID]; ok {
+			return repository.ErrUniqueConstraintFailure
+		}
+		patient.CreatedAt, patient.UpdatedAt = created(patient.CreatedAt, patient.UpdatedAt)
+		return savePatient(t, *patient)
+	})
+}
+
+// savePatient stores a patient whose email, whatever its case, and national
+// health identifier no other patient has
+func savePatient(t *tables, patient domain.Patient) error {
+	for _, other := range t.patients {
+		if other.ID == patient.ID {
+			continue
+		}
+		if strings.EqualFold(other.Email, patient.Email) {
+			return repository.ErrUniqueConstraintFailure
+		}
+		if other.NationalHealthID != nil && patient.NationalHealthID != nil &&
+			*other.NationalHealthID == *patient.NationalHealthID {
+			return repository.ErrUniqueConstraintFailure
+		}
+	}
+	if patient.BirthDate != nil {
+		birthDate := date(*patient.BirthDate)
+		patient.BirthDate = &birthDate
+	}
+	patient.AppointmentBookings, patient.Doses = nil, nil
+	t.setPatient(patient.ID, patient)
+	return nil
+}
+
+// Update saves the fields of a patient, empty ones included
+func (r patients) Update(ctx context.Context, patient *domain.Patient) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		current, ok := t.patients[patient.ID]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		current.Email = patient.Email
+		current.FirstName = patient.FirstName
+		current.LastName = patient.LastName
+		current.Phone = patient.Phone
+		current.BirthDate = patient.BirthDate
+		current.NationalHealthID = patient.NationalHealthID
+		current.RiskCategories = patient.RiskCategories
+		current.Profession = patient.Profession
+		current.UpdatedAt = now()
+		return savePatient(t, current)
+	})
+}
+
+func (r patients) FindByID(ctx context.Context, id uuid.UUID) (patient *domain.Patient, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		found, ok := t.patients[id]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		patient = &found
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patient, nil
+}
+
+// Delete deletes a patient along with its doses and waitlist entries, a
+// patient with bookings fails with ErrInvalidID
+func (r patients) Delete(ctx context.Context, patient *domain.Patient) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		for _, booking := range t.bookings {
+			if booking.PatientID == patient.ID {
+				return repository.ErrInvalidID
+			}
+		}
+		for id, dose := range t.doses {
+			if dose.PatientID == patient.ID {
+				t.deleteDose(id)
+			}
+		}
+		for id, entry := range t.waitlist {
+			if entry.PatientID == patient.ID {
+				t.deleteWaitlistEntry(id)
+			}
+		}
+		t.deletePatient(patient.ID)
+		return nil
+	})
+}
+
+// patientsSorting sorts the patients by creation by default
+var patientsSorting = sorting{
+	fields: map[string]sortField{
+		"created_at": func(item interface{}) interface{} { return item.(*domain.Patient).CreatedAt },
+		"last_name":  func(item interface{}) interface{} { return item.(*domain.Patient).LastName },
+		"email":      func(item interface{}) interface{} { return item.(*domain.Patient).Email },
+	},
+	defaultSort: "created_at",
+}
+
+func (r patients) All(ctx context.Context, request domain.PageRequest) (result []*domain.Patient,
+	page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, patient := range t.patients {
+			patient := patient
+			result = append(result, &patient)
+		}
+		page, err = paginate(&result, request, patientsSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// Duplicates returns the patients who may be the same person as the patient,
+// the ones sharing its last name, its national health identifier or its
+// canonical email are matched by domain.Patient.DuplicateReasons
+func (r patients) Duplicates(ctx context.Context, id uuid.UUID) ([]*domain.DuplicateCandidate, error) {
+	patient, err := r.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	email := domain.CanonicalEmail(patient.Email)
+	var lookalikes []*domain.Patient
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, other := range t.patients {
+			other := other
+			if other.ID == id {
+				continue
+			}
+			if strings.EqualFold(other.LastName, patient.LastName) ||
+				(email != "" && domain.CanonicalEmail(other.Email) == email) ||
+				(other.NationalHealthID != nil && patient.NationalHealthID != nil &&
+					*other.NationalHealthID == *patient.NationalHealthID) {
+				lookalikes = append(lookalikes, &other)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(lookalikes, func(i, j int) bool {
+		return compareTimes(lookalikes[i].CreatedAt, lookalikes[i].ID, lookalikes[j].CreatedAt, lookalikes[j].ID) < 0
+	})
+
+	candidates := []*domain.DuplicateCandidate{}
+	for _, lookalike := range lookalikes {
+		if reasons := patient.DuplicateReasons(lookalike); len(reasons) > 0 {
+			candidates = append(candidates, &domain.DuplicateCandidate{Patient: lookalike, Reasons: reasons})
+		}
+	}
+	return candidates, nil
+}
+
+// Merge moves the bookings, doses and waitlist entries of the duplicate patient
+// to the surviving one, which takes the phone, birth date and national health
+// identifier it misses, then deletes the duplicate. Two patients holding a seat
+// of the same appointment or with a dose of the same number can't be merged.
+func (r patients) Merge(ctx context.Context, survivorID, duplicateID uuid.UUID) error {
+	if survivorID == duplicateID {
+		return repository.ErrInvalidID
+	}
+	return r.db.transaction(ctx, func(t *tables) error {
+		survivor, ok := t.patients[survivorID]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		duplicate, ok := t.patients[duplicateID]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+
+		booked, numbers := map[uuid.UUID]bool{}, map[int]bool{}
+		for _, booking := range t.bookings {
+			if booking.PatientID == survivorID && holding(booking.Status) {
+				booked[booking.AppointmentID] = true
+			}
+		}
+		for _, dose := range t.doses {
+			if dose.PatientID == survivorID {
+				numbers[dose.Number] = true
+			}
+		}
+		for _, booking := range t.bookings {
+			if booking.PatientID == duplicateID && holding(booking.Status) && booked[booking.AppointmentID] {
+				return repository.ErrPatientsConflict
+			}
+		}
+		for _, dose := range t.doses {
+			if dose.PatientID == duplicateID && numbers[dose.Number] {
+				return repository.ErrPatientsConflict
+			}
+		}
+
+		// a patient waits once for a treatment center: an offer of the duplicate
+		// replaces the place of the survivor, which keeps its own otherwise
+		offered := map[uuid.UUID]bool{}
+		for _, entry := range t.waitlist {
+			if entry.PatientID == duplicateID && entry.Status == domain.Offered {
+				offered[entry.TreatmentCenterID] = true
+			}
+		}
+		waiting := map[uuid.UUID]bool{}
+		for id, entry := range t.waitlist {
+			if entry.PatientID != survivorID || !pending(entry.Status) {
+				continue
+			}
+			if entry.Status == domain.Waiting && offered[entry.TreatmentCenterID] {
+				entry.Status = domain.Withdrawn
+				entry.UpdatedAt = now()
+				t.setWaitlistEntry(id, entry)
+				continue
+			}
+			waiting[entry.TreatmentCenterID] = true
+		}
+		for _, entry := range t.waitlist {
+			if entry.PatientID != duplicateID {
+				continue
+			}
+			if pending(entry.Status) && waiting[entry.TreatmentCenterID] {
+				entry.Status = domain.Withdrawn
+			}
+			entry.PatientID = survivorID
+			entry.UpdatedAt = now()
+			if err := saveWaitlistEntry(t, entry); err != nil {
+				return err
+			}
+		}
+		for id, booking := range t.bookings {
+			if booking.PatientID == duplicateID {
+				booking.PatientID = survivorID
+				booking.UpdatedAt = now()
+				t.setBooking(id, booking)
+			}
+		}
+		for id, dose := range t.doses {
+			if dose.PatientID == duplicateID {
+				dose.PatientID = survivorID
+				dose.UpdatedAt = now()
+				t.setDose(id, dose)
+			}
+		}
+
+		// the duplicate goes first as the survivor may take its national health identifier
+		t.deletePatient(duplicateID)
+		if survivor.Phone == "" && duplicate.Phone != "" {
+			survivor.Phone = duplicate.Phone
+		}
+		if survivor.BirthDate == nil && duplicate.BirthDate != nil {
+			survivor.BirthDate = duplicate.BirthDate
+		}
+		if survivor.NationalHealthID == nil && duplicate.NationalHealthID != nil {
+			survivor.NationalHealthID = duplicate.NationalHealthID
+		}
+		survivor.UpdatedAt = now()
+		return savePatient(t, survivor)
+	})
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type reminders struct {
+	db db
+}
+
+func NewReminders(store *Store) repository.Reminders {
+	return reminders{db: store}
+}
+
+// Due returns the confirmed bookings starting within leadTime from now which
+// weren't reminded for this lead time or a shorter one, the first to start first
+func (r reminders) Due(ctx context.Context, now time.Time,
+	leadTime time.Duration) (result []*domain.PatientBooking, err error) {
+	leadMinutes := int(leadTime.Minutes())
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, booking := range t.bookings {
+			if booking.Status != domain.Confirmed {
+				continue
+			}
+			due := patientBooking(t, booking)
+			if !due.StartTime.After(now) || due.StartTime.After(now.Add(leadTime)) {
+				continue
+			}
+			reminded := false
+			for key := range t.reminders {
+				if key.appointmentBookingID == booking.ID && key.leadMinutes <= leadMinutes {
+					reminded = true
+				}
+			}
+			if !reminded {
+				result = append(result, due)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return compareTimes(&result[i].StartTime, result[i].ID, &result[j].StartTime, result[j].ID) < 0
+	})
+	return result, nil
+}
+
+// Claim records a reminder before it is sent, it reports false when the
+// reminder was already claimed so concurrent or restarted schedulers never
+// send it twice
+func (r reminders) Claim(ctx context.Context, reminder *domain.BookingReminder) (claimed bool, err error) {
+	err = r.db.transaction(ctx, func(t *tables) error {
+		if _, ok := t.bookings[reminder.AppointmentBookingID]; !ok {
+			return repository.ErrInvalidID
+		}
+		key := reminderKey{appointmentBookingID: reminder.AppointmentBookingID, leadMinutes: reminder.LeadMinutes}
+		if _, ok := t.reminders[key]; ok {
+			return nil
+		}
+		t.setReminder(key, *reminder)
+		claimed = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return claimed, nil
+}
+
+// Release forgets a claimed reminder which couldn't be sent so it is retried
+func (r reminders) Release(ctx context.Context, reminder *domain.BookingReminder) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		t.deleteReminder(reminderKey{
+			appointmentBookingID: reminder.AppointmentBookingID,
+			leadMinutes:          reminder.LeadMinutes,
+		})
+		return nil
+	})
+}
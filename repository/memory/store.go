@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/y9mo/covidvax/repository"
+)
+
+// db runs the operations of the repositories on the tables of a store or of
+// a unit of work
+type db interface {
+	// view runs fn reading the tables
+	view(ctx context.Context, fn func(t *tables) error) error
+	// transaction runs fn changing the tables, its changes are kept when it
+	// returns nil and dropped otherwise
+	transaction(ctx context.Context, fn func(t *tables) error) error
+}
+
+// Store keeps the data of the in memory repositories, it is safe for
+// concurrent use and its data is lost with the process
+type Store struct {
+	mu     sync.RWMutex
+	tables *tables
+}
+
+func NewStore() *Store {
+	return &Store{tables: newTables()}
+}
+
+func (s *Store) view(ctx context.Context, fn func(t *tables) error) error {
+	if err := ctx.Err(); err != nil {
+		return repository.ErrQueryCanceled
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.tables)
+}
+
+// transaction runs fn on the tables and undoes its changes when it fails, the
+// writers wait for each other like with the row locks of postgres
+func (s *Store) transaction(ctx context.Context, fn func(t *tables) error) error {
+	if err := ctx.Err(); err != nil {
+		return repository.ErrQueryCanceled
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.tables.commit()
+	return s.tables.savepoint(fn)
+}
+
+// tx is the db of a unit of work, the store is locked until it ends and its
+// tables log the changes to undo when it fails
+type tx struct {
+	tables *tables
+}
+
+func (d *tx) view(ctx context.Context, fn func(t *tables) error) error {
+	if err := ctx.Err(); err != nil {
+		return repository.ErrQueryCanceled
+	}
+	return fn(d.tables)
+}
+
+// transaction runs fn like a savepoint, its changes alone are undone when
+// it fails so the unit of work goes on
+func (d *tx) transaction(ctx context.Context, fn func(t *tables) error) error {
+	if err := ctx.Err(); err != nil {
+		return repository.ErrQueryCanceled
+	}
+	return d.tables.savepoint(fn)
+}
+
+// now is the time recorded on the rows created or updated
+func now() *time.Time {
+	t := time.Now().UTC()
+	return &t
+}
+
+// created returns the creation and update times of a new row, those set by
+// the caller are kept
+func created(createdAt, updatedAt *time.Time) (*time.Time, *time.Time) {
+	if createdAt == nil {
+		createdAt = now()
+	}
+	if updatedAt == nil {
+		updatedAt = now()
+	}
+	return createdAt, updatedAt
+}
+
+// date drops the time of day like a date column of postgres
+func date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
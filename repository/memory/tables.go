@@ -0,0 +1,284 @@
+package memory
+
+import (
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+)
+
+// reminderKey is the primary key of a booking reminder
+type reminderKey struct {
+	appointmentBookingID uuid.UUID
+	leadMinutes          int
+}
+
+// tables holds the rows of the repositories as values, the callers get
+// copies so they can't change the store behind its back. The rows are written
+// through the set and delete methods which log how to undo the changes of the
+// running transaction.
+type tables struct {
+	patients         map[uuid.UUID]domain.Patient
+	treatmentCenters map[uuid.UUID]domain.TreatmentCenter
+	openingHours     map[uuid.UUID]domain.OpeningHours
+	closures         map[uuid.UUID]domain.Closure
+	appointments     map[uuid.UUID]domain.Appointment
+	bookings         map[uuid.UUID]domain.AppointmentBooking
+	vaccineProducts  map[uuid.UUID]domain.VaccineProduct
+	doses            map[uuid.UUID]domain.Dose
+	vaccineLots      map[uuid.UUID]domain.VaccineLot
+	waitlist         map[uuid.UUID]domain.WaitlistEntry
+	reminders        map[reminderKey]domain.BookingReminder
+	eligibilityRules map[uuid.UUID]domain.EligibilityRule
+	undo             []func()
+}
+
+func newTables() *tables {
+	return &tables{
+		patients:         map[uuid.UUID]domain.Patient{},
+		treatmentCenters: map[uuid.UUID]domain.TreatmentCenter{},
+		openingHours:     map[uuid.UUID]domain.OpeningHours{},
+		closures:         map[uuid.UUID]domain.Closure{},
+		appointments:     map[uuid.UUID]domain.Appointment{},
+		bookings:         map[uuid.UUID]domain.AppointmentBooking{},
+		vaccineProducts:  map[uuid.UUID]domain.VaccineProduct{},
+		doses:            map[uuid.UUID]domain.Dose{},
+		vaccineLots:      map[uuid.UUID]domain.VaccineLot{},
+		waitlist:         map[uuid.UUID]domain.WaitlistEntry{},
+		reminders:        map[reminderKey]domain.BookingReminder{},
+		eligibilityRules: map[uuid.UUID]domain.EligibilityRule{},
+	}
+}
+
+// logUndo records how to undo a change of the running transaction
+func (t *tables) logUndo(undo func()) {
+	t.undo = append(t.undo, undo)
+}
+
+// savepoint runs fn and undoes its changes alone when it fails or panics, the
+// changes made before it are kept
+func (t *tables) savepoint(fn func(t *tables) error) error {
+	mark := len(t.undo)
+	done := false
+	defer func() {
+		if !done {
+			t.rollback(mark)
+		}
+	}()
+	if err := fn(t); err != nil {
+		return err
+	}
+	done = true
+	return nil
+}
+
+// rollback undoes the changes logged after mark, the latest first
+func (t *tables) rollback(mark int) {
+	for i := len(t.undo) - 1; i >= mark; i-- {
+		t.undo[i]()
+	}
+	t.undo = t.undo[:mark]
+}
+
+// commit keeps the changes of the transaction by forgetting how to undo them
+func (t *tables) commit() {
+	t.undo = nil
+}
+
+func (t *tables) setPatient(id uuid.UUID, patient domain.Patient) {
+	previous, ok := t.patients[id]
+	t.logUndo(func() {
+		if ok {
+			t.patients[id] = previous
+		} else {
+			delete(t.patients, id)
+		}
+	})
+	t.patients[id] = patient
+}
+
+func (t *tables) deletePatient(id uuid.UUID) {
+	if previous, ok := t.patients[id]; ok {
+		t.logUndo(func() { t.patients[id] = previous })
+		delete(t.patients, id)
+	}
+}
+
+func (t *tables) setTreatmentCenter(id uuid.UUID, treatmentCenter domain.TreatmentCenter) {
+	previous, ok := t.treatmentCenters[id]
+	t.logUndo(func() {
+		if ok {
+			t.treatmentCenters[id] = previous
+		} else {
+			delete(t.treatmentCenters, id)
+		}
+	})
+	t.treatmentCenters[id] = treatmentCenter
+}
+
+func (t *tables) setOpeningHours(id uuid.UUID, hours domain.OpeningHours) {
+	previous, ok := t.openingHours[id]
+	t.logUndo(func() {
+		if ok {
+			t.openingHours[id] = previous
+		} else {
+			delete(t.openingHours, id)
+		}
+	})
+	t.openingHours[id] = hours
+}
+
+func (t *tables) deleteOpeningHours(id uuid.UUID) {
+	if previous, ok := t.openingHours[id]; ok {
+		t.logUndo(func() { t.openingHours[id] = previous })
+		delete(t.openingHours, id)
+	}
+}
+
+func (t *tables) setClosure(id uuid.UUID, closure domain.Closure) {
+	previous, ok := t.closures[id]
+	t.logUndo(func() {
+		if ok {
+			t.closures[id] = previous
+		} else {
+			delete(t.closures, id)
+		}
+	})
+	t.closures[id] = closure
+}
+
+func (t *tables) deleteClosure(id uuid.UUID) {
+	if previous, ok := t.closures[id]; ok {
+		t.logUndo(func() { t.closures[id] = previous })
+		delete(t.closures, id)
+	}
+}
+
+func (t *tables) setAppointment(id uuid.UUID, appointment domain.Appointment) {
+	previous, ok := t.appointments[id]
+	t.logUndo(func() {
+		if ok {
+			t.appointments[id] = previous
+		} else {
+			delete(t.appointments, id)
+		}
+	})
+	t.appointments[id] = appointment
+}
+
+func (t *tables) setBooking(id uuid.UUID, booking domain.AppointmentBooking) {
+	previous, ok := t.bookings[id]
+	t.logUndo(func() {
+		if ok {
+			t.bookings[id] = previous
+		} else {
+			delete(t.bookings, id)
+		}
+	})
+	t.bookings[id] = booking
+}
+
+func (t *tables) deleteBooking(id uuid.UUID) {
+	if previous, ok := t.bookings[id]; ok {
+		t.logUndo(func() { t.bookings[id] = previous })
+		delete(t.bookings, id)
+	}
+}
+
+func (t *tables) setVaccineProduct(id uuid.UUID, vaccineProduct domain.VaccineProduct) {
+	previous, ok := t.vaccineProducts[id]
+	t.logUndo(func() {
+		if ok {
+			t.vaccineProducts[id] = previous
+		} else {
+			delete(t.vaccineProducts, id)
+		}
+	})
+	t.vaccineProducts[id] = vaccineProduct
+}
+
+func (t *tables) setDose(id uuid.UUID, dose domain.Dose) {
+	previous, ok := t.doses[id]
+	t.logUndo(func() {
+		if ok {
+			t.doses[id] = previous
+		} else {
+			delete(t.doses, id)
+		}
+	})
+	t.doses[id] = dose
+}
+
+func (t *tables) deleteDose(id uuid.UUID) {
+	if previous, ok := t.doses[id]; ok {
+		t.logUndo(func() { t.doses[id] = previous })
+		delete(t.doses, id)
+	}
+}
+
+func (t *tables) setVaccineLot(id uuid.UUID, vaccineLot domain.VaccineLot) {
+	previous, ok := t.vaccineLots[id]
+	t.logUndo(func() {
+		if ok {
+			t.vaccineLots[id] = previous
+		} else {
+			delete(t.vaccineLots, id)
+		}
+	})
+	t.vaccineLots[id] = vaccineLot
+}
+
+func (t *tables) setWaitlistEntry(id uuid.UUID, entry domain.WaitlistEntry) {
+	previous, ok := t.waitlist[id]
+	t.logUndo(func() {
+		if ok {
+			t.waitlist[id] = previous
+		} else {
+			delete(t.waitlist, id)
+		}
+	})
+	t.waitlist[id] = entry
+}
+
+func (t *tables) deleteWaitlistEntry(id uuid.UUID) {
+	if previous, ok := t.waitlist[id]; ok {
+		t.logUndo(func() { t.waitlist[id] = previous })
+		delete(t.waitlist, id)
+	}
+}
+
+func (t *tables) setReminder(key reminderKey, reminder domain.BookingReminder) {
+	previous, ok := t.reminders[key]
+	t.logUndo(func() {
+		if ok {
+			t.reminders[key] = previous
+		} else {
+			delete(t.reminders, key)
+		}
+	})
+	t.reminders[key] = reminder
+}
+
+func (t *tables) deleteReminder(key reminderKey) {
+	if previous, ok := t.reminders[key]; ok {
+		t.logUndo(func() { t.reminders[key] = previous })
+		delete(t.reminders, key)
+	}
+}
+
+func (t *tables) setEligibilityRule(id uuid.UUID, rule domain.EligibilityRule) {
+	previous, ok := t.eligibilityRules[id]
+	t.logUndo(func() {
+		if ok {
+			t.eligibilityRules[id] = previous
+		} else {
+			delete(t.eligibilityRules, id)
+		}
+	})
+	t.eligibilityRules[id] = rule
+}
+
+func (t *tables) deleteEligibilityRule(id uuid.UUID) {
+	if previous, ok := t.eligibilityRules[id]; ok {
+		t.logUndo(func() { t.eligibilityRules[id] = previous })
+		delete(t.eligibilityRules, id)
+	}
+}
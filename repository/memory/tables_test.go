@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+func TestTransactionUndoesFailedChanges(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+	kept := domain.Patient{ID: uuid.New(), FirstName: "Ada"}
+	removed := domain.Patient{ID: uuid.New(), FirstName: "Grace"}
+	require.NoError(t, store.transaction(ctx, func(tb *tables) error {
+		tb.setPatient(kept.ID, kept)
+		tb.setPatient(removed.ID, removed)
+		return nil
+	}))
+
+	failure := errors.New("failure")
+	added := uuid.New()
+	err := NewUnitOfWork(store).Do(ctx, func(r repository.Repositories) error {
+		db := r.(repositories).db
+		// a failed step is undone alone, the unit of work goes on
+		err := db.transaction(ctx, func(tb *tables) error {
+			tb.deletePatient(kept.ID)
+			return failure
+		})
+		assert.Equal(t, failure, err)
+		assert.NoError(t, db.view(ctx, func(tb *tables) error {
+			assert.Equal(t, kept, tb.patients[kept.ID])
+			return nil
+		}))
+		return db.transaction(ctx, func(tb *tables) error {
+			renamed := kept
+			renamed.FirstName = "Augusta"
+			tb.setPatient(kept.ID, renamed)
+			tb.setPatient(kept.ID, domain.Patient{ID: kept.ID, FirstName: "Lovelace"})
+			tb.deletePatient(removed.ID)
+			tb.setPatient(added, domain.Patient{ID: added})
+			return failure
+		})
+	})
+	assert.Equal(t, failure, err)
+
+	require.NoError(t, store.view(ctx, func(tb *tables) error {
+		assert.Equal(t, map[uuid.UUID]domain.Patient{kept.ID: kept, removed.ID: removed}, tb.patients)
+		assert.Empty(t, tb.undo)
+		return nil
+	}))
+}
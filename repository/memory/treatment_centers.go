@@ -0,0 +1,297 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type treatmentCenters struct {
+	db db
+}
+
+func NewTreatmentCenters(store *Store) repository.TreatmentCenters {
+	return treatmentCenters{db: store}
+}
+
+// Create saves a treatment center along with its opening hours and closures
+func (r treatmentCenters) Create(ctx context.Context, treatmentCenter *domain.TreatmentCenter) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		if _, ok := t.treatmentCenters[treatmentCenter.ID]; ok {
+			return repository.ErrUniqueConstraintFailure
+		}
+		treatmentCenter.CreatedAt, treatmentCenter.UpdatedAt = created(treatmentCenter.CreatedAt,
+			treatmentCenter.UpdatedAt)
+		row := *treatmentCenter
+		row.OpeningHours, row.Closures, row.Appointments = nil, nil, nil
+		t.setTreatmentCenter(row.ID, row)
+		return saveSchedule(t, treatmentCenter)
+	})
+}
+
+// saveSchedule adds the opening hours and closures of a treatment center, a
+// center is closed once a day
+func saveSchedule(t *tables, treatmentCenter *domain.TreatmentCenter) error {
+	for _, hours := range treatmentCenter.OpeningHours {
+		if _, ok := t.openingHours[hours.ID]; ok {
+			return repository.ErrUniqueConstraintFailure
+		}
+		hours.TreatmentCenterID = treatmentCenter.ID
+		t.setOpeningHours(hours.ID, *hours)
+	}
+	for _, closure := range treatmentCenter.Closures {
+		if _, ok := t.closures[closure.ID]; ok {
+			return repository.ErrUniqueConstraintFailure
+		}
+		closure.TreatmentCenterID = treatmentCenter.ID
+		row := *closure
+		row.Date = date(row.Date)
+		for _, other := range t.closures {
+			if other.TreatmentCenterID == row.TreatmentCenterID && other.Date.Equal(row.Date) {
+				return repository.ErrUniqueConstraintFailure
+			}
+		}
+		t.setClosure(row.ID, row)
+	}
+	return nil
+}
+
+// Update saves the details of a treatment center, empty ones included, its
+// schedule is saved by UpdateSchedule
+func (r treatmentCenters) Update(ctx context.Context, treatmentCenter *domain.TreatmentCenter) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		current, err := lockTreatmentCenter(t, treatmentCenter.ID)
+		if err != nil {
+			return err
+		}
+		current.Name = treatmentCenter.Name
+		current.Address = treatmentCenter.Address
+		current.Phone = treatmentCenter.Phone
+		current.Latitude = treatmentCenter.Latitude
+		current.Longitude = treatmentCenter.Longitude
+		current.UpdatedAt = now()
+		t.setTreatmentCenter(current.ID, current)
+		return nil
+	})
+}
+
+func (r treatmentCenters) FindByID(ctx context.Context, id uuid.UUID) (treatmentCenter *domain.TreatmentCenter,
+	err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		found, err := lockTreatmentCenter(t, id)
+		if err != nil {
+			return err
+		}
+		for _, hours := range t.openingHours {
+			hours := hours
+			if hours.TreatmentCenterID == id {
+				found.OpeningHours = append(found.OpeningHours, &hours)
+			}
+		}
+		sort.Slice(found.OpeningHours, func(i, j int) bool {
+			a, b := found.OpeningHours[i], found.OpeningHours[j]
+			if a.Weekday != b.Weekday {
+				return a.Weekday < b.Weekday
+			}
+			return a.OpensAt < b.OpensAt
+		})
+		for _, closure := range t.closures {
+			closure := closure
+			if closure.TreatmentCenterID == id {
+				found.Closures = append(found.Closures, &closure)
+			}
+		}
+		sort.Slice(found.Closures, func(i, j int) bool {
+			return found.Closures[i].Date.Before(found.Closures[j].Date)
+		})
+		treatmentCenter = &found
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return treatmentCenter, nil
+}
+
+// Delete deletes a treatment center along with its appointments starting after
+// now, they are kept for the bookings referencing them but can't be found anymore.
+// The patients waiting for the center leave its waitlist. A center with active
+// bookings after now fails with ErrTreatmentCenterBooked unless cancelBookings,
+// they are then cancelled and returned.
+func (r treatmentCenters) Delete(ctx context.Context, id uuid.UUID, cancelBookings bool,
+	now time.Time) (cancelled []*domain.PatientBooking, err error) {
+	err = r.db.transaction(ctx, func(t *tables) error {
+		treatmentCenter, err := lockTreatmentCenter(t, id)
+		if err != nil {
+			return err
+		}
+		upcoming := func(appointment domain.Appointment) bool {
+			return appointment.TreatmentCenterID == id && appointment.StartTime.After(now) &&
+				appointment.DeletedAt == nil
+		}
+		cancelled, err = withdrawBookings(t, cancelBookings, repository.ErrTreatmentCenterBooked, upcoming)
+		if err != nil {
+			return err
+		}
+
+		deletedAt := now.UTC()
+		for appointmentID, appointment := range t.appointments {
+			if upcoming(appointment) {
+				appointment.DeletedAt = &deletedAt
+				t.setAppointment(appointmentID, appointment)
+			}
+		}
+		for entryID, entry := range t.waitlist {
+			if entry.TreatmentCenterID == id && entry.Status == domain.Waiting {
+				entry.Status = domain.Withdrawn
+				t.setWaitlistEntry(entryID, entry)
+			}
+		}
+		treatmentCenter.DeletedAt = &deletedAt
+		t.setTreatmentCenter(id, treatmentCenter)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// treatmentCentersSorting sorts the treatment centers by name by default
+var treatmentCentersSorting = sorting{
+	fields: map[string]sortField{
+		"name":       func(item interface{}) interface{} { return item.(*domain.TreatmentCenter).Name },
+		"created_at": func(item interface{}) interface{} { return item.(*domain.TreatmentCenter).CreatedAt },
+	},
+	defaultSort: "name",
+}
+
+func (r treatmentCenters) All(ctx context.Context,
+	request domain.PageRequest) (result []*domain.TreatmentCenter, page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, treatmentCenter := range t.treatmentCenters {
+			treatmentCenter := treatmentCenter
+			if treatmentCenter.DeletedAt == nil {
+				result = append(result, &treatmentCenter)
+			}
+		}
+		page, err = paginate(&result, request, treatmentCentersSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// UpdateSchedule replaces the time zone, slot settings, opening hours
+// and closures of a treatment center
+func (r treatmentCenters) UpdateSchedule(ctx context.Context, treatmentCenter *domain.TreatmentCenter) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		current, err := lockTreatmentCenter(t, treatmentCenter.ID)
+		if err != nil {
+			return err
+		}
+		current.Timezone = treatmentCenter.Timezone
+		current.SlotDuration = treatmentCenter.SlotDuration
+		current.SlotCapacity = treatmentCenter.SlotCapacity
+		current.UpdatedAt = now()
+		t.setTreatmentCenter(current.ID, current)
+
+		for id, hours := range t.openingHours {
+			if hours.TreatmentCenterID == current.ID {
+				t.deleteOpeningHours(id)
+			}
+		}
+		for id, closure := range t.closures {
+			if closure.TreatmentCenterID == current.ID {
+				t.deleteClosure(id)
+			}
+		}
+		return saveSchedule(t, treatmentCenter)
+	})
+}
+
+// earthRadiusKm is the mean radius of the earth
+const earthRadiusKm = 6371.0
+
+// distanceKm is the great circle distance in km between two points
+func distanceKm(latitude, longitude, otherLatitude, otherLongitude float64) float64 {
+	radians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(
+		math.Pow(math.Sin(radians(otherLatitude-latitude)/2), 2)+
+			math.Cos(radians(latitude))*math.Cos(radians(otherLatitude))*
+				math.Pow(math.Sin(radians(otherLongitude-longitude)/2), 2)))
+}
+
+// nearbySorting sorts the nearby treatment centers by distance
+var nearbySorting = sorting{
+	fields: map[string]sortField{
+		"distance": func(item interface{}) interface{} { return item.(*domain.NearbyTreatmentCenter).DistanceKm },
+	},
+	defaultSort: "distance",
+}
+
+// Nearby returns the located treatment centers within the radius of the search,
+// the closest first, with their next appointment available after now
+func (r treatmentCenters) Nearby(ctx context.Context, search domain.NearbySearch, request domain.PageRequest,
+	now time.Time) (result []*domain.NearbyTreatmentCenter, page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, treatmentCenter := range t.treatmentCenters {
+			if treatmentCenter.DeletedAt != nil || treatmentCenter.Latitude == nil || treatmentCenter.Longitude == nil {
+				continue
+			}
+			distance := distanceKm(search.Latitude, search.Longitude, *treatmentCenter.Latitude,
+				*treatmentCenter.Longitude)
+			if distance > search.RadiusKm {
+				continue
+			}
+			nearby := &domain.NearbyTreatmentCenter{TreatmentCenter: treatmentCenter, DistanceKm: distance}
+			if next := nextAvailableAppointment(t, treatmentCenter.ID, now); next != nil {
+				nearby.NextAppointmentID, nearby.NextAppointmentAt = &next.ID, &next.StartTime
+			}
+			if search.AvailableUntil != nil &&
+				(nearby.NextAppointmentAt == nil || !nearby.NextAppointmentAt.Before(*search.AvailableUntil)) {
+				continue
+			}
+			result = append(result, nearby)
+		}
+		page, err = paginate(&result, request, nearbySorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// nextAvailableAppointment is the first appointment of the treatment center
+// starting after now with a remaining seat
+func nextAvailableAppointment(t *tables, treatmentCenterID uuid.UUID, now time.Time) (next *domain.Appointment) {
+	for _, appointment := range t.appointments {
+		appointment := appointment
+		if appointment.TreatmentCenterID != treatmentCenterID || !appointment.StartTime.After(now) ||
+			appointment.DeletedAt != nil || heldSeats(t, appointment.ID) >= appointment.Capacity {
+			continue
+		}
+		if next == nil || compareTimes(&appointment.StartTime, appointment.ID, &next.StartTime, next.ID) < 0 {
+			next = &appointment
+		}
+	}
+	return next
+}
+
+// lockTreatmentCenter returns a treatment center to change its stock or
+// appointments, it fails with ErrRecordNotFound if the center doesn't exist
+func lockTreatmentCenter(t *tables, treatmentCenterID uuid.UUID) (domain.TreatmentCenter, error) {
+	treatmentCenter, ok := t.treatmentCenters[treatmentCenterID]
+	if !ok || treatmentCenter.DeletedAt != nil {
+		return treatmentCenter, repository.ErrRecordNotFound
+	}
+	return treatmentCenter, nil
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/y9mo/covidvax/repository"
+)
+
+type unitOfWork struct {
+	store *Store
+}
+
+// NewUnitOfWork returns units of work holding the store until they end
+func NewUnitOfWork(store *Store) repository.UnitOfWork {
+	return unitOfWork{store: store}
+}
+
+// Do runs fn holding the store locked, the other readers and writers wait for
+// it to end. fn must only use the repositories it is given, those of the store
+// would wait for the lock fn holds forever.
+func (u unitOfWork) Do(ctx context.Context, fn func(r repository.Repositories) error) error {
+	if err := ctx.Err(); err != nil {
+		return repository.ErrQueryCanceled
+	}
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	defer u.store.tables.commit()
+	return u.store.tables.savepoint(func(t *tables) error {
+		if err := fn(repositories{db: &tx{tables: t}}); err != nil {
+			return err
+		}
+		// like a commit, a unit of work whose context is done leaves no change
+		if err := ctx.Err(); err != nil {
+			return repository.ErrQueryCanceled
+		}
+		return nil
+	})
+}
+
+// NewRepositories returns the repositories of the store
+func NewRepositories(store *Store) repository.Repositories {
+	return repositories{db: store}
+}
+
+type repositories struct {
+	db db
+}
+
+func (r repositories) Patients() repository.Patients {
+	return patients{db: r.db}
+}
+
+func (r repositories) TreatmentCenters() repository.TreatmentCenters {
+	return treatmentCenters{db: r.db}
+}
+
+func (r repositories) Appointments() repository.Appointments {
+	return appointments{db: r.db}
+}
+
+func (r repositories) AppointmentBookings() repository.AppointmentBookings {
+	return appointmentBookings{db: r.db}
+}
+
+func (r repositories) VaccineProducts() repository.VaccineProducts {
+	return vaccineProducts{db: r.db}
+}
+
+func (r repositories) Doses() repository.Doses {
+	return doses{db: r.db}
+}
+
+func (r repositories) VaccineLots() repository.VaccineLots {
+	return vaccineLots{db: r.db}
+}
+
+func (r repositories) Waitlist() repository.Waitlist {
+	return waitlist{db: r.db}
+}
+
+func (r repositories) EligibilityRules() repository.EligibilityRules {
+	return eligibilityRules{db: r.db}
+}
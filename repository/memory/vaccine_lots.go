@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type vaccineLots struct {
+	db db
+}
+
+func NewVaccineLots(store *Store) repository.VaccineLots {
+	return vaccineLots{db: store}
+}
+
+// Receive adds a delivered lot to the stock of its treatment center, a lot
+// number is received once per center and product
+func (r vaccineLots) Receive(ctx context.Context, vaccineLot *domain.VaccineLot) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		if _, err := lockTreatmentCenter(t, vaccineLot.TreatmentCenterID); err != nil {
+			return err
+		}
+		if _, ok := t.vaccineProducts[vaccineLot.VaccineProductID]; !ok {
+			return repository.ErrInvalidID
+		}
+		for _, other := range t.vaccineLots {
+			if other.ID == vaccineLot.ID || (other.TreatmentCenterID == vaccineLot.TreatmentCenterID &&
+				other.VaccineProductID == vaccineLot.VaccineProductID && other.LotNumber == vaccineLot.LotNumber) {
+				return repository.ErrUniqueConstraintFailure
+			}
+		}
+		vaccineLot.ExpiresOn = date(vaccineLot.ExpiresOn)
+		if vaccineLot.ReceivedAt == nil {
+			vaccineLot.ReceivedAt = now()
+		}
+		vaccineLot.CreatedAt, vaccineLot.UpdatedAt = created(vaccineLot.CreatedAt, vaccineLot.UpdatedAt)
+		t.setVaccineLot(vaccineLot.ID, *vaccineLot)
+		return nil
+	})
+}
+
+// vaccineLotsSorting sorts the lots by expiration by default, the first to expire first
+var vaccineLotsSorting = sorting{
+	fields: map[string]sortField{
+		"expires_on": func(item interface{}) interface{} { return item.(*domain.VaccineLot).ExpiresOn },
+		"lot_number": func(item interface{}) interface{} { return item.(*domain.VaccineLot).LotNumber },
+		"quantity":   func(item interface{}) interface{} { return item.(*domain.VaccineLot).Quantity },
+	},
+	defaultSort: "expires_on",
+}
+
+// AllByTreatmentCenterID returns the lots of a treatment center
+func (r vaccineLots) AllByTreatmentCenterID(ctx context.Context, treatmentCenterID uuid.UUID,
+	request domain.PageRequest) (result []*domain.VaccineLot, page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, vaccineLot := range t.vaccineLots {
+			vaccineLot := vaccineLot
+			if vaccineLot.TreatmentCenterID == treatmentCenterID {
+				result = append(result, &vaccineLot)
+			}
+		}
+		page, err = paginate(&result, request, vaccineLotsSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// checkStock fails with ErrInsufficientStock when the seats of the appointments of a
+// treatment center starting from since outnumber the doses of its lots usable on that day
+func checkStock(t *tables, treatmentCenterID uuid.UUID, since time.Time) error {
+	seats, doses := 0, 0
+	for _, appointment := range t.appointments {
+		if appointment.TreatmentCenterID == treatmentCenterID && !appointment.StartTime.Before(since) &&
+			appointment.DeletedAt == nil {
+			seats += appointment.Capacity
+		}
+	}
+	day := date(since.UTC())
+	for _, lot := range t.vaccineLots {
+		if lot.TreatmentCenterID == treatmentCenterID && !lot.ExpiresOn.Before(day) {
+			doses += lot.Quantity
+		}
+	}
+	if seats > doses {
+		return repository.ErrInsufficientStock
+	}
+	return nil
+}
+
+// useDose takes a dose of a lot of the treatment center of an appointment
+func useDose(t *tables, appointmentID uuid.UUID, vaccineProductID uuid.UUID, lotNumber string, at time.Time) error {
+	appointment := t.appointments[appointmentID]
+	for id, lot := range t.vaccineLots {
+		if lot.TreatmentCenterID != appointment.TreatmentCenterID || lot.VaccineProductID != vaccineProductID ||
+			lot.LotNumber != lotNumber {
+			continue
+		}
+		if lot.Quantity == 0 || lot.Expired(at) {
+			return repository.ErrLotUnusable
+		}
+		lot.Quantity--
+		t.setVaccineLot(id, lot)
+		return nil
+	}
+	return repository.ErrUnknownLot
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type vaccineProducts struct {
+	db db
+}
+
+func NewVaccineProducts(store *Store) repository.VaccineProducts {
+	return vaccineProducts{db: store}
+}
+
+// Create saves a vaccine product, the products have distinct names
+func (r vaccineProducts) Create(ctx context.Context, vaccineProduct *domain.VaccineProduct) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		for _, other := range t.vaccineProducts {
+			if other.ID == vaccineProduct.ID || other.Name == vaccineProduct.Name {
+				return repository.ErrUniqueConstraintFailure
+			}
+		}
+		vaccineProduct.CreatedAt, vaccineProduct.UpdatedAt = created(vaccineProduct.CreatedAt,
+			vaccineProduct.UpdatedAt)
+		t.setVaccineProduct(vaccineProduct.ID, *vaccineProduct)
+		return nil
+	})
+}
+
+func (r vaccineProducts) FindByID(ctx context.Context, id uuid.UUID) (vaccineProduct *domain.VaccineProduct,
+	err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		found, ok := t.vaccineProducts[id]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		vaccineProduct = &found
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vaccineProduct, nil
+}
+
+// vaccineProductsSorting sorts the vaccine products by name by default
+var vaccineProductsSorting = sorting{
+	fields: map[string]sortField{
+		"name":       func(item interface{}) interface{} { return item.(*domain.VaccineProduct).Name },
+		"created_at": func(item interface{}) interface{} { return item.(*domain.VaccineProduct).CreatedAt },
+	},
+	defaultSort: "name",
+}
+
+func (r vaccineProducts) All(ctx context.Context, request domain.PageRequest) (result []*domain.VaccineProduct,
+	page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, vaccineProduct := range t.vaccineProducts {
+			vaccineProduct := vaccineProduct
+			result = append(result, &vaccineProduct)
+		}
+		page, err = paginate(&result, request, vaccineProductsSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
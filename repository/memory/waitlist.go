@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+type waitlist struct {
+	db db
+}
+
+func NewWaitlist(store *Store) repository.Waitlist {
+	return waitlist{db: store}
+}
+
+// Join puts a patient on the waitlist of a treatment center, a patient
+// waits once per treatment center and a deleted center has no waitlist
+func (r waitlist) Join(ctx context.Context, entry *domain.WaitlistEntry) error {
+	entry.Status = domain.Waiting
+	return r.db.transaction(ctx, func(t *tables) error {
+		if _, err := lockTreatmentCenter(t, entry.TreatmentCenterID); err != nil {
+			return err
+		}
+		if _, ok := t.patients[entry.PatientID]; !ok {
+			return repository.ErrInvalidID
+		}
+		if _, ok := t.waitlist[entry.ID]; ok {
+			return repository.ErrUniqueConstraintFailure
+		}
+		entry.FromDate, entry.ToDate = date(entry.FromDate), date(entry.ToDate)
+		entry.CreatedAt, entry.UpdatedAt = created(entry.CreatedAt, entry.UpdatedAt)
+		return saveWaitlistEntry(t, *entry)
+	})
+}
+
+// saveWaitlistEntry stores a waitlist entry, a patient waits or is offered
+// a seat once per treatment center
+func saveWaitlistEntry(t *tables, entry domain.WaitlistEntry) error {
+	if pending(entry.Status) {
+		for _, other := range t.waitlist {
+			if other.ID != entry.ID && other.PatientID == entry.PatientID &&
+				other.TreatmentCenterID == entry.TreatmentCenterID && pending(other.Status) {
+				return repository.ErrUniqueConstraintFailure
+			}
+		}
+	}
+	t.setWaitlistEntry(entry.ID, entry)
+	return nil
+}
+
+func pending(status domain.WaitlistStatus) bool {
+	return status == domain.Waiting || status == domain.Offered
+}
+
+func (r waitlist) FindByID(ctx context.Context, id uuid.UUID) (entry *domain.WaitlistEntry, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		found, ok := t.waitlist[id]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		entry = &found
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// waitlistSorting sorts the waitlist entries by creation by default, the most recent first
+var waitlistSorting = sorting{
+	fields: map[string]sortField{
+		"created_at": func(item interface{}) interface{} { return item.(*domain.WaitlistEntry).CreatedAt },
+		"from_date":  func(item interface{}) interface{} { return item.(*domain.WaitlistEntry).FromDate },
+	},
+	defaultSort:      "created_at",
+	defaultDirection: domain.Descending,
+}
+
+// AllByPatientID returns the waitlist entries of a patient
+func (r waitlist) AllByPatientID(ctx context.Context, patientID uuid.UUID,
+	request domain.PageRequest) (result []*domain.WaitlistEntry, page domain.Page, err error) {
+	err = r.db.view(ctx, func(t *tables) error {
+		for _, entry := range t.waitlist {
+			entry := entry
+			if entry.PatientID == patientID {
+				result = append(result, &entry)
+			}
+		}
+		page, err = paginate(&result, request, waitlistSorting)
+		return err
+	})
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// Withdraw takes a waiting patient off the waitlist, any other status
+// will return ErrInvalidStatusTransition
+func (r waitlist) Withdraw(ctx context.Context, id uuid.UUID) error {
+	return r.db.transaction(ctx, func(t *tables) error {
+		entry, ok := t.waitlist[id]
+		if !ok {
+			return repository.ErrRecordNotFound
+		}
+		if entry.Status != domain.Waiting {
+			return repository.ErrInvalidStatusTransition
+		}
+		entry.Status = domain.Withdrawn
+		entry.UpdatedAt = now()
+		t.setWaitlistEntry(id, entry)
+		return nil
+	})
+}
+
+// OfferNext books a free seat of an upcoming appointment for the first patient
+// waiting for its treatment center and date, the booking awaits confirmation until
+// expiresAt. The patients with an active booking at the treatment center, whose
+// vaccine schedule doesn't allow the date or who don't meet the eligibility rules
+// in effect are skipped. No booking is returned when
+// the appointment is full or nobody is eligible.
+func (r waitlist) OfferNext(ctx context.Context, appointmentID uuid.UUID,
+	expiresAt time.Time) (*domain.AppointmentBooking, error) {
+	var offered *domain.AppointmentBooking
+	err := r.db.transaction(ctx, func(t *tables) error {
+		appointment, err := findAppointment(t, appointmentID)
+		if err != nil {
+			return err
+		}
+		if !appointment.StartTime.After(time.Now()) {
+			return nil
+		}
+
+		day := date(appointment.StartTime.UTC())
+		var entries []domain.WaitlistEntry
+		for _, entry := range t.waitlist {
+			if entry.TreatmentCenterID == appointment.TreatmentCenterID && entry.Status == domain.Waiting &&
+				!entry.FromDate.After(day) && !entry.ToDate.Before(day) &&
+				!bookedAt(t, entry.PatientID, entry.TreatmentCenterID) {
+				entries = append(entries, entry)
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return compareTimes(entries[i].CreatedAt, entries[i].ID, entries[j].CreatedAt, entries[j].ID) < 0
+		})
+
+		for _, entry := range entries {
+			booking := &domain.AppointmentBooking{
+				ID:            uuid.New(),
+				AppointmentID: appointmentID,
+				PatientID:     entry.PatientID,
+				Status:        domain.AwaitingConfirmation,
+				ExpiresAt:     &expiresAt,
+			}
+			err = book(t, booking)
+			if _, ineligible := err.(*domain.IneligibleError); ineligible {
+				continue
+			}
+			switch err {
+			case nil:
+			case repository.ErrAppointmentNotAvailable:
+				return nil
+			case repository.ErrUniqueConstraintFailure, domain.ErrDoseTooEarly, domain.ErrDoseTooLate,
+				domain.ErrSeriesCompleted:
+				continue
+			default:
+				return err
+			}
+
+			entry.Status = domain.Offered
+			entry.AppointmentBookingID = &booking.ID
+			entry.OfferedAt = now()
+			entry.UpdatedAt = entry.OfferedAt
+			t.setWaitlistEntry(entry.ID, entry)
+			offered = booking
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return offered, nil
+}
+
+// bookedAt reports whether a patient has an active booking at a treatment center
+func bookedAt(t *tables, patientID, treatmentCenterID uuid.UUID) bool {
+	for _, booking := range t.bookings {
+		if booking.PatientID == patientID && booking.Status.Active() &&
+			t.appointments[booking.AppointmentID].TreatmentCenterID == treatmentCenterID {
+			return true
+		}
+	}
+	return false
+}
+
+// settleOffers closes the waitlist entries offered the bookings, an accepted
+// offer is fulfilled and a released one lapses
+func settleOffers(t *tables, bookingIDs []uuid.UUID, status domain.WaitlistStatus) {
+	settled := map[uuid.UUID]bool{}
+	for _, id := range bookingIDs {
+		settled[id] = true
+	}
+	for id, entry := range t.waitlist {
+		if entry.Status == domain.Offered && entry.AppointmentBookingID != nil && settled[*entry.AppointmentBookingID] {
+			entry.Status = status
+			entry.UpdatedAt = now()
+			t.setWaitlistEntry(id, entry)
+		}
+	}
+}
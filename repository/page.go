@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/y9mo/covidvax/domain"
+)
+
+// sortField is a field a list can be sorted by. The column orders the list and
+// its unqualified name is the column of the field in the scanned items. expr
+// replaces the column in the cursor condition when it is an alias of the select.
+type sortField struct {
+	column string
+	expr   string
+	args   []interface{}
+}
+
+func (f sortField) name() string {
+	return f.column[strings.LastIndex(f.column, ".")+1:]
+}
+
+// sorting lists the fields a list can be sorted by, the id column breaks the ties
+type sorting struct {
+	fields           map[string]sortField
+	idColumn         string
+	defaultSort      string
+	defaultDirection domain.SortDirection
+}
+
+// paginate scans into dest, a pointer to a slice, the page of the query asked by
+// the request, the query must not be ordered or limited
+func paginate(db *gorm.DB, query *gorm.DB, request domain.PageRequest, sorting sorting,
+	dest interface{}) (page domain.Page, err error) {
+	sort, direction := request.Sort, request.Direction
+	if sort == "" {
+		sort = sorting.defaultSort
+	}
+	// the default sort keeps its direction unless the request gives one
+	if direction == "" && sort == sorting.defaultSort {
+		direction = sorting.defaultDirection
+	}
+	if direction == "" {
+		direction = domain.Ascending
+	}
+	field, ok := sorting.fields[sort]
+	if !ok {
+		return page, ErrInvalidSort
+	}
+	if request.Cursor != nil && (request.Cursor.Sort != sort || request.Cursor.Direction != direction) {
+		return page, ErrInvalidCursor
+	}
+
+	err = db.Raw("SELECT count(*) FROM (?) AS list", query.QueryExpr()).Row().Scan(&page.Total)
+	if err != nil {
+		return page, err
+	}
+
+	comparison, order := ">", "ASC"
+	if direction == domain.Descending {
+		comparison, order = "<", "DESC"
+	}
+	if request.Cursor != nil {
+		expr := field.expr
+		if expr == "" {
+			expr = field.column
+		}
+		value, err := cursorArg(db, dest, field, request.Cursor.Value)
+		if err != nil {
+			return page, err
+		}
+		args := append(append([]interface{}(nil), field.args...), value, request.Cursor.ID)
+		query = query.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", expr, sorting.idColumn, comparison), args...)
+	}
+	query = query.Order(fmt.Sprintf("%s %s, %s %s", field.column, order, sorting.idColumn, order))
+	// one more item tells whether there is a next page
+	if request.Limit > 0 {
+		query = query.Limit(request.Limit + 1)
+	}
+	if err = query.Scan(dest).Error; err != nil {
+		return page, err
+	}
+
+	items := reflect.ValueOf(dest).Elem()
+	if request.Limit == 0 || items.Len() <= request.Limit {
+		return page, nil
+	}
+	items.Set(items.Slice(0, request.Limit))
+	last := db.NewScope(items.Index(request.Limit - 1).Interface())
+	value, _ := last.FieldByName(field.name())
+	id, _ := last.FieldByName("id")
+	page.NextCursor = &domain.Cursor{
+		Sort:      sort,
+		Direction: direction,
+		Value:     cursorValue(value.Field.Interface()),
+		ID:        id.Field.Interface().(uuid.UUID),
+	}
+	return page, nil
+}
+
+// cursorArg parses the value of a cursor into the type of the sort field in the
+// items of dest, the databases compare it with the field then rather than with a string
+func cursorArg(db *gorm.DB, dest interface{}, field sortField, value string) (interface{}, error) {
+	item := reflect.TypeOf(dest).Elem().Elem()
+	if item.Kind() == reflect.Ptr {
+		item = item.Elem()
+	}
+	scoped, ok := db.NewScope(reflect.New(item).Interface()).FieldByName(field.name())
+	if !ok {
+		return value, nil
+	}
+
+	var arg interface{}
+	var err error
+	switch scoped.Field.Interface().(type) {
+	case time.Time, *time.Time:
+		arg, err = time.Parse(time.RFC3339Nano, value)
+	case float64:
+		arg, err = strconv.ParseFloat(value, 64)
+	case int:
+		arg, err = strconv.Atoi(value)
+	default:
+		arg = value
+	}
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return arg, nil
+}
+
+// cursorValue formats the value of a sort field as a parameter cursorArg reads back
+func cursorValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"context"
+	"strings"
+
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/y9mo/covidvax/domain"
@@ -8,11 +11,13 @@ import (
 )
 
 type Patients interface {
-	Create(patient *domain.Patient) error
-	Update(patient *domain.Patient) error
-	FindByID(id uuid.UUID) (*domain.Patient, error)
-	Delete(patient *domain.Patient) error
-	All() ([]*domain.Patient, error)
+	Create(ctx context.Context, patient *domain.Patient) error
+	Update(ctx context.Context, patient *domain.Patient) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.Patient, error)
+	Delete(ctx context.Context, patient *domain.Patient) error
+	All(ctx context.Context, request domain.PageRequest) ([]*domain.Patient, domain.Page, error)
+	Duplicates(ctx context.Context, id uuid.UUID) ([]*domain.DuplicateCandidate, error)
+	Merge(ctx context.Context, survivorID, duplicateID uuid.UUID) error
 }
 type patients struct {
 	db     *gorm.DB
@@ -26,19 +31,36 @@ func NewPatients(db *gorm.DB, logger *zap.Logger) Patients {
 	}
 }
 
-func (r patients) Create(patient *domain.Patient) error {
-	err := r.db.Debug().Create(patient).Error
+func (r patients) Create(ctx context.Context, patient *domain.Patient) error {
+	err := withContext(ctx, r.db).Debug().Create(patient).Error
 	return handleGormError(err, r.logger)
 }
 
-func (r patients) Update(patient *domain.Patient) error {
-	err := r.db.Debug().Update(patient).Error
-	return handleGormError(err, r.logger)
+// Update saves the fields of a patient, empty ones included
+func (r patients) Update(ctx context.Context, patient *domain.Patient) error {
+	db := withContext(ctx, r.db).Debug()
+	result := db.Model(&domain.Patient{}).Where("id = ?", patient.ID).Updates(map[string]interface{}{
+		"email":              patient.Email,
+		"first_name":         patient.FirstName,
+		"last_name":          patient.LastName,
+		"phone":              patient.Phone,
+		"birth_date":         patient.BirthDate,
+		"national_health_id": patient.NationalHealthID,
+		"risk_categories":    patient.RiskCategories,
+		"profession":         patient.Profession,
+	})
+	if err := handleGormError(result.Error, r.logger); err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
 }
 
-func (r patients) FindByID(id uuid.UUID) (*domain.Patient, error) {
+func (r patients) FindByID(ctx context.Context, id uuid.UUID) (*domain.Patient, error) {
 	patient := domain.Patient{}
-	err := r.db.Debug().Where("id = ?", id).Find(&patient).Error
+	err := withContext(ctx, r.db).Debug().Where("id = ?", id).Find(&patient).Error
 	err = handleGormError(err, r.logger)
 	if err != nil {
 		return nil, err
@@ -46,16 +68,152 @@ func (r patients) FindByID(id uuid.UUID) (*domain.Patient, error) {
 	return &patient, nil
 }
 
-func (r patients) Delete(patient *domain.Patient) error {
-	err := r.db.Debug().Delete(patient).Error
+func (r patients) Delete(ctx context.Context, patient *domain.Patient) error {
+	err := withContext(ctx, r.db).Debug().Delete(patient).Error
 	return handleGormError(err, r.logger)
 }
 
-func (r patients) All() (result []*domain.Patient, err error) {
-	err = r.db.Debug().Find(&result).Error
+// patientsSorting sorts the patients by creation by default
+var patientsSorting = sorting{
+	fields: map[string]sortField{
+		"created_at": {column: "patients.created_at"},
+		"last_name":  {column: "patients.last_name"},
+		"email":      {column: "patients.email"},
+	},
+	idColumn:    "patients.id",
+	defaultSort: "created_at",
+}
+
+func (r patients) All(ctx context.Context, request domain.PageRequest) (result []*domain.Patient,
+	page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	page, err = paginate(db, db.Model(&domain.Patient{}), request, patientsSorting, &result)
 	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// Duplicates returns the patients who may be the same person as the patient,
+// the ones sharing its last name, its national health identifier or its
+// canonical email are matched by domain.Patient.DuplicateReasons
+func (r patients) Duplicates(ctx context.Context, id uuid.UUID) ([]*domain.DuplicateCandidate, error) {
+	patient, err := r.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+
+	lookalike := []string{"lower(last_name) = lower(?)", "national_health_id = ?"}
+	args := []interface{}{patient.LastName, patient.NationalHealthID}
+	if email := domain.CanonicalEmail(patient.Email); email != "" {
+		// the column generated by the migrations is indexed
+		lookalike = append(lookalike, "canonical_email = ?")
+		args = append(args, email)
+	}
+	var lookalikes []*domain.Patient
+	err = withContext(ctx, r.db).Debug().
+		Where("id <> ?", id).
+		Where(strings.Join(lookalike, " OR "), args...).
+		Order("created_at").
+		Find(&lookalikes).Error
+	if err = handleGormError(err, r.logger); err != nil {
+		return nil, err
+	}
+
+	candidates := []*domain.DuplicateCandidate{}
+	for _, lookalike := range lookalikes {
+		if reasons := patient.DuplicateReasons(lookalike); len(reasons) > 0 {
+			candidates = append(candidates, &domain.DuplicateCandidate{Patient: lookalike, Reasons: reasons})
+		}
+	}
+	return candidates, nil
+}
+
+// Merge moves the bookings, doses and waitlist entries of the duplicate patient
+// to the surviving one, which takes the phone, birth date and national health
+// identifier it misses, then deletes the duplicate. Two patients holding a seat
+// of the same appointment or with a dose of the same number can't be merged.
+func (r patients) Merge(ctx context.Context, survivorID, duplicateID uuid.UUID) error {
+	if survivorID == duplicateID {
+		return ErrInvalidID
+	}
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		var locked []*domain.Patient
+		err := forUpdate(tx, "FOR UPDATE").
+			Where("id IN (?)", []uuid.UUID{survivorID, duplicateID}).Order("id").Find(&locked).Error
+		if err != nil {
+			return err
+		}
+		if len(locked) != 2 {
+			return ErrRecordNotFound
+		}
+		survivor, duplicate := locked[0], locked[1]
+		if survivor.ID != survivorID {
+			survivor, duplicate = duplicate, survivor
+		}
+
+		var conflicts int
+		err = tx.Raw(`SELECT
+			(SELECT COUNT(*) FROM appointment_bookings d
+				JOIN appointment_bookings s ON s.appointment_id = d.appointment_id
+				WHERE d.patient_id = ? AND s.patient_id = ? AND d.status IN (?) AND s.status IN (?)) +
+			(SELECT COUNT(*) FROM doses d
+				JOIN doses s ON s.number = d.number
+				WHERE d.patient_id = ? AND s.patient_id = ?)`,
+			duplicateID, survivorID, domain.HoldingStatuses, domain.HoldingStatuses,
+			duplicateID, survivorID).Row().Scan(&conflicts)
+		if err != nil {
+			return err
+		}
+		if conflicts > 0 {
+			return ErrPatientsConflict
+		}
+
+		// a patient waits once for a treatment center: an offer of the duplicate
+		// replaces the place of the survivor, which keeps its own otherwise
+		pending := []domain.WaitlistStatus{domain.Waiting, domain.Offered}
+		err = tx.Model(&domain.WaitlistEntry{}).
+			Where("patient_id = ? AND status = ?", survivorID, domain.Waiting).
+			Where(`treatment_center_id IN (SELECT treatment_center_id FROM waitlist_entries
+				WHERE patient_id = ? AND status = ?)`, duplicateID, domain.Offered).
+			Update("status", domain.Withdrawn).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&domain.WaitlistEntry{}).
+			Where("patient_id = ? AND status IN (?)", duplicateID, pending).
+			Where(`treatment_center_id IN (SELECT treatment_center_id FROM waitlist_entries
+				WHERE patient_id = ? AND status IN (?))`, survivorID, pending).
+			Update("status", domain.Withdrawn).Error
+		if err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&domain.AppointmentBooking{}, &domain.Dose{}, &domain.WaitlistEntry{}} {
+			err = tx.Model(model).Where("patient_id = ?", duplicateID).Update("patient_id", survivorID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// the duplicate goes first as the survivor may take its national health identifier
+		if err = tx.Where("id = ?", duplicateID).Delete(&domain.Patient{}).Error; err != nil {
+			return err
+		}
+		missing := map[string]interface{}{}
+		if survivor.Phone == "" && duplicate.Phone != "" {
+			missing["phone"] = duplicate.Phone
+		}
+		if survivor.BirthDate == nil && duplicate.BirthDate != nil {
+			missing["birth_date"] = duplicate.BirthDate
+		}
+		if survivor.NationalHealthID == nil && duplicate.NationalHealthID != nil {
+			missing["national_health_id"] = duplicate.NationalHealthID
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		return tx.Model(&domain.Patient{}).Where("id = ?", survivorID).Updates(missing).Error
+	})
+	return handleGormError(err, r.logger)
 }
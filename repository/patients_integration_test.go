@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/y9mo/covidvax/domain"
@@ -54,15 +56,26 @@ func (s *PatientsIntegrationTestSuite) TestCreate() {
 			},
 			wantErr: ErrUniqueConstraintFailure,
 		},
+		{
+			name: "EmailCase",
+			id:   uuid.MustParse("1b1e2f3a-4c5d-4e6f-8a9b-0c1d2e3f4a5b"),
+			patient: &domain.Patient{
+				ID:        uuid.MustParse("1b1e2f3a-4c5d-4e6f-8a9b-0c1d2e3f4a5b"),
+				Email:     "Patient.One@Some.com",
+				FirstName: "Patient",
+				LastName:  "One",
+			},
+			wantErr: ErrUniqueConstraintFailure,
+		},
 	}
 	for _, tc := range tests {
 		tc := tc
 		s.Run(tc.name, func() {
-			err := s.patientsRepository.Create(tc.patient)
+			err := s.patientsRepository.Create(context.Background(), tc.patient)
 			if tc.wantErr != nil {
 				s.Assert().Equal(tc.wantErr, err)
 			} else {
-				gotPatient, err := s.patientsRepository.FindByID(tc.id)
+				gotPatient, err := s.patientsRepository.FindByID(context.Background(), tc.id)
 				s.Assert().NoError(err)
 
 				s.Assert().Equal(tc.wantErr, err)
@@ -75,11 +88,215 @@ func (s *PatientsIntegrationTestSuite) TestCreate() {
 	}
 }
 
-func TestPatientsIntegrationTestSuite(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skipping PatientsIntegrationTest in short mode.")
-		return
+func (s *PatientsIntegrationTestSuite) TestUpdate() {
+	tests := []struct {
+		name    string
+		patient *domain.Patient
+		wantErr error
+	}{
+		{
+			name: "Successful",
+			patient: &domain.Patient{
+				ID:        uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"),
+				Email:     "patient.one@other.com",
+				FirstName: "Patient",
+				LastName:  "First",
+				Phone:     "0611223344",
+			},
+			wantErr: nil,
+		},
+		{
+			name: "EmailTaken",
+			patient: &domain.Patient{
+				ID:        uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"),
+				Email:     "patient.two@other.com",
+				FirstName: "Patient",
+				LastName:  "One",
+			},
+			wantErr: ErrUniqueConstraintFailure,
+		},
+		{
+			name: "NotFound",
+			patient: &domain.Patient{
+				ID:        uuid.MustParse("d93f7ecc-816f-4124-b41e-dcfa58f03761"),
+				Email:     "patient.zero@some.com",
+				FirstName: "Patient",
+				LastName:  "Zero",
+			},
+			wantErr: ErrRecordNotFound,
+		},
 	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			err := s.patientsRepository.Update(context.Background(), tc.patient)
+			s.Assert().Equal(tc.wantErr, err)
+			if tc.wantErr != nil {
+				return
+			}
+			gotPatient, err := s.patientsRepository.FindByID(context.Background(), tc.patient.ID)
+			s.Assert().NoError(err)
+			s.Assert().Equal(tc.patient.Email, gotPatient.Email)
+			s.Assert().Equal(tc.patient.LastName, gotPatient.LastName)
+			s.Assert().Equal(tc.patient.Phone, gotPatient.Phone)
+		})
+	}
+}
+
+func (s *PatientsIntegrationTestSuite) TestAll() {
+	lastNames := func(patients []*domain.Patient) (names []string) {
+		for _, patient := range patients {
+			names = append(names, patient.LastName)
+		}
+		return names
+	}
+
+	got, page, err := s.patientsRepository.All(context.Background(), domain.PageRequest{Limit: 4, Sort: "last_name"})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"Five", "Four", "One", "Six"}, lastNames(got))
+	s.Assert().Equal(int64(6), page.Total)
+	s.Require().NotNil(page.NextCursor)
+
+	got, page, err = s.patientsRepository.All(context.Background(),
+		domain.PageRequest{Limit: 4, Sort: "last_name", Cursor: page.NextCursor})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"Three", "Two"}, lastNames(got))
+	s.Assert().Nil(page.NextCursor)
+
+	got, _, err = s.patientsRepository.All(context.Background(),
+		domain.PageRequest{Limit: 2, Sort: "last_name", Direction: domain.Descending})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"Two", "Three"}, lastNames(got))
+
+	_, _, err = s.patientsRepository.All(context.Background(), domain.PageRequest{Sort: "phone"})
+	s.Assert().Equal(ErrInvalidSort, err)
+}
+
+func (s *PatientsIntegrationTestSuite) TestDuplicates() {
+	birthDate := time.Date(1980, 5, 17, 0, 0, 0, 0, time.UTC)
+	nhi := "180057512345678"
+	one := uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c")
+	err := s.patientsRepository.Update(context.Background(), &domain.Patient{ID: one, Email: "patient.one@some.com",
+		FirstName: "Patient", LastName: "One", BirthDate: &birthDate})
+	s.Require().NoError(err)
+	for _, patient := range []*domain.Patient{
+		{ID: uuid.New(), Email: "p.one@other.com", FirstName: "patient", LastName: "ONE", BirthDate: &birthDate},
+		{ID: uuid.New(), Email: "patientone+vax@some.com", FirstName: "Patricia", LastName: "Smith"},
+		{ID: uuid.New(), Email: "patient.one@some.fr", FirstName: "Patricia", LastName: "Smith"},
+		{ID: uuid.New(), Email: "someone@any.com", FirstName: "Patient", LastName: "One"},
+	} {
+		s.Require().NoError(s.patientsRepository.Create(context.Background(), patient))
+	}
+
+	got, err := s.patientsRepository.Duplicates(context.Background(), one)
+	s.Require().NoError(err)
+	s.Require().Len(got, 2)
+	s.Assert().Equal("p.one@other.com", got[0].Email)
+	s.Assert().Equal([]string{domain.DuplicateNameAndBirthDate}, got[0].Reasons)
+	s.Assert().Equal("patientone+vax@some.com", got[1].Email)
+	s.Assert().Equal([]string{domain.DuplicateEmail}, got[1].Reasons)
+
+	err = s.patientsRepository.Update(context.Background(), &domain.Patient{ID: one, Email: "patient.one@some.com",
+		FirstName: "Patient", LastName: "One", NationalHealthID: &nhi})
+	s.Require().NoError(err)
+	err = s.patientsRepository.Create(context.Background(), &domain.Patient{ID: uuid.New(), Email: "other@any.com",
+		FirstName: "Other", LastName: "Patient", NationalHealthID: &nhi})
+	s.Assert().Equal(ErrUniqueConstraintFailure, err)
+
+	got, err = s.patientsRepository.Duplicates(context.Background(),
+		uuid.MustParse("24e32685-0a32-4a9d-bc22-0e98cdaf5884"))
+	s.Require().NoError(err)
+	s.Assert().Empty(got)
+
+	_, err = s.patientsRepository.Duplicates(context.Background(),
+		uuid.MustParse("d93f7ecc-816f-4124-b41e-dcfa58f03761"))
+	s.Assert().Equal(ErrRecordNotFound, err)
+}
+
+func (s *PatientsIntegrationTestSuite) TestMerge() {
+	dosesRepository := NewDoses(s.IntegrationSuite.DB(), zap.NewExample())
+	waitlistRepository := NewWaitlist(s.IntegrationSuite.DB(), zap.NewExample())
+	bookingsRepository := NewAppointmentBookings(s.IntegrationSuite.DB(), zap.NewExample())
+	one := uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c")
+	two := uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a")
+	three := uuid.MustParse("24e32685-0a32-4a9d-bc22-0e98cdaf5884")
+	four := uuid.MustParse("6f0bd1a3-7d0a-4f5e-9a61-2b1c3d4e5f60")
+	six := uuid.MustParse("3e5b7d9f-1a2c-4e6a-8b0d-c2e4f6a8b0d2")
+
+	s.Run("Successful", func() {
+		birthDate := time.Date(1980, 5, 17, 0, 0, 0, 0, time.UTC)
+		err := s.patientsRepository.Update(context.Background(), &domain.Patient{ID: six, Email: "patient.six@any.com",
+			FirstName: "Patient", LastName: "Six", Phone: "+33611223344", BirthDate: &birthDate})
+		s.Require().NoError(err)
+
+		s.Require().NoError(s.patientsRepository.Merge(context.Background(), three, six))
+
+		_, err = s.patientsRepository.FindByID(context.Background(), six)
+		s.Assert().Equal(ErrRecordNotFound, err)
+		survivor, err := s.patientsRepository.FindByID(context.Background(), three)
+		s.Require().NoError(err)
+		s.Assert().Equal("patient.three@any.com", survivor.Email)
+		s.Assert().Equal("+33611223344", survivor.Phone)
+		s.Require().NotNil(survivor.BirthDate)
+		s.Assert().True(birthDate.Equal(*survivor.BirthDate))
+
+		doses, _, err := dosesRepository.AllByPatientID(context.Background(), three, domain.PageRequest{})
+		s.Require().NoError(err)
+		s.Require().Len(doses, 1)
+		s.Assert().Equal(uuid.MustParse("5c4b3a29-1807-46f5-a4e3-d2c1b0a9f8e7"), doses[0].ID)
+		entry, err := waitlistRepository.FindByID(context.Background(),
+			uuid.MustParse("1c2d3e4f-5a6b-4c7d-8e9f-a0b1c2d3e4f5"))
+		s.Require().NoError(err)
+		s.Assert().Equal(three, entry.PatientID)
+		s.Assert().Equal(domain.Waiting, entry.Status)
+	})
+
+	s.Run("BothWaiting", func() {
+		s.Require().NoError(s.patientsRepository.Merge(context.Background(), one, two))
+
+		entry, err := waitlistRepository.FindByID(context.Background(),
+			uuid.MustParse("2d3e4f5a-6b7c-4d8e-9fa0-b1c2d3e4f5a6"))
+		s.Require().NoError(err)
+		s.Assert().Equal(one, entry.PatientID)
+		s.Assert().Equal(domain.Withdrawn, entry.Status)
+		entry, err = waitlistRepository.FindByID(context.Background(),
+			uuid.MustParse("3e4f5a6b-7c8d-4e9f-a0b1-c2d3e4f5a6b7"))
+		s.Require().NoError(err)
+		s.Assert().Equal(domain.Waiting, entry.Status)
+	})
+
+	s.Run("SameAppointment", func() {
+		err := s.IntegrationSuite.DB().Create(&domain.AppointmentBooking{
+			ID:            uuid.New(),
+			AppointmentID: uuid.MustParse("4cdb532d-bfe8-4af6-b9b5-d5078985a350"),
+			PatientID:     four,
+			Status:        domain.Confirmed,
+		}).Error
+		s.Require().NoError(err)
+
+		s.Assert().Equal(ErrPatientsConflict, s.patientsRepository.Merge(context.Background(), three, four))
+		_, err = s.patientsRepository.FindByID(context.Background(), four)
+		s.Assert().NoError(err)
+		booking, err := bookingsRepository.FindByID(context.Background(),
+			uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"))
+		s.Require().NoError(err)
+		s.Assert().Equal(three, booking.PatientID)
+	})
+
+	s.Run("NotFound", func() {
+		err := s.patientsRepository.Merge(context.Background(),
+			three, uuid.MustParse("d93f7ecc-816f-4124-b41e-dcfa58f03761"))
+		s.Assert().Equal(ErrRecordNotFound, err)
+	})
+
+	s.Run("SamePatient", func() {
+		s.Assert().Equal(ErrInvalidID, s.patientsRepository.Merge(context.Background(), three, three))
+	})
+}
+
+func TestPatientsIntegrationTestSuite(t *testing.T) {
 	t.Parallel()
-	suite.Run(t, new(PatientsIntegrationTestSuite))
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &PatientsIntegrationTestSuite{IntegrationSuite: backend}
+	})
 }
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/y9mo/covidvax/domain"
+	"go.uber.org/zap"
+)
+
+type Reminders interface {
+	Due(ctx context.Context, now time.Time, leadTime time.Duration) ([]*domain.PatientBooking, error)
+	Claim(ctx context.Context, reminder *domain.BookingReminder) (bool, error)
+	Release(ctx context.Context, reminder *domain.BookingReminder) error
+}
+type reminders struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewReminders(db *gorm.DB, logger *zap.Logger) Reminders {
+	return reminders{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Due returns the confirmed bookings starting within leadTime from now which
+// weren't reminded for this lead time or a shorter one, the first to start first
+func (r reminders) Due(ctx context.Context, now time.Time,
+	leadTime time.Duration) (result []*domain.PatientBooking, err error) {
+	err = patientBookings(withContext(ctx, r.db).Debug()).
+		Where("appointment_bookings.status = ?", domain.Confirmed).
+		Where("a.start_time > ? AND a.start_time <= ?", now, now.Add(leadTime)).
+		Where(`NOT EXISTS (SELECT 1 FROM booking_reminders br
+			WHERE br.appointment_booking_id = appointment_bookings.id AND br.lead_minutes <= ?)`,
+			int(leadTime.Minutes())).
+		Order("a.start_time, appointment_bookings.id").
+		Scan(&result).Error
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Claim records a reminder before it is sent, it reports false when the
+// reminder was already claimed so concurrent or restarted schedulers never
+// send it twice
+func (r reminders) Claim(ctx context.Context, reminder *domain.BookingReminder) (bool, error) {
+	result := withContext(ctx, r.db).Debug().Set("gorm:insert_option", "ON CONFLICT DO NOTHING").Create(reminder)
+	// postgres returns no key when the insert is skipped, sqlite affects no row
+	if result.Error == sql.ErrNoRows || result.Error == nil && result.RowsAffected == 0 {
+		return false, nil
+	}
+	err := handleGormError(result.Error, r.logger)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Release forgets a claimed reminder which couldn't be sent so it is retried
+func (r reminders) Release(ctx context.Context, reminder *domain.BookingReminder) error {
+	err := withContext(ctx, r.db).Debug().
+		Where("appointment_booking_id = ? AND lead_minutes = ?", reminder.AppointmentBookingID, reminder.LeadMinutes).
+		Delete(&domain.BookingReminder{}).Error
+	return handleGormError(err, r.logger)
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/testutils"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type RemindersIntegrationTestSuite struct {
+	testutils.IntegrationSuite
+	remindersRepository Reminders
+}
+
+func (s *RemindersIntegrationTestSuite) SetupSuite() {
+	s.IntegrationSuite.SetupSuite()
+	s.remindersRepository = NewReminders(s.IntegrationSuite.DB(), zap.NewExample())
+}
+
+func (s *RemindersIntegrationTestSuite) TearDownSuite() {
+	s.IntegrationSuite.TearDownSuite()
+}
+
+func (s *RemindersIntegrationTestSuite) TestDue() {
+	// the fixture booking f859 is confirmed for 2021-11-13 18:00
+	now := time.Date(2021, 11, 13, 17, 0, 0, 0, time.UTC)
+	bookingID := uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710")
+
+	due, err := s.remindersRepository.Due(context.Background(), now, 30*time.Minute)
+	s.Require().NoError(err)
+	s.Assert().Empty(due)
+
+	due, err = s.remindersRepository.Due(context.Background(), now, 2*time.Hour)
+	s.Require().NoError(err)
+	s.Require().Len(due, 1)
+	s.Assert().Equal(bookingID, due[0].ID)
+	s.Assert().Equal("Center in the game", due[0].TreatmentCenterName)
+
+	reminder := &domain.BookingReminder{AppointmentBookingID: bookingID, LeadMinutes: 120, SentAt: now}
+	claimed, err := s.remindersRepository.Claim(context.Background(), reminder)
+	s.Require().NoError(err)
+	s.Assert().True(claimed)
+
+	claimed, err = s.remindersRepository.Claim(context.Background(), reminder)
+	s.Require().NoError(err)
+	s.Assert().False(claimed)
+
+	// a reminder for a shorter lead time covers the longer ones
+	for _, leadTime := range []time.Duration{2 * time.Hour, 24 * time.Hour} {
+		due, err = s.remindersRepository.Due(context.Background(), now, leadTime)
+		s.Require().NoError(err)
+		s.Assert().Empty(due)
+	}
+
+	s.Require().NoError(s.remindersRepository.Release(context.Background(), reminder))
+	due, err = s.remindersRepository.Due(context.Background(), now, 2*time.Hour)
+	s.Require().NoError(err)
+	s.Assert().Len(due, 1)
+}
+
+func TestRemindersIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &RemindersIntegrationTestSuite{IntegrationSuite: backend}
+	})
+}
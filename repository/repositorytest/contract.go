@@ -0,0 +1,848 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+)
+
+// Backend is a storage the contract suite runs against
+type Backend struct {
+	repository.Repositories
+	Reminders  repository.Reminders
+	UnitOfWork repository.UnitOfWork
+}
+
+// ContractSuite checks a storage backend keeps the semantics the api relies
+// on, every backend must pass it. NewBackend returns an empty backend for
+// each test.
+type ContractSuite struct {
+	suite.Suite
+	NewBackend func() Backend
+	backend    Backend
+	ctx        context.Context
+}
+
+func (s *ContractSuite) SetupTest() {
+	s.backend = s.NewBackend()
+	s.ctx = context.Background()
+}
+
+func (s *ContractSuite) patient(email string) *domain.Patient {
+	patient := &domain.Patient{ID: uuid.New(), Email: email, FirstName: "Ada", LastName: "Lovelace"}
+	s.Require().NoError(s.backend.Patients().Create(s.ctx, patient))
+	return patient
+}
+
+func (s *ContractSuite) treatmentCenter(name string, latitude, longitude float64) *domain.TreatmentCenter {
+	treatmentCenter := &domain.TreatmentCenter{
+		ID:           uuid.New(),
+		Name:         name,
+		Address:      "1 rue de la Paix, Paris",
+		Phone:        "+33100000000",
+		Latitude:     &latitude,
+		Longitude:    &longitude,
+		Timezone:     "Europe/Paris",
+		SlotDuration: 10,
+		SlotCapacity: 1,
+	}
+	s.Require().NoError(s.backend.TreatmentCenters().Create(s.ctx, treatmentCenter))
+	return treatmentCenter
+}
+
+func (s *ContractSuite) vaccineProduct(name string) *domain.VaccineProduct {
+	vaccineProduct := &domain.VaccineProduct{ID: uuid.New(), Name: name, Doses: 2, MinIntervalDays: 21}
+	s.Require().NoError(s.backend.VaccineProducts().Create(s.ctx, vaccineProduct))
+	return vaccineProduct
+}
+
+// stock receives a lot of quantity doses expiring in a year at the treatment center
+func (s *ContractSuite) stock(treatmentCenter *domain.TreatmentCenter, quantity int) *domain.VaccineLot {
+	receivedAt := time.Now().UTC()
+	lot := &domain.VaccineLot{
+		ID:                uuid.New(),
+		TreatmentCenterID: treatmentCenter.ID,
+		VaccineProductID:  s.vaccineProduct("Vaccine " + uuid.NewString()).ID,
+		LotNumber:         "LOT-1",
+		Quantity:          quantity,
+		ExpiresOn:         receivedAt.AddDate(1, 0, 0),
+		ReceivedAt:        &receivedAt,
+	}
+	s.Require().NoError(s.backend.VaccineLots().Receive(s.ctx, lot))
+	return lot
+}
+
+func (s *ContractSuite) appointment(treatmentCenter *domain.TreatmentCenter, start time.Time,
+	capacity int) *domain.Appointment {
+	appointment := &domain.Appointment{
+		ID:                uuid.New(),
+		TreatmentCenterID: treatmentCenter.ID,
+		StartTime:         start,
+		Capacity:          capacity,
+	}
+	s.Require().NoError(s.backend.Appointments().Create(s.ctx, appointment))
+	return appointment
+}
+
+func (s *ContractSuite) book(appointment *domain.Appointment, patient *domain.Patient) *domain.AppointmentBooking {
+	booking := &domain.AppointmentBooking{
+		ID:            uuid.New(),
+		AppointmentID: appointment.ID,
+		PatientID:     patient.ID,
+		Status:        domain.AwaitingConfirmation,
+	}
+	s.Require().NoError(s.backend.AppointmentBookings().Create(s.ctx, booking))
+	return booking
+}
+
+// upcoming is a start time in days, on the hour so that it is a valid slot
+func upcoming(days int) time.Time {
+	return time.Now().UTC().Truncate(time.Hour).AddDate(0, 0, days)
+}
+
+func (s *ContractSuite) TestRecordNotFound() {
+	unknown := uuid.New()
+	_, err := s.backend.Patients().FindByID(s.ctx, unknown)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+	_, err = s.backend.TreatmentCenters().FindByID(s.ctx, unknown)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+	_, err = s.backend.Appointments().FindByID(s.ctx, unknown)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+	_, err = s.backend.AppointmentBookings().FindByID(s.ctx, unknown)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+	_, err = s.backend.VaccineProducts().FindByID(s.ctx, unknown)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+	_, err = s.backend.Waitlist().FindByID(s.ctx, unknown)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+	_, err = s.backend.EligibilityRules().FindByID(s.ctx, unknown)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+
+	s.Assert().Equal(repository.ErrRecordNotFound,
+		s.backend.Patients().Update(s.ctx, &domain.Patient{ID: unknown, Email: "nobody@example.com"}))
+	s.Assert().Equal(repository.ErrRecordNotFound,
+		s.backend.TreatmentCenters().Update(s.ctx, &domain.TreatmentCenter{ID: unknown}))
+	s.Assert().Equal(repository.ErrRecordNotFound, s.backend.AppointmentBookings().Confirm(s.ctx, unknown))
+	s.Assert().Equal(repository.ErrRecordNotFound, s.backend.AppointmentBookings().Cancel(s.ctx, unknown, "test"))
+	s.Assert().Equal(repository.ErrRecordNotFound, s.backend.Waitlist().Withdraw(s.ctx, unknown))
+	s.Assert().Equal(repository.ErrRecordNotFound, s.backend.EligibilityRules().Delete(s.ctx, unknown))
+}
+
+func (s *ContractSuite) TestPatientsUniqueConstraints() {
+	nationalHealthID := "1 46 03 75 123 456 78"
+	patient := s.patient("ada@example.com")
+	patient.NationalHealthID = &nationalHealthID
+	s.Require().NoError(s.backend.Patients().Update(s.ctx, patient))
+
+	same := &domain.Patient{ID: uuid.New(), Email: "ADA@example.com", FirstName: "Ada", LastName: "King"}
+	s.Assert().Equal(repository.ErrUniqueConstraintFailure, s.backend.Patients().Create(s.ctx, same))
+	same.Email, same.NationalHealthID = "ada.king@example.com", &nationalHealthID
+	s.Assert().Equal(repository.ErrUniqueConstraintFailure, s.backend.Patients().Create(s.ctx, same))
+
+	other := s.patient("grace@example.com")
+	other.Email = "Ada@Example.com"
+	s.Assert().Equal(repository.ErrUniqueConstraintFailure, s.backend.Patients().Update(s.ctx, other))
+
+	s.vaccineProduct("Comirnaty")
+	s.Assert().Equal(repository.ErrUniqueConstraintFailure, s.backend.VaccineProducts().Create(s.ctx,
+		&domain.VaccineProduct{ID: uuid.New(), Name: "Comirnaty", Doses: 2}))
+}
+
+func (s *ContractSuite) TestAvailableSeats() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 10)
+	appointment := s.appointment(treatmentCenter, upcoming(2), 2)
+	first, second := s.patient("ada@example.com"), s.patient("grace@example.com")
+
+	booking := s.book(appointment, first)
+	duplicate := &domain.AppointmentBooking{ID: uuid.New(), AppointmentID: appointment.ID, PatientID: first.ID,
+		Status: domain.AwaitingConfirmation}
+	s.Assert().Equal(repository.ErrUniqueConstraintFailure,
+		s.backend.AppointmentBookings().Create(s.ctx, duplicate))
+	s.book(appointment, second)
+	third := &domain.AppointmentBooking{ID: uuid.New(), AppointmentID: appointment.ID,
+		PatientID: s.patient("katherine@example.com").ID, Status: domain.AwaitingConfirmation}
+	s.Assert().Equal(repository.ErrAppointmentNotAvailable, s.backend.AppointmentBookings().Create(s.ctx, third))
+
+	got, err := s.backend.Appointments().FindByID(s.ctx, appointment.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(0, got.RemainingSeats)
+	available, err := s.backend.Appointments().AllAvailable(s.ctx)
+	s.Require().NoError(err)
+	s.Assert().Empty(available)
+	found, _, err := s.backend.Appointments().Search(s.ctx, domain.AppointmentSearch{}, domain.PageRequest{},
+		time.Now())
+	s.Require().NoError(err)
+	s.Assert().Empty(found)
+
+	s.Require().NoError(s.backend.AppointmentBookings().Cancel(s.ctx, booking.ID, domain.ReleaseReasonCancelled))
+	got, err = s.backend.Appointments().FindByID(s.ctx, appointment.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(1, got.RemainingSeats)
+	found, page, err := s.backend.Appointments().Search(s.ctx,
+		domain.AppointmentSearch{TreatmentCenterID: &treatmentCenter.ID}, domain.PageRequest{}, time.Now())
+	s.Require().NoError(err)
+	s.Require().Len(found, 1)
+	s.Assert().Equal(appointment.ID, found[0].ID)
+	s.Assert().Equal(int64(1), page.Total)
+	s.Require().NoError(s.backend.AppointmentBookings().Create(s.ctx, third))
+}
+
+func (s *ContractSuite) TestConcurrentBookings() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 2)
+	appointment := s.appointment(treatmentCenter, upcoming(2), 2)
+	var patients []*domain.Patient
+	for i := 0; i < 5; i++ {
+		patients = append(patients, s.patient(fmt.Sprintf("patient%d@example.com", i)))
+	}
+
+	errs := make(chan error, len(patients))
+	for _, patient := range patients {
+		go func(patient *domain.Patient) {
+			errs <- s.backend.AppointmentBookings().Create(s.ctx, &domain.AppointmentBooking{ID: uuid.New(),
+				AppointmentID: appointment.ID, PatientID: patient.ID, Status: domain.AwaitingConfirmation})
+		}(patient)
+	}
+	booked := 0
+	for range patients {
+		switch err := <-errs; err {
+		case nil:
+			booked++
+		default:
+			s.Assert().Equal(repository.ErrAppointmentNotAvailable, err)
+		}
+	}
+	s.Assert().Equal(2, booked)
+}
+
+func (s *ContractSuite) TestAppointmentsStockAndDuplicates() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 2)
+	start := upcoming(2)
+	s.appointment(treatmentCenter, start, 1)
+
+	same := &domain.Appointment{ID: uuid.New(), TreatmentCenterID: treatmentCenter.ID, StartTime: start, Capacity: 1}
+	s.Assert().Equal(repository.ErrUniqueConstraintFailure, s.backend.Appointments().Create(s.ctx, same))
+	tooMany := &domain.Appointment{ID: uuid.New(), TreatmentCenterID: treatmentCenter.ID,
+		StartTime: start.Add(time.Hour), Capacity: 2}
+	s.Assert().Equal(repository.ErrInsufficientStock, s.backend.Appointments().Create(s.ctx, tooMany))
+
+	created, err := s.backend.Appointments().CreateMissing(s.ctx, []*domain.Appointment{
+		same,
+		{ID: uuid.New(), TreatmentCenterID: treatmentCenter.ID, StartTime: start.Add(time.Hour), Capacity: 1},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(created, 1)
+	s.Assert().True(start.Add(time.Hour).Equal(created[0].StartTime))
+	all, err := s.backend.Appointments().AllByTreatmentCenterID(s.ctx, treatmentCenter.ID)
+	s.Require().NoError(err)
+	s.Assert().Len(all, 2)
+
+	unknown := &domain.Appointment{ID: uuid.New(), TreatmentCenterID: uuid.New(), StartTime: start, Capacity: 1}
+	s.Assert().Equal(repository.ErrRecordNotFound, s.backend.Appointments().Create(s.ctx, unknown))
+}
+
+func (s *ContractSuite) TestSearchAppointments() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 3)
+	day := upcoming(2).Truncate(24 * time.Hour)
+	morning := s.appointment(treatmentCenter, day.Add(7*time.Hour), 1)
+	afternoon := s.appointment(treatmentCenter, day.Add(14*time.Hour), 1)
+	nextDay := s.appointment(treatmentCenter, day.AddDate(0, 0, 1).Add(14*time.Hour), 1)
+
+	// Paris is one or two hours ahead of UTC
+	noon := domain.Clock("12:00")
+	found, page, err := s.backend.Appointments().Search(s.ctx, domain.AppointmentSearch{After: &noon},
+		domain.PageRequest{Limit: 1}, time.Now())
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(2), page.Total)
+	s.Require().Len(found, 1)
+	s.Assert().Equal(afternoon.ID, found[0].ID)
+	s.Require().NotNil(page.NextCursor)
+	found, page, err = s.backend.Appointments().Search(s.ctx, domain.AppointmentSearch{After: &noon},
+		domain.PageRequest{Limit: 1, Cursor: page.NextCursor}, time.Now())
+	s.Require().NoError(err)
+	s.Require().Len(found, 1)
+	s.Assert().Equal(nextDay.ID, found[0].ID)
+	s.Assert().Nil(page.NextCursor)
+
+	found, _, err = s.backend.Appointments().Search(s.ctx, domain.AppointmentSearch{Before: &noon},
+		domain.PageRequest{}, time.Now())
+	s.Require().NoError(err)
+	s.Require().Len(found, 1)
+	s.Assert().Equal(morning.ID, found[0].ID)
+
+	s.book(afternoon, s.patient("ada@example.com"))
+	s.book(nextDay, s.patient("grace@example.com"))
+	booked, page, err := s.backend.Appointments().AllBookedByTreatmentCenterIDForDate(s.ctx, treatmentCenter.ID,
+		day.Add(time.Hour), domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(1), page.Total)
+	s.Require().Len(booked, 1)
+	s.Assert().Equal(afternoon.ID, booked[0].ID)
+	s.Assert().Equal(0, booked[0].RemainingSeats)
+}
+
+func (s *ContractSuite) TestDuplicates() {
+	patient := &domain.Patient{ID: uuid.New(), Email: "a_b@example.com", FirstName: "Ada", LastName: "Lovelace"}
+	s.Require().NoError(s.backend.Patients().Create(s.ctx, patient))
+	duplicate := &domain.Patient{ID: uuid.New(), Email: "A_B+vax@Example.com", FirstName: "Ada", LastName: "King"}
+	s.Require().NoError(s.backend.Patients().Create(s.ctx, duplicate))
+	other := &domain.Patient{ID: uuid.New(), Email: "grace@example.com", FirstName: "Grace", LastName: "Hopper"}
+	s.Require().NoError(s.backend.Patients().Create(s.ctx, other))
+
+	candidates, err := s.backend.Patients().Duplicates(s.ctx, patient.ID)
+	s.Require().NoError(err)
+	s.Require().Len(candidates, 1)
+	s.Assert().Equal(duplicate.ID, candidates[0].ID)
+	s.Assert().Equal([]string{domain.DuplicateEmail}, candidates[0].Reasons)
+
+	s.Require().NoError(s.backend.Patients().Merge(s.ctx, patient.ID, duplicate.ID))
+	_, err = s.backend.Patients().FindByID(s.ctx, duplicate.ID)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+}
+
+func (s *ContractSuite) TestDuplicateEmailsOfOtherNames() {
+	patient := &domain.Patient{ID: uuid.New(), Email: "jane.doe@some.com", FirstName: "Jane", LastName: "Doe"}
+	s.Require().NoError(s.backend.Patients().Create(s.ctx, patient))
+	typo := &domain.Patient{ID: uuid.New(), Email: "jane.deo@some.com", FirstName: "Janet", LastName: "Doe"}
+	s.Require().NoError(s.backend.Patients().Create(s.ctx, typo))
+	tagged := &domain.Patient{ID: uuid.New(), Email: "JaneDoe+vax@some.com", FirstName: "Jane", LastName: "Martin"}
+	s.Require().NoError(s.backend.Patients().Create(s.ctx, tagged))
+	// the same name on another domain is another person
+	s.Require().NoError(s.backend.Patients().Create(s.ctx,
+		&domain.Patient{ID: uuid.New(), Email: "jane.doe@other.org", FirstName: "Jane", LastName: "Martin"}))
+	s.patient("john@some.com")
+
+	candidates, err := s.backend.Patients().Duplicates(s.ctx, patient.ID)
+	s.Require().NoError(err)
+	s.Require().Len(candidates, 2)
+	s.Assert().Equal(typo.ID, candidates[0].ID)
+	s.Assert().Equal([]string{domain.DuplicateEmail}, candidates[0].Reasons)
+	s.Assert().Equal(tagged.ID, candidates[1].ID)
+	s.Assert().Equal([]string{domain.DuplicateEmail}, candidates[1].Reasons)
+}
+
+func (s *ContractSuite) TestMergeBookedOnSameAppointment() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 2)
+	appointment := s.appointment(treatmentCenter, upcoming(2), 2)
+	survivor, duplicate := s.patient("ada@example.com"), s.patient("ada.l@example.com")
+	s.book(appointment, survivor)
+	booking := s.book(appointment, duplicate)
+
+	s.Assert().Equal(repository.ErrPatientsConflict, s.backend.Patients().Merge(s.ctx, survivor.ID, duplicate.ID))
+	// a cancelled booking no longer holds a seat
+	s.Require().NoError(s.backend.AppointmentBookings().Cancel(s.ctx, booking.ID, "duplicate"))
+	s.Require().NoError(s.backend.Patients().Merge(s.ctx, survivor.ID, duplicate.ID))
+	bookings, _, err := s.backend.AppointmentBookings().AllByPatientID(s.ctx, survivor.ID, domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Assert().Len(bookings, 2)
+}
+
+func (s *ContractSuite) TestMergeOfferedWaitlistEntries() {
+	survivor, duplicate := s.patient("ada@example.com"), s.patient("ada.l@example.com")
+	join := func(treatmentCenter *domain.TreatmentCenter, patient *domain.Patient) *domain.WaitlistEntry {
+		entry := &domain.WaitlistEntry{ID: uuid.New(), PatientID: patient.ID, TreatmentCenterID: treatmentCenter.ID,
+			FromDate: upcoming(0), ToDate: upcoming(7)}
+		s.Require().NoError(s.backend.Waitlist().Join(s.ctx, entry))
+		return entry
+	}
+	offer := func(appointment *domain.Appointment) {
+		offered, err := s.backend.Waitlist().OfferNext(s.ctx, appointment.ID, time.Now().Add(time.Hour))
+		s.Require().NoError(err)
+		s.Require().NotNil(offered)
+	}
+
+	// the duplicate is offered a seat of the first center the survivor waits for
+	first := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(first, 1)
+	offeredDuplicate, waitingSurvivor := join(first, duplicate), join(first, survivor)
+	offer(s.appointment(first, upcoming(2), 1))
+	// both are offered a seat of the second center
+	second := s.treatmentCenter("Hôpital Cochin", 48.8367, 2.3394)
+	s.stock(second, 2)
+	otherDuplicate, offeredSurvivor := join(second, duplicate), join(second, survivor)
+	offer(s.appointment(second, upcoming(2), 1))
+	offer(s.appointment(second, upcoming(3), 1))
+
+	s.Require().NoError(s.backend.Patients().Merge(s.ctx, survivor.ID, duplicate.ID))
+	for entry, status := range map[*domain.WaitlistEntry]domain.WaitlistStatus{
+		offeredDuplicate: domain.Offered,
+		waitingSurvivor:  domain.Withdrawn,
+		offeredSurvivor:  domain.Offered,
+		otherDuplicate:   domain.Withdrawn,
+	} {
+		got, err := s.backend.Waitlist().FindByID(s.ctx, entry.ID)
+		s.Require().NoError(err)
+		s.Assert().Equal(survivor.ID, got.PatientID)
+		s.Assert().Equal(status, got.Status)
+	}
+}
+
+func (s *ContractSuite) TestDeleteBookedAppointment() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 2)
+	appointment := s.appointment(treatmentCenter, upcoming(2), 1)
+	booking := s.book(appointment, s.patient("ada@example.com"))
+
+	_, err := s.backend.Appointments().Delete(s.ctx, appointment.ID, false)
+	s.Assert().Equal(repository.ErrAppointmentBooked, err)
+	cancelled, err := s.backend.Appointments().Delete(s.ctx, appointment.ID, true)
+	s.Require().NoError(err)
+	s.Require().Len(cancelled, 1)
+	s.Assert().Equal(booking.ID, cancelled[0].ID)
+	s.Assert().Equal(domain.Cancelled, cancelled[0].Status)
+	s.Assert().Equal(treatmentCenter.Name, cancelled[0].TreatmentCenterName)
+
+	_, err = s.backend.Appointments().FindByID(s.ctx, appointment.ID)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+	s.Assert().Equal(repository.ErrRecordNotFound, s.backend.AppointmentBookings().Create(s.ctx,
+		&domain.AppointmentBooking{ID: uuid.New(), AppointmentID: appointment.ID,
+			PatientID: booking.PatientID, Status: domain.AwaitingConfirmation}))
+}
+
+func (s *ContractSuite) TestDeleteTreatmentCenter() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 2)
+	s.book(s.appointment(treatmentCenter, upcoming(2), 1), s.patient("ada@example.com"))
+
+	_, err := s.backend.TreatmentCenters().Delete(s.ctx, treatmentCenter.ID, false, time.Now())
+	s.Assert().Equal(repository.ErrTreatmentCenterBooked, err)
+	cancelled, err := s.backend.TreatmentCenters().Delete(s.ctx, treatmentCenter.ID, true, time.Now())
+	s.Require().NoError(err)
+	s.Assert().Len(cancelled, 1)
+
+	_, err = s.backend.TreatmentCenters().FindByID(s.ctx, treatmentCenter.ID)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+	all, _, err := s.backend.TreatmentCenters().All(s.ctx, domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Assert().Empty(all)
+	appointments, err := s.backend.Appointments().AllByTreatmentCenterID(s.ctx, treatmentCenter.ID)
+	s.Require().NoError(err)
+	s.Assert().Empty(appointments)
+}
+
+func (s *ContractSuite) TestBookingStatusTransitions() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 2)
+	first := s.appointment(treatmentCenter, upcoming(2), 1)
+	second := s.appointment(treatmentCenter, upcoming(3), 1)
+	booking := s.book(first, s.patient("ada@example.com"))
+
+	s.Require().NoError(s.backend.AppointmentBookings().Confirm(s.ctx, booking.ID))
+	s.Assert().Equal(repository.ErrInvalidStatusTransition, s.backend.AppointmentBookings().Confirm(s.ctx, booking.ID))
+
+	rescheduled, err := s.backend.AppointmentBookings().Reschedule(s.ctx, booking.ID, second.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(second.ID, rescheduled.AppointmentID)
+	s.Assert().Equal(domain.Confirmed, rescheduled.Status)
+	previous, err := s.backend.AppointmentBookings().FindByID(s.ctx, booking.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.Cancelled, previous.Status)
+	s.Assert().Equal(domain.ReleaseReasonRescheduled, previous.ReleaseReason)
+	s.Assert().Equal(repository.ErrInvalidStatusTransition,
+		s.backend.AppointmentBookings().Cancel(s.ctx, booking.ID, domain.ReleaseReasonCancelled))
+
+	bookings, page, err := s.backend.AppointmentBookings().AllByPatientID(s.ctx, booking.PatientID,
+		domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(2), page.Total)
+	s.Require().Len(bookings, 2)
+	s.Assert().Equal(rescheduled.ID, bookings[0].ID)
+}
+
+func (s *ContractSuite) TestBookStartedAppointment() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 2)
+	started := s.appointment(treatmentCenter, time.Now().Add(-time.Minute), 1)
+	patient := s.patient("ada@example.com")
+
+	booking := &domain.AppointmentBooking{ID: uuid.New(), AppointmentID: started.ID, PatientID: patient.ID,
+		Status: domain.AwaitingConfirmation}
+	s.Assert().Equal(domain.ErrAppointmentStarted, s.backend.AppointmentBookings().Create(s.ctx, booking))
+
+	booking = s.book(s.appointment(treatmentCenter, upcoming(2), 1), patient)
+	_, err := s.backend.AppointmentBookings().Reschedule(s.ctx, booking.ID, started.ID)
+	s.Assert().Equal(domain.ErrAppointmentStarted, err)
+	got, err := s.backend.AppointmentBookings().FindByID(s.ctx, booking.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.AwaitingConfirmation, got.Status)
+	appointment, err := s.backend.Appointments().FindByID(s.ctx, started.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(1, appointment.RemainingSeats)
+}
+
+func (s *ContractSuite) TestExpireUnconfirmed() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 2)
+	appointment := s.appointment(treatmentCenter, upcoming(2), 2)
+	expiresAt := time.Now().Add(-time.Minute)
+	booking := &domain.AppointmentBooking{ID: uuid.New(), AppointmentID: appointment.ID,
+		PatientID: s.patient("ada@example.com").ID, Status: domain.AwaitingConfirmation, ExpiresAt: &expiresAt}
+	s.Require().NoError(s.backend.AppointmentBookings().Create(s.ctx, booking))
+	s.book(appointment, s.patient("grace@example.com"))
+
+	expired, err := s.backend.AppointmentBookings().ExpireUnconfirmed(s.ctx, time.Now(),
+		domain.ReleaseReasonUnconfirmed)
+	s.Require().NoError(err)
+	s.Require().Len(expired, 1)
+	s.Assert().Equal(booking.ID, expired[0].ID)
+	s.Assert().Equal(domain.Expired, expired[0].Status)
+	got, err := s.backend.Appointments().FindByID(s.ctx, appointment.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(1, got.RemainingSeats)
+}
+
+func (s *ContractSuite) TestDoses() {
+	patient := s.patient("ada@example.com")
+	vaccineProduct := s.vaccineProduct("Comirnaty")
+	first := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{first, first.AddDate(0, 0, 21)} {
+		dose := &domain.Dose{ID: uuid.New(), PatientID: patient.ID, VaccineProductID: vaccineProduct.ID,
+			AdministeredAt: at}
+		_, err := s.backend.Doses().Create(s.ctx, dose)
+		s.Require().NoError(err)
+	}
+	_, err := s.backend.Doses().Create(s.ctx, &domain.Dose{ID: uuid.New(),
+		PatientID: uuid.New(), VaccineProductID: vaccineProduct.ID, AdministeredAt: first})
+	s.Assert().Equal(repository.ErrInvalidID, err)
+
+	doses, page, err := s.backend.Doses().AllByPatientID(s.ctx, patient.ID, domain.PageRequest{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(doses, 1)
+	s.Assert().Equal(1, doses[0].Number)
+	s.Assert().Equal(int64(2), page.Total)
+	s.Require().NotNil(page.NextCursor)
+	doses, page, err = s.backend.Doses().AllByPatientID(s.ctx, patient.ID,
+		domain.PageRequest{Limit: 1, Cursor: page.NextCursor})
+	s.Require().NoError(err)
+	s.Require().Len(doses, 1)
+	s.Assert().Equal(2, doses[0].Number)
+	s.Assert().Nil(page.NextCursor)
+
+	// the series is completed, the vaccine has no booster
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 1)
+	booking := &domain.AppointmentBooking{ID: uuid.New(), AppointmentID: s.appointment(treatmentCenter, upcoming(2),
+		1).ID, PatientID: patient.ID, Status: domain.AwaitingConfirmation}
+	s.Assert().Equal(domain.ErrSeriesCompleted, s.backend.AppointmentBookings().Create(s.ctx, booking))
+}
+
+func (s *ContractSuite) TestDoseBeforeNextBooking() {
+	patient := s.patient("ada@example.com")
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	lot := s.stock(treatmentCenter, 3)
+	tooSoon := s.book(s.appointment(treatmentCenter, upcoming(10), 1), patient)
+	next := s.book(s.appointment(treatmentCenter, upcoming(25), 1), patient)
+
+	booked, err := s.backend.AppointmentBookings().BookedAt(s.ctx, patient.ID, treatmentCenter.ID)
+	s.Require().NoError(err)
+	s.Assert().True(booked)
+	booked, err = s.backend.AppointmentBookings().BookedAt(s.ctx, patient.ID,
+		s.treatmentCenter("Hôpital Cochin", 48.8375, 2.3397).ID)
+	s.Require().NoError(err)
+	s.Assert().False(booked)
+
+	// the dose is recorded, the booking 10 days after it is cancelled as the vaccine
+	// needs 21 and the one after 25 days is kept
+	dose := &domain.Dose{ID: uuid.New(), PatientID: patient.ID, VaccineProductID: lot.VaccineProductID,
+		AdministeredAt: time.Now().UTC()}
+	cancelled, err := s.backend.Doses().Create(s.ctx, dose)
+	s.Require().NoError(err)
+	s.Require().Len(cancelled, 1)
+	s.Assert().Equal(tooSoon.ID, cancelled[0].ID)
+	s.Assert().Equal(domain.Cancelled, cancelled[0].Status)
+	s.Assert().Equal(domain.ReleaseReasonOffSchedule, cancelled[0].ReleaseReason)
+	s.Assert().Equal(treatmentCenter.Name, cancelled[0].TreatmentCenterName)
+	doses, _, err := s.backend.Doses().AllByPatientID(s.ctx, patient.ID, domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Assert().Len(doses, 1)
+	got, err := s.backend.AppointmentBookings().FindByID(s.ctx, next.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.AwaitingConfirmation, got.Status)
+}
+
+func (s *ContractSuite) TestPagination() {
+	for _, email := range []string{"b@example.com", "c@example.com", "a@example.com"} {
+		s.patient(email)
+	}
+	request := domain.PageRequest{Limit: 2, Sort: "email"}
+	patients, page, err := s.backend.Patients().All(s.ctx, request)
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(3), page.Total)
+	s.Require().Len(patients, 2)
+	s.Assert().Equal("a@example.com", patients[0].Email)
+	s.Assert().Equal("b@example.com", patients[1].Email)
+	s.Require().NotNil(page.NextCursor)
+
+	request.Cursor = page.NextCursor
+	patients, page, err = s.backend.Patients().All(s.ctx, request)
+	s.Require().NoError(err)
+	s.Require().Len(patients, 1)
+	s.Assert().Equal("c@example.com", patients[0].Email)
+	s.Assert().Nil(page.NextCursor)
+
+	patients, _, err = s.backend.Patients().All(s.ctx, domain.PageRequest{Sort: "email",
+		Direction: domain.Descending})
+	s.Require().NoError(err)
+	s.Require().Len(patients, 3)
+	s.Assert().Equal("c@example.com", patients[0].Email)
+
+	_, _, err = s.backend.Patients().All(s.ctx, domain.PageRequest{Sort: "first_name"})
+	s.Assert().Equal(repository.ErrInvalidSort, err)
+	_, _, err = s.backend.Patients().All(s.ctx, domain.PageRequest{Sort: "last_name", Cursor: request.Cursor})
+	s.Assert().Equal(repository.ErrInvalidCursor, err)
+}
+
+func (s *ContractSuite) TestDefaultSortDirection() {
+	var emails []string
+	for i, email := range []string{"b@example.com", "c@example.com", "a@example.com"} {
+		createdAt := time.Date(2021, 11, 1+i, 9, 0, 0, 0, time.UTC)
+		patient := &domain.Patient{ID: uuid.New(), Email: email, FirstName: "Ada", LastName: "Lovelace",
+			CreatedAt: &createdAt}
+		s.Require().NoError(s.backend.Patients().Create(s.ctx, patient))
+		emails = append([]string{email}, emails...)
+	}
+
+	// the direction of the request applies to the default sort
+	patients, page, err := s.backend.Patients().All(s.ctx,
+		domain.PageRequest{Limit: 2, Direction: domain.Descending})
+	s.Require().NoError(err)
+	s.Require().Len(patients, 2)
+	s.Assert().Equal(emails[:2], []string{patients[0].Email, patients[1].Email})
+	s.Require().NotNil(page.NextCursor)
+	s.Assert().Equal("created_at", page.NextCursor.Sort)
+	s.Assert().Equal(domain.Descending, page.NextCursor.Direction)
+
+	// the waitlist lists the most recent entries first, asked for its default sort or not
+	patient := s.patient("ada@example.com")
+	for i := 0; i < 3; i++ {
+		createdAt := time.Date(2021, 11, 1+i, 9, 0, 0, 0, time.UTC)
+		entry := &domain.WaitlistEntry{ID: uuid.New(), PatientID: patient.ID,
+			TreatmentCenterID: s.treatmentCenter(fmt.Sprintf("Center %d", i), 48.8462, 2.3155).ID,
+			FromDate:          upcoming(0), ToDate: upcoming(7), CreatedAt: &createdAt}
+		s.Require().NoError(s.backend.Waitlist().Join(s.ctx, entry))
+	}
+	byDefault, _, err := s.backend.Waitlist().AllByPatientID(s.ctx, patient.ID, domain.PageRequest{})
+	s.Require().NoError(err)
+	sorted, page, err := s.backend.Waitlist().AllByPatientID(s.ctx, patient.ID,
+		domain.PageRequest{Limit: 3, Sort: "created_at"})
+	s.Require().NoError(err)
+	s.Require().Len(sorted, 3)
+	s.Assert().Equal(byDefault, sorted)
+	s.Assert().True(sorted[0].CreatedAt.After(*sorted[2].CreatedAt))
+	ascending, _, err := s.backend.Waitlist().AllByPatientID(s.ctx, patient.ID,
+		domain.PageRequest{Sort: "created_at", Direction: domain.Ascending})
+	s.Require().NoError(err)
+	s.Require().Len(ascending, 3)
+	s.Assert().Equal(sorted[2].ID, ascending[0].ID)
+}
+
+func (s *ContractSuite) TestNearby() {
+	necker := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.treatmentCenter("Hôpital Bichat", 48.8989, 2.3317)
+	s.treatmentCenter("CHU de Lyon", 45.7417, 4.8798)
+	s.stock(necker, 1)
+	appointment := s.appointment(necker, upcoming(2), 1)
+
+	nearby, page, err := s.backend.TreatmentCenters().Nearby(s.ctx,
+		domain.NearbySearch{Latitude: 48.8566, Longitude: 2.3522, RadiusKm: 10}, domain.PageRequest{}, time.Now())
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(2), page.Total)
+	s.Require().Len(nearby, 2)
+	s.Assert().Equal("Hôpital Necker", nearby[0].Name)
+	s.Assert().InDelta(2.9, nearby[0].DistanceKm, 0.1)
+	s.Require().NotNil(nearby[0].NextAppointmentID)
+	s.Assert().Equal(appointment.ID, *nearby[0].NextAppointmentID)
+	s.Assert().Nil(nearby[1].NextAppointmentID)
+
+	nearby, page, err = s.backend.TreatmentCenters().Nearby(s.ctx,
+		domain.NearbySearch{Latitude: 48.8566, Longitude: 2.3522, RadiusKm: 10}, domain.PageRequest{Limit: 1},
+		time.Now())
+	s.Require().NoError(err)
+	s.Require().NotNil(page.NextCursor)
+	nearby, _, err = s.backend.TreatmentCenters().Nearby(s.ctx,
+		domain.NearbySearch{Latitude: 48.8566, Longitude: 2.3522, RadiusKm: 10},
+		domain.PageRequest{Limit: 1, Cursor: page.NextCursor}, time.Now())
+	s.Require().NoError(err)
+	s.Require().Len(nearby, 1)
+	s.Assert().Equal("Hôpital Bichat", nearby[0].Name)
+
+	// the center is 494 km away, further east than the radius is wide at the latitude of the search
+	polar := s.treatmentCenter("Station polaire", 81, 26.5)
+	nearby, _, err = s.backend.TreatmentCenters().Nearby(s.ctx,
+		domain.NearbySearch{Latitude: 80, Longitude: 0, RadiusKm: 500}, domain.PageRequest{}, time.Now())
+	s.Require().NoError(err)
+	s.Require().Len(nearby, 1)
+	s.Assert().Equal(polar.ID, nearby[0].ID)
+	s.Assert().InDelta(494.1, nearby[0].DistanceKm, 0.1)
+
+	until := upcoming(3)
+	nearby, _, err = s.backend.TreatmentCenters().Nearby(s.ctx, domain.NearbySearch{Latitude: 48.8566,
+		Longitude: 2.3522, RadiusKm: 1000, AvailableUntil: &until}, domain.PageRequest{}, time.Now())
+	s.Require().NoError(err)
+	s.Require().Len(nearby, 1)
+	s.Assert().Equal(necker.ID, nearby[0].ID)
+}
+
+func (s *ContractSuite) TestWaitlistOffer() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 1)
+	appointment := s.appointment(treatmentCenter, upcoming(2), 1)
+	booking := s.book(appointment, s.patient("ada@example.com"))
+	waiting := s.patient("grace@example.com")
+
+	entry := &domain.WaitlistEntry{ID: uuid.New(), PatientID: waiting.ID, TreatmentCenterID: treatmentCenter.ID,
+		FromDate: upcoming(0), ToDate: upcoming(7)}
+	s.Require().NoError(s.backend.Waitlist().Join(s.ctx, entry))
+	again := &domain.WaitlistEntry{ID: uuid.New(), PatientID: waiting.ID, TreatmentCenterID: treatmentCenter.ID,
+		FromDate: upcoming(0), ToDate: upcoming(7)}
+	s.Assert().Equal(repository.ErrUniqueConstraintFailure, s.backend.Waitlist().Join(s.ctx, again))
+
+	offered, err := s.backend.Waitlist().OfferNext(s.ctx, appointment.ID, time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+	s.Assert().Nil(offered)
+
+	s.Require().NoError(s.backend.AppointmentBookings().Cancel(s.ctx, booking.ID, domain.ReleaseReasonCancelled))
+	offered, err = s.backend.Waitlist().OfferNext(s.ctx, appointment.ID, time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+	s.Require().NotNil(offered)
+	s.Assert().Equal(waiting.ID, offered.PatientID)
+	got, err := s.backend.Waitlist().FindByID(s.ctx, entry.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.Offered, got.Status)
+	s.Assert().Equal(repository.ErrInvalidStatusTransition, s.backend.Waitlist().Withdraw(s.ctx, entry.ID))
+
+	s.Require().NoError(s.backend.AppointmentBookings().Confirm(s.ctx, offered.ID))
+	got, err = s.backend.Waitlist().FindByID(s.ctx, entry.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.Fulfilled, got.Status)
+}
+
+func (s *ContractSuite) TestRemindersClaimedOnce() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 1)
+	booking := s.book(s.appointment(treatmentCenter, time.Now().Add(time.Hour), 1), s.patient("ada@example.com"))
+	s.Require().NoError(s.backend.AppointmentBookings().Confirm(s.ctx, booking.ID))
+
+	due, err := s.backend.Reminders.Due(s.ctx, time.Now(), 2*time.Hour)
+	s.Require().NoError(err)
+	s.Require().Len(due, 1)
+	s.Assert().Equal(booking.ID, due[0].ID)
+
+	reminder := &domain.BookingReminder{AppointmentBookingID: booking.ID, LeadMinutes: 120, SentAt: time.Now()}
+	claimed, err := s.backend.Reminders.Claim(s.ctx, reminder)
+	s.Require().NoError(err)
+	s.Assert().True(claimed)
+	claimed, err = s.backend.Reminders.Claim(s.ctx, reminder)
+	s.Require().NoError(err)
+	s.Assert().False(claimed)
+	due, err = s.backend.Reminders.Due(s.ctx, time.Now(), 2*time.Hour)
+	s.Require().NoError(err)
+	s.Assert().Empty(due)
+
+	s.Require().NoError(s.backend.Reminders.Release(s.ctx, reminder))
+	due, err = s.backend.Reminders.Due(s.ctx, time.Now(), 2*time.Hour)
+	s.Require().NoError(err)
+	s.Assert().Len(due, 1)
+}
+
+func (s *ContractSuite) TestEligibilityRules() {
+	minAge := 75
+	until := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	rule := &domain.EligibilityRule{ID: uuid.New(), Name: "Elderly", MinAge: &minAge,
+		EffectiveFrom: time.Date(2021, 1, 18, 0, 0, 0, 0, time.UTC), EffectiveUntil: &until}
+	s.Require().NoError(s.backend.EligibilityRules().Create(s.ctx, rule))
+
+	effective, err := s.backend.EligibilityRules().Effective(s.ctx, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC))
+	s.Require().NoError(err)
+	s.Require().Len(effective, 1)
+	s.Assert().Equal(rule.ID, effective[0].ID)
+	effective, err = s.backend.EligibilityRules().Effective(s.ctx, until)
+	s.Require().NoError(err)
+	s.Assert().Empty(effective)
+}
+
+func (s *ContractSuite) TestIneligibleBookings() {
+	treatmentCenter := s.treatmentCenter("Hôpital Necker", 48.8462, 2.3155)
+	s.stock(treatmentCenter, 2)
+	first := s.appointment(treatmentCenter, upcoming(2), 1)
+	second := s.appointment(treatmentCenter, upcoming(3), 1)
+	booking := s.book(first, s.patient("ada@example.com"))
+	rule := &domain.EligibilityRule{ID: uuid.New(), Name: "Health workers", Professions: domain.StringList{"nurse"},
+		EffectiveFrom: time.Now().Add(-time.Hour)}
+	s.Require().NoError(s.backend.EligibilityRules().Create(s.ctx, rule))
+
+	var ineligible *domain.IneligibleError
+	_, err := s.backend.AppointmentBookings().Reschedule(s.ctx, booking.ID, second.ID)
+	s.Require().True(errors.As(err, &ineligible), "%v", err)
+	s.Assert().Equal(rule.ID, ineligible.Rule.ID)
+	got, err := s.backend.AppointmentBookings().FindByID(s.ctx, booking.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.AwaitingConfirmation, got.Status)
+
+	// the waitlist skips the patients who don't meet the rule
+	s.Require().NoError(s.backend.AppointmentBookings().Cancel(s.ctx, booking.ID, domain.ReleaseReasonCancelled))
+	waiting := s.patient("grace@example.com")
+	nurse := &domain.Patient{ID: uuid.New(), Email: "florence@example.com", FirstName: "Florence",
+		LastName: "Nightingale", Profession: "nurse"}
+	s.Require().NoError(s.backend.Patients().Create(s.ctx, nurse))
+	for i, patient := range []*domain.Patient{waiting, nurse} {
+		createdAt := time.Now().UTC().Add(time.Duration(i-2) * time.Minute)
+		s.Require().NoError(s.backend.Waitlist().Join(s.ctx, &domain.WaitlistEntry{ID: uuid.New(),
+			PatientID: patient.ID, TreatmentCenterID: treatmentCenter.ID,
+			FromDate: upcoming(0), ToDate: upcoming(7), CreatedAt: &createdAt}))
+	}
+	offered, err := s.backend.Waitlist().OfferNext(s.ctx, first.ID, time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+	s.Require().NotNil(offered)
+	s.Assert().Equal(nurse.ID, offered.PatientID)
+
+	err = s.backend.AppointmentBookings().Create(s.ctx, &domain.AppointmentBooking{ID: uuid.New(),
+		AppointmentID: second.ID, PatientID: waiting.ID, Status: domain.AwaitingConfirmation})
+	s.Require().True(errors.As(err, &ineligible), "%v", err)
+}
+
+func (s *ContractSuite) TestUnitOfWork() {
+	failed := &domain.Patient{ID: uuid.New(), Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace"}
+	err := s.backend.UnitOfWork.Do(s.ctx, func(r repository.Repositories) error {
+		if err := r.Patients().Create(s.ctx, failed); err != nil {
+			return err
+		}
+		return repository.ErrInvalidID
+	})
+	s.Assert().Equal(repository.ErrInvalidID, err)
+	_, err = s.backend.Patients().FindByID(s.ctx, failed.ID)
+	s.Assert().Equal(repository.ErrRecordNotFound, err)
+
+	// a failed step leaves the unit of work going on
+	kept := &domain.Patient{ID: uuid.New(), Email: "grace@example.com", FirstName: "Grace", LastName: "Hopper"}
+	duplicate := &domain.Patient{ID: uuid.New(), Email: "GRACE@example.com", FirstName: "Grace", LastName: "Hopper"}
+	err = s.backend.UnitOfWork.Do(s.ctx, func(r repository.Repositories) error {
+		if err := r.Patients().Create(s.ctx, kept); err != nil {
+			return err
+		}
+		s.Assert().Equal(repository.ErrUniqueConstraintFailure, r.Patients().Create(s.ctx, duplicate))
+		_, err := r.Patients().FindByID(s.ctx, kept.ID)
+		return err
+	})
+	s.Require().NoError(err)
+	_, err = s.backend.Patients().FindByID(s.ctx, kept.ID)
+	s.Assert().NoError(err)
+}
+
+func (s *ContractSuite) TestCanceledContext() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+	_, err := s.backend.Patients().FindByID(ctx, uuid.New())
+	s.Assert().Equal(repository.ErrQueryCanceled, err)
+	s.Assert().Equal(repository.ErrQueryCanceled, s.backend.Patients().Create(ctx,
+		&domain.Patient{ID: uuid.New(), Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace"}))
+}
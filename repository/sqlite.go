@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jinzhu/gorm"
+	"github.com/mattn/go-sqlite3"
+	"github.com/y9mo/covidvax/db"
+)
+
+// sqliteDriverName is the driver of the SQLite databases of the repositories,
+// their connections have the functions the queries need
+const sqliteDriverName = "covidvax_sqlite3"
+
+func init() {
+	sql.Register(sqliteDriverName, sqliteDriver{SQLiteDriver: sqlite3.SQLiteDriver{ConnectHook: connectSQLite}})
+}
+
+// OpenSQLite opens the SQLite database of the connection string, a file name or
+// :memory:, and migrates it. SQLite writes one transaction at a time so the
+// database is used through a single connection, which also keeps a :memory:
+// database for the life of the process.
+func OpenSQLite(connection string) (*gorm.DB, error) {
+	sep := "?"
+	if strings.Contains(connection, "?") {
+		sep = "&"
+	}
+	// the times are read back in UTC like they are written
+	sqlDB, err := sql.Open(sqliteDriverName, connection+sep+"_loc=UTC")
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err = migrateSQLite(sqlDB); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("sqlite migrations: %w", err)
+	}
+	return gorm.Open("sqlite3", sqlDB)
+}
+
+// migrateSQLite applies the migrations of db/sqlite, the database is kept open
+func migrateSQLite(sqlDB *sql.DB) error {
+	source, err := iofs.New(db.SQLiteMigrations, db.SQLiteMigrationsDir)
+	if err != nil {
+		return err
+	}
+	driver, err := migratesqlite.WithInstance(sqlDB, &migratesqlite.Config{})
+	if err != nil {
+		return err
+	}
+	migrations, err := migrate.NewWithInstance("iofs", source, "sqlite3", driver)
+	if err != nil {
+		return err
+	}
+	if err = migrations.Up(); err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+	return nil
+}
+
+// connectSQLite enforces the foreign keys of a new connection and registers the
+// functions Postgres has built in
+func connectSQLite(conn *sqlite3.SQLiteConn) error {
+	if _, err := conn.Exec("PRAGMA foreign_keys = ON", nil); err != nil {
+		return err
+	}
+	functions := map[string]interface{}{
+		"radians":       func(x interface{}) float64 { return number(x) * math.Pi / 180 },
+		"sin":           func(x interface{}) float64 { return math.Sin(number(x)) },
+		"cos":           func(x interface{}) float64 { return math.Cos(number(x)) },
+		"asin":          func(x interface{}) float64 { return math.Asin(number(x)) },
+		"sqrt":          func(x interface{}) float64 { return math.Sqrt(number(x)) },
+		"power":         func(x, y interface{}) float64 { return math.Pow(number(x), number(y)) },
+		"clock_in_zone": clockInZone,
+	}
+	for name, impl := range functions {
+		if err := conn.RegisterFunc(name, impl, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// number reads a numeric argument of a function, anything else is NaN which
+// SQLite returns as NULL
+func number(x interface{}) float64 {
+	switch v := x.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return math.NaN()
+	}
+}
+
+// clockInZone is the time of day of a stored time in a time zone as HH:MM:SS,
+// which compares as text with a domain.Clock
+func clockInZone(value, timezone string) (string, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return "", err
+	}
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(format, strings.TrimSuffix(value, "Z"), time.UTC); err == nil {
+			return t.In(location).Format("15:04:05"), nil
+		}
+	}
+	return "", fmt.Errorf("invalid time %q", value)
+}
+
+// sqliteDriver opens the connections of the SQLite databases
+type sqliteDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d sqliteDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteConn{SQLiteConn: conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// sqliteConn writes the times in UTC, SQLite stores them as text so that they
+// only compare in the same time zone
+type sqliteConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c sqliteConn) CheckNamedValue(value *driver.NamedValue) error {
+	switch v := value.Value.(type) {
+	case time.Time:
+		value.Value = v.UTC()
+	case *time.Time:
+		if v != nil {
+			value.Value = v.UTC()
+		}
+	}
+	// the default conversion goes on with the time in UTC
+	return driver.ErrSkip
+}
+
+// isSQLite tells whether the queries of db run against SQLite rather than Postgres
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialect().GetName() == "sqlite3"
+}
+
+// forUpdate locks the rows the query selects until the end of the transaction with
+// the locking clause, SQLite runs a single transaction at a time so it needs none
+func forUpdate(tx *gorm.DB, clause string) *gorm.DB {
+	if isSQLite(tx) {
+		return tx
+	}
+	return tx.Set("gorm:query_option", clause)
+}
+
+// startClock compares with operator the time of day of the start time of an
+// appointment, in the time zone of its treatment center tc, to a domain.Clock argument
+func startClock(db *gorm.DB, operator string) string {
+	if isSQLite(db) {
+		return "clock_in_zone(appointments.start_time, tc.timezone) " + operator + " ?"
+	}
+	return "CAST(appointments.start_time AT TIME ZONE tc.timezone AS time) " + operator + " CAST(? AS time)"
+}
+
+// sqliteError maps the constraint errors of SQLite to the repository ones
+func sqliteError(err sqlite3.Error) error {
+	switch err.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return ErrUniqueConstraintFailure
+	case sqlite3.ErrConstraintForeignKey:
+		return ErrInvalidID
+	default:
+		return err
+	}
+}
+
+// date is the day of t in its own time zone, at midnight UTC, like Postgres
+// reads a time argument compared to a date column
+func date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -1,6 +1,11 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/y9mo/covidvax/domain"
@@ -8,11 +13,14 @@ import (
 )
 
 type TreatmentCenters interface {
-	Create(treatmentCenter *domain.TreatmentCenter) error
-	Update(treatmentCenter *domain.TreatmentCenter) error
-	FindByID(id uuid.UUID) (*domain.TreatmentCenter, error)
-	Delete(treatmentCenter *domain.TreatmentCenter) error
-	All() ([]*domain.TreatmentCenter, error)
+	Create(ctx context.Context, treatmentCenter *domain.TreatmentCenter) error
+	Update(ctx context.Context, treatmentCenter *domain.TreatmentCenter) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.TreatmentCenter, error)
+	Delete(ctx context.Context, id uuid.UUID, cancelBookings bool, now time.Time) ([]*domain.PatientBooking, error)
+	All(ctx context.Context, request domain.PageRequest) ([]*domain.TreatmentCenter, domain.Page, error)
+	UpdateSchedule(ctx context.Context, treatmentCenter *domain.TreatmentCenter) error
+	Nearby(ctx context.Context, search domain.NearbySearch, request domain.PageRequest,
+		now time.Time) ([]*domain.NearbyTreatmentCenter, domain.Page, error)
 }
 type treatmentCenters struct {
 	db     *gorm.DB
@@ -26,19 +34,38 @@ func NewTreatmentCenters(db *gorm.DB, logger *zap.Logger) TreatmentCenters {
 	}
 }
 
-func (r treatmentCenters) Create(treatmentCenter *domain.TreatmentCenter) error {
-	err := r.db.Debug().Create(treatmentCenter).Error
+func (r treatmentCenters) Create(ctx context.Context, treatmentCenter *domain.TreatmentCenter) error {
+	err := withContext(ctx, r.db).Debug().Create(treatmentCenter).Error
 	return handleGormError(err, r.logger)
 }
 
-func (r treatmentCenters) Update(treatmentCenter *domain.TreatmentCenter) error {
-	err := r.db.Debug().Update(treatmentCenter).Error
-	return handleGormError(err, r.logger)
+// Update saves the details of a treatment center, empty ones included, its
+// schedule is saved by UpdateSchedule
+func (r treatmentCenters) Update(ctx context.Context, treatmentCenter *domain.TreatmentCenter) error {
+	result := withContext(ctx, r.db).Debug().Model(&domain.TreatmentCenter{}).Where("id = ?", treatmentCenter.ID).
+		Updates(map[string]interface{}{
+			"name":      treatmentCenter.Name,
+			"address":   treatmentCenter.Address,
+			"phone":     treatmentCenter.Phone,
+			"latitude":  treatmentCenter.Latitude,
+			"longitude": treatmentCenter.Longitude,
+		})
+	if err := handleGormError(result.Error, r.logger); err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
 }
 
-func (r treatmentCenters) FindByID(id uuid.UUID) (*domain.TreatmentCenter, error) {
+func (r treatmentCenters) FindByID(ctx context.Context, id uuid.UUID) (*domain.TreatmentCenter, error) {
 	treatmentCenter := domain.TreatmentCenter{}
-	err := r.db.Debug().Where("id = ?", id).Find(&treatmentCenter).Error
+	err := withContext(ctx, r.db).Debug().Preload("OpeningHours", func(db *gorm.DB) *gorm.DB {
+		return db.Order("weekday, opens_at")
+	}).Preload("Closures", func(db *gorm.DB) *gorm.DB {
+		return db.Order("date")
+	}).Where("id = ?", id).Find(&treatmentCenter).Error
 	err = handleGormError(err, r.logger)
 	if err != nil {
 		return nil, err
@@ -46,16 +73,177 @@ func (r treatmentCenters) FindByID(id uuid.UUID) (*domain.TreatmentCenter, error
 	return &treatmentCenter, nil
 }
 
-func (r treatmentCenters) Delete(treatmentCenter *domain.TreatmentCenter) error {
-	err := r.db.Debug().Delete(treatmentCenter).Error
+// Delete deletes a treatment center along with its appointments starting after
+// now, they are kept for the bookings referencing them but can't be found anymore.
+// The patients waiting for the center leave its waitlist. A center with active
+// bookings after now fails with ErrTreatmentCenterBooked unless cancelBookings,
+// they are then cancelled and returned.
+func (r treatmentCenters) Delete(ctx context.Context, id uuid.UUID, cancelBookings bool,
+	now time.Time) (cancelled []*domain.PatientBooking, err error) {
+	err = transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := lockTreatmentCenter(tx, id); err != nil {
+			return err
+		}
+		var err error
+		cancelled, err = withdrawBookings(tx, cancelBookings, ErrTreatmentCenterBooked,
+			"a.treatment_center_id = ? AND a.start_time > ? AND a.deleted_at IS NULL", id, now)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("treatment_center_id = ? AND start_time > ?", id, now).Delete(&domain.Appointment{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&domain.WaitlistEntry{}).
+			Where("treatment_center_id = ? AND status = ?", id, domain.Waiting).
+			Update("status", domain.Withdrawn).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&domain.TreatmentCenter{}).Error
+	})
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// treatmentCentersSorting sorts the treatment centers by name by default
+var treatmentCentersSorting = sorting{
+	fields: map[string]sortField{
+		"name":       {column: "treatment_centers.name"},
+		"created_at": {column: "treatment_centers.created_at"},
+	},
+	idColumn:    "treatment_centers.id",
+	defaultSort: "name",
+}
+
+func (r treatmentCenters) All(ctx context.Context,
+	request domain.PageRequest) (result []*domain.TreatmentCenter, page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	page, err = paginate(db, db.Model(&domain.TreatmentCenter{}), request,
+		treatmentCentersSorting, &result)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// UpdateSchedule replaces the time zone, slot settings, opening hours
+// and closures of a treatment center
+func (r treatmentCenters) UpdateSchedule(ctx context.Context, treatmentCenter *domain.TreatmentCenter) error {
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		result := tx.Model(&domain.TreatmentCenter{}).Where("id = ?", treatmentCenter.ID).Updates(map[string]interface{}{
+			"timezone":      treatmentCenter.Timezone,
+			"slot_duration": treatmentCenter.SlotDuration,
+			"slot_capacity": treatmentCenter.SlotCapacity,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+
+		if err := tx.Where("treatment_center_id = ?", treatmentCenter.ID).Delete(&domain.OpeningHours{}).Error; err != nil {
+			return err
+		}
+		for _, hours := range treatmentCenter.OpeningHours {
+			hours.TreatmentCenterID = treatmentCenter.ID
+			if err := tx.Create(hours).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("treatment_center_id = ?", treatmentCenter.ID).Delete(&domain.Closure{}).Error; err != nil {
+			return err
+		}
+		for _, closure := range treatmentCenter.Closures {
+			closure.TreatmentCenterID = treatmentCenter.ID
+			if err := tx.Create(closure).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	return handleGormError(err, r.logger)
 }
 
-func (r treatmentCenters) All() (result []*domain.TreatmentCenter, err error) {
-	err = r.db.Debug().Find(&result).Error
+// earthRadiusKm is the mean radius of the earth
+const earthRadiusKm = 6371.0
+
+// distanceKm is the great circle distance in km between the treatment center and
+// the point of the arguments, its latitude is given twice then its longitude
+var distanceKm = fmt.Sprintf(`2 * %g * asin(sqrt(
+	power(sin(radians(tc.latitude - ?) / 2), 2) +
+	cos(radians(?)) * cos(radians(tc.latitude)) * power(sin(radians(tc.longitude - ?) / 2), 2)))`, earthRadiusKm)
+
+// nextAvailableAppointment is the first appointment of the treatment center
+// starting after the now argument with a remaining seat
+const nextAvailableAppointment = `LEFT JOIN appointments na ON na.id = (
+	SELECT a.id FROM appointments a
+	WHERE a.treatment_center_id = tc.id AND a.start_time > ? AND a.deleted_at IS NULL
+	AND a.capacity > (SELECT count(*) FROM appointment_bookings ab
+		WHERE ab.appointment_id = a.id AND ab.status IN (?))
+	ORDER BY a.start_time, a.id
+	LIMIT 1)`
+
+// Nearby returns the located treatment centers within the radius of the search,
+// the closest first, with their next appointment available after now
+func (r treatmentCenters) Nearby(ctx context.Context, search domain.NearbySearch, request domain.PageRequest,
+	now time.Time) (result []*domain.NearbyTreatmentCenter, page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	query := db.Table("treatment_centers tc").
+		Select("tc.*, "+distanceKm+" AS distance_km, na.id AS next_appointment_id, na.start_time AS next_appointment_at",
+			search.Latitude, search.Latitude, search.Longitude).
+		Joins(nextAvailableAppointment, now, domain.HoldingStatuses).
+		Where("tc.deleted_at IS NULL")
+
+	// the bounding box of the radius lets the location index discard the far centers
+	angularRadius := search.RadiusKm / earthRadiusKm
+	latitudeDelta := angularRadius * 180 / math.Pi
+	query = query.Where("tc.latitude BETWEEN ? AND ?", search.Latitude-latitudeDelta, search.Latitude+latitudeDelta)
+	if west, east, ok := boundingLongitudes(search.Latitude, search.Longitude, angularRadius); ok {
+		query = query.Where("tc.longitude BETWEEN ? AND ?", west, east)
+	}
+	query = query.Where(distanceKm+" <= ?", search.Latitude, search.Latitude, search.Longitude, search.RadiusKm)
+
+	if search.AvailableUntil != nil {
+		query = query.Where("na.start_time < ?", *search.AvailableUntil)
+	}
+
+	sorting := sorting{
+		fields: map[string]sortField{
+			"distance": {column: "distance_km", expr: distanceKm,
+				args: []interface{}{search.Latitude, search.Latitude, search.Longitude}},
+		},
+		idColumn:    "tc.id",
+		defaultSort: "distance",
+	}
+	page, err = paginate(db, query, request, sorting, &result)
 	err = handleGormError(err, r.logger)
 	if err != nil {
-		return nil, err
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// boundingLongitudes returns the longitudes bounding the circle of the angular
+// radius in radians around the point, there are none when the circle reaches a
+// pole or crosses the antimeridian
+func boundingLongitudes(latitude, longitude, angularRadius float64) (west, east float64, ok bool) {
+	// the circle is widest north of the point in the northern hemisphere, not at its latitude
+	ratio := math.Sin(angularRadius) / math.Cos(latitude*math.Pi/180)
+	if math.Abs(latitude)+angularRadius*180/math.Pi >= 90 || ratio >= 1 {
+		return 0, 0, false
+	}
+	delta := math.Asin(ratio) * 180 / math.Pi
+	west, east = longitude-delta, longitude+delta
+	if west < -180 || east > 180 {
+		return 0, 0, false
 	}
-	return result, nil
+	return west, east, true
 }
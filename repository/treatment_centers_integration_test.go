@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/y9mo/covidvax/domain"
@@ -36,10 +38,13 @@ func (s *TreatmentCentersIntegrationTestSuite) TestCreate() {
 			name: "Successful",
 			id:   uuid.MustParse("1e79b4d8-cae6-418a-bc95-0b6799540f3b"),
 			patient: &domain.TreatmentCenter{
-				ID:      uuid.MustParse("1e79b4d8-cae6-418a-bc95-0b6799540f3b"),
-				Name:    "Center One",
-				Address: "One place middle earth",
-				Phone:   "0933420011",
+				ID:           uuid.MustParse("1e79b4d8-cae6-418a-bc95-0b6799540f3b"),
+				Name:         "Center One",
+				Address:      "One place middle earth",
+				Phone:        "0933420011",
+				Timezone:     "Europe/Paris",
+				SlotDuration: 10,
+				SlotCapacity: 1,
 			},
 			wantErr: nil,
 		},
@@ -47,10 +52,13 @@ func (s *TreatmentCentersIntegrationTestSuite) TestCreate() {
 			name: "AlreadyExist",
 			id:   uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
 			patient: &domain.TreatmentCenter{
-				ID:      uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
-				Name:    "Center Two",
-				Address: "Two is the right number",
-				Phone:   "0422420033",
+				ID:           uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
+				Name:         "Center Two",
+				Address:      "Two is the right number",
+				Phone:        "0422420033",
+				Timezone:     "Europe/Paris",
+				SlotDuration: 10,
+				SlotCapacity: 1,
 			},
 			wantErr: ErrUniqueConstraintFailure,
 		},
@@ -58,11 +66,11 @@ func (s *TreatmentCentersIntegrationTestSuite) TestCreate() {
 	for _, tc := range tests {
 		tc := tc
 		s.Run(tc.name, func() {
-			err := s.treatmentCentersRepository.Create(tc.patient)
+			err := s.treatmentCentersRepository.Create(context.Background(), tc.patient)
 			if tc.wantErr != nil {
 				s.Assert().Equal(tc.wantErr, err)
 			} else {
-				gotTreatmentCenter, err := s.treatmentCentersRepository.FindByID(tc.id)
+				gotTreatmentCenter, err := s.treatmentCentersRepository.FindByID(context.Background(), tc.id)
 				s.Assert().NoError(err)
 
 				s.Assert().Equal(tc.wantErr, err)
@@ -75,11 +83,185 @@ func (s *TreatmentCentersIntegrationTestSuite) TestCreate() {
 	}
 }
 
-func TestTreatmentCentersIntegrationTestSuite(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skipping TreatmentCentersIntegrationTest in short mode.")
-		return
+func (s *TreatmentCentersIntegrationTestSuite) TestDelete() {
+	appointmentsRepository := NewAppointments(s.IntegrationSuite.DB(), zap.NewExample())
+	id := uuid.MustParse("10063726-d378-472c-9b50-22a48331635d")
+	now := time.Date(2021, 11, 12, 0, 0, 0, 0, time.UTC)
+
+	_, err := s.treatmentCentersRepository.Delete(context.Background(), id, false, now)
+	s.Assert().Equal(ErrTreatmentCenterBooked, err)
+	_, err = s.treatmentCentersRepository.FindByID(context.Background(), id)
+	s.Assert().NoError(err)
+
+	cancelled, err := s.treatmentCentersRepository.Delete(context.Background(), id, true, now)
+	s.Require().NoError(err)
+	s.Require().Len(cancelled, 1)
+	s.Assert().Equal(uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710"), cancelled[0].ID)
+	s.Assert().Equal(domain.Cancelled, cancelled[0].Status)
+
+	_, err = s.treatmentCentersRepository.FindByID(context.Background(), id)
+	s.Assert().Equal(ErrRecordNotFound, err)
+	centers, page, err := s.treatmentCentersRepository.All(context.Background(), domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Assert().Len(centers, 3)
+	s.Assert().Equal(int64(3), page.Total)
+
+	// the past appointments stay for their bookings, the upcoming ones are deleted
+	_, err = appointmentsRepository.FindByID(context.Background(),
+		uuid.MustParse("5edac3af-6805-469e-ae94-f9610c09516a"))
+	s.Assert().NoError(err)
+	_, err = appointmentsRepository.FindByID(context.Background(),
+		uuid.MustParse("83a18a46-babe-414a-b873-035459e01a90"))
+	s.Assert().Equal(ErrRecordNotFound, err)
+
+	_, err = s.treatmentCentersRepository.Delete(context.Background(), id, true, now)
+	s.Assert().Equal(ErrRecordNotFound, err)
+}
+
+func (s *TreatmentCentersIntegrationTestSuite) TestFindByIDWithSchedule() {
+	got, err := s.treatmentCentersRepository.FindByID(context.Background(),
+		uuid.MustParse("32b2edf2-a380-4436-9f98-b70f78f1934d"))
+	s.Require().NoError(err)
+	s.Assert().Equal("Europe/Paris", got.Timezone)
+	s.Assert().Equal(60, got.SlotDuration)
+	s.Assert().Equal(2, got.SlotCapacity)
+	s.Require().Len(got.OpeningHours, 2)
+	s.Assert().Equal(time.Monday, got.OpeningHours[0].Weekday)
+	s.Assert().Equal(domain.Clock("08:00"), got.OpeningHours[0].OpensAt)
+	s.Assert().Equal(domain.Clock("12:00"), got.OpeningHours[0].ClosesAt)
+	s.Require().Len(got.Closures, 1)
+	s.Assert().Equal("Winter holiday", got.Closures[0].Reason)
+}
+
+func (s *TreatmentCentersIntegrationTestSuite) TestUpdateSchedule() {
+	tests := []struct {
+		name            string
+		treatmentCenter *domain.TreatmentCenter
+		wantErr         error
+	}{
+		{
+			name: "Successful",
+			treatmentCenter: &domain.TreatmentCenter{
+				ID:           uuid.MustParse("32b2edf2-a380-4436-9f98-b70f78f1934d"),
+				Timezone:     "UTC",
+				SlotDuration: 15,
+				SlotCapacity: 3,
+				OpeningHours: []*domain.OpeningHours{
+					{
+						ID:       uuid.MustParse("5c9a4b3d-6e7f-4081-9c23-d4e5f6071829"),
+						Weekday:  time.Saturday,
+						OpensAt:  "09:00",
+						ClosesAt: "11:00",
+					},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "NotFound",
+			treatmentCenter: &domain.TreatmentCenter{
+				ID:           uuid.MustParse("6dab5c4e-7f80-4192-ad34-e5f60718293a"),
+				Timezone:     "UTC",
+				SlotDuration: 15,
+				SlotCapacity: 3,
+			},
+			wantErr: ErrRecordNotFound,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			err := s.treatmentCentersRepository.UpdateSchedule(context.Background(), tc.treatmentCenter)
+			s.Assert().Equal(tc.wantErr, err)
+			if tc.wantErr == nil {
+				got, err := s.treatmentCentersRepository.FindByID(context.Background(), tc.treatmentCenter.ID)
+				s.Require().NoError(err)
+				s.Assert().Equal("Center of Light", got.Name)
+				s.Assert().Equal(15, got.SlotDuration)
+				s.Assert().Equal(3, got.SlotCapacity)
+				s.Require().Len(got.OpeningHours, 1)
+				s.Assert().Equal(time.Saturday, got.OpeningHours[0].Weekday)
+				s.Assert().Empty(got.Closures)
+			}
+		})
 	}
+}
+
+func (s *TreatmentCentersIntegrationTestSuite) TestNearby() {
+	now := time.Date(2021, 11, 13, 10, 30, 0, 0, time.UTC)
+	until := time.Date(2021, 11, 14, 0, 0, 0, 0, time.UTC)
+	centerTwo := uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef")
+	centerOfLight := uuid.MustParse("32b2edf2-a380-4436-9f98-b70f78f1934d")
+	centerForProfit := uuid.MustParse("0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8")
+
+	tests := []struct {
+		name    string
+		search  domain.NearbySearch
+		page    domain.PageRequest
+		wantIDs []uuid.UUID
+	}{
+		{
+			name:    "Radius",
+			search:  domain.NearbySearch{Latitude: 48.8566, Longitude: 2.3522, RadiusKm: 30},
+			wantIDs: []uuid.UUID{centerTwo, centerOfLight},
+		},
+		{
+			name:    "Limit",
+			search:  domain.NearbySearch{Latitude: 48.8, Longitude: 2.1, RadiusKm: 500},
+			page:    domain.PageRequest{Limit: 2},
+			wantIDs: []uuid.UUID{centerOfLight, centerTwo},
+		},
+		{
+			name: "AvailableUntil",
+			search: domain.NearbySearch{Latitude: 48.8566, Longitude: 2.3522, RadiusKm: 500,
+				AvailableUntil: &until},
+			wantIDs: []uuid.UUID{centerTwo, centerForProfit},
+		},
+		{
+			name:   "NoneAround",
+			search: domain.NearbySearch{Latitude: -33.8688, Longitude: 151.2093, RadiusKm: 100},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			got, _, err := s.treatmentCentersRepository.Nearby(context.Background(), tc.search, tc.page, now)
+			s.Require().NoError(err)
+			var ids []uuid.UUID
+			for _, treatmentCenter := range got {
+				ids = append(ids, treatmentCenter.ID)
+			}
+			s.Assert().Equal(tc.wantIDs, ids)
+		})
+	}
+
+	paris := domain.NearbySearch{Latitude: 48.8566, Longitude: 2.3522, RadiusKm: 30}
+	got, page, err := s.treatmentCentersRepository.Nearby(context.Background(), paris, domain.PageRequest{}, now)
+	s.Require().NoError(err)
+	s.Require().Len(got, 2)
+	s.Assert().Equal(int64(2), page.Total)
+	s.Assert().InDelta(0, got[0].DistanceKm, 0.01)
+	s.Require().NotNil(got[0].NextAppointmentID)
+	s.Assert().Equal(uuid.MustParse("96261e44-6d19-4d12-af0b-f28f56f665e2"), *got[0].NextAppointmentID)
+	s.Assert().InDelta(17.9, got[1].DistanceKm, 0.5)
+	s.Assert().Nil(got[1].NextAppointmentID)
+
+	got, page, err = s.treatmentCentersRepository.Nearby(context.Background(), paris,
+		domain.PageRequest{Limit: 1}, now)
+	s.Require().NoError(err)
+	s.Require().Len(got, 1)
+	s.Require().NotNil(page.NextCursor)
+	got, page, err = s.treatmentCentersRepository.Nearby(context.Background(), paris,
+		domain.PageRequest{Limit: 1, Cursor: page.NextCursor}, now)
+	s.Require().NoError(err)
+	s.Require().Len(got, 1)
+	s.Assert().Equal(centerOfLight, got[0].ID)
+	s.Assert().Nil(page.NextCursor)
+}
+
+func TestTreatmentCentersIntegrationTestSuite(t *testing.T) {
 	t.Parallel()
-	suite.Run(t, new(TreatmentCentersIntegrationTestSuite))
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &TreatmentCentersIntegrationTestSuite{IntegrationSuite: backend}
+	})
 }
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"go.uber.org/zap"
+)
+
+// Repositories hands out repositories sharing the transaction of a unit of work
+type Repositories interface {
+	Patients() Patients
+	TreatmentCenters() TreatmentCenters
+	Appointments() Appointments
+	AppointmentBookings() AppointmentBookings
+	VaccineProducts() VaccineProducts
+	Doses() Doses
+	VaccineLots() VaccineLots
+	Waitlist() Waitlist
+	EligibilityRules() EligibilityRules
+}
+
+// UnitOfWork runs the operations spanning several repositories or statements
+// atomically
+type UnitOfWork interface {
+	// Do runs fn in a transaction, it commits the changes made through the
+	// repositories it is given when fn returns nil and rolls them back otherwise.
+	// fn must only use those repositories, the other ones don't take part in the
+	// transaction and may wait for it to end.
+	Do(ctx context.Context, fn func(r Repositories) error) error
+}
+
+type unitOfWork struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewUnitOfWork(db *gorm.DB, logger *zap.Logger) UnitOfWork {
+	return unitOfWork{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (u unitOfWork) Do(ctx context.Context, fn func(r Repositories) error) error {
+	err := transaction(ctx, u.db, func(tx *gorm.DB) error {
+		return fn(NewRepositories(tx, u.logger))
+	})
+	return handleGormError(err, u.logger)
+}
+
+// NewRepositories returns the repositories of the database
+func NewRepositories(db *gorm.DB, logger *zap.Logger) Repositories {
+	return repositories{db: db, logger: logger}
+}
+
+type repositories struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func (r repositories) Patients() Patients {
+	return NewPatients(r.db, r.logger)
+}
+
+func (r repositories) TreatmentCenters() TreatmentCenters {
+	return NewTreatmentCenters(r.db, r.logger)
+}
+
+func (r repositories) Appointments() Appointments {
+	return NewAppointments(r.db, r.logger)
+}
+
+func (r repositories) AppointmentBookings() AppointmentBookings {
+	return NewAppointmentBookings(r.db, r.logger)
+}
+
+func (r repositories) VaccineProducts() VaccineProducts {
+	return NewVaccineProducts(r.db, r.logger)
+}
+
+func (r repositories) Doses() Doses {
+	return NewDoses(r.db, r.logger)
+}
+
+func (r repositories) VaccineLots() VaccineLots {
+	return NewVaccineLots(r.db, r.logger)
+}
+
+func (r repositories) Waitlist() Waitlist {
+	return NewWaitlist(r.db, r.logger)
+}
+
+func (r repositories) EligibilityRules() EligibilityRules {
+	return NewEligibilityRules(r.db, r.logger)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/testutils"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type UnitOfWorkIntegrationTestSuite struct {
+	testutils.IntegrationSuite
+	unitOfWork         UnitOfWork
+	patientsRepository Patients
+	bookingsRepository AppointmentBookings
+}
+
+func (s *UnitOfWorkIntegrationTestSuite) SetupSuite() {
+	s.IntegrationSuite.SetupSuite()
+	s.unitOfWork = NewUnitOfWork(s.IntegrationSuite.DB(), zap.NewExample())
+	s.patientsRepository = NewPatients(s.IntegrationSuite.DB(), zap.NewExample())
+	s.bookingsRepository = NewAppointmentBookings(s.IntegrationSuite.DB(), zap.NewExample())
+}
+
+// SetupTest postpones the fixture appointment the tests book
+func (s *UnitOfWorkIntegrationTestSuite) SetupTest() {
+	s.IntegrationSuite.SetupTest()
+	s.IntegrationSuite.Postpone("appointments", "start_time", "eecce415-2d4c-440d-ac90-9780a3bd3371")
+}
+
+func (s *UnitOfWorkIntegrationTestSuite) TearDownSuite() {
+	s.IntegrationSuite.TearDownSuite()
+}
+
+func (s *UnitOfWorkIntegrationTestSuite) newPatient() *domain.Patient {
+	return &domain.Patient{
+		ID:        uuid.MustParse("d93f7ecc-816f-4124-b41e-dcfa58f03761"),
+		Email:     "patient.zero@some.com",
+		FirstName: "Patient",
+		LastName:  "Zero",
+	}
+}
+
+func (s *UnitOfWorkIntegrationTestSuite) newBooking(patientID uuid.UUID) *domain.AppointmentBooking {
+	return &domain.AppointmentBooking{
+		ID:            uuid.MustParse("755deca6-e171-4478-abfc-fa794830c7e1"),
+		AppointmentID: uuid.MustParse("eecce415-2d4c-440d-ac90-9780a3bd3371"),
+		PatientID:     patientID,
+		Status:        domain.Confirmed,
+	}
+}
+
+func (s *UnitOfWorkIntegrationTestSuite) TestCommit() {
+	patient := s.newPatient()
+	booking := s.newBooking(patient.ID)
+	err := s.unitOfWork.Do(context.Background(), func(r Repositories) error {
+		if err := r.Patients().Create(context.Background(), patient); err != nil {
+			return err
+		}
+		return r.AppointmentBookings().Create(context.Background(), booking)
+	})
+	s.Require().NoError(err)
+
+	_, err = s.patientsRepository.FindByID(context.Background(), patient.ID)
+	s.Assert().NoError(err)
+	_, err = s.bookingsRepository.FindByID(context.Background(), booking.ID)
+	s.Assert().NoError(err)
+}
+
+func (s *UnitOfWorkIntegrationTestSuite) TestRollback() {
+	patient := s.newPatient()
+	booking := s.newBooking(patient.ID)
+	failure := errors.New("failure")
+	err := s.unitOfWork.Do(context.Background(), func(r Repositories) error {
+		if err := r.Patients().Create(context.Background(), patient); err != nil {
+			return err
+		}
+		if err := r.AppointmentBookings().Create(context.Background(), booking); err != nil {
+			return err
+		}
+		return failure
+	})
+	s.Assert().Equal(failure, err)
+
+	_, err = s.patientsRepository.FindByID(context.Background(), patient.ID)
+	s.Assert().Equal(ErrRecordNotFound, err)
+	_, err = s.bookingsRepository.FindByID(context.Background(), booking.ID)
+	s.Assert().Equal(ErrRecordNotFound, err)
+}
+
+func (s *UnitOfWorkIntegrationTestSuite) TestFailedStepGoesOn() {
+	patient := s.newPatient()
+	err := s.unitOfWork.Do(context.Background(), func(r Repositories) error {
+		if err := r.Patients().Create(context.Background(), patient); err != nil {
+			return err
+		}
+		// the failed booking is rolled back alone and the transaction stays usable
+		duplicate := s.newBooking(patient.ID)
+		duplicate.ID = uuid.MustParse("f859ae2c-e24f-46e8-9c27-4431112fc710")
+		s.Assert().Equal(ErrUniqueConstraintFailure, r.AppointmentBookings().Create(context.Background(), duplicate))
+		return r.AppointmentBookings().Create(context.Background(), s.newBooking(patient.ID))
+	})
+	s.Require().NoError(err)
+
+	_, err = s.bookingsRepository.FindByID(context.Background(), s.newBooking(patient.ID).ID)
+	s.Assert().NoError(err)
+}
+
+func TestUnitOfWorkIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &UnitOfWorkIntegrationTestSuite{IntegrationSuite: backend}
+	})
+}
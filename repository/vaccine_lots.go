@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/y9mo/covidvax/domain"
+	"go.uber.org/zap"
+)
+
+type VaccineLots interface {
+	Receive(ctx context.Context, vaccineLot *domain.VaccineLot) error
+	AllByTreatmentCenterID(ctx context.Context, treatmentCenterID uuid.UUID,
+		request domain.PageRequest) ([]*domain.VaccineLot, domain.Page, error)
+}
+type vaccineLots struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewVaccineLots(db *gorm.DB, logger *zap.Logger) VaccineLots {
+	return vaccineLots{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Receive adds a delivered lot to the stock of its treatment center
+func (r vaccineLots) Receive(ctx context.Context, vaccineLot *domain.VaccineLot) error {
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := lockTreatmentCenter(tx, vaccineLot.TreatmentCenterID); err != nil {
+			return err
+		}
+		// gorm inserts a nil time rather than leaving the column to its default
+		if vaccineLot.ReceivedAt == nil {
+			receivedAt := time.Now().UTC()
+			vaccineLot.ReceivedAt = &receivedAt
+		}
+		return tx.Create(vaccineLot).Error
+	})
+	return handleGormError(err, r.logger)
+}
+
+// vaccineLotsSorting sorts the lots by expiration by default, the first to expire first
+var vaccineLotsSorting = sorting{
+	fields: map[string]sortField{
+		"expires_on": {column: "vaccine_lots.expires_on"},
+		"lot_number": {column: "vaccine_lots.lot_number"},
+		"quantity":   {column: "vaccine_lots.quantity"},
+	},
+	idColumn:    "vaccine_lots.id",
+	defaultSort: "expires_on",
+}
+
+// AllByTreatmentCenterID returns the lots of a treatment center
+func (r vaccineLots) AllByTreatmentCenterID(ctx context.Context, treatmentCenterID uuid.UUID,
+	request domain.PageRequest) (result []*domain.VaccineLot, page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	query := db.Model(&domain.VaccineLot{}).Where("treatment_center_id = ?", treatmentCenterID)
+	page, err = paginate(db, query, request, vaccineLotsSorting, &result)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// lockTreatmentCenter serializes the changes of the stock and appointments of a
+// treatment center, it fails with ErrRecordNotFound if the center doesn't exist
+func lockTreatmentCenter(tx *gorm.DB, treatmentCenterID uuid.UUID) error {
+	return forUpdate(tx, "FOR UPDATE").Select("id").
+		Where("id = ?", treatmentCenterID).First(&domain.TreatmentCenter{}).Error
+}
+
+// checkStock fails with ErrInsufficientStock when the seats of the appointments of a
+// treatment center starting from since outnumber the doses of its lots usable on that day
+func checkStock(tx *gorm.DB, treatmentCenterID uuid.UUID, since time.Time) error {
+	var projection struct {
+		Seats int
+		Doses int
+	}
+	err := tx.Raw(`SELECT
+		(SELECT COALESCE(SUM(capacity), 0) FROM appointments
+			WHERE treatment_center_id = ? AND start_time >= ? AND deleted_at IS NULL) AS seats,
+		(SELECT COALESCE(SUM(quantity), 0) FROM vaccine_lots
+			WHERE treatment_center_id = ? AND expires_on >= ?) AS doses`,
+		treatmentCenterID, since, treatmentCenterID, date(since)).Scan(&projection).Error
+	if err != nil {
+		return err
+	}
+	if projection.Seats > projection.Doses {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// useDose takes a dose of a lot of the treatment center of an appointment
+func useDose(tx *gorm.DB, appointmentID uuid.UUID, vaccineProductID uuid.UUID, lotNumber string, at time.Time) error {
+	lot := domain.VaccineLot{}
+	err := forUpdate(tx, "FOR UPDATE").
+		Where("treatment_center_id = (SELECT treatment_center_id FROM appointments WHERE id = ?)", appointmentID).
+		Where("vaccine_product_id = ? AND lot_number = ?", vaccineProductID, lotNumber).
+		First(&lot).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrUnknownLot
+	}
+	if err != nil {
+		return err
+	}
+	if lot.Quantity == 0 || lot.Expired(at) {
+		return ErrLotUnusable
+	}
+	return tx.Model(&lot).UpdateColumn("quantity", gorm.Expr("quantity - 1")).Error
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/testutils"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type VaccineLotsIntegrationTestSuite struct {
+	testutils.IntegrationSuite
+	vaccineLotsRepository  VaccineLots
+	appointmentsRepository Appointments
+}
+
+func (s *VaccineLotsIntegrationTestSuite) SetupSuite() {
+	s.IntegrationSuite.SetupSuite()
+	s.vaccineLotsRepository = NewVaccineLots(s.IntegrationSuite.DB(), zap.NewExample())
+	s.appointmentsRepository = NewAppointments(s.IntegrationSuite.DB(), zap.NewExample())
+}
+
+func (s *VaccineLotsIntegrationTestSuite) TearDownSuite() {
+	s.IntegrationSuite.TearDownSuite()
+}
+
+func (s *VaccineLotsIntegrationTestSuite) TestReceive() {
+	tests := []struct {
+		name       string
+		vaccineLot *domain.VaccineLot
+		wantErr    error
+	}{
+		{
+			name: "Successful",
+			vaccineLot: &domain.VaccineLot{
+				ID:                uuid.MustParse("5d4c3b2a-1908-4f6e-8d7c-c4b5a6f7e8d9"),
+				TreatmentCenterID: uuid.MustParse("0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8"),
+				VaccineProductID:  uuid.MustParse("7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8"),
+				LotNumber:         "FF2593",
+				Quantity:          30,
+				ExpiresOn:         time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "LotAlreadyReceived",
+			vaccineLot: &domain.VaccineLot{
+				ID:                uuid.MustParse("4c3b2a19-08f7-4e5d-8c6b-b5a6f7e8d9c0"),
+				TreatmentCenterID: uuid.MustParse("10063726-d378-472c-9b50-22a48331635d"),
+				VaccineProductID:  uuid.MustParse("7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8"),
+				LotNumber:         "FK5618",
+				Quantity:          30,
+				ExpiresOn:         time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: ErrUniqueConstraintFailure,
+		},
+		{
+			name: "UnknownTreatmentCenter",
+			vaccineLot: &domain.VaccineLot{
+				ID:                uuid.MustParse("3b2a1908-f7e6-4d4c-9b5a-a6f7e8d9c0b1"),
+				TreatmentCenterID: uuid.MustParse("e46e6fff-3cc9-42cd-839d-e0528166b40b"),
+				VaccineProductID:  uuid.MustParse("7a1e2b3c-4d5e-4f60-8172-93a4b5c6d7e8"),
+				LotNumber:         "FF2593",
+				Quantity:          30,
+				ExpiresOn:         time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: ErrRecordNotFound,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			s.Assert().Equal(tc.wantErr, s.vaccineLotsRepository.Receive(context.Background(), tc.vaccineLot))
+		})
+	}
+}
+
+func (s *VaccineLotsIntegrationTestSuite) TestAllByTreatmentCenterID() {
+	r, _, err := s.vaccineLotsRepository.AllByTreatmentCenterID(context.Background(),
+		uuid.MustParse("10063726-d378-472c-9b50-22a48331635d"),
+		domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(r, 2)
+	s.Assert().Equal("EL0140", r[0].LotNumber)
+	s.Assert().Equal("FK5618", r[1].LotNumber)
+	s.Assert().Equal(10, r[1].Quantity)
+}
+
+func (s *VaccineLotsIntegrationTestSuite) TestAppointmentsCappedByStock() {
+	treatmentCenterID := uuid.MustParse("0de6c3f4-2451-4ec2-989c-1b6ee8ba7dc8")
+	appointment := &domain.Appointment{
+		ID:                uuid.MustParse("2a1908f7-e6d5-4c3b-8a4f-f7e8d9c0b1a2"),
+		TreatmentCenterID: treatmentCenterID,
+		StartTime:         time.Date(2099, 1, 4, 9, 0, 0, 0, time.UTC),
+		Capacity:          2,
+	}
+	s.Assert().Equal(ErrInsufficientStock, s.appointmentsRepository.Create(context.Background(), appointment))
+
+	s.Require().NoError(s.vaccineLotsRepository.Receive(context.Background(), &domain.VaccineLot{
+		ID:                uuid.MustParse("19080f7e-6d5c-4b3a-9f4e-e8d9c0b1a2f3"),
+		TreatmentCenterID: treatmentCenterID,
+		VaccineProductID:  uuid.MustParse("0f9e8d7c-6b5a-4493-8271-605f4e3d2c1b"),
+		LotNumber:         "XD961",
+		Quantity:          2,
+		ExpiresOn:         time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC),
+	}))
+	s.Require().NoError(s.appointmentsRepository.Create(context.Background(), appointment))
+
+	_, err := s.appointmentsRepository.CreateMissing(context.Background(), []*domain.Appointment{{
+		ID:                uuid.MustParse("080f7e6d-5c4b-4a39-8e3d-d9c0b1a2f3e4"),
+		TreatmentCenterID: treatmentCenterID,
+		StartTime:         time.Date(2099, 1, 4, 10, 0, 0, 0, time.UTC),
+		Capacity:          1,
+	}})
+	s.Assert().Equal(ErrInsufficientStock, err)
+}
+
+func TestVaccineLotsIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &VaccineLotsIntegrationTestSuite{IntegrationSuite: backend}
+	})
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/y9mo/covidvax/domain"
+	"go.uber.org/zap"
+)
+
+type VaccineProducts interface {
+	Create(ctx context.Context, vaccineProduct *domain.VaccineProduct) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.VaccineProduct, error)
+	All(ctx context.Context, request domain.PageRequest) ([]*domain.VaccineProduct, domain.Page, error)
+}
+type vaccineProducts struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewVaccineProducts(db *gorm.DB, logger *zap.Logger) VaccineProducts {
+	return vaccineProducts{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r vaccineProducts) Create(ctx context.Context, vaccineProduct *domain.VaccineProduct) error {
+	err := withContext(ctx, r.db).Debug().Create(vaccineProduct).Error
+	return handleGormError(err, r.logger)
+}
+
+func (r vaccineProducts) FindByID(ctx context.Context, id uuid.UUID) (*domain.VaccineProduct, error) {
+	vaccineProduct := domain.VaccineProduct{}
+	err := withContext(ctx, r.db).Debug().Where("id = ?", id).Find(&vaccineProduct).Error
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return &vaccineProduct, nil
+}
+
+// vaccineProductsSorting sorts the vaccine products by name by default
+var vaccineProductsSorting = sorting{
+	fields: map[string]sortField{
+		"name":       {column: "vaccine_products.name"},
+		"created_at": {column: "vaccine_products.created_at"},
+	},
+	idColumn:    "vaccine_products.id",
+	defaultSort: "name",
+}
+
+func (r vaccineProducts) All(ctx context.Context, request domain.PageRequest) (result []*domain.VaccineProduct,
+	page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	page, err = paginate(db, db.Model(&domain.VaccineProduct{}), request,
+		vaccineProductsSorting, &result)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
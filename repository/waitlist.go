@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/y9mo/covidvax/domain"
+	"go.uber.org/zap"
+)
+
+type Waitlist interface {
+	Join(ctx context.Context, entry *domain.WaitlistEntry) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.WaitlistEntry, error)
+	AllByPatientID(ctx context.Context, patientID uuid.UUID,
+		request domain.PageRequest) ([]*domain.WaitlistEntry, domain.Page, error)
+	Withdraw(ctx context.Context, id uuid.UUID) error
+	OfferNext(ctx context.Context, appointmentID uuid.UUID, expiresAt time.Time) (*domain.AppointmentBooking, error)
+}
+type waitlist struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewWaitlist(db *gorm.DB, logger *zap.Logger) Waitlist {
+	return waitlist{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Join puts a patient on the waitlist of a treatment center, a patient
+// waits once per treatment center and a deleted center has no waitlist
+func (r waitlist) Join(ctx context.Context, entry *domain.WaitlistEntry) error {
+	entry.Status = domain.Waiting
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := lockTreatmentCenter(tx, entry.TreatmentCenterID); err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+	return handleGormError(err, r.logger)
+}
+
+func (r waitlist) FindByID(ctx context.Context, id uuid.UUID) (*domain.WaitlistEntry, error) {
+	entry := domain.WaitlistEntry{}
+	err := withContext(ctx, r.db).Debug().Where("id = ?", id).Find(&entry).Error
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// waitlistSorting sorts the waitlist entries by creation by default, the most recent first
+var waitlistSorting = sorting{
+	fields: map[string]sortField{
+		"created_at": {column: "waitlist_entries.created_at"},
+		"from_date":  {column: "waitlist_entries.from_date"},
+	},
+	idColumn:         "waitlist_entries.id",
+	defaultSort:      "created_at",
+	defaultDirection: domain.Descending,
+}
+
+// AllByPatientID returns the waitlist entries of a patient
+func (r waitlist) AllByPatientID(ctx context.Context, patientID uuid.UUID,
+	request domain.PageRequest) (result []*domain.WaitlistEntry, page domain.Page, err error) {
+	db := withContext(ctx, r.db).Debug()
+	query := db.Model(&domain.WaitlistEntry{}).Where("patient_id = ?", patientID)
+	page, err = paginate(db, query, request, waitlistSorting, &result)
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, page, err
+	}
+	return result, page, nil
+}
+
+// Withdraw takes a waiting patient off the waitlist, any other status
+// will return ErrInvalidStatusTransition
+func (r waitlist) Withdraw(ctx context.Context, id uuid.UUID) error {
+	result := withContext(ctx, r.db).Debug().Model(&domain.WaitlistEntry{}).
+		Where("id = ? AND status = ?", id, domain.Waiting).
+		Update("status", domain.Withdrawn)
+	err := handleGormError(result.Error, r.logger)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		if _, err = r.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrInvalidStatusTransition
+	}
+	return nil
+}
+
+// OfferNext books a free seat of an upcoming appointment for the first patient
+// waiting for its treatment center and date, the booking awaits confirmation until
+// expiresAt. The patients with an active booking at the treatment center, whose
+// vaccine schedule doesn't allow the date or who don't meet the eligibility rules
+// in effect are skipped. No booking is returned when
+// the appointment is full or nobody is eligible.
+func (r waitlist) OfferNext(ctx context.Context, appointmentID uuid.UUID,
+	expiresAt time.Time) (*domain.AppointmentBooking, error) {
+	var offered *domain.AppointmentBooking
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		appointment := domain.Appointment{}
+		err := tx.Where("id = ?", appointmentID).First(&appointment).Error
+		if err != nil {
+			return err
+		}
+		if !appointment.StartTime.After(time.Now()) {
+			return nil
+		}
+
+		day := date(appointment.StartTime.UTC())
+		var entries []*domain.WaitlistEntry
+		err = forUpdate(tx, "FOR UPDATE SKIP LOCKED").
+			Where("treatment_center_id = ? AND status = ?", appointment.TreatmentCenterID, domain.Waiting).
+			Where("from_date <= ? AND to_date >= ?", day, day).
+			Where(`NOT EXISTS (SELECT 1 FROM appointment_bookings b
+				JOIN appointments a ON a.id = b.appointment_id
+				WHERE b.patient_id = waitlist_entries.patient_id
+				AND a.treatment_center_id = waitlist_entries.treatment_center_id
+				AND b.status IN (?))`, domain.ActiveStatuses).
+			Order("created_at, id").Find(&entries).Error
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			booking := &domain.AppointmentBooking{
+				ID:            uuid.New(),
+				AppointmentID: appointmentID,
+				PatientID:     entry.PatientID,
+				Status:        domain.AwaitingConfirmation,
+				ExpiresAt:     &expiresAt,
+			}
+			err = book(tx, booking)
+			if _, ineligible := err.(*domain.IneligibleError); ineligible {
+				continue
+			}
+			switch err {
+			case nil:
+			case ErrAppointmentNotAvailable:
+				return nil
+			case ErrUniqueConstraintFailure, domain.ErrDoseTooEarly, domain.ErrDoseTooLate, domain.ErrSeriesCompleted:
+				continue
+			default:
+				return err
+			}
+
+			err = tx.Model(entry).Updates(map[string]interface{}{
+				"status":                 domain.Offered,
+				"appointment_booking_id": booking.ID,
+				"offered_at":             time.Now().UTC(),
+			}).Error
+			if err != nil {
+				return err
+			}
+			offered = booking
+			return nil
+		}
+		return nil
+	})
+	err = handleGormError(err, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	return offered, nil
+}
+
+// settleOffers closes the waitlist entries offered the bookings, an accepted
+// offer is fulfilled and a released one lapses
+func settleOffers(tx *gorm.DB, bookingIDs []uuid.UUID, status domain.WaitlistStatus) error {
+	if len(bookingIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&domain.WaitlistEntry{}).
+		Where("appointment_booking_id IN (?) AND status = ?", bookingIDs, domain.Offered).
+		Update("status", status).Error
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/testutils"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type WaitlistIntegrationTestSuite struct {
+	testutils.IntegrationSuite
+	waitlistRepository            Waitlist
+	appointmentsRepository        Appointments
+	appointmentBookingsRepository AppointmentBookings
+}
+
+func (s *WaitlistIntegrationTestSuite) SetupSuite() {
+	s.IntegrationSuite.SetupSuite()
+	s.waitlistRepository = NewWaitlist(s.IntegrationSuite.DB(), zap.NewExample())
+	s.appointmentsRepository = NewAppointments(s.IntegrationSuite.DB(), zap.NewExample())
+	s.appointmentBookingsRepository = NewAppointmentBookings(s.IntegrationSuite.DB(), zap.NewExample())
+}
+
+func (s *WaitlistIntegrationTestSuite) TearDownSuite() {
+	s.IntegrationSuite.TearDownSuite()
+}
+
+func (s *WaitlistIntegrationTestSuite) TestJoin() {
+	tests := []struct {
+		name    string
+		entry   *domain.WaitlistEntry
+		wantErr error
+	}{
+		{
+			name: "Successful",
+			entry: &domain.WaitlistEntry{
+				ID:                uuid.MustParse("4f5a6b7c-8d9e-4fa0-b1c2-d3e4f5a6b7c8"),
+				PatientID:         uuid.MustParse("6f0bd1a3-7d0a-4f5e-9a61-2b1c3d4e5f60"),
+				TreatmentCenterID: uuid.MustParse("10063726-d378-472c-9b50-22a48331635d"),
+				FromDate:          time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC),
+				ToDate:            time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "AlreadyWaiting",
+			entry: &domain.WaitlistEntry{
+				ID:                uuid.MustParse("5a6b7c8d-9eaf-40b1-82c3-e4f5a6b7c8d9"),
+				PatientID:         uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+				TreatmentCenterID: uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
+				FromDate:          time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC),
+				ToDate:            time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: ErrUniqueConstraintFailure,
+		},
+		{
+			name: "UnknownPatient",
+			entry: &domain.WaitlistEntry{
+				ID:                uuid.MustParse("6b7c8d9e-afb0-41c2-93d4-f5a6b7c8d9e0"),
+				PatientID:         uuid.MustParse("3dc1ba24-9e41-4c5a-b73f-79d9d4c2ba0e"),
+				TreatmentCenterID: uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
+				FromDate:          time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC),
+				ToDate:            time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: ErrInvalidID,
+		},
+		{
+			name: "UnknownTreatmentCenter",
+			entry: &domain.WaitlistEntry{
+				ID:                uuid.MustParse("7c8d9eaf-b0c1-42d3-a4e5-a6b7c8d9e0f1"),
+				PatientID:         uuid.MustParse("6f0bd1a3-7d0a-4f5e-9a61-2b1c3d4e5f60"),
+				TreatmentCenterID: uuid.MustParse("3dc1ba24-9e41-4c5a-b73f-79d9d4c2ba0e"),
+				FromDate:          time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC),
+				ToDate:            time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: ErrRecordNotFound,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		s.Run(tc.name, func() {
+			err := s.waitlistRepository.Join(context.Background(), tc.entry)
+			s.Assert().Equal(tc.wantErr, err)
+			if err == nil {
+				s.Assert().Equal(domain.Waiting, tc.entry.Status)
+			}
+		})
+	}
+}
+
+func (s *WaitlistIntegrationTestSuite) TestWithdraw() {
+	id := uuid.MustParse("2d3e4f5a-6b7c-4d8e-9fa0-b1c2d3e4f5a6")
+	s.Require().NoError(s.waitlistRepository.Withdraw(context.Background(), id))
+	s.Assert().Equal(ErrInvalidStatusTransition, s.waitlistRepository.Withdraw(context.Background(), id))
+	s.Assert().Equal(ErrRecordNotFound, s.waitlistRepository.Withdraw(context.Background(),
+		uuid.MustParse("3dc1ba24-9e41-4c5a-b73f-79d9d4c2ba0e")))
+
+	entries, _, err := s.waitlistRepository.AllByPatientID(context.Background(),
+		uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"),
+		domain.PageRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(entries, 1)
+	s.Assert().Equal(domain.Withdrawn, entries[0].Status)
+}
+
+func (s *WaitlistIntegrationTestSuite) TestOfferNext() {
+	past, err := s.waitlistRepository.OfferNext(context.Background(),
+		uuid.MustParse("eab38294-8b76-410c-8058-3e152e64dced"),
+		time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+	s.Assert().Nil(past)
+
+	appointment := &domain.Appointment{
+		ID:                uuid.MustParse("7c8d9eaf-b0c1-42d3-a4e5-a6b7c8d9e0f1"),
+		TreatmentCenterID: uuid.MustParse("52b2edf2-a380-4436-9f98-b70f78f174ef"),
+		StartTime:         time.Date(2099, 1, 5, 9, 0, 0, 0, time.UTC),
+		Capacity:          2,
+	}
+	s.Require().NoError(s.appointmentsRepository.Create(context.Background(), appointment))
+
+	// the first patient of the waitlist is too late for the next dose and is skipped
+	first, err := s.waitlistRepository.OfferNext(context.Background(), appointment.ID, time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+	s.Require().NotNil(first)
+	s.Assert().Equal(uuid.MustParse("98953c1f-e91e-494e-8935-1904fb6bb33a"), first.PatientID)
+	s.Assert().Equal(domain.AwaitingConfirmation, first.Status)
+
+	second, err := s.waitlistRepository.OfferNext(context.Background(), appointment.ID, time.Now().Add(-time.Minute))
+	s.Require().NoError(err)
+	s.Require().NotNil(second)
+	s.Assert().Equal(uuid.MustParse("8152fcbe-3228-46c9-b483-edcb6317d99c"), second.PatientID)
+
+	full, err := s.waitlistRepository.OfferNext(context.Background(), appointment.ID, time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+	s.Assert().Nil(full)
+
+	s.Require().NoError(s.appointmentBookingsRepository.Confirm(context.Background(), first.ID))
+	_, err = s.appointmentBookingsRepository.ExpireUnconfirmed(context.Background(),
+		time.Now(), domain.ReleaseReasonUnconfirmed)
+	s.Require().NoError(err)
+
+	entry, err := s.waitlistRepository.FindByID(context.Background(),
+		uuid.MustParse("2d3e4f5a-6b7c-4d8e-9fa0-b1c2d3e4f5a6"))
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.Fulfilled, entry.Status)
+	s.Assert().Equal(&first.ID, entry.AppointmentBookingID)
+	s.Assert().NotNil(entry.OfferedAt)
+
+	entry, err = s.waitlistRepository.FindByID(context.Background(),
+		uuid.MustParse("3e4f5a6b-7c8d-4e9f-a0b1-c2d3e4f5a6b7"))
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.Lapsed, entry.Status)
+
+	// the seat of the lapsed offer has nobody left to go to
+	next, err := s.waitlistRepository.OfferNext(context.Background(), appointment.ID, time.Now().Add(time.Hour))
+	s.Require().NoError(err)
+	s.Assert().Nil(next)
+}
+
+func TestWaitlistIntegrationTestSuite(t *testing.T) {
+	t.Parallel()
+	runOnBackends(t, func(backend testutils.IntegrationSuite) suite.TestingSuite {
+		return &WaitlistIntegrationTestSuite{IntegrationSuite: backend}
+	})
+}
@@ -0,0 +1,91 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidSlotDuration = errors.New("invalid slot duration")
+var ErrInvalidRange = errors.New("invalid date range")
+
+// maxRange bounds the number of days generated at once
+const maxRange = 366 * 24 * time.Hour
+
+// Generator materializes the appointments of treatment centers
+// from their opening hours
+type Generator struct {
+	treatmentCentersRepository repository.TreatmentCenters
+	appointmentsRepository     repository.Appointments
+	logger                     *zap.Logger
+}
+
+func NewGenerator(treatmentCentersRepository repository.TreatmentCenters,
+	appointmentsRepository repository.Appointments,
+	logger *zap.Logger) *Generator {
+	return &Generator{
+		treatmentCentersRepository: treatmentCentersRepository,
+		appointmentsRepository:     appointmentsRepository,
+		logger:                     logger.With(zap.String("component", "Generator")),
+	}
+}
+
+// In returns a generator creating the appointments with the repositories of a
+// unit of work
+func (g *Generator) In(r repository.Repositories) *Generator {
+	return &Generator{
+		treatmentCentersRepository: r.TreatmentCenters(),
+		appointmentsRepository:     r.Appointments(),
+		logger:                     g.logger,
+	}
+}
+
+// Generate creates the appointments of a treatment center for the days from
+// the first to the last one included, the slots which already exist are skipped
+// so it can be run several times over the same range
+func (g *Generator) Generate(ctx context.Context, treatmentCenterID uuid.UUID,
+	first, last time.Time) ([]*domain.Appointment, error) {
+	if last.Before(first) || last.Sub(first) > maxRange {
+		return nil, ErrInvalidRange
+	}
+	center, err := g.treatmentCentersRepository.FindByID(ctx, treatmentCenterID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := center.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	from := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+	to := time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, loc)
+	slots, err := Slots(center, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	appointments := make([]*domain.Appointment, 0, len(slots))
+	for _, start := range slots {
+		appointments = append(appointments, &domain.Appointment{
+			ID:                uuid.New(),
+			TreatmentCenterID: center.ID,
+			StartTime:         start,
+			Capacity:          center.SlotCapacity,
+		})
+	}
+
+	created, err := g.appointmentsRepository.CreateMissing(ctx, appointments)
+	if err != nil {
+		return nil, err
+	}
+	g.logger.Info("appointments generated",
+		zap.Stringer("treatment_center_id", center.ID),
+		zap.Int("slots", len(slots)),
+		zap.Int("created", len(created)))
+	return created, nil
+}
@@ -0,0 +1,59 @@
+package scheduling
+
+import (
+	"time"
+
+	"github.com/y9mo/covidvax/domain"
+)
+
+// Slots returns the start time of every slot a treatment center opens
+// in [from, to), following its opening hours and skipping its closures
+func Slots(center *domain.TreatmentCenter, from, to time.Time) ([]time.Time, error) {
+	loc, err := center.Location()
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Duration(center.SlotDuration) * time.Minute
+	if duration <= 0 {
+		return nil, ErrInvalidSlotDuration
+	}
+
+	closed := make(map[string]bool, len(center.Closures))
+	for _, closure := range center.Closures {
+		closed[closure.Date.Format(dateLayout)] = true
+	}
+
+	var slots []time.Time
+	for day := startOfDay(from.In(loc)); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if closed[day.Format(dateLayout)] {
+			continue
+		}
+		for _, hours := range center.OpeningHours {
+			if hours.Weekday != day.Weekday() {
+				continue
+			}
+			opens, err := hours.OpensAt.Duration()
+			if err != nil {
+				return nil, err
+			}
+			closes, err := hours.ClosesAt.Duration()
+			if err != nil {
+				return nil, err
+			}
+			for offset := opens; offset+duration <= closes; offset += duration {
+				// built from the wall clock so daylight saving changes are honored
+				start := time.Date(day.Year(), day.Month(), day.Day(), 0, int(offset/time.Minute), 0, 0, loc)
+				if !start.Before(from) && start.Before(to) {
+					slots = append(slots, start.UTC())
+				}
+			}
+		}
+	}
+	return slots, nil
+}
+
+const dateLayout = "2006-01-02"
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,78 @@
+package scheduling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y9mo/covidvax/domain"
+)
+
+func TestSlots(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+
+	center := &domain.TreatmentCenter{
+		Timezone:     "Europe/Paris",
+		SlotDuration: 60,
+		OpeningHours: []*domain.OpeningHours{
+			{Weekday: time.Monday, OpensAt: "08:00", ClosesAt: "10:30"},
+			{Weekday: time.Tuesday, OpensAt: "14:00", ClosesAt: "15:00"},
+		},
+		Closures: []*domain.Closure{
+			{Date: time.Date(2021, 12, 21, 0, 0, 0, 0, time.UTC), Reason: "Winter holiday"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		center  *domain.TreatmentCenter
+		from    time.Time
+		to      time.Time
+		want    []time.Time
+		wantErr error
+	}{
+		{
+			name:   "Week",
+			center: center,
+			from:   time.Date(2021, 12, 13, 0, 0, 0, 0, paris),
+			to:     time.Date(2021, 12, 20, 0, 0, 0, 0, paris),
+			want: []time.Time{
+				time.Date(2021, 12, 13, 7, 0, 0, 0, time.UTC),
+				time.Date(2021, 12, 13, 8, 0, 0, 0, time.UTC),
+				time.Date(2021, 12, 14, 13, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "Closure",
+			center: center,
+			from:   time.Date(2021, 12, 21, 0, 0, 0, 0, paris),
+			to:     time.Date(2021, 12, 22, 0, 0, 0, 0, paris),
+			want:   nil,
+		},
+		{
+			name:   "PartialRange",
+			center: center,
+			from:   time.Date(2021, 12, 13, 9, 0, 0, 0, paris),
+			to:     time.Date(2021, 12, 14, 0, 0, 0, 0, paris),
+			want: []time.Time{
+				time.Date(2021, 12, 13, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "InvalidSlotDuration",
+			center:  &domain.TreatmentCenter{},
+			from:    time.Date(2021, 12, 13, 0, 0, 0, 0, paris),
+			to:      time.Date(2021, 12, 20, 0, 0, 0, 0, paris),
+			wantErr: ErrInvalidSlotDuration,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Slots(tc.center, tc.from, tc.to)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
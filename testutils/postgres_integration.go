@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -20,8 +21,11 @@ import (
 	"github.com/stretchr/testify/suite"
 )
 
+// IntegrationSuite runs against a Postgres container unless Open is set, it then
+// opens the migrated database of the suite, e.g. an in-memory SQLite database
 type IntegrationSuite struct {
 	suite.Suite
+	Open              func() (*gorm.DB, error)
 	postgresContainer *postgresContainer
 	db                *gorm.DB
 	fixtures          *testfixtures.Loader
@@ -76,31 +80,51 @@ func (s *IntegrationSuite) DB() *gorm.DB {
 	return s.db
 }
 
-func (s *IntegrationSuite) ApplyMigrations() {
+// IsSQLite tells whether the suite runs against SQLite rather than Postgres
+func (s *IntegrationSuite) IsSQLite() bool {
+	return s.db.Dialect().GetName() == "sqlite3"
+}
+
+func (s *IntegrationSuite) migrations() *migrate.Migrate {
 	driver, err := postgres.WithInstance(s.db.DB(), &postgres.Config{})
 	if err != nil {
 		log.Fatalf("error while creating postgres-migrate: %s", err)
 	}
-	migrate, err := migrate.NewWithDatabaseInstance(
+	migrations, err := migrate.NewWithDatabaseInstance(
 		"file://../db/migrations",
 		"postgres", driver)
 	if err != nil {
 		log.Fatalf("error while creating migrate instance: %s", err)
 	}
-	err = migrate.Up()
+	return migrations
+}
+
+func (s *IntegrationSuite) ApplyMigrations() {
+	err := s.migrations().Up()
 	if err != nil {
 		log.Fatalf("error while applying migration: %s", err)
 	}
 }
 
+// MigrateTo applies or reverts the migrations until the version, the tests of a
+// migration seed the database at the version before it
+func (s *IntegrationSuite) MigrateTo(version uint) {
+	s.Require().NoError(s.migrations().Migrate(version))
+}
+
 func (s *IntegrationSuite) SetupFixtures() {
-	var err error
-	s.fixtures, err = testfixtures.New(
+	options := []func(*testfixtures.Loader) error{
 		testfixtures.Database(s.db.DB()),
-		testfixtures.Dialect("postgres"),
 		testfixtures.Directory("../testdata/fixtures"),
-		testfixtures.UseAlterConstraint(),
-	)
+	}
+	if s.IsSQLite() {
+		// an in-memory database has no name telling it is a test one
+		options = append(options, testfixtures.Dialect("sqlite"), testfixtures.DangerousSkipTestDatabaseCheck())
+	} else {
+		options = append(options, testfixtures.Dialect("postgres"), testfixtures.UseAlterConstraint())
+	}
+	var err error
+	s.fixtures, err = testfixtures.New(options...)
 	s.Require().NoError(err)
 }
 
@@ -108,10 +132,39 @@ func (s *IntegrationSuite) LoadDatabaseWithFixtures() {
 	s.Require().NoError(s.fixtures.Load())
 }
 
+// tables are the tables the tests fill, the referencing ones first
+var tables = []string{"eligibility_rules", "booking_reminders", "waitlist_entries", "doses", "vaccine_lots",
+	"vaccine_products", "appointment_bookings", "appointments", "treatment_center_opening_hours",
+	"treatment_center_closures", "treatment_centers", "patients"}
+
+// fixturesEpoch is the day of the earliest fixture date
+var fixturesEpoch = time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+
+// Postpone moves the column dates of fixture rows of table past now. Every row
+// moves by the same number of days, a fixture appointment postponed along with
+// the doses of its patient still comes as early after them.
+func (s *IntegrationSuite) Postpone(table, column string, ids ...string) {
+	days := int(time.Since(fixturesEpoch).Hours()/24) + 1
+	for _, id := range ids {
+		var at time.Time
+		s.Require().NoError(s.db.Table(table).Where("id = ?", id).Select(column).Row().Scan(&at))
+		err := s.db.Table(table).Where("id = ?", id).UpdateColumn(column, at.AddDate(0, 0, days)).Error
+		s.Require().NoError(err)
+	}
+}
+
 func (s *IntegrationSuite) Cleanup() {
-	truncateQuery := `TRUNCATE TABLE appointment_bookings, appointments, treatment_centers, patients;`
+	if s.IsSQLite() {
+		// SQLite has no TRUNCATE
+		for _, table := range tables {
+			if err := s.db.Exec("DELETE FROM " + table).Error; err != nil {
+				log.Fatal("impossible to cleanup DB", err)
+			}
+		}
+		return
+	}
 
-	err := s.db.Exec(truncateQuery).Error
+	err := s.db.Exec("TRUNCATE TABLE " + strings.Join(tables, ", ")).Error
 	if err != nil {
 		log.Fatal("impossible to cleanup DB", err)
 	}
@@ -126,6 +179,13 @@ func (s *IntegrationSuite) TearDownTest() {
 }
 
 func (s *IntegrationSuite) SetupSuite() {
+	if s.Open != nil {
+		var err error
+		s.db, err = s.Open()
+		s.Require().NoError(err)
+		s.SetupFixtures()
+		return
+	}
 	s.SetupPostgres(context.Background())
 	s.InitSQLClient()
 	s.ApplyMigrations()
@@ -133,5 +193,9 @@ func (s *IntegrationSuite) SetupSuite() {
 }
 
 func (s *IntegrationSuite) TearDownSuite() {
+	if s.Open != nil {
+		_ = s.db.Close()
+		return
+	}
 	s.PurgeContainer()
 }
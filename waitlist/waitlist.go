@@ -0,0 +1,86 @@
+package waitlist
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/y9mo/covidvax/confirmation"
+	"github.com/y9mo/covidvax/domain"
+	"github.com/y9mo/covidvax/repository"
+	"go.uber.org/zap"
+)
+
+// Service offers the seats freed or opened at a treatment center to the
+// patients of its waitlist, an offer is a booking held until the confirmation
+// deadline, when it isn't accepted in time the seat goes to the next patient
+type Service struct {
+	waitlistRepository repository.Waitlist
+	confirmations      *confirmation.Service
+	logger             *zap.Logger
+}
+
+func NewService(
+	waitlistRepository repository.Waitlist,
+	confirmations *confirmation.Service,
+	logger *zap.Logger) *Service {
+	return &Service{
+		waitlistRepository: waitlistRepository,
+		confirmations:      confirmations,
+		logger:             logger.With(zap.String("component", "WaitlistService")),
+	}
+}
+
+// In returns a service holding the offers with the repositories of a unit of
+// work, so they are committed or rolled back with the seats they take
+func (s *Service) In(r repository.Repositories) *Service {
+	return &Service{
+		waitlistRepository: r.Waitlist(),
+		confirmations:      s.confirmations,
+		logger:             s.logger,
+	}
+}
+
+// Offer offers every free seat of the appointments to the waitlisted
+// patients and returns the bookings offered, failures are logged
+func (s *Service) Offer(ctx context.Context, appointmentIDs ...uuid.UUID) []*domain.AppointmentBooking {
+	offered := s.Hold(ctx, appointmentIDs...)
+	s.Notify(ctx, offered)
+	return offered
+}
+
+// Hold books every free seat of the appointments for the waitlisted patients
+// without telling them, failures are logged
+func (s *Service) Hold(ctx context.Context, appointmentIDs ...uuid.UUID) []*domain.AppointmentBooking {
+	var offered []*domain.AppointmentBooking
+	for _, appointmentID := range appointmentIDs {
+		for {
+			booking, err := s.waitlistRepository.OfferNext(ctx, appointmentID, s.confirmations.Deadline())
+			if err != nil {
+				s.logger.Error("unable to offer appointment",
+					zap.Stringer("appointment_id", appointmentID), zap.Error(err))
+				break
+			}
+			if booking == nil {
+				break
+			}
+			s.logger.Info("appointment offered",
+				zap.Stringer("appointment_id", appointmentID),
+				zap.Stringer("booking_id", booking.ID),
+				zap.Stringer("patient_id", booking.PatientID))
+			offered = append(offered, booking)
+		}
+	}
+	return offered
+}
+
+// Notify emails the patients the bookings held for them, once the unit of work
+// holding them is committed
+func (s *Service) Notify(ctx context.Context, offered []*domain.AppointmentBooking) {
+	for _, booking := range offered {
+		// an offer the patient doesn't hear about lapses like any unconfirmed booking
+		if err := s.confirmations.Offer(ctx, booking); err != nil {
+			s.logger.Error("unable to send appointment offer",
+				zap.Stringer("booking_id", booking.ID), zap.Error(err))
+		}
+	}
+}